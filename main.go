@@ -1,47 +1,416 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/loag/mcp-server-test/mcp"
 	"github.com/loag/mcp-server-test/server"
+	"golang.org/x/crypto/acme/autocert"
 )
 
+// defaultShutdownGracePeriod is how long in-flight requests may take to finish on shutdown
+const defaultShutdownGracePeriod = 30 * time.Second
+
+// defaultAutocertCache is where certificates obtained from Let's Encrypt are kept
+const defaultAutocertCache = ".autocert-cache"
+
+// parseOrigins splits a comma-separated list of origins
+func parseOrigins(origins string) []string {
+	var list []string
+	for _, origin := range strings.Split(origins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			list = append(list, origin)
+		}
+	}
+	return list
+}
+
+// corsConfig allows cross-origin requests from a comma-separated list of
+// origins, or from any origin if the list is empty
+func corsConfig(origins string) middleware.CORSConfig {
+	allowOrigins := parseOrigins(origins)
+	if len(allowOrigins) == 0 {
+		allowOrigins = []string{"*"}
+	}
+
+	return middleware.CORSConfig{
+		AllowOrigins:  allowOrigins,
+		AllowMethods:  []string{http.MethodGet, http.MethodPost},
+		ExposeHeaders: []string{echo.HeaderXRequestID},
+	}
+}
+
 func main() {
-	// Create a new Echo instance
+	// Log everything as JSON lines on standard output
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+	// Create a new Echo instance. Its startup messages are replaced by ours.
 	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+
+	// Add middleware. MCP requests are logged by the server itself.
+	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
+		LogErrorFunc: func(c echo.Context, err error, stack []byte) error {
+			logger.Error("panic", slog.String("error", err.Error()), slog.String("stack", string(stack)))
+			return err
+		},
+	}))
+	e.Use(middleware.CORSWithConfig(corsConfig(os.Getenv("MCP_CORS_ORIGINS"))))
+
+	// Configure the filesystem provider
+	var fsOptions []mcp.FilesystemOption
+	if mountList := os.Getenv("MCP_MOUNTS"); mountList != "" {
+		mounts := make(map[string]string)
+		for _, mount := range strings.Split(mountList, ",") {
+			name, rootDir, ok := strings.Cut(mount, "=")
+			if !ok || name == "" || rootDir == "" || strings.Contains(name, ":") {
+				logger.Error("Invalid MCP_MOUNTS entry: expected name=directory", slog.String("entry", mount))
+				os.Exit(1)
+			}
+			mounts[name] = rootDir
+		}
+		fsOptions = append(fsOptions, mcp.WithMounts(mounts))
+	}
+	if readOnly := os.Getenv("MCP_READ_ONLY"); readOnly != "" {
+		enabled, err := strconv.ParseBool(readOnly)
+		if err != nil {
+			logger.Error("Invalid MCP_READ_ONLY value", slog.String("value", readOnly), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		fsOptions = append(fsOptions, mcp.WithReadOnly(enabled))
+	}
+	if extensions := os.Getenv("MCP_ALLOWED_EXTENSIONS"); extensions != "" {
+		fsOptions = append(fsOptions, mcp.WithAllowedExtensions(strings.Split(extensions, ",")...))
+	}
+	if encoding := os.Getenv("MCP_DEFAULT_ENCODING"); encoding != "" {
+		switch encoding {
+		case mcp.EncodingText, mcp.EncodingBase64, mcp.EncodingAuto, mcp.EncodingDataURL:
+		default:
+			logger.Error("Invalid MCP_DEFAULT_ENCODING value: must be text, base64, auto or data_url", slog.String("value", encoding))
+			os.Exit(1)
+		}
+		fsOptions = append(fsOptions, mcp.WithDefaultEncoding(encoding))
+	}
+	if maxReadBytes := os.Getenv("MCP_MAX_READ_BYTES"); maxReadBytes != "" {
+		limit, err := strconv.ParseInt(maxReadBytes, 10, 64)
+		if err != nil {
+			logger.Error("Invalid MCP_MAX_READ_BYTES value", slog.String("value", maxReadBytes), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		fsOptions = append(fsOptions, mcp.WithMaxReadBytes(limit))
+	}
+	if maxWriteBytes := os.Getenv("MCP_MAX_WRITE_BYTES"); maxWriteBytes != "" {
+		limit, err := strconv.ParseInt(maxWriteBytes, 10, 64)
+		if err != nil {
+			logger.Error("Invalid MCP_MAX_WRITE_BYTES value", slog.String("value", maxWriteBytes), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		fsOptions = append(fsOptions, mcp.WithMaxWriteBytes(limit))
+	}
+	if trashDir := os.Getenv("MCP_TRASH_DIR"); trashDir != "" {
+		fsOptions = append(fsOptions, mcp.WithTrashDir(trashDir))
+	}
+	if protectedPaths := os.Getenv("MCP_PROTECTED_PATHS"); protectedPaths != "" {
+		fsOptions = append(fsOptions, mcp.WithProtectedPaths(strings.Split(protectedPaths, ",")...))
+	}
+	if internalErrors := os.Getenv("MCP_INTERNAL_ERRORS"); internalErrors != "" {
+		enabled, err := strconv.ParseBool(internalErrors)
+		if err != nil {
+			logger.Error("Invalid MCP_INTERNAL_ERRORS value", slog.String("value", internalErrors), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		fsOptions = append(fsOptions, mcp.WithInternalErrors(enabled))
+	}
+	if maxWatchers := os.Getenv("MCP_MAX_WATCHERS"); maxWatchers != "" {
+		limit, err := strconv.Atoi(maxWatchers)
+		if err != nil {
+			logger.Error("Invalid MCP_MAX_WATCHERS value", slog.String("value", maxWatchers), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		fsOptions = append(fsOptions, mcp.WithMaxWatchers(limit))
+	}
+	if maxConcurrency := os.Getenv("MCP_MAX_CONCURRENCY"); maxConcurrency != "" {
+		limit, err := strconv.Atoi(maxConcurrency)
+		if err != nil {
+			logger.Error("Invalid MCP_MAX_CONCURRENCY value", slog.String("value", maxConcurrency), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		var waitTimeout time.Duration
+		if wait := os.Getenv("MCP_CONCURRENCY_WAIT"); wait != "" {
+			waitTimeout, err = time.ParseDuration(wait)
+			if err != nil {
+				logger.Error("Invalid MCP_CONCURRENCY_WAIT value", slog.String("value", wait), slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+		}
+		fsOptions = append(fsOptions, mcp.WithMaxConcurrency(limit, waitTimeout))
+	}
+	if cacheBytes := os.Getenv("MCP_READ_CACHE_BYTES"); cacheBytes != "" {
+		maxBytes, err := strconv.ParseInt(cacheBytes, 10, 64)
+		if err != nil {
+			logger.Error("Invalid MCP_READ_CACHE_BYTES value", slog.String("value", cacheBytes), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		maxEntries := 0
+		if cacheEntries := os.Getenv("MCP_READ_CACHE_ENTRIES"); cacheEntries != "" {
+			maxEntries, err = strconv.Atoi(cacheEntries)
+			if err != nil {
+				logger.Error("Invalid MCP_READ_CACHE_ENTRIES value", slog.String("value", cacheEntries), slog.String("error", err.Error()))
+				os.Exit(1)
+			}
+		}
+		if maxBytes > 0 {
+			fsOptions = append(fsOptions, mcp.WithReadCache(maxEntries, maxBytes))
+		}
+	}
+
+	// Create the MCP server with the filesystem tools, from the config file if
+	// there is one. Its settings take precedence over environment variables.
+	var config *server.Config
+	var mcpServer *server.MCPServer
+	if configPath := os.Getenv("MCP_CONFIG"); configPath != "" {
+		var err error
+		config, err = server.LoadConfig(configPath)
+		if err != nil {
+			logger.Error("Failed to load config", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		mcpServer, err = server.NewMCPServerFromConfig(config, fsOptions...)
+		if err != nil {
+			logger.Error("Failed to create server", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	} else {
+		mcpServer = server.NewMCPServer(
+			"Filesystem MCP Server",
+			"1.0.0",
+			"A Model Context Protocol server implementation that provides access to the local file system",
+		)
+		if err := mcpServer.RegisterProvider(mcp.NewFilesystemProvider(fsOptions...)); err != nil {
+			logger.Error("Failed to register provider", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	}
+	mcpServer.Logger = logger
+	mcpServer.AllowedOrigins = parseOrigins(os.Getenv("MCP_CORS_ORIGINS"))
+
+	// Configure the request timeout
+	if timeout := os.Getenv("MCP_REQUEST_TIMEOUT"); timeout != "" {
+		duration, err := time.ParseDuration(timeout)
+		if err != nil {
+			logger.Error("Invalid MCP_REQUEST_TIMEOUT value", slog.String("value", timeout), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		mcpServer.RequestTimeout = duration
+	}
 
-	// Add middleware
-	e.Use(middleware.Logger())
-	e.Use(middleware.Recover())
-	e.Use(middleware.CORS())
+	// Configure how many calls of a batch run in parallel
+	if concurrency := os.Getenv("MCP_BATCH_CONCURRENCY"); concurrency != "" {
+		value, err := strconv.Atoi(concurrency)
+		if err != nil {
+			logger.Error("Invalid MCP_BATCH_CONCURRENCY value", slog.String("value", concurrency), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		mcpServer.BatchConcurrency = value
+	}
+
+	// Configure how long results of calls with an idempotency key are kept
+	if ttl := os.Getenv("MCP_IDEMPOTENCY_TTL"); ttl != "" {
+		duration, err := time.ParseDuration(ttl)
+		if err != nil {
+			logger.Error("Invalid MCP_IDEMPOTENCY_TTL value", slog.String("value", ttl), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		mcpServer.IdempotencyTTL = duration
+	}
+	if keys := os.Getenv("MCP_IDEMPOTENCY_KEYS"); keys != "" {
+		value, err := strconv.Atoi(keys)
+		if err != nil {
+			logger.Error("Invalid MCP_IDEMPOTENCY_KEYS value", slog.String("value", keys), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		mcpServer.IdempotencyKeys = value
+	}
 
-	// Create MCP server
-	mcpServer := server.NewMCPServer(
-		"Filesystem MCP Server",
-		"1.0.0",
-		"A Model Context Protocol server implementation that provides access to the local file system",
-	)
+	// Require a bearer token if one is configured
+	if mcpServer.AuthToken == "" {
+		mcpServer.AuthToken = os.Getenv("MCP_AUTH_TOKEN")
+	}
+
+	// Configure per-client rate limiting
+	if rateLimit := os.Getenv("MCP_RATE_LIMIT"); rateLimit != "" {
+		limit, err := strconv.ParseFloat(rateLimit, 64)
+		if err != nil {
+			logger.Error("Invalid MCP_RATE_LIMIT value", slog.String("value", rateLimit), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		mcpServer.RateLimit = limit
+		mcpServer.RateBurst = int(limit)
+	}
+	if rateBurst := os.Getenv("MCP_RATE_BURST"); rateBurst != "" {
+		burst, err := strconv.Atoi(rateBurst)
+		if err != nil {
+			logger.Error("Invalid MCP_RATE_BURST value", slog.String("value", rateBurst), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		mcpServer.RateBurst = burst
+	}
 
-	// Register filesystem tools
-	fsProvider := mcp.NewFilesystemProvider()
-	mcpServer.RegisterProvider(fsProvider)
+	// Expose Prometheus metrics if enabled
+	if metrics := os.Getenv("MCP_METRICS"); metrics != "" {
+		enabled, err := strconv.ParseBool(metrics)
+		if err != nil {
+			logger.Error("Invalid MCP_METRICS value", slog.String("value", metrics), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		if enabled {
+			mcpServer.Metrics = server.NewMetrics()
+		}
+	}
+
+	// Compress responses if enabled
+	if compress := os.Getenv("MCP_COMPRESS"); compress != "" {
+		enabled, err := strconv.ParseBool(compress)
+		if err != nil {
+			logger.Error("Invalid MCP_COMPRESS value", slog.String("value", compress), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		mcpServer.Compress = enabled
+	}
+
+	// Report failed tool calls and resource loads with matching HTTP statuses
+	if strict := os.Getenv("MCP_STRICT_HTTP_STATUS"); strict != "" {
+		enabled, err := strconv.ParseBool(strict)
+		if err != nil {
+			logger.Error("Invalid MCP_STRICT_HTTP_STATUS value", slog.String("value", strict), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		mcpServer.StrictHTTPStatus = enabled
+	}
+
+	// Bound the size of request bodies
+	if maxBodyBytes := os.Getenv("MCP_MAX_BODY_BYTES"); maxBodyBytes != "" && (config == nil || config.MaxBodyBytes == 0) {
+		limit, err := strconv.ParseInt(maxBodyBytes, 10, 64)
+		if err != nil {
+			logger.Error("Invalid MCP_MAX_BODY_BYTES value", slog.String("value", maxBodyBytes), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		mcpServer.MaxBodyBytes = limit
+	}
 
 	// Setup MCP routes
 	mcpServer.RegisterRoutes(e)
 
+	// Choose between HTTPS with a certificate from Let's Encrypt, HTTPS with
+	// the given certificate, and plain HTTP
+	tlsCert, tlsKey := os.Getenv("MCP_TLS_CERT"), os.Getenv("MCP_TLS_KEY")
+	if (tlsCert == "") != (tlsKey == "") {
+		logger.Error("MCP_TLS_CERT and MCP_TLS_KEY must be set together")
+		os.Exit(1)
+	}
+	domain := os.Getenv("MCP_DOMAIN")
+	if domain != "" && tlsCert != "" {
+		logger.Error("Set either MCP_DOMAIN or MCP_TLS_CERT and MCP_TLS_KEY, not both")
+		os.Exit(1)
+	}
+
 	// Determine port
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
+		if domain != "" {
+			port = "443"
+		}
+	}
+	addr := ":" + port
+
+	var mode string
+	var start func() error
+	switch {
+	case domain != "":
+		cacheDir := os.Getenv("MCP_AUTOCERT_CACHE")
+		if cacheDir == "" {
+			cacheDir = defaultAutocertCache
+		}
+		e.AutoTLSManager.HostPolicy = autocert.HostWhitelist(domain)
+		e.AutoTLSManager.Cache = autocert.DirCache(cacheDir)
+		mode = fmt.Sprintf("HTTPS with a Let's Encrypt certificate for %s (cached in %s)", domain, cacheDir)
+		start = func() error { return e.StartAutoTLS(addr) }
+	case tlsCert != "":
+		mode = fmt.Sprintf("HTTPS with certificate %s", tlsCert)
+		start = func() error { return e.StartTLS(addr, tlsCert, tlsKey) }
+	default:
+		mode = "plain HTTP"
+		start = func() error { return e.Start(addr) }
+	}
+
+	// Start the providers
+	if err := mcpServer.Start(context.Background()); err != nil {
+		logger.Error("Failed to start providers", slog.String("error", err.Error()))
+		os.Exit(1)
 	}
 
+	// Determine how long in-flight requests may take to finish on shutdown
+	gracePeriod := defaultShutdownGracePeriod
+	if grace := os.Getenv("MCP_SHUTDOWN_GRACE_PERIOD"); grace != "" {
+		duration, err := time.ParseDuration(grace)
+		if err != nil {
+			logger.Error("Invalid MCP_SHUTDOWN_GRACE_PERIOD value", slog.String("value", grace), slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		gracePeriod = duration
+	}
+
+	// Stop on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Start server
-	log.Printf("Starting MCP server on port %s", port)
-	if err := e.Start(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("Starting MCP server", slog.String("port", port), slog.String("mode", mode))
+		if err := start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	// Wait for a signal or for the server to fail
+	var err error
+	select {
+	case <-ctx.Done():
+		stop()
+		logger.Info("Shutting down, waiting for in-flight requests", slog.Duration("grace_period", gracePeriod))
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+		defer cancel()
+		if shutdownErr := e.Shutdown(shutdownCtx); shutdownErr != nil {
+			logger.Error("Failed to drain in-flight requests", slog.String("error", shutdownErr.Error()))
+		} else {
+			logger.Info("All in-flight requests finished")
+		}
+	case err = <-serverErr:
+	}
+
+	// Stop the providers
+	if stopErr := mcpServer.Stop(context.Background()); stopErr != nil {
+		logger.Error("Failed to stop providers", slog.String("error", stopErr.Error()))
+	}
+	if err != nil {
+		logger.Error("Failed to start server", slog.String("error", err.Error()))
+		os.Exit(1)
 	}
+	logger.Info("MCP server stopped")
 }
@@ -1,28 +1,49 @@
 package main
 
 import (
+	"archive/zip"
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 	"github.com/loag/mcp-server-test/mcp"
 	"github.com/loag/mcp-server-test/server"
+	"github.com/santhosh-tekuri/jsonschema/v6"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
 )
 
-func setupTestServer() *echo.Echo {
+func setupTestServer(opts ...mcp.FilesystemOption) *echo.Echo {
 	e := echo.New()
 	mcpServer := server.NewMCPServer(
 		"Test Filesystem MCP Server",
 		"1.0.0",
 		"A test MCP server implementation",
 	)
-	fsProvider := mcp.NewFilesystemProvider()
+	fsProvider := mcp.NewFilesystemProvider(opts...)
 	mcpServer.RegisterProvider(fsProvider)
 	mcpServer.RegisterRoutes(e)
 	return e
@@ -64,13 +85,13 @@ func TestDiscover(t *testing.T) {
 }
 
 func TestListDirectory(t *testing.T) {
-	e := setupTestServer()
-
 	// Create a temporary test directory
 	tempDir, err := os.MkdirTemp("", "mcp-test")
 	assert.NoError(t, err)
 	defer os.RemoveAll(tempDir)
 
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
 	// Create a test file
 	testFile := filepath.Join(tempDir, "test.txt")
 	err = os.WriteFile(testFile, []byte("test content"), 0644)
@@ -133,13 +154,13 @@ func TestListDirectory(t *testing.T) {
 }
 
 func TestReadFile(t *testing.T) {
-	e := setupTestServer()
-
 	// Create a temporary test file
 	tempFile, err := os.CreateTemp("", "mcp-test-*.txt")
 	assert.NoError(t, err)
 	defer os.Remove(tempFile.Name())
 
+	e := setupTestServer(mcp.WithRootDir(filepath.Dir(tempFile.Name())))
+
 	// Write test content
 	testContent := "Hello, MCP!"
 	_, err = tempFile.WriteString(testContent)
@@ -183,3 +204,6069 @@ func TestReadFile(t *testing.T) {
 	assert.Equal(t, testContent, content["content"])
 	assert.Equal(t, true, content["is_text"])
 }
+
+// callTool posts a call-tool request to the test server and decodes the result
+func callTool(t *testing.T, e *echo.Echo, toolID string, arguments map[string]interface{}) mcp.CallToolResult {
+	t.Helper()
+
+	rec := callToolRaw(t, e, toolID, arguments)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response mcp.CallToolResult
+	err := json.Unmarshal(rec.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	return response
+}
+
+// callToolRaw posts a call-tool request to the test server and returns the raw response
+func callToolRaw(t *testing.T, e *echo.Echo, toolID string, arguments map[string]interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	requestBody := map[string]interface{}{
+		"tool_id":    toolID,
+		"request_id": "test-" + toolID,
+		"params": map[string]interface{}{
+			"arguments": arguments,
+		},
+	}
+	jsonBody, err := json.Marshal(requestBody)
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/call-tool", bytes.NewReader(jsonBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestScaffold(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	spec := []interface{}{
+		map[string]interface{}{
+			"name": "src",
+			"children": []interface{}{
+				map[string]interface{}{"name": "main.go", "content": "package main\n"},
+			},
+		},
+		map[string]interface{}{"name": "docs", "type": "directory"},
+		map[string]interface{}{"name": "README.md", "content": "# Project\n"},
+	}
+
+	// A dry run reports the paths without creating them
+	response := callTool(t, e, "filesystem.scaffold", map[string]interface{}{
+		"path":    tempDir,
+		"spec":    spec,
+		"dry_run": true,
+	})
+	assert.Equal(t, "success", response.Status)
+	content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, true, content["dry_run"])
+	assert.Len(t, content["created"], 4)
+	_, err = os.Stat(filepath.Join(tempDir, "src"))
+	assert.True(t, os.IsNotExist(err))
+
+	// A real run materializes the tree
+	response = callTool(t, e, "filesystem.scaffold", map[string]interface{}{
+		"path": tempDir,
+		"spec": spec,
+	})
+	assert.Equal(t, "success", response.Status)
+	content = response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, []interface{}{
+		filepath.Join(tempDir, "src"),
+		filepath.Join(tempDir, "src", "main.go"),
+		filepath.Join(tempDir, "docs"),
+		filepath.Join(tempDir, "README.md"),
+	}, content["created"])
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "src", "main.go"))
+	assert.NoError(t, err)
+	assert.Equal(t, "package main\n", string(data))
+
+	// Running again reports the existing files as failures
+	response = callTool(t, e, "filesystem.scaffold", map[string]interface{}{
+		"path": tempDir,
+		"spec": spec,
+	})
+	assert.Equal(t, "success", response.Status)
+	content = response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Empty(t, content["created"])
+	assert.Len(t, content["failed"], 2)
+
+	// Specs that escape their parent are rejected before anything is created
+	response = callTool(t, e, "filesystem.scaffold", map[string]interface{}{
+		"path": tempDir,
+		"spec": []interface{}{
+			map[string]interface{}{"name": "ok.txt"},
+			map[string]interface{}{"name": "../escape.txt"},
+		},
+	})
+	assert.Equal(t, "error", response.Status)
+	_, err = os.Stat(filepath.Join(tempDir, "ok.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPathContainment(t *testing.T) {
+	rootDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(rootDir)
+
+	// Create a file just outside of the root
+	outsideFile := rootDir + "-outside.txt"
+	err = os.WriteFile(outsideFile, []byte("secret"), 0644)
+	assert.NoError(t, err)
+	defer os.Remove(outsideFile)
+
+	err = os.WriteFile(filepath.Join(rootDir, "inside.txt"), []byte("public"), 0644)
+	assert.NoError(t, err)
+
+	e := setupTestServer(mcp.WithRootDir(rootDir))
+
+	escapes := []string{
+		"../" + filepath.Base(outsideFile),
+		"sub/../../" + filepath.Base(outsideFile),
+		outsideFile,
+		"/etc/passwd",
+	}
+	for _, path := range escapes {
+		response := callTool(t, e, "filesystem.read", map[string]interface{}{"path": path})
+		assert.Equal(t, "error", response.Status, path)
+		if assert.NotNil(t, response.Error, path) {
+			assert.Contains(t, response.Error.Message, "outside of root directory", path)
+		}
+	}
+
+	// Relative and absolute paths within the root are still allowed
+	for _, path := range []string{"inside.txt", filepath.Join(rootDir, "inside.txt")} {
+		response := callTool(t, e, "filesystem.read", map[string]interface{}{"path": path})
+		assert.Equal(t, "success", response.Status, path)
+	}
+}
+
+func TestFilesEqual(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	// Spread the difference past the first comparison chunk
+	base := bytes.Repeat([]byte("a"), 100*1024)
+	changed := bytes.Clone(base)
+	changed[70000] = 'b'
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "a.bin"), base, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "copy.bin"), base, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "changed.bin"), changed, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "short.bin"), base[:10], 0644))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	tests := []struct {
+		other           string
+		equal           bool
+		firstDifference interface{}
+	}{
+		{"copy.bin", true, nil},
+		{"changed.bin", false, float64(70000)},
+		{"short.bin", false, nil},
+	}
+	for _, tt := range tests {
+		response := callTool(t, e, "filesystem.files-equal", map[string]interface{}{
+			"path_a": "a.bin",
+			"path_b": tt.other,
+		})
+		assert.Equal(t, "success", response.Status, tt.other)
+		content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+		assert.Equal(t, tt.equal, content["equal"], tt.other)
+		assert.Equal(t, tt.firstDifference, content["first_difference"], tt.other)
+	}
+
+	response := callTool(t, e, "filesystem.files-equal", map[string]interface{}{
+		"path_a": "a.bin",
+		"path_b": "missing.bin",
+	})
+	assert.Equal(t, "error", response.Status)
+}
+
+func TestDiffTool(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"old.txt":              "one\ntwo\nthree\n",
+		"same.txt":             "one\ntwo\nthree\n",
+		"new.txt":              "one\n2\nthree\n",
+		"left/same.txt":        "same",
+		"left/sub/edited.txt":  "abcd",
+		"left/resized.txt":     "short",
+		"left/removed.txt":     "gone",
+		"right/same.txt":       "same",
+		"right/sub/edited.txt": "abce",
+		"right/resized.txt":    "much longer",
+		"right/added.txt":      "new",
+		"copy/same.txt":        "same",
+	}
+	for name, content := range files {
+		path := filepath.Join(tempDir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+	diff := func(pathA, pathB string) map[string]interface{} {
+		response := callTool(t, e, "filesystem.diff", map[string]interface{}{"path_a": pathA, "path_b": pathB})
+		assert.Equal(t, "success", response.Status, pathA+" "+pathB)
+		return response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	}
+
+	// Identical files have no diff
+	content := diff("old.txt", "same.txt")
+	assert.Equal(t, true, content["identical"])
+	assert.Nil(t, content["diff"])
+
+	// Modified files get a unified diff
+	content = diff("old.txt", "new.txt")
+	assert.Equal(t, false, content["identical"])
+	assert.Equal(t, "file", content["type"])
+	diffText := content["diff"].(string)
+	assert.Contains(t, diffText, "--- a/old.txt\n+++ b/new.txt\n")
+	assert.Contains(t, diffText, "-two\n+2\n")
+
+	// Identical directories
+	content = diff("left", "left")
+	assert.Equal(t, true, content["identical"])
+	assert.Equal(t, "directory", content["type"])
+
+	// Added, removed and changed files
+	content = diff("left", "right")
+	assert.Equal(t, false, content["identical"])
+	assert.Equal(t, []interface{}{"added.txt"}, content["added"])
+	assert.Equal(t, []interface{}{"removed.txt"}, content["removed"])
+	assert.Equal(t, []interface{}{"resized.txt", "sub/edited.txt"}, content["changed"])
+
+	// A file cannot be compared with a directory
+	response := callTool(t, e, "filesystem.diff", map[string]interface{}{"path_a": "old.txt", "path_b": "left"})
+	assert.Equal(t, "error", response.Status)
+	assert.Equal(t, mcp.ErrorCodeInvalidArgument, response.Error.Code)
+
+	// Paths are confined to the root
+	response = callTool(t, e, "filesystem.diff", map[string]interface{}{"path_a": "left", "path_b": "../.."})
+	assert.Equal(t, "error", response.Status)
+	assert.Equal(t, map[string]interface{}{"argument": "path_b"}, response.Error.Details)
+}
+
+func TestSymlinkContainment(t *testing.T) {
+	rootDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(rootDir)
+
+	outsideDir, err := os.MkdirTemp("", "mcp-test-outside")
+	assert.NoError(t, err)
+	defer os.RemoveAll(outsideDir)
+
+	err = os.WriteFile(filepath.Join(outsideDir, "secret.txt"), []byte("secret"), 0644)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(rootDir, "inside.txt"), []byte("public"), 0644)
+	assert.NoError(t, err)
+
+	// Symlinks pointing outside the root, including a dangling one
+	assert.NoError(t, os.Symlink(outsideDir, filepath.Join(rootDir, "escape")))
+	assert.NoError(t, os.Symlink(filepath.Join(outsideDir, "new.txt"), filepath.Join(rootDir, "dangling")))
+	// A symlink that stays within the root
+	assert.NoError(t, os.Symlink("inside.txt", filepath.Join(rootDir, "alias.txt")))
+
+	e := setupTestServer(mcp.WithRootDir(rootDir))
+
+	response := callTool(t, e, "filesystem.read", map[string]interface{}{"path": "escape/secret.txt"})
+	assert.Equal(t, "error", response.Status)
+	if assert.NotNil(t, response.Error) {
+		assert.Contains(t, response.Error.Message, "outside of root directory")
+	}
+
+	for _, path := range []string{"escape/new.txt", "dangling"} {
+		response = callTool(t, e, "filesystem.write", map[string]interface{}{
+			"path":    path,
+			"content": "pwned",
+		})
+		assert.Equal(t, "error", response.Status, path)
+		if assert.NotNil(t, response.Error, path) {
+			assert.Contains(t, response.Error.Message, "outside of root directory", path)
+		}
+	}
+	_, err = os.Stat(filepath.Join(outsideDir, "new.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	response = callTool(t, e, "filesystem.read", map[string]interface{}{"path": "alias.txt"})
+	assert.Equal(t, "success", response.Status)
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	testFile := filepath.Join(tempDir, "test.txt")
+	err = os.WriteFile(testFile, []byte("test content"), 0644)
+	assert.NoError(t, err)
+
+	e := setupTestServer(mcp.WithRootDir(tempDir), mcp.WithReadOnly(true))
+
+	// Mutating tools are blocked without touching the disk
+	response := callTool(t, e, "filesystem.write", map[string]interface{}{
+		"path":    "test.txt",
+		"content": "changed",
+	})
+	assert.Equal(t, "error", response.Status)
+	if assert.NotNil(t, response.Error) {
+		assert.Equal(t, "read_only", response.Error.Code)
+	}
+
+	response = callTool(t, e, "filesystem.delete", map[string]interface{}{"path": "test.txt"})
+	assert.Equal(t, "error", response.Status)
+	if assert.NotNil(t, response.Error) {
+		assert.Equal(t, "read_only", response.Error.Code)
+	}
+
+	data, err := os.ReadFile(testFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "test content", string(data))
+
+	// Reads still work
+	response = callTool(t, e, "filesystem.read", map[string]interface{}{"path": "test.txt"})
+	assert.Equal(t, "success", response.Status)
+
+	// Discovery omits the mutating tools
+	req := httptest.NewRequest(http.MethodPost, "/v1/discover", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var discover mcp.DiscoverResponse
+	err = json.Unmarshal(rec.Body.Bytes(), &discover)
+	assert.NoError(t, err)
+
+	toolIDs := make([]string, 0)
+	for _, tool := range discover.Providers[0].Tools {
+		toolIDs = append(toolIDs, tool.ID)
+	}
+	assert.Contains(t, toolIDs, "filesystem.read")
+	assert.Contains(t, toolIDs, "filesystem.list")
+	assert.NotContains(t, toolIDs, "filesystem.write")
+	assert.NotContains(t, toolIDs, "filesystem.delete")
+	assert.NotContains(t, toolIDs, "filesystem.scaffold")
+}
+
+func TestStat(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	err = os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("test content"), 0640)
+	assert.NoError(t, err)
+	err = os.Chmod(filepath.Join(tempDir, "test.txt"), 0640)
+	assert.NoError(t, err)
+	err = os.Mkdir(filepath.Join(tempDir, "sub"), 0755)
+	assert.NoError(t, err)
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	response := callTool(t, e, "filesystem.stat", map[string]interface{}{"path": "test.txt"})
+	assert.Equal(t, "success", response.Status)
+	content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, true, content["exists"])
+	assert.Equal(t, "test.txt", content["name"])
+	assert.Equal(t, float64(12), content["size"])
+	assert.Equal(t, false, content["is_dir"])
+	assert.Equal(t, "0640", content["mode"])
+
+	response = callTool(t, e, "filesystem.stat", map[string]interface{}{"path": "sub"})
+	assert.Equal(t, "success", response.Status)
+	content = response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, true, content["exists"])
+	assert.Equal(t, true, content["is_dir"])
+
+	response = callTool(t, e, "filesystem.stat", map[string]interface{}{"path": "missing.txt"})
+	assert.Equal(t, "success", response.Status)
+	content = response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, false, content["exists"])
+}
+
+func TestReadFileGitBlobHash(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	err = os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("Hello, MCP!"), 0644)
+	assert.NoError(t, err)
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	response := callTool(t, e, "filesystem.read", map[string]interface{}{
+		"path":          "test.txt",
+		"git_blob_hash": true,
+	})
+	assert.Equal(t, "success", response.Status)
+	content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	// Matches `git hash-object` for the same content
+	assert.Equal(t, "3f57b3a2014c94cd6d70babab32d15cd4f7e90f9", content["git_blob_hash"])
+
+	// The hash is only included on request
+	response = callTool(t, e, "filesystem.read", map[string]interface{}{"path": "test.txt"})
+	content = response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.NotContains(t, content, "git_blob_hash")
+}
+
+func TestSearch(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	for _, path := range []string{"a.go", "b.txt", "sub/c.go", "sub/deeper/d.go"} {
+		fullPath := filepath.Join(tempDir, path)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		assert.NoError(t, os.WriteFile(fullPath, []byte("package main\n"), 0644))
+	}
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	names := func(response mcp.CallToolResult) []string {
+		content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+		names := make([]string, 0)
+		for _, file := range content["files"].([]interface{}) {
+			names = append(names, file.(map[string]interface{})["path"].(string))
+		}
+		return names
+	}
+
+	response := callTool(t, e, "filesystem.search", map[string]interface{}{
+		"path":    ".",
+		"pattern": "*.go",
+	})
+	assert.Equal(t, "success", response.Status)
+	assert.ElementsMatch(t, []string{"a.go", "sub/c.go", "sub/deeper/d.go"}, names(response))
+
+	response = callTool(t, e, "filesystem.search", map[string]interface{}{
+		"path":      ".",
+		"pattern":   "*.go",
+		"max_depth": 2,
+	})
+	assert.ElementsMatch(t, []string{"a.go", "sub/c.go"}, names(response))
+
+	response = callTool(t, e, "filesystem.search", map[string]interface{}{
+		"path":        ".",
+		"pattern":     "*.go",
+		"max_results": 1,
+	})
+	assert.Len(t, names(response), 1)
+	content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, true, content["truncated"])
+
+	response = callTool(t, e, "filesystem.search", map[string]interface{}{
+		"path":    ".",
+		"pattern": "[",
+	})
+	assert.Equal(t, "error", response.Status)
+}
+
+func TestSearchPermissionErrors(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permission checks do not apply to root")
+	}
+
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "a.go"), nil, 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(tempDir, "locked"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "locked", "b.go"), nil, 0644))
+	assert.NoError(t, os.Chmod(filepath.Join(tempDir, "locked"), 0000))
+	defer os.Chmod(filepath.Join(tempDir, "locked"), 0755)
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	search := func(policy string) mcp.CallToolResult {
+		return callTool(t, e, "filesystem.search", map[string]interface{}{
+			"path":                ".",
+			"pattern":             "*.go",
+			"on_permission_error": policy,
+		})
+	}
+
+	response := search("skip")
+	assert.Equal(t, "success", response.Status)
+	content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Len(t, content["files"], 1)
+	assert.NotContains(t, content, "inaccessible")
+
+	response = search("report")
+	assert.Equal(t, "success", response.Status)
+	content = response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Len(t, content["files"], 1)
+	assert.Equal(t, []interface{}{"locked"}, content["inaccessible"])
+
+	response = search("fail")
+	assert.Equal(t, "error", response.Status)
+}
+
+func TestPermissionErrorPolicies(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permission checks do not apply to root")
+	}
+
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(tempDir, "src", "locked"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "src", "a.go"), []byte("package a\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "src", "locked", "b.go"), []byte("package b\n"), 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(tempDir, "other"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "other", "a.go"), []byte("package a\n"), 0644))
+	assert.NoError(t, os.Chmod(filepath.Join(tempDir, "src", "locked"), 0000))
+	defer os.Chmod(filepath.Join(tempDir, "src", "locked"), 0755)
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	tools := []struct {
+		tool      string
+		arguments func(policy string) map[string]interface{}
+	}{
+		{"filesystem.search", func(string) map[string]interface{} {
+			return map[string]interface{}{"path": "src", "pattern": "*.go"}
+		}},
+		{"filesystem.grep", func(string) map[string]interface{} {
+			return map[string]interface{}{"path": "src", "query": "package"}
+		}},
+		{"filesystem.walk", func(string) map[string]interface{} {
+			return map[string]interface{}{"path": "src"}
+		}},
+		{"filesystem.list-symlinks", func(string) map[string]interface{} {
+			return map[string]interface{}{"path": "src"}
+		}},
+		{"filesystem.list", func(string) map[string]interface{} {
+			return map[string]interface{}{"path": "src", "recursive": true}
+		}},
+		{"filesystem.copy", func(policy string) map[string]interface{} {
+			return map[string]interface{}{"path": "src", "destination": "copy-" + policy, "recursive": true}
+		}},
+		{"filesystem.du", func(string) map[string]interface{} {
+			return map[string]interface{}{"path": "src"}
+		}},
+		{"filesystem.zip", func(policy string) map[string]interface{} {
+			return map[string]interface{}{"path": "src", "destination": "src-" + policy + ".zip"}
+		}},
+		{"filesystem.diff", func(string) map[string]interface{} {
+			return map[string]interface{}{"path_a": "src", "path_b": "other"}
+		}},
+	}
+
+	for _, tt := range tools {
+		t.Run(tt.tool, func(t *testing.T) {
+			call := func(policy string) mcp.CallToolResult {
+				arguments := tt.arguments(policy)
+				arguments["on_permission_error"] = policy
+				return callTool(t, e, tt.tool, arguments)
+			}
+
+			response := call("skip")
+			assert.Equal(t, "success", response.Status)
+			content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+			assert.NotContains(t, content, "inaccessible")
+
+			response = call("report")
+			assert.Equal(t, "success", response.Status)
+			content = response.Result.(map[string]interface{})["json"].(map[string]interface{})
+			assert.Equal(t, []interface{}{"src/locked"}, content["inaccessible"])
+
+			response = call("fail")
+			assert.Equal(t, "error", response.Status)
+			if assert.NotNil(t, response.Error) {
+				assert.Equal(t, mcp.ErrorCodePermissionDenied, response.Error.Code)
+			}
+		})
+	}
+
+	// A zip that failed leaves no partial archive behind
+	_, err = os.Stat(filepath.Join(tempDir, "src-fail.zip"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestListSymlinks(t *testing.T) {
+	rootDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(rootDir)
+
+	outsideDir, err := os.MkdirTemp("", "mcp-test-outside")
+	assert.NoError(t, err)
+	defer os.RemoveAll(outsideDir)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(rootDir, "file.txt"), nil, 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(rootDir, "sub"), 0755))
+	assert.NoError(t, os.Symlink("../file.txt", filepath.Join(rootDir, "sub", "inside")))
+	assert.NoError(t, os.Symlink(outsideDir, filepath.Join(rootDir, "outside")))
+	assert.NoError(t, os.Symlink("missing.txt", filepath.Join(rootDir, "broken")))
+
+	e := setupTestServer(mcp.WithRootDir(rootDir))
+
+	response := callTool(t, e, "filesystem.list-symlinks", map[string]interface{}{"path": "."})
+	assert.Equal(t, "success", response.Status)
+
+	content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	symlinks := make(map[string]map[string]interface{})
+	for _, link := range content["symlinks"].([]interface{}) {
+		link := link.(map[string]interface{})
+		symlinks[link["path"].(string)] = link
+	}
+	assert.Len(t, symlinks, 3)
+
+	assert.Equal(t, "../file.txt", symlinks["sub/inside"]["target"])
+	assert.Equal(t, true, symlinks["sub/inside"]["inside_root"])
+	assert.Equal(t, false, symlinks["sub/inside"]["broken"])
+
+	assert.Equal(t, outsideDir, symlinks["outside"]["target"])
+	assert.Equal(t, false, symlinks["outside"]["inside_root"])
+	assert.Equal(t, false, symlinks["outside"]["broken"])
+
+	assert.Equal(t, true, symlinks["broken"]["broken"])
+	assert.Equal(t, true, symlinks["broken"]["inside_root"])
+}
+
+func TestGrep(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("hello world\nHELLO again\nbye\n"), 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(tempDir, "sub"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "sub", "b.txt"), []byte("nothing\nsay hello\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "binary.bin"), []byte("hello\x00world"), 0644))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	grep := func(arguments map[string]interface{}) map[string]interface{} {
+		response := callTool(t, e, "filesystem.grep", arguments)
+		assert.Equal(t, "success", response.Status)
+		return response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	}
+
+	// Literal, case-sensitive search across the tree skips binary files
+	content := grep(map[string]interface{}{"path": ".", "query": "hello"})
+	files := content["files"].([]interface{})
+	assert.Len(t, files, 2)
+	first := files[0].(map[string]interface{})
+	assert.Equal(t, "a.txt", first["path"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"line": float64(1), "text": "hello world"},
+	}, first["matches"])
+
+	// Case-insensitive search
+	content = grep(map[string]interface{}{"path": "a.txt", "query": "hello", "case_insensitive": true})
+	files = content["files"].([]interface{})
+	assert.Len(t, files, 1)
+	assert.Len(t, files[0].(map[string]interface{})["matches"], 2)
+
+	// Regular expressions
+	content = grep(map[string]interface{}{"path": ".", "query": "^b.e$", "regex": true})
+	files = content["files"].([]interface{})
+	assert.Len(t, files, 1)
+	assert.Equal(t, "a.txt", files[0].(map[string]interface{})["path"])
+
+	// Match limits
+	content = grep(map[string]interface{}{"path": ".", "query": "hello", "case_insensitive": true, "max_matches": 2})
+	files = content["files"].([]interface{})
+	assert.Len(t, files, 1)
+	assert.Equal(t, true, content["truncated"])
+
+	response := callTool(t, e, "filesystem.grep", map[string]interface{}{"path": ".", "query": "(", "regex": true})
+	assert.Equal(t, "error", response.Status)
+}
+
+func TestCASKey(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	err = os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("Hello, MCP!"), 0644)
+	assert.NoError(t, err)
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	response := callTool(t, e, "filesystem.cas-key", map[string]interface{}{"path": "test.txt"})
+	assert.Equal(t, "success", response.Status)
+	content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, "sha256", content["algorithm"])
+	assert.Equal(t, "7b34392da91ae86392858f92a2fd7356931b1e12ca418cc6fc9df0015b977aea", content["hash"])
+	assert.Equal(t, "7b/34392da91ae86392858f92a2fd7356931b1e12ca418cc6fc9df0015b977aea", content["key"])
+
+	response = callTool(t, e, "filesystem.cas-key", map[string]interface{}{
+		"path":          "test.txt",
+		"algorithm":     "md5",
+		"prefix_length": 4,
+		"include_size":  true,
+	})
+	assert.Equal(t, "success", response.Status)
+	content = response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, "7d3a/8d87f9f0af2df69e7c179b28ee88-11", content["key"])
+
+	rec := callToolRaw(t, e, "filesystem.cas-key", map[string]interface{}{
+		"path":      "test.txt",
+		"algorithm": "crc32",
+	})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHash(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	err = os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("Hello, MCP!"), 0644)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Mkdir(filepath.Join(tempDir, "sub"), 0755))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	response := callTool(t, e, "filesystem.hash", map[string]interface{}{"path": "test.txt"})
+	assert.Equal(t, "success", response.Status)
+	content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, "sha256", content["algorithm"])
+	assert.Equal(t, "7b34392da91ae86392858f92a2fd7356931b1e12ca418cc6fc9df0015b977aea", content["hash"])
+	assert.Equal(t, float64(11), content["size"])
+
+	response = callTool(t, e, "filesystem.hash", map[string]interface{}{
+		"path":      "test.txt",
+		"algorithm": "md5",
+	})
+	assert.Equal(t, "success", response.Status)
+	content = response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, "7d3a8d87f9f0af2df69e7c179b28ee88", content["hash"])
+
+	response = callTool(t, e, "filesystem.hash", map[string]interface{}{"path": "sub"})
+	assert.Equal(t, "error", response.Status)
+}
+
+func TestCounterIncrement(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	err = os.WriteFile(filepath.Join(tempDir, "bad.txt"), []byte("seven"), 0644)
+	assert.NoError(t, err)
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	// A missing counter starts at zero
+	response := callTool(t, e, "filesystem.counter-increment", map[string]interface{}{"path": "counter"})
+	assert.Equal(t, "success", response.Status)
+	content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, float64(0), content["previous"])
+	assert.Equal(t, float64(1), content["value"])
+
+	response = callTool(t, e, "filesystem.counter-increment", map[string]interface{}{
+		"path":  "counter",
+		"delta": 5,
+	})
+	assert.Equal(t, "success", response.Status)
+	content = response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, float64(6), content["value"])
+
+	// Concurrent increments never lose an update
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			callTool(t, e, "filesystem.counter-increment", map[string]interface{}{"path": "counter"})
+		}()
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "counter"))
+	assert.NoError(t, err)
+	assert.Equal(t, "26\n", string(data))
+
+	response = callTool(t, e, "filesystem.counter-increment", map[string]interface{}{"path": "bad.txt"})
+	assert.Equal(t, "error", response.Status)
+	if assert.NotNil(t, response.Error) {
+		assert.Equal(t, mcp.ErrorCodeParseError, response.Error.Code)
+	}
+
+	// Counters refuse to wrap around and are left as they were
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "max"), []byte("9223372036854775807\n"), 0644))
+	response = callTool(t, e, "filesystem.counter-increment", map[string]interface{}{"path": "max"})
+	assert.Equal(t, "error", response.Status)
+	if assert.NotNil(t, response.Error) {
+		assert.Equal(t, mcp.ErrorCodeInvalidArgument, response.Error.Code)
+	}
+	data, err = os.ReadFile(filepath.Join(tempDir, "max"))
+	assert.NoError(t, err)
+	assert.Equal(t, "9223372036854775807\n", string(data))
+
+	response = callTool(t, e, "filesystem.counter-increment", map[string]interface{}{"path": "max", "delta": -1})
+	assert.Equal(t, "success", response.Status)
+
+	// The value is renamed into place, leaving no other files behind
+	entries, err := os.ReadDir(tempDir)
+	assert.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.NotContains(t, strings.Join(names, " "), ".tmp-")
+	assert.NotContains(t, strings.Join(names, " "), ".lock")
+}
+
+func TestBuildLineIndex(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	testFile := filepath.Join(tempDir, "log.txt")
+	err = os.WriteFile(testFile, []byte("one\ntwo\n\nfour"), 0644)
+	assert.NoError(t, err)
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	response := callTool(t, e, "filesystem.build-line-index", map[string]interface{}{"path": "log.txt"})
+	assert.Equal(t, "success", response.Status)
+	content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, float64(4), content["line_count"])
+	assert.Equal(t, []interface{}{float64(0), float64(4), float64(8), float64(9)}, content["offsets"])
+	assert.Equal(t, false, content["cached"])
+
+	// The second call is served from the cache
+	response = callTool(t, e, "filesystem.build-line-index", map[string]interface{}{"path": "log.txt"})
+	content = response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, true, content["cached"])
+
+	// Changing the file invalidates the cache
+	err = os.WriteFile(testFile, []byte("one\ntwo\n"), 0644)
+	assert.NoError(t, err)
+	response = callTool(t, e, "filesystem.build-line-index", map[string]interface{}{
+		"path":            "log.txt",
+		"include_offsets": false,
+	})
+	content = response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, false, content["cached"])
+	assert.Equal(t, float64(2), content["line_count"])
+	assert.NotContains(t, content, "offsets")
+}
+
+func TestCancelledRequest(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	err = os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("Hello, MCP!"), 0644)
+	assert.NoError(t, err)
+
+	provider := mcp.NewFilesystemProvider(mcp.WithRootDir(tempDir))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Calls whose context is already done are aborted by the provider
+	calls := map[string]map[string]interface{}{
+		"hash":   {"path": "test.txt"},
+		"read":   {"path": "test.txt"},
+		"search": {"path": ".", "pattern": "*"},
+	}
+	for toolName, arguments := range calls {
+		response, err := provider.CallTool(ctx, toolName, mcp.CallToolRequest{
+			RequestID: "test-cancelled",
+			Params:    mcp.CallToolParams{Arguments: arguments},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "error", response.Status, toolName)
+		if assert.NotNil(t, response.Error, toolName) {
+			assert.Contains(t, response.Error.Message, context.Canceled.Error(), toolName)
+		}
+	}
+}
+
+// slowProvider is a provider whose tools and resources block until the request is cancelled
+type slowProvider struct {
+	mcp.NoHealthCheck
+}
+
+func (slowProvider) GetName() string { return "slow" }
+
+func (slowProvider) GetInfo() mcp.ProviderInfo { return mcp.ProviderInfo{Name: "slow"} }
+
+func (slowProvider) CallTool(ctx context.Context, toolName string, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (slowProvider) LoadResource(ctx context.Context, resourceName string, request mcp.LoadResourceRequest) (*mcp.LoadResourceResult, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRequestTimeout(t *testing.T) {
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	mcpServer.RequestTimeout = 50 * time.Millisecond
+	mcpServer.RegisterProvider(slowProvider{})
+	mcpServer.RegisterRoutes(e)
+
+	requests := map[string]string{
+		"/v1/call-tool":     `{"tool_id": "slow.wait", "request_id": "test-timeout"}`,
+		"/v1/load-resource": `{"resource_id": "slow.wait", "request_id": "test-timeout"}`,
+	}
+	for path, body := range requests {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader([]byte(body)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusGatewayTimeout, rec.Code, path)
+
+		var response mcp.ErrorResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "timeout", response.Error, path)
+	}
+}
+
+func TestNormalizeWhitespace(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	testFile := filepath.Join(tempDir, "test.txt")
+	original := "clean\ntrailing  \n\tindented\t\n\n\n"
+	assert.NoError(t, os.WriteFile(testFile, []byte(original), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "crlf.txt"), []byte("a \r\nb\r\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "binary.bin"), []byte("a\x00b "), 0644))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	// A dry run reports the changes without writing them
+	response := callTool(t, e, "filesystem.normalize-whitespace", map[string]interface{}{
+		"path":    "test.txt",
+		"dry_run": true,
+	})
+	assert.Equal(t, "success", response.Status)
+	content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, true, content["changed"])
+	assert.Equal(t, []interface{}{float64(2), float64(3), float64(4), float64(5)}, content["changed_lines"])
+	data, err := os.ReadFile(testFile)
+	assert.NoError(t, err)
+	assert.Equal(t, original, string(data))
+
+	response = callTool(t, e, "filesystem.normalize-whitespace", map[string]interface{}{
+		"path":        "test.txt",
+		"expand_tabs": true,
+		"tab_width":   2,
+	})
+	assert.Equal(t, "success", response.Status)
+	data, err = os.ReadFile(testFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "clean\ntrailing\n  indented\n", string(data))
+
+	// Normalizing again is a no-op
+	response = callTool(t, e, "filesystem.normalize-whitespace", map[string]interface{}{"path": "test.txt"})
+	content = response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, false, content["changed"])
+	assert.Equal(t, float64(0), content["lines_changed"])
+
+	// CRLF line endings are preserved
+	response = callTool(t, e, "filesystem.normalize-whitespace", map[string]interface{}{"path": "crlf.txt"})
+	assert.Equal(t, "success", response.Status)
+	data, err = os.ReadFile(filepath.Join(tempDir, "crlf.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "a\r\nb\r\n", string(data))
+
+	response = callTool(t, e, "filesystem.normalize-whitespace", map[string]interface{}{"path": "binary.bin"})
+	assert.Equal(t, "error", response.Status)
+}
+
+func TestWalk(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	for _, path := range []string{"a.go", "b.txt", "sub/c.go", "sub/deeper/d.go", "sub.go", "vendor/e.go"} {
+		fullPath := filepath.Join(tempDir, path)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		assert.NoError(t, os.WriteFile(fullPath, []byte("package main\n"), 0644))
+	}
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	walk := func(arguments map[string]interface{}) ([]string, map[string]interface{}) {
+		response := callTool(t, e, "filesystem.walk", arguments)
+		assert.Equal(t, "success", response.Status)
+		content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+		paths := make([]string, 0)
+		for _, file := range content["files"].([]interface{}) {
+			paths = append(paths, file.(map[string]interface{})["path"].(string))
+		}
+		return paths, content
+	}
+
+	paths, content := walk(map[string]interface{}{"path": ".", "ignore": []interface{}{"vendor"}})
+	assert.Equal(t, []string{"a.go", "b.txt", "sub/c.go", "sub/deeper/d.go", "sub.go"}, paths)
+	assert.Equal(t, false, content["has_more"])
+
+	// Paging through the tree visits every entry exactly once
+	var all []string
+	cursor := ""
+	for {
+		paths, content = walk(map[string]interface{}{
+			"path":         ".",
+			"pattern":      "*.go",
+			"include_dirs": true,
+			"limit":        2,
+			"cursor":       cursor,
+		})
+		all = append(all, paths...)
+		if content["has_more"] != true {
+			break
+		}
+		cursor = content["next_cursor"].(string)
+	}
+	assert.Equal(t, []string{"a.go", "sub/c.go", "sub/deeper/d.go", "sub.go", "vendor/e.go"}, all)
+
+	response := callTool(t, e, "filesystem.walk", map[string]interface{}{"path": ".", "cursor": "%%%"})
+	assert.Equal(t, "error", response.Status)
+}
+
+func TestStreamResource(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	data := bytes.Repeat([]byte{0x00, 0xff, 'a'}, 100*1024)
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "blob.bin"), data, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes.txt"), []byte("hello"), 0644))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	stream := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/stream-resource?"+query, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := stream("resource_id=filesystem.file&path=blob.bin")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/octet-stream", rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, "307200", rec.Header().Get(echo.HeaderContentLength))
+	assert.Equal(t, data, rec.Body.Bytes())
+
+	rec = stream("resource_id=filesystem.file&path=notes.txt")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentType), "text/plain")
+	assert.Equal(t, "hello", rec.Body.String())
+
+	// Files that browsers would render are sent as sandboxed attachments
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "page.html"), []byte("<script>alert(1)</script>"), 0644))
+	rec = stream("resource_id=filesystem.file&path=page.html")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentType), "text/html")
+	assert.Equal(t, `attachment; filename=page.html`, rec.Header().Get(echo.HeaderContentDisposition))
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "sandbox", rec.Header().Get("Content-Security-Policy"))
+
+	rec = stream("resource_id=filesystem.file&path=missing.bin")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = stream("resource_id=filesystem.file&path=../outside.bin")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = stream("resource_id=filesystem.directory&path=.")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestStagedWrite(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	testFile := filepath.Join(tempDir, "test.txt")
+	assert.NoError(t, os.WriteFile(testFile, []byte("one\ntwo\nthree\n"), 0600))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	stage := func(path, content string) map[string]interface{} {
+		response := callTool(t, e, "filesystem.stage-write", map[string]interface{}{
+			"path":    path,
+			"content": content,
+		})
+		assert.Equal(t, "success", response.Status)
+		return response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	}
+
+	// Staging reports a diff without touching the file
+	content := stage("test.txt", "one\n2\nthree\n")
+	assert.Equal(t, "--- a/test.txt\n+++ b/test.txt\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n", content["diff"])
+	data, err := os.ReadFile(testFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "one\ntwo\nthree\n", string(data))
+
+	response := callTool(t, e, "filesystem.commit-write", map[string]interface{}{"token": content["token"]})
+	assert.Equal(t, "success", response.Status)
+	data, err = os.ReadFile(testFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "one\n2\nthree\n", string(data))
+	info, err := os.Stat(testFile)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Tokens can only be used once
+	response = callTool(t, e, "filesystem.commit-write", map[string]interface{}{"token": content["token"]})
+	assert.Equal(t, "error", response.Status)
+
+	// New files are staged against an empty file
+	content = stage("new.txt", "hello\n")
+	assert.Equal(t, true, content["new_file"])
+	assert.Equal(t, "--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1 @@\n+hello\n", content["diff"])
+
+	// Commits are refused if the file changed after staging
+	content = stage("test.txt", "replaced\n")
+	assert.NoError(t, os.WriteFile(testFile, []byte("concurrent edit\n"), 0600))
+	response = callTool(t, e, "filesystem.commit-write", map[string]interface{}{"token": content["token"]})
+	assert.Equal(t, "error", response.Status)
+	data, err = os.ReadFile(testFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "concurrent edit\n", string(data))
+}
+
+func TestStagedWriteExpiry(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	e := setupTestServer(mcp.WithRootDir(tempDir), mcp.WithStagingTTL(time.Millisecond))
+
+	response := callTool(t, e, "filesystem.stage-write", map[string]interface{}{
+		"path":    "test.txt",
+		"content": "hello",
+	})
+	assert.Equal(t, "success", response.Status)
+	token := response.Result.(map[string]interface{})["json"].(map[string]interface{})["token"]
+
+	time.Sleep(5 * time.Millisecond)
+	response = callTool(t, e, "filesystem.commit-write", map[string]interface{}{"token": token})
+	assert.Equal(t, "error", response.Status)
+	_, err = os.Stat(filepath.Join(tempDir, "test.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestStagedWriteLimits(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "existing.txt"), []byte(strings.Repeat("x", 60)), 0644))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir), mcp.WithStagingLimits(3, 100))
+	stage := func(path, content string) mcp.CallToolResult {
+		return callTool(t, e, "filesystem.stage-write", map[string]interface{}{"path": path, "content": content})
+	}
+	token := func(response mcp.CallToolResult) interface{} {
+		return response.Result.(map[string]interface{})["json"].(map[string]interface{})["token"]
+	}
+
+	// The original content of a file counts towards the byte limit
+	first := stage("existing.txt", strings.Repeat("y", 30))
+	assert.Equal(t, "success", first.Status)
+	response := stage("new.txt", strings.Repeat("z", 20))
+	assert.Equal(t, "error", response.Status)
+	if assert.NotNil(t, response.Error) {
+		assert.Equal(t, mcp.ErrorCodeLimitExceeded, response.Error.Code)
+	}
+
+	// So does the number of staged writes
+	assert.Equal(t, "success", stage("a.txt", "a").Status)
+	assert.Equal(t, "success", stage("b.txt", "b").Status)
+	response = stage("c.txt", "c")
+	assert.Equal(t, "error", response.Status)
+	if assert.NotNil(t, response.Error) {
+		assert.Equal(t, mcp.ErrorCodeLimitExceeded, response.Error.Code)
+	}
+
+	// Committing a write makes room for others
+	response = callTool(t, e, "filesystem.commit-write", map[string]interface{}{"token": token(first)})
+	assert.Equal(t, "success", response.Status)
+	assert.Equal(t, "success", stage("new.txt", strings.Repeat("z", 20)).Status)
+}
+
+func TestListDirectoryPagination(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	for _, name := range []string{"d.txt", "a.txt", "c.txt", "b.txt", "e.txt"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(tempDir, name), nil, 0644))
+	}
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	names := func(content map[string]interface{}) []string {
+		names := make([]string, 0)
+		for _, file := range content["files"].([]interface{}) {
+			names = append(names, file.(map[string]interface{})["name"].(string))
+		}
+		return names
+	}
+
+	response := callTool(t, e, "filesystem.list", map[string]interface{}{
+		"path":   ".",
+		"offset": 1,
+		"limit":  2,
+	})
+	assert.Equal(t, "success", response.Status)
+	content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, []string{"b.txt", "c.txt"}, names(content))
+	assert.Equal(t, float64(5), content["total"])
+	assert.Equal(t, true, content["has_more"])
+
+	response = callTool(t, e, "filesystem.list", map[string]interface{}{
+		"path":   ".",
+		"offset": 3,
+		"limit":  2,
+	})
+	content = response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, []string{"d.txt", "e.txt"}, names(content))
+	assert.Equal(t, false, content["has_more"])
+
+	// Offsets past the end return an empty page
+	response = callTool(t, e, "filesystem.list", map[string]interface{}{
+		"path":   ".",
+		"offset": 10,
+	})
+	assert.Equal(t, "success", response.Status)
+	content = response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Empty(t, content["files"])
+	assert.Equal(t, float64(5), content["total"])
+
+	// The directory resource pages the same way
+	requestBody := map[string]interface{}{
+		"resource_id": "filesystem.directory",
+		"request_id":  "test-pagination",
+		"params": map[string]interface{}{
+			"path":  ".",
+			"limit": 1,
+		},
+	}
+	jsonBody, err := json.Marshal(requestBody)
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/load-resource", bytes.NewReader(jsonBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var resource mcp.LoadResourceResult
+	err = json.Unmarshal(rec.Body.Bytes(), &resource)
+	assert.NoError(t, err)
+	assert.Equal(t, "success", resource.Status)
+	content = resource.Content.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, []string{"a.txt"}, names(content))
+	assert.Equal(t, true, content["has_more"])
+}
+
+func TestListDirectoryPageTokens(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(tempDir, "other"), 0755))
+	for _, name := range []string{"b.txt", "d.txt", "f.txt", "h.txt", "j.txt"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(tempDir, name), nil, 0644))
+	}
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+	list := func(arguments map[string]interface{}) ([]string, string) {
+		t.Helper()
+		response := callTool(t, e, "filesystem.list", arguments)
+		assert.Equal(t, "success", response.Status)
+		content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+		names := make([]string, 0)
+		for _, file := range content["files"].([]interface{}) {
+			names = append(names, file.(map[string]interface{})["name"].(string))
+		}
+		token, _ := content["next_token"].(string)
+		assert.Equal(t, token != "", content["has_more"])
+		return names, token
+	}
+
+	// Entries added or removed between pages neither repeat nor skip the rest
+	names, token := list(map[string]interface{}{"path": ".", "type": "file", "limit": 2})
+	assert.Equal(t, []string{"b.txt", "d.txt"}, names)
+	assert.NotEmpty(t, token)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "a.txt"), nil, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "e.txt"), nil, 0644))
+	assert.NoError(t, os.Remove(filepath.Join(tempDir, "d.txt")))
+	names, token = list(map[string]interface{}{"path": ".", "type": "file", "limit": 2, "page_token": token})
+	assert.Equal(t, []string{"e.txt", "f.txt"}, names)
+	assert.NotEmpty(t, token)
+
+	assert.NoError(t, os.Remove(filepath.Join(tempDir, "f.txt")))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "i.txt"), nil, 0644))
+	names, token = list(map[string]interface{}{"path": "./", "type": "file", "limit": 2, "page_token": token})
+	assert.Equal(t, []string{"h.txt", "i.txt"}, names)
+
+	names, token = list(map[string]interface{}{"path": ".", "type": "file", "limit": 2, "page_token": token})
+	assert.Equal(t, []string{"j.txt"}, names)
+	assert.Empty(t, token)
+
+	// Descending listings resume the same way
+	names, token = list(map[string]interface{}{"path": ".", "type": "file", "limit": 3, "sort_desc": true})
+	assert.Equal(t, []string{"j.txt", "i.txt", "h.txt"}, names)
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "g.txt"), nil, 0644))
+	descToken := token
+	names, _ = list(map[string]interface{}{"path": ".", "type": "file", "limit": 3, "sort_desc": true, "page_token": descToken})
+	assert.Equal(t, []string{"g.txt", "e.txt", "b.txt"}, names)
+
+	// Tokens only resume the listing they were returned for
+	_, token = list(map[string]interface{}{"path": ".", "limit": 1})
+	for _, arguments := range []map[string]interface{}{
+		{"path": "other", "page_token": token},
+		{"path": ".", "page_token": token, "sort_desc": true},
+		{"path": ".", "page_token": token, "sort_by": "size"},
+		{"path": ".", "page_token": descToken},
+		{"path": ".", "page_token": token, "offset": 1},
+		{"path": ".", "page_token": "not a token"},
+	} {
+		response := callTool(t, e, "filesystem.list", arguments)
+		assert.Equal(t, "error", response.Status, arguments)
+		if assert.NotNil(t, response.Error, arguments) {
+			assert.Equal(t, mcp.ErrorCodeInvalidArgument, response.Error.Code, arguments)
+		}
+	}
+
+	// Other sort keys have no stable position to resume from
+	response := callTool(t, e, "filesystem.list", map[string]interface{}{"path": ".", "limit": 1, "sort_by": "size"})
+	assert.Equal(t, "success", response.Status)
+	content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, true, content["has_more"])
+	assert.NotContains(t, content, "next_token")
+}
+
+func TestReadStructured(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"config.json": `{"name": "mcp", "ports": [8080, 8081]}`,
+		"config.yml":  "name: mcp\nports:\n  - 8080\n  - 8081\n",
+		"config.toml": "name = \"mcp\"\nports = [8080, 8081]\n",
+		"config.conf": "name: mcp\nports: [8080, 8081]\n",
+		"broken.json": "{\n  \"name\": \"mcp\",\n  oops\n}",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644))
+	}
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	expected := map[string]interface{}{
+		"name":  "mcp",
+		"ports": []interface{}{float64(8080), float64(8081)},
+	}
+	for _, arguments := range []map[string]interface{}{
+		{"path": "config.json"},
+		{"path": "config.yml"},
+		{"path": "config.toml"},
+		{"path": "config.conf", "format": "yaml"},
+	} {
+		response := callTool(t, e, "filesystem.read-structured", arguments)
+		assert.Equal(t, "success", response.Status, arguments["path"])
+		content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+		assert.Equal(t, expected, content["data"], arguments["path"])
+	}
+
+	// Parse errors point at the problem
+	response := callTool(t, e, "filesystem.read-structured", map[string]interface{}{"path": "broken.json"})
+	assert.Equal(t, "error", response.Status)
+	if assert.NotNil(t, response.Error) {
+		assert.Contains(t, response.Error.Message, "line 3, column 3")
+	}
+
+	// Unknown extensions need an explicit format
+	response = callTool(t, e, "filesystem.read-structured", map[string]interface{}{"path": "config.conf"})
+	assert.Equal(t, "error", response.Status)
+}
+
+func TestListDirectorySorting(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	// Give every entry a distinct size and modification time
+	fixtures := []struct {
+		name string
+		size int
+		age  time.Duration
+	}{
+		{"b.txt", 30, 3 * time.Hour},
+		{"a.txt", 10, 1 * time.Hour},
+		{"c.txt", 20, 2 * time.Hour},
+	}
+	now := time.Now()
+	for _, fixture := range fixtures {
+		path := filepath.Join(tempDir, fixture.name)
+		assert.NoError(t, os.WriteFile(path, bytes.Repeat([]byte("x"), fixture.size), 0644))
+		assert.NoError(t, os.Chtimes(path, now.Add(-fixture.age), now.Add(-fixture.age)))
+	}
+	assert.NoError(t, os.Mkdir(filepath.Join(tempDir, "z-dir"), 0755))
+	assert.NoError(t, os.Chtimes(filepath.Join(tempDir, "z-dir"), now.Add(-4*time.Hour), now.Add(-4*time.Hour)))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	list := func(arguments map[string]interface{}) []string {
+		arguments["path"] = "."
+		response := callTool(t, e, "filesystem.list", arguments)
+		assert.Equal(t, "success", response.Status)
+		content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+		names := make([]string, 0)
+		for _, file := range content["files"].([]interface{}) {
+			names = append(names, file.(map[string]interface{})["name"].(string))
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"a.txt", "b.txt", "c.txt", "z-dir"}, list(map[string]interface{}{}))
+	assert.Equal(t, []string{"z-dir", "c.txt", "b.txt", "a.txt"}, list(map[string]interface{}{"sort_desc": true}))
+	assert.Equal(t, []string{"z-dir", "a.txt", "b.txt", "c.txt"}, list(map[string]interface{}{"dirs_first": true}))
+	assert.Equal(t, []string{"z-dir", "b.txt", "c.txt", "a.txt"}, list(map[string]interface{}{"sort_by": "mod_time"}))
+	assert.Equal(t, []string{"a.txt", "c.txt", "b.txt", "z-dir"}, list(map[string]interface{}{"sort_by": "mod_time", "sort_desc": true}))
+	assert.Equal(t, []string{"z-dir", "b.txt", "c.txt", "a.txt"}, list(map[string]interface{}{
+		"sort_by":    "size",
+		"sort_desc":  true,
+		"dirs_first": true,
+	}))
+
+	// Pagination follows the sort order
+	assert.Equal(t, []string{"c.txt"}, list(map[string]interface{}{
+		"sort_by": "size",
+		"offset":  1,
+		"limit":   1,
+	}))
+
+	rec := callToolRaw(t, e, "filesystem.list", map[string]interface{}{"path": ".", "sort_by": "owner"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCheckLineEndings(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "lf.txt"), []byte("a\nb\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "mixed.txt"), []byte("a\r\nb\nc\r\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "binary.bin"), []byte("a\x00\r\n"), 0644))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	check := func(arguments map[string]interface{}) map[string]interface{} {
+		response := callTool(t, e, "filesystem.check-line-endings", arguments)
+		assert.Equal(t, "success", response.Status)
+		return response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	}
+
+	content := check(map[string]interface{}{"path": "lf.txt"})
+	assert.Equal(t, "lf", content["style"])
+	assert.Equal(t, float64(2), content["lf"])
+
+	content = check(map[string]interface{}{"path": "mixed.txt"})
+	assert.Equal(t, "mixed", content["style"])
+	assert.Equal(t, float64(1), content["lf"])
+	assert.Equal(t, float64(2), content["crlf"])
+	assert.Equal(t, false, content["fixed"])
+
+	// Checking never modifies the file
+	response := callTool(t, e, "filesystem.check-line-endings", map[string]interface{}{"path": "mixed.txt", "fix": true})
+	assert.Equal(t, "error", response.Status)
+	if assert.NotNil(t, response.Error) {
+		assert.Equal(t, mcp.ErrorCodeInvalidArgument, response.Error.Code)
+	}
+
+	fix := func(arguments map[string]interface{}) map[string]interface{} {
+		response := callTool(t, e, "filesystem.fix-line-endings", arguments)
+		assert.Equal(t, "success", response.Status)
+		return response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	}
+	content = fix(map[string]interface{}{"path": "mixed.txt", "target": "crlf"})
+	assert.Equal(t, "mixed", content["style"])
+	assert.Equal(t, true, content["fixed"])
+	data, err := os.ReadFile(filepath.Join(tempDir, "mixed.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "a\r\nb\r\nc\r\n", string(data))
+
+	// Files that already match the target are left alone
+	content = fix(map[string]interface{}{"path": "lf.txt"})
+	assert.Equal(t, false, content["fixed"])
+
+	response = callTool(t, e, "filesystem.check-line-endings", map[string]interface{}{"path": "binary.bin"})
+	assert.Equal(t, "error", response.Status)
+	response = callTool(t, e, "filesystem.fix-line-endings", map[string]interface{}{"path": "binary.bin"})
+	assert.Equal(t, "error", response.Status)
+
+	// Fixing is held to the allowed extensions and the write limit
+	e = setupTestServer(mcp.WithRootDir(tempDir), mcp.WithAllowedExtensions(".md"))
+	response = callTool(t, e, "filesystem.fix-line-endings", map[string]interface{}{"path": "mixed.txt"})
+	assert.Equal(t, "error", response.Status)
+	if assert.NotNil(t, response.Error) {
+		assert.Equal(t, mcp.ErrorCodeExtensionNotAllowed, response.Error.Code)
+	}
+	e = setupTestServer(mcp.WithRootDir(tempDir), mcp.WithMaxWriteBytes(4))
+	response = callTool(t, e, "filesystem.fix-line-endings", map[string]interface{}{"path": "lf.txt", "target": "crlf"})
+	assert.Equal(t, "error", response.Status)
+	if assert.NotNil(t, response.Error) {
+		assert.Equal(t, mcp.ErrorCodeFileTooLarge, response.Error.Code)
+	}
+	data, err = os.ReadFile(filepath.Join(tempDir, "lf.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "a\nb\n", string(data))
+
+	// Reporting still works in read-only mode but fixing does not
+	e = setupTestServer(mcp.WithRootDir(tempDir), mcp.WithReadOnly(true))
+	response = callTool(t, e, "filesystem.check-line-endings", map[string]interface{}{"path": "lf.txt"})
+	assert.Equal(t, "success", response.Status)
+	response = callTool(t, e, "filesystem.fix-line-endings", map[string]interface{}{"path": "lf.txt"})
+	assert.Equal(t, "error", response.Status)
+	if assert.NotNil(t, response.Error) {
+		assert.Equal(t, "read_only", response.Error.Code)
+	}
+}
+
+// callRPC posts a raw JSON-RPC request to the test server and decodes the response
+func callRPC(t *testing.T, e *echo.Echo, body string) map[string]interface{} {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/rpc", bytes.NewReader([]byte(body)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(rec.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "2.0", response["jsonrpc"])
+	return response
+}
+
+func TestJSONRPC(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	err = os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("Hello, MCP!"), 0644)
+	assert.NoError(t, err)
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	response := callRPC(t, e, `{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": {}}`)
+	assert.Equal(t, float64(1), response["id"])
+	result := response["result"].(map[string]interface{})
+	assert.Equal(t, "Test Filesystem MCP Server", result["serverInfo"].(map[string]interface{})["name"])
+	assert.Contains(t, result["capabilities"], "tools")
+
+	response = callRPC(t, e, `{"jsonrpc": "2.0", "id": "list", "method": "tools/list"}`)
+	assert.Equal(t, "list", response["id"])
+	assert.NotEmpty(t, response["result"].(map[string]interface{})["tools"])
+
+	response = callRPC(t, e, `{"jsonrpc": "2.0", "id": 2, "method": "tools/call", "params": {"name": "filesystem.read", "arguments": {"path": "test.txt"}}}`)
+	result = response["result"].(map[string]interface{})
+	assert.Equal(t, "success", result["status"])
+	content := result["result"].(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, "Hello, MCP!", content["content"])
+
+	response = callRPC(t, e, `{"jsonrpc": "2.0", "id": 3, "method": "resources/read", "params": {"name": "filesystem.file", "arguments": {"path": "test.txt"}}}`)
+	result = response["result"].(map[string]interface{})
+	assert.Equal(t, "success", result["status"])
+
+	// Protocol errors use the standard codes
+	errorCode := func(response map[string]interface{}) interface{} {
+		if rpcErr, ok := response["error"].(map[string]interface{}); ok {
+			return rpcErr["code"]
+		}
+		return nil
+	}
+	assert.Equal(t, float64(-32700), errorCode(callRPC(t, e, `{not json`)))
+	assert.Equal(t, float64(-32600), errorCode(callRPC(t, e, `{"jsonrpc": "1.0", "id": 4, "method": "initialize"}`)))
+	assert.Equal(t, float64(-32601), errorCode(callRPC(t, e, `{"jsonrpc": "2.0", "id": 5, "method": "tools/unknown"}`)))
+	assert.Equal(t, float64(-32602), errorCode(callRPC(t, e, `{"jsonrpc": "2.0", "id": 6, "method": "tools/call", "params": {"name": "missing.tool"}}`)))
+
+	// Notifications get no response body
+	req := httptest.NewRequest(http.MethodPost, "/rpc", bytes.NewReader([]byte(`{"jsonrpc": "2.0", "method": "notifications/initialized"}`)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestCallToolEventStream(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	for _, path := range []string{"a.go", "b.go", "sub/c.go", "d.txt"} {
+		fullPath := filepath.Join(tempDir, path)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		assert.NoError(t, os.WriteFile(fullPath, []byte("package main\n"), 0644))
+	}
+
+	ts := httptest.NewServer(setupTestServer(mcp.WithRootDir(tempDir)))
+	defer ts.Close()
+
+	// readEvents posts a call-tool request asking for an event stream and returns its events in order
+	type event struct {
+		name string
+		data map[string]interface{}
+	}
+	readEvents := func(toolID string, arguments map[string]interface{}) []event {
+		jsonBody, err := json.Marshal(map[string]interface{}{
+			"tool_id":    toolID,
+			"request_id": "test-stream",
+			"params":     map[string]interface{}{"arguments": arguments},
+		})
+		assert.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/v1/call-tool", bytes.NewReader(jsonBody))
+		assert.NoError(t, err)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAccept, "text/event-stream")
+
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, "text/event-stream", resp.Header.Get(echo.HeaderContentType))
+
+		var events []event
+		var current event
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				current.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &current.data))
+			case line == "":
+				events = append(events, current)
+				current = event{}
+			}
+		}
+		assert.NoError(t, scanner.Err())
+		return events
+	}
+
+	events := readEvents("filesystem.search", map[string]interface{}{"path": ".", "pattern": "*.go"})
+	if assert.Len(t, events, 4) {
+		for i, path := range []string{"a.go", "b.go", "sub/c.go"} {
+			assert.Equal(t, "chunk", events[i].name)
+			assert.Equal(t, path, events[i].data["path"])
+		}
+		assert.Equal(t, "result", events[3].name)
+		assert.Equal(t, "success", events[3].data["status"])
+		assert.Equal(t, "test-stream", events[3].data["request_id"])
+	}
+
+	events = readEvents("filesystem.grep", map[string]interface{}{"path": ".", "query": "package"})
+	assert.Len(t, events, 5)
+
+	// Tools that don't stream send a single result event
+	events = readEvents("filesystem.read", map[string]interface{}{"path": "a.go"})
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "result", events[0].name)
+	}
+}
+
+// lifecycleProvider records the lifecycle calls made by the server
+type lifecycleProvider struct {
+	slowProvider
+	name     string
+	startErr error
+	calls    *[]string
+}
+
+func (p lifecycleProvider) GetName() string { return p.name }
+
+func (p lifecycleProvider) Start(ctx context.Context) error {
+	*p.calls = append(*p.calls, "start "+p.name)
+	return p.startErr
+}
+
+func (p lifecycleProvider) Stop(ctx context.Context) error {
+	*p.calls = append(*p.calls, "stop "+p.name)
+	return nil
+}
+
+func TestRegisterProviderValidation(t *testing.T) {
+	var calls []string
+	mcpServer := server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	assert.NoError(t, mcpServer.RegisterProvider(lifecycleProvider{name: "one", calls: &calls}))
+
+	// Names must be unique, non-empty and free of dots
+	for _, name := range []string{"one", "", "my.fs"} {
+		err := mcpServer.RegisterProvider(lifecycleProvider{name: name, calls: &calls})
+		assert.Error(t, err, name)
+	}
+	assert.Equal(t, []string{"one"}, mcpServer.ListProviders())
+
+	// A name can be reused once its provider is unregistered
+	assert.True(t, mcpServer.UnregisterProvider("one"))
+	assert.NoError(t, mcpServer.RegisterProvider(lifecycleProvider{name: "one", calls: &calls}))
+}
+
+func TestFunctionProvider(t *testing.T) {
+	tools := mcp.NewFunctionProvider("util", "Utility tools")
+	err := tools.RegisterTool("util.echo", mcp.ToolInfo{
+		Description: "Returns its text argument",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}},
+			"required":   []string{"text"},
+		},
+		ReadOnly: true,
+	}, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText(request.Params.Arguments["text"].(string)), nil
+	})
+	assert.NoError(t, err)
+
+	// IDs must belong to the provider and be unique
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) { return nil, nil }
+	for _, id := range []string{"util.echo", "other.echo", "echo", "util."} {
+		assert.Error(t, tools.RegisterTool(id, mcp.ToolInfo{}, handler), id)
+	}
+	assert.Error(t, tools.RegisterTool("util.nothing", mcp.ToolInfo{}, nil))
+
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	mcpServer.Logger = nil
+	assert.NoError(t, mcpServer.RegisterProvider(tools))
+	mcpServer.RegisterRoutes(e)
+
+	// The tool is discovered
+	req := httptest.NewRequest(http.MethodPost, "/v1/discover", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var discover mcp.DiscoverResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &discover))
+	if assert.Len(t, discover.Providers, 1) && assert.Len(t, discover.Providers[0].Tools, 1) {
+		tool := discover.Providers[0].Tools[0]
+		assert.Equal(t, "util.echo", tool.ID)
+		assert.Equal(t, "echo", tool.Name)
+		assert.True(t, tool.ReadOnly)
+	}
+
+	// and called end to end, with its arguments validated against its schema
+	response := callTool(t, e, "util.echo", map[string]interface{}{"text": "hello"})
+	assert.Equal(t, "success", response.Status)
+	assert.Equal(t, "test-util.echo", response.RequestID)
+	assert.Equal(t, map[string]interface{}{"type": "text", "text": "hello"}, response.Result)
+
+	rec = callToolRaw(t, e, "util.echo", map[string]interface{}{"text": 42})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	response = callTool(t, e, "util.missing", map[string]interface{}{})
+	assert.Equal(t, "error", response.Status)
+	assert.Equal(t, mcp.ErrorCodeUnknownTool, response.Error.Code)
+}
+
+func TestLoadConfig(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	rootDir := filepath.Join(tempDir, "root")
+	assert.NoError(t, os.Mkdir(rootDir, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(rootDir, "test.txt"), []byte("content"), 0644))
+
+	writeConfig := func(name, content string) string {
+		path := filepath.Join(tempDir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	// The same settings in YAML and JSON
+	yamlPath := writeConfig("config.yaml", fmt.Sprintf(`
+name: Config Server
+version: 2.0.0
+description: Configured from a file
+root_dir: %s
+read_only: true
+max_read_bytes: 4
+max_body_bytes: 1024
+auth_token: secret
+`, rootDir))
+	jsonPath := writeConfig("config.json", fmt.Sprintf(`{
+		"name": "Config Server",
+		"version": "2.0.0",
+		"description": "Configured from a file",
+		"root_dir": %q,
+		"read_only": true,
+		"max_read_bytes": 4,
+		"max_body_bytes": 1024,
+		"auth_token": "secret"
+	}`, rootDir))
+
+	for _, path := range []string{yamlPath, jsonPath} {
+		config, err := server.LoadConfig(path)
+		if !assert.NoError(t, err, path) {
+			continue
+		}
+		assert.Equal(t, &server.Config{
+			Name:         "Config Server",
+			Version:      "2.0.0",
+			Description:  "Configured from a file",
+			RootDir:      rootDir,
+			ReadOnly:     true,
+			MaxReadBytes: 4,
+			MaxBodyBytes: 1024,
+			AuthToken:    "secret",
+		}, config, path)
+	}
+
+	// The server is set up as configured
+	config, err := server.LoadConfig(yamlPath)
+	assert.NoError(t, err)
+	mcpServer, err := server.NewMCPServerFromConfig(config)
+	assert.NoError(t, err)
+	assert.Equal(t, "Config Server", mcpServer.Name)
+	assert.Equal(t, "2.0.0", mcpServer.Version)
+	assert.Equal(t, int64(1024), mcpServer.MaxBodyBytes)
+
+	e := echo.New()
+	mcpServer.Logger = nil
+	mcpServer.RegisterRoutes(e)
+	call := func(tool string, arguments map[string]interface{}) mcp.CallToolResult {
+		jsonBody, err := json.Marshal(map[string]interface{}{"tool_id": tool, "params": map[string]interface{}{"arguments": arguments}})
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/v1/call-tool", bytes.NewReader(jsonBody))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var response mcp.CallToolResult
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		return response
+	}
+	response := call("filesystem.read", map[string]interface{}{"path": "test.txt"})
+	assert.Equal(t, "error", response.Status)
+	assert.Equal(t, mcp.ErrorCodeFileTooLarge, response.Error.Code)
+	response = call("filesystem.write", map[string]interface{}{"path": "new.txt", "content": "a"})
+	assert.Equal(t, "error", response.Status)
+	assert.Equal(t, mcp.ErrorCodeReadOnly, response.Error.Code)
+
+	// Invalid configs are rejected with the reason
+	for name, test := range map[string]struct {
+		content string
+		message string
+	}{
+		"missing-root.yaml": {"name: x\nversion: '1'\nroot_dir: " + filepath.Join(tempDir, "missing"), "does not exist"},
+		"file-root.yaml":    {"name: x\nversion: '1'\nroot_dir: " + yamlPath, "is not a directory"},
+		"no-name.json":      {`{"version": "1"}`, "name is required"},
+		"negative.json":     {`{"name": "x", "version": "1", "max_write_bytes": -1}`, "max_write_bytes must not be negative"},
+		"unknown.yaml":      {"name: x\nversion: '1'\nread_onyl: true", "read_onyl"},
+		"malformed.json":    {`{"name": `, "parsing config"},
+		"unsupported.toml":  {`name = "x"`, "extension"},
+	} {
+		_, err := server.LoadConfig(writeConfig(name, test.content))
+		if assert.Error(t, err, name) {
+			assert.Contains(t, err.Error(), test.message, name)
+		}
+	}
+	_, err = server.LoadConfig(filepath.Join(tempDir, "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestProviderLifecycle(t *testing.T) {
+	var calls []string
+	mcpServer := server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	mcpServer.RegisterProvider(lifecycleProvider{name: "one", calls: &calls})
+	mcpServer.RegisterProvider(slowProvider{})
+
+	assert.NoError(t, mcpServer.Start(context.Background()))
+	assert.Equal(t, []string{"start one"}, calls)
+	assert.NoError(t, mcpServer.Stop(context.Background()))
+	assert.Equal(t, []string{"start one", "stop one"}, calls)
+
+	// A failing provider stops the ones started before it
+	calls = nil
+	mcpServer = server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	mcpServer.RegisterProvider(lifecycleProvider{name: "broken", startErr: assert.AnError, calls: &calls})
+	err := mcpServer.Start(context.Background())
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, []string{"start broken"}, calls)
+}
+
+func TestFilesystemProviderLifecycle(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	mcpServer.RegisterProvider(mcp.NewFilesystemProvider(mcp.WithRootDir(tempDir)))
+	mcpServer.RegisterRoutes(e)
+
+	assert.NoError(t, mcpServer.Start(context.Background()))
+	assert.Error(t, mcpServer.Start(context.Background()))
+
+	response := callTool(t, e, "filesystem.stage-write", map[string]interface{}{
+		"path":    "test.txt",
+		"content": "hello",
+	})
+	assert.Equal(t, "success", response.Status)
+	token := response.Result.(map[string]interface{})["json"].(map[string]interface{})["token"]
+
+	// Stopping the provider discards pending staged writes
+	assert.NoError(t, mcpServer.Stop(context.Background()))
+	response = callTool(t, e, "filesystem.commit-write", map[string]interface{}{"token": token})
+	assert.Equal(t, "error", response.Status)
+
+	// The provider can be started again after it was stopped
+	assert.NoError(t, mcpServer.Start(context.Background()))
+	assert.NoError(t, mcpServer.Stop(context.Background()))
+}
+
+func TestBearerTokenAuth(t *testing.T) {
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	mcpServer.AuthToken = "s3cret"
+	mcpServer.RegisterProvider(mcp.NewFilesystemProvider())
+	mcpServer.RegisterRoutes(e)
+
+	discover := func(authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/discover", nil)
+		if authorization != "" {
+			req.Header.Set(echo.HeaderAuthorization, authorization)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, discover("Bearer s3cret").Code)
+
+	for _, authorization := range []string{"", "Bearer wrong", "s3cret", "Basic s3cret"} {
+		rec := discover(authorization)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, authorization)
+
+		var response mcp.ErrorResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "unauthorized", response.Error)
+	}
+
+	// The JSON-RPC transport is protected as well
+	req := httptest.NewRequest(http.MethodPost, "/rpc", bytes.NewReader([]byte(`{"jsonrpc": "2.0", "id": 1, "method": "initialize"}`)))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// The info endpoint stays open
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestRateLimit(t *testing.T) {
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	mcpServer.RateLimit = 1
+	mcpServer.RateBurst = 3
+	mcpServer.RegisterProvider(mcp.NewFilesystemProvider())
+	mcpServer.RegisterRoutes(e)
+
+	discover := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/discover", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Fire requests faster than the limit allows
+	limited := 0
+	for i := 0; i < 10; i++ {
+		rec := discover("192.0.2.1:1234")
+		if rec.Code == http.StatusTooManyRequests {
+			limited++
+
+			var response mcp.ErrorResponse
+			err := json.Unmarshal(rec.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "rate_limited", response.Error)
+		} else {
+			assert.Equal(t, http.StatusOK, rec.Code)
+		}
+	}
+	assert.GreaterOrEqual(t, limited, 6)
+
+	// Other clients have their own bucket
+	assert.Equal(t, http.StatusOK, discover("192.0.2.2:1234").Code)
+
+	// The limiter is disabled when the rate is zero
+	mcpServer.RateLimit = 0
+	assert.Equal(t, http.StatusOK, discover("192.0.2.1:1234").Code)
+}
+
+// unhealthyProvider is a provider whose health check always fails
+type unhealthyProvider struct {
+	slowProvider
+}
+
+func (unhealthyProvider) GetName() string { return "unhealthy" }
+
+func (unhealthyProvider) HealthCheck() error { return errors.New("backend unreachable") }
+
+func TestHealthEndpoints(t *testing.T) {
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	mcpServer.AuthToken = "s3cret"
+	mcpServer.RegisterProvider(mcp.NewFilesystemProvider())
+	mcpServer.RegisterRoutes(e)
+
+	get := func(path string) (*httptest.ResponseRecorder, mcp.HealthResponse) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		var response mcp.HealthResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		return rec, response
+	}
+
+	// Healthy: both probes succeed without a token
+	rec, response := get("/healthz")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ok", response.Status)
+
+	rec, response = get("/readyz")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ok", response.Status)
+	assert.Empty(t, response.Failures)
+
+	// Unhealthy: readiness names the failing provider, liveness is unaffected
+	mcpServer.RegisterProvider(unhealthyProvider{})
+
+	rec, response = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "unavailable", response.Status)
+	assert.Equal(t, map[string]string{"unhealthy": "backend unreachable"}, response.Failures)
+
+	rec, response = get("/healthz")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ok", response.Status)
+}
+
+func TestMetrics(t *testing.T) {
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	mcpServer.RegisterProvider(mcp.NewFilesystemProvider())
+	mcpServer.RegisterRoutes(e)
+
+	// Metrics are disabled by default
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	e = echo.New()
+	mcpServer.Metrics = server.NewMetrics()
+	mcpServer.RegisterRoutes(e)
+
+	calls := map[string]string{
+		"/v1/call-tool":     `{"tool_id": "filesystem.list", "request_id": "1", "params": {"arguments": {"path": "."}}}`,
+		"/v1/load-resource": `{"resource_id": "filesystem.file", "request_id": "2", "params": {"path": "go.mod"}}`,
+	}
+	for path, body := range calls {
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader([]byte(body)))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusOK, rec.Code)
+		}
+	}
+
+	// A failing call
+	req = httptest.NewRequest(http.MethodPost, "/v1/call-tool", bytes.NewReader([]byte(`{"tool_id": "filesystem.read", "request_id": "3", "params": {"arguments": {"path": "does-not-exist.txt"}}}`)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	// Calls over JSON-RPC, SSE and NDJSON are counted too
+	callRPC(t, e, `{"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": {"name": "filesystem.stat", "arguments": {"path": "go.mod"}}}`)
+	callRPC(t, e, `{"jsonrpc": "2.0", "id": 2, "method": "resources/read", "params": {"uri": "filesystem.file", "arguments": {"path": "go.mod"}}}`)
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/call-tool", bytes.NewReader([]byte(`{"tool_id": "filesystem.stat", "request_id": "4", "params": {"arguments": {"path": "go.mod"}}}`)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderAccept, "text/event-stream")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, "text/event-stream", rec.Header().Get(echo.HeaderContentType))
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/walk?path=mcp", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Scrape the metrics
+	req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentType), "text/plain")
+
+	body := rec.Body.String()
+	assert.Contains(t, body, "# TYPE mcp_tool_calls_total counter")
+	assert.Contains(t, body, `mcp_tool_calls_total{tool="filesystem.list"} 2`)
+	assert.Contains(t, body, `mcp_tool_calls_errors_total{tool="filesystem.list"} 0`)
+	assert.Contains(t, body, `mcp_tool_calls_total{tool="filesystem.read"} 1`)
+	assert.Contains(t, body, `mcp_tool_calls_errors_total{tool="filesystem.read"} 1`)
+	assert.Contains(t, body, `mcp_tool_calls_duration_seconds_bucket{tool="filesystem.list",le="+Inf"} 2`)
+	assert.Contains(t, body, `mcp_tool_calls_duration_seconds_count{tool="filesystem.list"} 2`)
+	assert.Contains(t, body, `mcp_tool_calls_total{tool="filesystem.stat"} 2`)
+	assert.Contains(t, body, `mcp_tool_calls_errors_total{tool="filesystem.stat"} 0`)
+	assert.Contains(t, body, `mcp_tool_calls_total{tool="filesystem.walk"} 1`)
+	assert.Contains(t, body, `mcp_resource_loads_total{resource="filesystem.file"} 3`)
+	assert.Contains(t, body, `mcp_resource_loads_errors_total{resource="filesystem.file"} 0`)
+}
+
+func TestArgumentValidation(t *testing.T) {
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	mcpServer.RegisterProvider(mcp.NewFilesystemProvider())
+	mcpServer.RegisterRoutes(e)
+
+	invalid := func(toolID string, arguments map[string]interface{}) []mcp.FieldError {
+		t.Helper()
+
+		rec := callToolRaw(t, e, toolID, arguments)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		var response mcp.ErrorResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "invalid_arguments", response.Error)
+		return response.Details
+	}
+
+	// Missing required parameters
+	assert.Equal(t, []mcp.FieldError{
+		{Field: "content", Message: "is required"},
+		{Field: "path", Message: "is required"},
+	}, invalid("filesystem.write", map[string]interface{}{}))
+
+	// Wrong types
+	details := invalid("filesystem.list", map[string]interface{}{"path": 42, "limit": "ten"})
+	if assert.Len(t, details, 2) {
+		assert.Equal(t, "limit", details[0].Field)
+		assert.Contains(t, details[0].Message, "want integer")
+		assert.Equal(t, "path", details[1].Field)
+		assert.Contains(t, details[1].Message, "want string")
+	}
+
+	// Values outside an enum
+	details = invalid("filesystem.read", map[string]interface{}{"path": "go.mod", "encoding": "utf-16"})
+	if assert.Len(t, details, 1) {
+		assert.Equal(t, "encoding", details[0].Field)
+	}
+
+	// Valid arguments reach the provider
+	response := callTool(t, e, "filesystem.read", map[string]interface{}{"path": "go.mod"})
+	assert.Equal(t, "success", response.Status)
+
+	// The JSON-RPC transport validates too
+	rpcResponse := callRPC(t, e, `{"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": {"name": "filesystem.read", "arguments": {}}}`)
+	if assert.Contains(t, rpcResponse, "error") {
+		assert.Equal(t, float64(mcp.JSONRPCInvalidParams), rpcResponse["error"].(map[string]interface{})["code"])
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	err = os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("Hello, MCP!"), 0644)
+	assert.NoError(t, err)
+	err = os.Mkdir(filepath.Join(tempDir, "subdir"), 0755)
+	assert.NoError(t, err)
+
+	provider := mcp.NewFilesystemProvider(mcp.WithRootDir(tempDir))
+
+	tests := []struct {
+		tool      string
+		arguments map[string]interface{}
+		code      string
+		argument  string
+	}{
+		{"read", map[string]interface{}{}, mcp.ErrorCodeInvalidArgument, "path"},
+		{"read", map[string]interface{}{"path": "missing.txt"}, mcp.ErrorCodeNotFound, "path"},
+		{"read", map[string]interface{}{"path": "subdir"}, mcp.ErrorCodeIsDirectory, "path"},
+		{"read", map[string]interface{}{"path": "../outside.txt"}, mcp.ErrorCodeInvalidArgument, "path"},
+		{"list", map[string]interface{}{"path": "test.txt"}, mcp.ErrorCodeNotDirectory, "path"},
+		{"list", map[string]interface{}{"path": ".", "limit": float64(-1)}, mcp.ErrorCodeInvalidArgument, "limit"},
+		{"files-equal", map[string]interface{}{"path_a": "test.txt", "path_b": "missing.txt"}, mcp.ErrorCodeNotFound, "path_b"},
+		{"walk", map[string]interface{}{"path": ".", "cursor": "!!"}, mcp.ErrorCodeInvalidArgument, "cursor"},
+		{"commit-write", map[string]interface{}{"token": "unknown"}, mcp.ErrorCodeNotFound, "token"},
+		{"no-such-tool", map[string]interface{}{}, mcp.ErrorCodeUnknownTool, ""},
+	}
+	for _, test := range tests {
+		response, err := provider.CallTool(context.Background(), test.tool, mcp.CallToolRequest{
+			RequestID: "test-error-codes",
+			Params:    mcp.CallToolParams{Arguments: test.arguments},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "error", response.Status, test.tool)
+		if assert.NotNil(t, response.Error, test.tool) {
+			assert.Equal(t, test.code, response.Error.Code, "%s %v", test.tool, test.arguments)
+			assert.NotEmpty(t, response.Error.Message)
+			if test.argument == "" {
+				assert.Nil(t, response.Error.Details)
+			} else {
+				assert.Equal(t, map[string]interface{}{"argument": test.argument}, response.Error.Details, test.tool)
+			}
+		}
+	}
+
+	// Resources use the same codes
+	response, err := provider.LoadResource(context.Background(), "file", mcp.LoadResourceRequest{
+		RequestID: "test-error-codes",
+		Params:    map[string]interface{}{"path": "missing.txt"},
+	})
+	assert.NoError(t, err)
+	if assert.NotNil(t, response.Error) {
+		assert.Equal(t, mcp.ErrorCodeNotFound, response.Error.Code)
+		assert.Equal(t, map[string]interface{}{"argument": "path"}, response.Error.Details)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	assert.NoError(t, os.Mkdir(filepath.Join(tempDir, "sub"), 0755))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir), mcp.WithMaxWatchers(1))
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	// startWatch opens a watch stream and returns a channel of its events
+	type event struct {
+		name string
+		data map[string]interface{}
+	}
+	startWatch := func(ctx context.Context, path string) <-chan event {
+		jsonBody, err := json.Marshal(map[string]interface{}{
+			"tool_id":    "filesystem.watch",
+			"request_id": "test-watch",
+			"params":     map[string]interface{}{"arguments": map[string]interface{}{"path": path}},
+		})
+		assert.NoError(t, err)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, ts.URL+"/v1/call-tool", bytes.NewReader(jsonBody))
+		assert.NoError(t, err)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAccept, "text/event-stream")
+
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+
+		events := make(chan event, 100)
+		go func() {
+			defer close(events)
+			defer resp.Body.Close()
+
+			var current event
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				line := scanner.Text()
+				switch {
+				case strings.HasPrefix(line, "event: "):
+					current.name = strings.TrimPrefix(line, "event: ")
+				case strings.HasPrefix(line, "data: "):
+					json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &current.data)
+				case line == "":
+					events <- current
+					current = event{}
+				}
+			}
+		}()
+		return events
+	}
+
+	// waitForChange rewrites a file until the watch reports it, since the
+	// watch may still be registering its directories when the stream opens
+	waitForChange := func(events <-chan event, path string) event {
+		for attempt := 0; attempt < 50; attempt++ {
+			assert.NoError(t, os.WriteFile(filepath.Join(tempDir, path), []byte("changed"), 0644))
+			select {
+			case ev := <-events:
+				return ev
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+		t.Fatalf("no event for %s", path)
+		return event{}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := startWatch(ctx, ".")
+
+	// Changes in subdirectories are reported relative to the root
+	ev := waitForChange(events, "sub/file.txt")
+	assert.Equal(t, "chunk", ev.name)
+	assert.Equal(t, filepath.Join("sub", "file.txt"), ev.data["path"])
+	assert.Contains(t, []interface{}{"create", "write"}, ev.data["op"])
+
+	assert.NoError(t, os.Remove(filepath.Join(tempDir, "sub", "file.txt")))
+	for ev = range events {
+		if ev.data["op"] == "remove" {
+			break
+		}
+	}
+	assert.Equal(t, filepath.Join("sub", "file.txt"), ev.data["path"])
+
+	// Only one watch may run at a time
+	response := callTool(t, e, "filesystem.watch", map[string]interface{}{"path": "."})
+	assert.Equal(t, mcp.ErrorCodeStreamingRequired, response.Error.Code)
+
+	otherEvents := startWatch(context.Background(), ".")
+	ev = <-otherEvents
+	assert.Equal(t, "result", ev.name)
+	assert.Equal(t, "error", ev.data["status"])
+	assert.Equal(t, mcp.ErrorCodeLimitExceeded, ev.data["error"].(map[string]interface{})["code"])
+
+	// Disconnecting tears the watch down and frees its slot
+	cancel()
+	for range events {
+	}
+	assert.Eventually(t, func() bool {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		ev, ok := <-startWatch(ctx, ".")
+		return !ok || ev.name != "result"
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestAllowedExtensions(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	err = os.WriteFile(filepath.Join(tempDir, "tool.exe"), []byte("MZ"), 0644)
+	assert.NoError(t, err)
+
+	e := setupTestServer(mcp.WithRootDir(tempDir), mcp.WithAllowedExtensions(".txt", "json", ".md"))
+
+	// Allowed extensions are matched case-insensitively, with or without the dot
+	for _, path := range []string{"notes.txt", "data.JSON", "README.Md"} {
+		response := callTool(t, e, "filesystem.write", map[string]interface{}{"path": path, "content": "hello"})
+		assert.Equal(t, "success", response.Status, path)
+	}
+
+	// Other extensions are rejected
+	for _, path := range []string{"tool.exe", "Makefile", "notes.txt.exe"} {
+		response := callTool(t, e, "filesystem.write", map[string]interface{}{"path": path, "content": "hello"})
+		assert.Equal(t, "error", response.Status, path)
+		assert.Equal(t, mcp.ErrorCodeExtensionNotAllowed, response.Error.Code, path)
+		_, err := os.Stat(filepath.Join(tempDir, path))
+		if path != "tool.exe" {
+			assert.True(t, os.IsNotExist(err), path)
+		}
+	}
+	content, err := os.ReadFile(filepath.Join(tempDir, "tool.exe"))
+	assert.NoError(t, err)
+	assert.Equal(t, "MZ", string(content))
+
+	// Reads and the file resource are restricted too
+	response := callTool(t, e, "filesystem.read", map[string]interface{}{"path": "tool.exe"})
+	assert.Equal(t, mcp.ErrorCodeExtensionNotAllowed, response.Error.Code)
+
+	response = callTool(t, e, "filesystem.read", map[string]interface{}{"path": "notes.txt"})
+	assert.Equal(t, "success", response.Status)
+
+	body := `{"resource_id": "filesystem.file", "request_id": "test-ext", "params": {"path": "tool.exe"}}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/load-resource", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var resource mcp.LoadResourceResult
+	err = json.Unmarshal(rec.Body.Bytes(), &resource)
+	assert.NoError(t, err)
+	if assert.NotNil(t, resource.Error) {
+		assert.Equal(t, mcp.ErrorCodeExtensionNotAllowed, resource.Error.Code)
+	}
+}
+
+func TestFileSizeLimits(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	err = os.WriteFile(filepath.Join(tempDir, "small.txt"), []byte(strings.Repeat("a", 10)), 0644)
+	assert.NoError(t, err)
+	err = os.WriteFile(filepath.Join(tempDir, "large.txt"), []byte(strings.Repeat("a", 11)), 0644)
+	assert.NoError(t, err)
+
+	e := setupTestServer(mcp.WithRootDir(tempDir), mcp.WithMaxReadBytes(10), mcp.WithMaxWriteBytes(10))
+
+	// Reads up to the limit succeed
+	response := callTool(t, e, "filesystem.read", map[string]interface{}{"path": "small.txt"})
+	assert.Equal(t, "success", response.Status)
+
+	response = callTool(t, e, "filesystem.read", map[string]interface{}{"path": "large.txt"})
+	assert.Equal(t, "error", response.Status)
+	assert.Equal(t, mcp.ErrorCodeFileTooLarge, response.Error.Code)
+
+	// Writes are limited by the decoded content length
+	response = callTool(t, e, "filesystem.write", map[string]interface{}{"path": "out.txt", "content": strings.Repeat("b", 10)})
+	assert.Equal(t, "success", response.Status)
+
+	response = callTool(t, e, "filesystem.write", map[string]interface{}{"path": "out.txt", "content": strings.Repeat("b", 11)})
+	assert.Equal(t, "error", response.Status)
+	assert.Equal(t, mcp.ErrorCodeFileTooLarge, response.Error.Code)
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("c", 10)))
+	response = callTool(t, e, "filesystem.write", map[string]interface{}{"path": "out.bin", "content": encoded, "encoding": "base64"})
+	assert.Equal(t, "success", response.Status)
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "out.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Repeat("b", 10), string(content))
+}
+
+func TestWriteRulesApplyToEveryWrite(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes.txt"), []byte("trailing   \n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "large.md"), []byte(strings.Repeat("a", 11)), 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(tempDir, "dir"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "dir", "large.md"), []byte(strings.Repeat("a", 11)), 0644))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir), mcp.WithAllowedExtensions(".md", ".zip"), mcp.WithMaxWriteBytes(10))
+
+	tests := []struct {
+		name      string
+		tool      string
+		arguments map[string]interface{}
+		code      string
+		created   string
+	}{
+		{"scaffold extension", "filesystem.scaffold", map[string]interface{}{
+			"path": ".",
+			"spec": []interface{}{map[string]interface{}{"name": "run.sh", "content": "echo"}},
+		}, mcp.ErrorCodeExtensionNotAllowed, "run.sh"},
+		{"scaffold size", "filesystem.scaffold", map[string]interface{}{
+			"path": ".",
+			"spec": []interface{}{map[string]interface{}{"name": "big.md", "content": strings.Repeat("b", 11)}},
+		}, mcp.ErrorCodeFileTooLarge, "big.md"},
+		{"whitespace extension", "filesystem.normalize-whitespace", map[string]interface{}{"path": "notes.txt"}, mcp.ErrorCodeExtensionNotAllowed, ""},
+		{"counter extension", "filesystem.counter-increment", map[string]interface{}{"path": "count.txt"}, mcp.ErrorCodeExtensionNotAllowed, "count.txt"},
+		{"copy size", "filesystem.copy", map[string]interface{}{"path": "large.md", "destination": "copy.md"}, mcp.ErrorCodeFileTooLarge, "copy.md"},
+		{"zip size", "filesystem.zip", map[string]interface{}{"path": "dir", "destination": "dir.zip"}, mcp.ErrorCodeFileTooLarge, "dir.zip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := callTool(t, e, tt.tool, tt.arguments)
+			assert.Equal(t, "error", response.Status)
+			if assert.NotNil(t, response.Error) {
+				assert.Equal(t, tt.code, response.Error.Code)
+			}
+			if tt.created != "" {
+				_, err := os.Stat(filepath.Join(tempDir, tt.created))
+				assert.True(t, os.IsNotExist(err))
+			}
+		})
+	}
+
+	// Nothing was rewritten
+	data, err := os.ReadFile(filepath.Join(tempDir, "notes.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "trailing   \n", string(data))
+
+	// Directory copies list the files over the limit instead of failing
+	response := callTool(t, e, "filesystem.copy", map[string]interface{}{"path": "dir", "destination": "copy", "recursive": true})
+	assert.Equal(t, "success", response.Status)
+	content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Len(t, content["errors"], 1)
+	_, err = os.Stat(filepath.Join(tempDir, "copy", "large.md"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGzipCompression(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	testContent := strings.Repeat("compress me ", 100)
+	err = os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte(testContent), 0644)
+	assert.NoError(t, err)
+
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	mcpServer.Compress = true
+	mcpServer.RegisterProvider(mcp.NewFilesystemProvider(mcp.WithRootDir(tempDir)))
+	mcpServer.RegisterRoutes(e)
+
+	post := func(acceptEncoding string) *httptest.ResponseRecorder {
+		body := `{"tool_id": "filesystem.read", "request_id": "test-gzip", "params": {"arguments": {"path": "test.txt"}}}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/call-tool", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if acceptEncoding != "" {
+			req.Header.Set(echo.HeaderAcceptEncoding, acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Clients that accept gzip get a compressed body
+	rec := post("gzip")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "gzip", rec.Header().Get(echo.HeaderContentEncoding))
+
+	reader, err := gzip.NewReader(rec.Body)
+	assert.NoError(t, err)
+	decompressed, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+
+	var response mcp.CallToolResult
+	err = json.Unmarshal(decompressed, &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "success", response.Status)
+	content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, testContent, content["content"])
+
+	// Other clients get plain JSON
+	rec = post("")
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.True(t, json.Valid(rec.Body.Bytes()))
+
+	// Resource streams are sent unmodified
+	req := httptest.NewRequest(http.MethodGet, "/v1/stream-resource?resource_id=filesystem.file&path=test.txt", nil)
+	req.Header.Set(echo.HeaderAcceptEncoding, "gzip")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, testContent, rec.Body.String())
+}
+
+func TestBatchToolCalls(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	err = os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("Hello, MCP!"), 0644)
+	assert.NoError(t, err)
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	body := `[
+		{"tool_id": "filesystem.read", "request_id": "read-ok", "params": {"arguments": {"path": "test.txt"}}},
+		{"tool_id": "filesystem.read", "request_id": "read-missing", "params": {"arguments": {"path": "missing.txt"}}},
+		{"tool_id": "filesystem.list", "request_id": "list", "params": {"arguments": {"path": "."}}},
+		{"tool_id": "nope.read", "request_id": "bad-provider", "params": {"arguments": {}}},
+		{"tool_id": "filesystem.read", "request_id": "bad-arguments", "params": {"arguments": {}}}
+	]`
+	req := httptest.NewRequest(http.MethodPost, "/v1/batch", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var results []mcp.CallToolResult
+	err = json.Unmarshal(rec.Body.Bytes(), &results)
+	assert.NoError(t, err)
+	if !assert.Len(t, results, 5) {
+		return
+	}
+
+	// Results come back in the order of the calls, tagged with their request IDs
+	var requestIDs []string
+	for _, result := range results {
+		requestIDs = append(requestIDs, result.RequestID)
+	}
+	assert.Equal(t, []string{"read-ok", "read-missing", "list", "bad-provider", "bad-arguments"}, requestIDs)
+
+	assert.Equal(t, "success", results[0].Status)
+	content := results[0].Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, "Hello, MCP!", content["content"])
+
+	assert.Equal(t, "error", results[1].Status)
+	assert.Equal(t, mcp.ErrorCodeNotFound, results[1].Error.Code)
+
+	assert.Equal(t, "success", results[2].Status)
+	listing := results[2].Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Len(t, listing["files"], 1)
+
+	assert.Equal(t, "error", results[3].Status)
+	assert.Equal(t, "provider_not_found", results[3].Error.Code)
+
+	assert.Equal(t, "error", results[4].Status)
+	assert.Equal(t, "invalid_arguments", results[4].Error.Code)
+
+	// The body must be an array
+	req = httptest.NewRequest(http.MethodPost, "/v1/batch", strings.NewReader(`{"tool_id": "filesystem.read"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestChmod(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	testFile := filepath.Join(tempDir, "test.txt")
+	err = os.WriteFile(testFile, []byte("Hello, MCP!"), 0644)
+	assert.NoError(t, err)
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	response := callTool(t, e, "filesystem.chmod", map[string]interface{}{"path": "test.txt", "mode": "0600"})
+	assert.Equal(t, "success", response.Status)
+	result := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, "0644", result["previous"])
+	assert.Equal(t, "0600", result["mode"])
+
+	info, err := os.Stat(testFile)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Malformed modes are rejected without touching the file
+	for _, mode := range []string{"0999", "rw-r--r--", "", "10644"} {
+		response = callTool(t, e, "filesystem.chmod", map[string]interface{}{"path": "test.txt", "mode": mode})
+		assert.Equal(t, "error", response.Status, mode)
+		assert.Equal(t, mcp.ErrorCodeInvalidArgument, response.Error.Code, mode)
+		assert.Equal(t, map[string]interface{}{"argument": "mode"}, response.Error.Details, mode)
+	}
+
+	info, err = os.Stat(testFile)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Missing paths are reported
+	response = callTool(t, e, "filesystem.chmod", map[string]interface{}{"path": "missing.txt", "mode": "0644"})
+	assert.Equal(t, mcp.ErrorCodeNotFound, response.Error.Code)
+}
+
+func TestRename(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(tempDir, "docs"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "docs", "draft.txt"), []byte("draft"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "docs", "final.txt"), []byte("final"), 0644))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	// The file is renamed within its directory
+	response := callTool(t, e, "filesystem.rename", map[string]interface{}{"path": "docs/draft.txt", "new_name": "notes.txt"})
+	assert.Equal(t, "success", response.Status)
+	result := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, "docs/draft.txt", result["path"])
+	assert.Equal(t, "docs/notes.txt", result["new_path"])
+	data, err := os.ReadFile(filepath.Join(tempDir, "docs", "notes.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "draft", string(data))
+	_, err = os.Stat(filepath.Join(tempDir, "docs", "draft.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Names with path separators would relocate the file and are rejected
+	for _, newName := range []string{"../notes.txt", "sub/notes.txt", `sub\notes.txt`, "..", ""} {
+		response = callTool(t, e, "filesystem.rename", map[string]interface{}{"path": "docs/notes.txt", "new_name": newName})
+		assert.Equal(t, "error", response.Status, newName)
+		assert.Equal(t, mcp.ErrorCodeInvalidArgument, response.Error.Code, newName)
+		assert.Equal(t, map[string]interface{}{"argument": "new_name"}, response.Error.Details, newName)
+	}
+	_, err = os.Stat(filepath.Join(tempDir, "docs", "notes.txt"))
+	assert.NoError(t, err)
+
+	// An existing sibling is never overwritten
+	response = callTool(t, e, "filesystem.rename", map[string]interface{}{"path": "docs/notes.txt", "new_name": "final.txt"})
+	assert.Equal(t, "error", response.Status)
+	assert.Equal(t, mcp.ErrorCodeAlreadyExists, response.Error.Code)
+	data, err = os.ReadFile(filepath.Join(tempDir, "docs", "final.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "final", string(data))
+
+	// Directories can be renamed, but not the root itself
+	response = callTool(t, e, "filesystem.rename", map[string]interface{}{"path": "docs", "new_name": "archive"})
+	assert.Equal(t, "success", response.Status)
+	_, err = os.Stat(filepath.Join(tempDir, "archive", "final.txt"))
+	assert.NoError(t, err)
+
+	response = callTool(t, e, "filesystem.rename", map[string]interface{}{"path": ".", "new_name": "elsewhere"})
+	assert.Equal(t, "error", response.Status)
+	assert.Equal(t, mcp.ErrorCodeProtectedPath, response.Error.Code)
+
+	response = callTool(t, e, "filesystem.rename", map[string]interface{}{"path": "missing.txt", "new_name": "other.txt"})
+	assert.Equal(t, "error", response.Status)
+	assert.Equal(t, mcp.ErrorCodeNotFound, response.Error.Code)
+}
+
+func TestMove(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(tempDir, "inbox"), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(tempDir, "archive"), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(tempDir, "project", "src"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "inbox", "report.txt"), []byte("report"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "project", "src", "main.txt"), []byte("main"), 0644))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+	destination := func(response mcp.CallToolResult) interface{} {
+		return response.Result.(map[string]interface{})["json"].(map[string]interface{})["destination"]
+	}
+
+	// A file moved to an existing directory lands inside it under its own name
+	response := callTool(t, e, "filesystem.move", map[string]interface{}{"path": "inbox/report.txt", "destination": "archive"})
+	assert.Equal(t, "success", response.Status)
+	assert.Equal(t, "archive/report.txt", destination(response))
+	data, err := os.ReadFile(filepath.Join(tempDir, "archive", "report.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "report", string(data))
+	_, err = os.Stat(filepath.Join(tempDir, "inbox", "report.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	// A dry run lists the paths of a nested directory that would be moved
+	// without moving anything
+	response = callTool(t, e, "filesystem.move", map[string]interface{}{"path": "project", "destination": "archive", "dry_run": true})
+	assert.Equal(t, "success", response.Status)
+	dryRun := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, true, dryRun["dry_run"])
+	assert.Equal(t, "move", dryRun["operation"])
+	assert.Equal(t, "archive/project", dryRun["destination"])
+	assert.Equal(t, []interface{}{"project", "project/src", "project/src/main.txt"}, dryRun["paths"])
+	_, err = os.Stat(filepath.Join(tempDir, "project", "src", "main.txt"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(tempDir, "archive", "project"))
+	assert.True(t, os.IsNotExist(err))
+
+	// and still validates the request
+	response = callTool(t, e, "filesystem.move", map[string]interface{}{"path": "project", "destination": "project/src/nested", "dry_run": true})
+	assert.Equal(t, mcp.ErrorCodeInvalidArgument, response.Error.Code)
+	response = callTool(t, e, "filesystem.move", map[string]interface{}{"path": "project", "destination": "new/dir/project", "dry_run": true})
+	assert.Equal(t, "success", response.Status)
+	_, err = os.Stat(filepath.Join(tempDir, "new"))
+	assert.True(t, os.IsNotExist(err))
+
+	// So does a directory, with its contents
+	response = callTool(t, e, "filesystem.move", map[string]interface{}{"path": "project", "destination": "archive"})
+	assert.Equal(t, "success", response.Status)
+	assert.Equal(t, "archive/project", destination(response))
+	data, err = os.ReadFile(filepath.Join(tempDir, "archive", "project", "src", "main.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "main", string(data))
+
+	// A destination that does not exist is the new path
+	response = callTool(t, e, "filesystem.move", map[string]interface{}{"path": "archive/report.txt", "destination": "reports/2024.txt"})
+	assert.Equal(t, "success", response.Status)
+	assert.Equal(t, "reports/2024.txt", destination(response))
+	_, err = os.Stat(filepath.Join(tempDir, "reports", "2024.txt"))
+	assert.NoError(t, err)
+
+	// An entry of the same name in the directory is not replaced
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "inbox", "2024.txt"), []byte("other"), 0644))
+	response = callTool(t, e, "filesystem.move", map[string]interface{}{"path": "reports/2024.txt", "destination": "inbox"})
+	assert.Equal(t, "error", response.Status)
+	assert.Equal(t, mcp.ErrorCodeAlreadyExists, response.Error.Code)
+	data, err = os.ReadFile(filepath.Join(tempDir, "inbox", "2024.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "other", string(data))
+
+	// A directory cannot be moved into itself, nor anything outside the root
+	response = callTool(t, e, "filesystem.move", map[string]interface{}{"path": "archive", "destination": "archive/project"})
+	assert.Equal(t, "error", response.Status)
+	assert.Equal(t, mcp.ErrorCodeInvalidArgument, response.Error.Code)
+	response = callTool(t, e, "filesystem.move", map[string]interface{}{"path": "inbox/2024.txt", "destination": ".."})
+	assert.Equal(t, "error", response.Status)
+	assert.Equal(t, mcp.ErrorCodeInvalidArgument, response.Error.Code)
+	_, err = os.Stat(filepath.Join(tempDir, "inbox", "2024.txt"))
+	assert.NoError(t, err)
+}
+
+func TestProtectedPaths(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(tempDir, "data", "config", "keys"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "data", "config", "keys", "id.txt"), []byte("key"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "data", "notes.txt"), []byte("notes"), 0644))
+	assert.NoError(t, os.Symlink(filepath.Join(tempDir, "data", "config"), filepath.Join(tempDir, "config-link")))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir), mcp.WithProtectedPaths("data/config"))
+	assertProtected := func(tool string, arguments map[string]interface{}) {
+		t.Helper()
+		response := callTool(t, e, tool, arguments)
+		assert.Equal(t, "error", response.Status, arguments)
+		if assert.NotNil(t, response.Error, arguments) {
+			assert.Equal(t, mcp.ErrorCodeProtectedPath, response.Error.Code, arguments)
+		}
+	}
+
+	// The root cannot be deleted, however it is spelled
+	for _, path := range []string{".", "", "data/..", tempDir} {
+		assertProtected("filesystem.delete", map[string]interface{}{"path": path, "recursive": true})
+	}
+
+	// Nor can a protected subtree, directly, through a symbolic link or with
+	// its parent
+	for _, path := range []string{"data/config", "data/config/", "./data/../data/config", "config-link", "data"} {
+		assertProtected("filesystem.delete", map[string]interface{}{"path": path, "recursive": true})
+		assertProtected("filesystem.delete", map[string]interface{}{"path": path, "recursive": true, "dry_run": true})
+	}
+	assertProtected("filesystem.move", map[string]interface{}{"path": "data/config", "destination": "elsewhere"})
+	assertProtected("filesystem.move", map[string]interface{}{"path": ".", "destination": "elsewhere"})
+	assertProtected("filesystem.rename", map[string]interface{}{"path": "data", "new_name": "other"})
+	_, err = os.Stat(filepath.Join(tempDir, "data", "config", "keys", "id.txt"))
+	assert.NoError(t, err)
+
+	// Everything else can still be deleted
+	response := callTool(t, e, "filesystem.delete", map[string]interface{}{"path": "data/notes.txt"})
+	assert.Equal(t, "success", response.Status)
+	_, err = os.Stat(filepath.Join(tempDir, "data", "notes.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReadAutoEncoding(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	// A multi-byte character straddles the end of the sniffed bytes
+	longText := strings.Repeat("a", 8*1024-1) + "é and more"
+	files := map[string][]byte{
+		"utf8.txt":   []byte("Grüße, MCP! ✓\n"),
+		"long.txt":   []byte(longText),
+		"binary.bin": {0x7f, 'E', 'L', 'F', 0, 1, 2, 3},
+		"latin1.txt": {'c', 'a', 'f', 0xe9},
+	}
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(tempDir, name), content, 0644))
+	}
+
+	read := func(e *echo.Echo, arguments map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		response := callTool(t, e, "filesystem.read", arguments)
+		assert.Equal(t, "success", response.Status)
+		return response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	}
+
+	// Auto is the default and sends text as is
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+	for _, path := range []string{"utf8.txt", "long.txt"} {
+		content := read(e, map[string]interface{}{"path": path})
+		assert.Equal(t, "text", content["encoding"], path)
+		assert.Equal(t, true, content["is_text"], path)
+		assert.Equal(t, string(files[path]), content["content"], path)
+	}
+
+	// Binary and non-UTF-8 files are base64-encoded
+	for _, path := range []string{"binary.bin", "latin1.txt"} {
+		content := read(e, map[string]interface{}{"path": path, "encoding": "auto"})
+		assert.Equal(t, "base64", content["encoding"], path)
+		assert.Equal(t, false, content["is_text"], path)
+		assert.Equal(t, base64.StdEncoding.EncodeToString(files[path]), content["content"], path)
+	}
+
+	// An explicit encoding is used as is
+	content := read(e, map[string]interface{}{"path": "utf8.txt", "encoding": "base64"})
+	assert.Equal(t, "base64", content["encoding"])
+
+	// The default can be configured
+	e = setupTestServer(mcp.WithRootDir(tempDir), mcp.WithDefaultEncoding(mcp.EncodingText))
+	content = read(e, map[string]interface{}{"path": "latin1.txt"})
+	assert.Equal(t, "text", content["encoding"])
+}
+
+func TestEncodingValidation(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("content"), 0644))
+
+	provider := mcp.NewFilesystemProvider(mcp.WithRootDir(tempDir))
+	call := func(tool string, arguments map[string]interface{}) *mcp.CallToolResult {
+		response, err := provider.CallTool(context.Background(), tool, mcp.CallToolRequest{
+			RequestID: "test-encoding",
+			Params:    mcp.CallToolParams{Arguments: arguments},
+		})
+		assert.NoError(t, err)
+		return response
+	}
+	load := func(encoding interface{}) *mcp.LoadResourceResult {
+		response, err := provider.LoadResource(context.Background(), "file", mcp.LoadResourceRequest{
+			RequestID: "test-encoding",
+			Params:    map[string]interface{}{"path": "test.txt", "encoding": encoding},
+		})
+		assert.NoError(t, err)
+		return response
+	}
+	assertInvalidEncoding := func(status string, errorInfo *mcp.ErrorInfo) {
+		t.Helper()
+		assert.Equal(t, "error", status)
+		if assert.NotNil(t, errorInfo) {
+			assert.Equal(t, mcp.ErrorCodeInvalidArgument, errorInfo.Code)
+			assert.Equal(t, map[string]interface{}{"argument": "encoding"}, errorInfo.Details)
+		}
+	}
+
+	// Valid encodings are accepted
+	for _, encoding := range []string{"text", "base64", "auto", "data_url"} {
+		response := call("read", map[string]interface{}{"path": "test.txt", "encoding": encoding})
+		assert.Equal(t, "success", response.Status, encoding)
+		assert.Equal(t, "success", load(encoding).Status, encoding)
+	}
+	for _, encoding := range []string{"text", "base64"} {
+		content := "content"
+		if encoding == "base64" {
+			content = base64.StdEncoding.EncodeToString([]byte(content))
+		}
+		response := call("write", map[string]interface{}{"path": "test.txt", "content": content, "encoding": encoding})
+		assert.Equal(t, "success", response.Status, encoding)
+		response = call("stage-write", map[string]interface{}{"path": "test.txt", "content": content, "encoding": encoding})
+		assert.Equal(t, "success", response.Status, encoding)
+	}
+	data, err := os.ReadFile(filepath.Join(tempDir, "test.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+
+	// Unknown encodings, and auto for writes, are rejected instead of treated as text
+	for _, encoding := range []interface{}{"base46", "", float64(64)} {
+		response := call("read", map[string]interface{}{"path": "test.txt", "encoding": encoding})
+		assertInvalidEncoding(response.Status, response.Error)
+		resource := load(encoding)
+		assertInvalidEncoding(resource.Status, resource.Error)
+	}
+	for _, encoding := range []interface{}{"base46", "auto"} {
+		response := call("write", map[string]interface{}{"path": "test.txt", "content": "other", "encoding": encoding})
+		assertInvalidEncoding(response.Status, response.Error)
+		response = call("stage-write", map[string]interface{}{"path": "test.txt", "content": "other", "encoding": encoding})
+		assertInvalidEncoding(response.Status, response.Error)
+	}
+	data, err = os.ReadFile(filepath.Join(tempDir, "test.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+}
+
+func TestReadDataURL(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(1, 1, color.RGBA{G: 255, A: 255})
+	var encoded bytes.Buffer
+	assert.NoError(t, png.Encode(&encoded, img))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "image.png"), encoded.Bytes(), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes.txt"), []byte("hello"), 0644))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+	read := func(path string) map[string]interface{} {
+		response := callTool(t, e, "filesystem.read", map[string]interface{}{"path": path, "encoding": "data_url"})
+		assert.Equal(t, "success", response.Status)
+		return response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	}
+
+	// The content is a data URL of the detected type; the other fields stay
+	content := read("image.png")
+	assert.Equal(t, "data_url", content["encoding"])
+	assert.Equal(t, "image/png", content["mime_type"])
+	assert.Equal(t, false, content["is_text"])
+	dataURL := content["content"].(string)
+	if assert.True(t, strings.HasPrefix(dataURL, "data:image/png;base64,"), dataURL) {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(dataURL, "data:image/png;base64,"))
+		assert.NoError(t, err)
+		assert.Equal(t, encoded.Bytes(), decoded)
+	}
+
+	// Media type parameters are kept, without spaces
+	content = read("notes.txt")
+	assert.Equal(t, true, content["is_text"])
+	assert.Equal(t, "data:text/plain;charset=utf-8;base64,aGVsbG8=", content["content"])
+
+	// The file resource supports it too
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"resource_id": "filesystem.file",
+		"params":      map[string]interface{}{"path": "image.png", "encoding": "data_url"},
+	})
+	assert.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/v1/load-resource", bytes.NewReader(requestBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	var resource mcp.LoadResourceResult
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resource))
+	assert.Equal(t, "success", resource.Status)
+	assert.Contains(t, rec.Body.String(), `"content":"data:image/png;base64,`)
+}
+
+func TestMimeTypeDetection(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), 0, 0, 0, 13, 'I', 'H', 'D', 'R')
+	files := map[string][]byte{
+		"image.png": png,
+		"data.json": []byte(`{"name": "mcp"}`),
+		"notes.txt": []byte("Hello, MCP!"),
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(tempDir, name), content, 0644)
+		assert.NoError(t, err)
+	}
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	tests := []struct {
+		path     string
+		mimeType string
+		isText   bool
+	}{
+		{"image.png", "image/png", false},
+		{"data.json", "application/json", true},
+		{"notes.txt", "text/plain; charset=utf-8", true},
+	}
+	for _, tt := range tests {
+		// Tool reads
+		response := callTool(t, e, "filesystem.read", map[string]interface{}{"path": tt.path, "encoding": "base64"})
+		assert.Equal(t, "success", response.Status, tt.path)
+		content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+		assert.Equal(t, tt.mimeType, content["mime_type"], tt.path)
+		assert.Equal(t, tt.isText, content["is_text"], tt.path)
+
+		// The file resource
+		body := `{"resource_id": "filesystem.file", "request_id": "test-mime", "params": {"path": "` + tt.path + `", "encoding": "base64"}}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/load-resource", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		var resource mcp.LoadResourceResult
+		err = json.Unmarshal(rec.Body.Bytes(), &resource)
+		assert.NoError(t, err)
+		assert.Equal(t, "success", resource.Status, tt.path)
+		content = resource.Content.(map[string]interface{})["json"].(map[string]interface{})
+		assert.Equal(t, tt.mimeType, content["mime_type"], tt.path)
+		assert.Equal(t, tt.isText, content["is_text"], tt.path)
+
+		// Stats
+		response = callTool(t, e, "filesystem.stat", map[string]interface{}{"path": tt.path})
+		assert.Equal(t, "success", response.Status, tt.path)
+		stat := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+		assert.Equal(t, tt.mimeType, stat["mime_type"], tt.path)
+	}
+
+	// Directories have no MIME type
+	response := callTool(t, e, "filesystem.stat", map[string]interface{}{"path": "."})
+	stat := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.NotContains(t, stat, "mime_type")
+}
+
+func TestListDirectoryFilters(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	for _, name := range []string{"main.go", "util.go", "README.md", "notes.txt"} {
+		err := os.WriteFile(filepath.Join(tempDir, name), []byte("content"), 0644)
+		assert.NoError(t, err)
+	}
+	for _, name := range []string{"cmd", "internal", "docs.go"} {
+		err := os.Mkdir(filepath.Join(tempDir, name), 0755)
+		assert.NoError(t, err)
+	}
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	list := func(arguments map[string]interface{}) ([]string, float64) {
+		t.Helper()
+		arguments["path"] = "."
+		response := callTool(t, e, "filesystem.list", arguments)
+		if !assert.Equal(t, "success", response.Status) {
+			return nil, 0
+		}
+		content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+		var names []string
+		for _, file := range content["files"].([]interface{}) {
+			names = append(names, file.(map[string]interface{})["name"].(string))
+		}
+		return names, content["total"].(float64)
+	}
+
+	names, total := list(map[string]interface{}{"pattern": "*.go"})
+	assert.Equal(t, []string{"docs.go", "main.go", "util.go"}, names)
+	assert.Equal(t, float64(3), total)
+
+	names, _ = list(map[string]interface{}{"pattern": "*.go", "type": "file"})
+	assert.Equal(t, []string{"main.go", "util.go"}, names)
+
+	names, total = list(map[string]interface{}{"type": "dir"})
+	assert.Equal(t, []string{"cmd", "docs.go", "internal"}, names)
+	assert.Equal(t, float64(3), total)
+
+	// Pagination applies to the filtered entries
+	names, total = list(map[string]interface{}{"type": "file", "limit": 2, "offset": 1})
+	assert.Equal(t, []string{"main.go", "notes.txt"}, names)
+	assert.Equal(t, float64(4), total)
+
+	names, _ = list(map[string]interface{}{"type": "all"})
+	assert.Len(t, names, 7)
+
+	// Invalid patterns are rejected rather than matching nothing
+	response := callTool(t, e, "filesystem.list", map[string]interface{}{"path": ".", "pattern": "[a-"})
+	assert.Equal(t, "error", response.Status)
+	assert.Equal(t, mcp.ErrorCodeInvalidArgument, response.Error.Code)
+	assert.Equal(t, map[string]interface{}{"argument": "pattern"}, response.Error.Details)
+
+	// Unknown types fail argument validation
+	rec := callToolRaw(t, e, "filesystem.list", map[string]interface{}{"path": ".", "type": "symlink"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestDiskUsage(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]int{
+		"top.txt":          10,
+		"a/one.txt":        100,
+		"a/nested/two.txt": 200,
+		"b/three.txt":      1000,
+	}
+	for name, size := range files {
+		path := filepath.Join(tempDir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, bytes.Repeat([]byte("x"), size), 0644))
+	}
+	assert.NoError(t, os.Mkdir(filepath.Join(tempDir, "empty"), 0755))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	du := func(arguments map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		arguments["path"] = "."
+		response := callTool(t, e, "filesystem.du", arguments)
+		if !assert.Equal(t, "success", response.Status) {
+			return nil
+		}
+		return response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	}
+
+	usage := du(map[string]interface{}{})
+	assert.Equal(t, float64(1310), usage["bytes"])
+	assert.Equal(t, float64(4), usage["files"])
+	assert.Equal(t, float64(4), usage["directories"])
+	assert.NotContains(t, usage, "subdirs")
+
+	// max_depth stops the walk from descending
+	usage = du(map[string]interface{}{"max_depth": 1})
+	assert.Equal(t, float64(10), usage["bytes"])
+	assert.Equal(t, float64(1), usage["files"])
+	assert.Equal(t, float64(3), usage["directories"])
+
+	// by_subdir breaks the usage down per immediate subdirectory
+	usage = du(map[string]interface{}{"by_subdir": true})
+	assert.Equal(t, float64(1310), usage["bytes"])
+	subdirs := usage["subdirs"].([]interface{})
+	if assert.Len(t, subdirs, 3) {
+		a := subdirs[0].(map[string]interface{})
+		assert.Equal(t, "a", a["name"])
+		assert.Equal(t, float64(300), a["bytes"])
+		assert.Equal(t, float64(2), a["files"])
+		assert.Equal(t, float64(1), a["directories"])
+
+		b := subdirs[1].(map[string]interface{})
+		assert.Equal(t, "b", b["name"])
+		assert.Equal(t, float64(1000), b["bytes"])
+
+		empty := subdirs[2].(map[string]interface{})
+		assert.Equal(t, "empty", empty["name"])
+		assert.Equal(t, float64(0), empty["bytes"])
+	}
+
+	// Unreadable directories are skipped
+	if os.Geteuid() != 0 {
+		locked := filepath.Join(tempDir, "b")
+		assert.NoError(t, os.Chmod(locked, 0))
+		defer os.Chmod(locked, 0755)
+
+		usage = du(map[string]interface{}{})
+		assert.Equal(t, float64(310), usage["bytes"])
+	}
+
+	// Files are not directories
+	response := callTool(t, e, "filesystem.du", map[string]interface{}{"path": "top.txt"})
+	assert.Equal(t, mcp.ErrorCodeNotDirectory, response.Error.Code)
+}
+
+func TestMounts(t *testing.T) {
+	docsDir, err := os.MkdirTemp("", "mcp-docs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(docsDir)
+	codeDir, err := os.MkdirTemp("", "mcp-code")
+	assert.NoError(t, err)
+	defer os.RemoveAll(codeDir)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(docsDir, "guide.md"), []byte("# Guide"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(codeDir, "main.go"), []byte("package main"), 0644))
+	assert.NoError(t, os.Symlink(filepath.Join(codeDir, "main.go"), filepath.Join(docsDir, "link.go")))
+
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test MCP Server", "Test server for MCP", "1.0.0")
+	mcpServer.RegisterProvider(mcp.NewMountedFilesystemProvider(map[string]string{"docs": docsDir, "code": codeDir}))
+	mcpServer.RegisterRoutes(e)
+
+	// Discovery advertises the mounts
+	req := httptest.NewRequest(http.MethodPost, "/v1/discover", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	var discovery mcp.DiscoverResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &discovery))
+	if assert.Len(t, discovery.Providers, 1) {
+		assert.Equal(t, []string{"code", "docs"}, discovery.Providers[0].Mounts)
+	}
+
+	// Each mount serves its own directory
+	response := callTool(t, e, "filesystem.read", map[string]interface{}{"path": "docs:guide.md"})
+	assert.Equal(t, "success", response.Status)
+	assert.Equal(t, "# Guide", response.Result.(map[string]interface{})["json"].(map[string]interface{})["content"])
+
+	response = callTool(t, e, "filesystem.read", map[string]interface{}{"path": "code:main.go"})
+	assert.Equal(t, "success", response.Status)
+
+	response = callTool(t, e, "filesystem.list", map[string]interface{}{"path": "code:"})
+	assert.Equal(t, "success", response.Status)
+
+	// A path in one mount cannot reach the files of another
+	relToCode, err := filepath.Rel(docsDir, filepath.Join(codeDir, "main.go"))
+	assert.NoError(t, err)
+	for _, path := range []string{
+		"docs:main.go",
+		"docs:" + relToCode,
+		"docs:" + filepath.Join(codeDir, "main.go"),
+		"docs:link.go",
+	} {
+		response = callTool(t, e, "filesystem.read", map[string]interface{}{"path": path})
+		assert.Equal(t, "error", response.Status, path)
+	}
+
+	// Paths must name a known mount
+	for _, path := range []string{"guide.md", "other:guide.md", filepath.Join(docsDir, "guide.md")} {
+		response = callTool(t, e, "filesystem.read", map[string]interface{}{"path": path})
+		assert.Equal(t, "error", response.Status, path)
+		assert.Equal(t, mcp.ErrorCodeInvalidArgument, response.Error.Code, path)
+	}
+}
+
+func TestUnregisterProvider(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	mcpServer.RegisterRoutes(e)
+	assert.Empty(t, mcpServer.ListProviders())
+
+	discover := func() []string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/v1/discover", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		var response mcp.DiscoverResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		names := make([]string, 0)
+		for _, provider := range response.Providers {
+			names = append(names, provider.Name)
+		}
+		return names
+	}
+
+	// Registering a provider makes it discoverable and callable
+	mcpServer.RegisterProvider(mcp.NewFilesystemProvider(mcp.WithRootDir(tempDir)))
+	assert.Equal(t, []string{"filesystem"}, mcpServer.ListProviders())
+	assert.Equal(t, []string{"filesystem"}, discover())
+
+	response := callTool(t, e, "filesystem.list", map[string]interface{}{"path": "."})
+	assert.Equal(t, "success", response.Status)
+
+	// Once unregistered it disappears
+	assert.True(t, mcpServer.UnregisterProvider("filesystem"))
+	assert.False(t, mcpServer.UnregisterProvider("filesystem"))
+	assert.Empty(t, mcpServer.ListProviders())
+	assert.Empty(t, discover())
+
+	rec := callToolRaw(t, e, "filesystem.list", map[string]interface{}{"path": "."})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	var errorResponse mcp.ErrorResponse
+	err = json.Unmarshal(rec.Body.Bytes(), &errorResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, "provider_not_found", errorResponse.Error)
+
+	body := `{"resource_id": "filesystem.directory", "request_id": "test-unregister", "params": {"path": "."}}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/load-resource", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestConcurrentProviderRegistration(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	mcpServer.RegisterProvider(mcp.NewFilesystemProvider(mcp.WithRootDir(tempDir)))
+	mcpServer.RegisterRoutes(e)
+
+	// Register and unregister providers while requests are being handled;
+	// run with -race to detect unsynchronized access
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var calls []string
+		for i := range 50 {
+			name := fmt.Sprintf("extra-%d", i)
+			mcpServer.RegisterProvider(lifecycleProvider{name: name, calls: &calls})
+			mcpServer.ListProviders()
+			mcpServer.UnregisterProvider(name)
+		}
+	}()
+
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 25 {
+				req := httptest.NewRequest(http.MethodPost, "/v1/discover", nil)
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+				assert.Equal(t, http.StatusOK, rec.Code)
+
+				response := callTool(t, e, "filesystem.list", map[string]interface{}{"path": "."})
+				assert.Equal(t, "success", response.Status)
+
+				callRPC(t, e, `{"jsonrpc": "2.0", "id": 1, "method": "tools/list"}`)
+
+				req = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+				rec = httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+				assert.Equal(t, http.StatusOK, rec.Code)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, []string{"filesystem"}, mcpServer.ListProviders())
+}
+
+func TestInitialize(t *testing.T) {
+	e := setupTestServer()
+
+	initialize := func(body string) mcp.InitializeResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/v1/initialize", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response mcp.InitializeResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		return response
+	}
+
+	// A supported version is accepted
+	response := initialize(`{"protocol_version": "` + server.ProtocolVersion + `"}`)
+	assert.Equal(t, server.ProtocolVersion, response.ProtocolVersion)
+	assert.Equal(t, "Test Filesystem MCP Server", response.ServerInfo.Name)
+	assert.Equal(t, "1.0.0", response.ServerInfo.Version)
+	assert.Equal(t, mcp.Capabilities{Tools: true, Resources: true, Streaming: true, Subscriptions: false}, response.Capabilities)
+
+	// Otherwise the server offers its newest version
+	response = initialize(`{"protocol_version": "1999-01-01"}`)
+	assert.Equal(t, server.SupportedProtocolVersions[0], response.ProtocolVersion)
+
+	response = initialize(`{}`)
+	assert.Equal(t, server.SupportedProtocolVersions[0], response.ProtocolVersion)
+
+	// Malformed bodies are rejected
+	req := httptest.NewRequest(http.MethodPost, "/v1/initialize", strings.NewReader(`{"protocol_version": 1`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestWebSocket(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("content"), 0644))
+
+	ts := httptest.NewServer(setupTestServer(mcp.WithRootDir(tempDir)))
+	defer ts.Close()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", "", ts.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ws.Close()
+
+	receive := func() map[string]interface{} {
+		t.Helper()
+		var response map[string]interface{}
+		assert.NoError(t, ws.SetReadDeadline(time.Now().Add(5*time.Second)))
+		assert.NoError(t, websocket.JSON.Receive(ws, &response))
+		return response
+	}
+
+	// A list request is answered with a result frame
+	assert.NoError(t, websocket.Message.Send(ws, `{"jsonrpc": "2.0", "id": 1, "method": "tools/list"}`))
+	response := receive()
+	assert.Equal(t, float64(1), response["id"])
+	tools := response["result"].(map[string]interface{})["tools"].([]interface{})
+	assert.NotEmpty(t, tools)
+
+	// Several requests can be in flight at once; responses carry their ids
+	assert.NoError(t, websocket.Message.Send(ws, `{"jsonrpc": "2.0", "id": "read", "method": "tools/call", "params": {"name": "filesystem.read", "arguments": {"path": "test.txt"}}}`))
+	assert.NoError(t, websocket.Message.Send(ws, `{"jsonrpc": "2.0", "id": "list", "method": "tools/call", "params": {"name": "filesystem.list", "arguments": {"path": "."}}}`))
+	responses := map[interface{}]map[string]interface{}{}
+	for range 2 {
+		response := receive()
+		responses[response["id"]] = response
+	}
+	assert.Contains(t, responses, "read")
+	assert.Contains(t, responses, "list")
+	assert.Nil(t, responses["read"]["error"])
+	assert.Equal(t, "success", responses["read"]["result"].(map[string]interface{})["status"])
+
+	// Malformed frames get an error without closing the connection
+	assert.NoError(t, websocket.Message.Send(ws, `not json`))
+	response = receive()
+	assert.Equal(t, float64(mcp.JSONRPCParseError), response["error"].(map[string]interface{})["code"])
+
+	assert.NoError(t, websocket.Message.Send(ws, `{"jsonrpc": "2.0", "id": 2, "method": "missing"}`))
+	response = receive()
+	assert.Equal(t, float64(mcp.JSONRPCMethodNotFound), response["error"].(map[string]interface{})["code"])
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test Filesystem MCP Server", "1.0.0", "A test MCP server implementation")
+	mcpServer.RegisterProvider(mcp.NewFilesystemProvider())
+	mcpServer.RegisterRoutes(e)
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws"
+
+	dial := func(origin string) error {
+		ws, err := websocket.Dial(wsURL, "", origin)
+		if err == nil {
+			ws.Close()
+		}
+		return err
+	}
+
+	// Pages of other sites cannot connect unless their origin is allowed
+	assert.NoError(t, dial(ts.URL))
+	assert.Error(t, dial("https://evil.example.com"))
+
+	mcpServer.AllowedOrigins = []string{"https://app.example.com"}
+	assert.NoError(t, dial("https://app.example.com"))
+	assert.Error(t, dial("https://evil.example.com"))
+	assert.Error(t, dial(ts.URL))
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+	path := filepath.Join(tempDir, "test.txt")
+
+	write := func(key, content string) *httptest.ResponseRecorder {
+		jsonBody, err := json.Marshal(map[string]interface{}{
+			"tool_id":         "filesystem.write",
+			"request_id":      "write-" + key,
+			"idempotency_key": key,
+			"params": map[string]interface{}{
+				"arguments": map[string]interface{}{"path": "test.txt", "content": content},
+			},
+		})
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/call-tool", bytes.NewReader(jsonBody))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	readFile := func() string {
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		return string(data)
+	}
+
+	rec := write("key-1", "first")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Idempotent-Replayed"))
+	var first mcp.CallToolResult
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &first))
+	assert.Equal(t, "success", first.Status)
+	assert.Equal(t, "first", readFile())
+
+	// A retry with the same key returns the first result without writing again
+	assert.NoError(t, os.WriteFile(path, []byte("changed"), 0644))
+	rec = write("key-1", "first")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get("Idempotent-Replayed"))
+	var retry mcp.CallToolResult
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &retry))
+	assert.Equal(t, first, retry)
+	assert.Equal(t, "changed", readFile())
+
+	// Reusing the key for different arguments is refused
+	rec = write("key-1", "second")
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	var errorResponse mcp.ErrorResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResponse))
+	assert.Equal(t, "idempotency_key_conflict", errorResponse.Error)
+	assert.Equal(t, "changed", readFile())
+
+	// Retries asking for Server-Sent Events get the first result too
+	sse := func(key, content string) string {
+		jsonBody, err := json.Marshal(map[string]interface{}{
+			"tool_id":         "filesystem.write",
+			"request_id":      "sse-" + key,
+			"idempotency_key": key,
+			"params": map[string]interface{}{
+				"arguments": map[string]interface{}{"path": "test.txt", "content": content},
+			},
+		})
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/call-tool", bytes.NewReader(jsonBody))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAccept, "text/event-stream")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Body.String()
+	}
+	assert.Contains(t, sse("key-1", "first"), "event: result\n")
+	assert.Equal(t, "changed", readFile())
+	assert.Contains(t, sse("key-1", "second"), "idempotency_key_conflict")
+	assert.Contains(t, sse("key-sse", "streamed"), "event: result\n")
+	assert.Equal(t, "streamed", readFile())
+	assert.NoError(t, os.WriteFile(path, []byte("changed"), 0644))
+	assert.Contains(t, sse("key-sse", "streamed"), "event: result\n")
+	assert.Equal(t, "changed", readFile())
+
+	// Other keys, and calls without one, run every time
+	rec = write("key-2", "first")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, "first", readFile())
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, os.WriteFile(path, []byte("changed"), 0644))
+		response := callTool(t, e, "filesystem.write", map[string]interface{}{"path": "test.txt", "content": "first"})
+		assert.Equal(t, "success", response.Status)
+		assert.Equal(t, "first", readFile())
+	}
+}
+
+func TestIdempotencyKeyTimeout(t *testing.T) {
+	var executions atomic.Int32
+	release := make(chan struct{})
+	tools := mcp.NewFunctionProvider("slow", "Slow tools")
+	err := tools.RegisterTool("slow.write", mcp.ToolInfo{Description: "Finishes when released, whatever its context"}, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		executions.Add(1)
+		<-release
+		return mcp.NewToolResultText("written"), nil
+	})
+	assert.NoError(t, err)
+	err = tools.RegisterTool("slow.touch", mcp.ToolInfo{Description: "Finishes right away"}, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("touched"), nil
+	})
+	assert.NoError(t, err)
+
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	mcpServer.Logger = nil
+	mcpServer.RequestTimeout = 50 * time.Millisecond
+	mcpServer.IdempotencyTTL = 200 * time.Millisecond
+	mcpServer.IdempotencyKeys = 1
+	assert.NoError(t, mcpServer.RegisterProvider(tools))
+	mcpServer.RegisterRoutes(e)
+
+	callKey := func(toolID, key, requestID string) *httptest.ResponseRecorder {
+		jsonBody, err := json.Marshal(map[string]interface{}{
+			"tool_id":         toolID,
+			"request_id":      requestID,
+			"idempotency_key": key,
+			"params":          map[string]interface{}{"arguments": map[string]interface{}{}},
+		})
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/call-tool", bytes.NewReader(jsonBody))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	call := func(requestID string) *httptest.ResponseRecorder {
+		return callKey("slow.write", "key-1", requestID)
+	}
+
+	// The first call times out while the tool keeps running
+	rec := call("first")
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+
+	// A retry waits for it rather than running the tool a second time
+	rec = call("second")
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	assert.Equal(t, int32(1), executions.Load())
+
+	// Pending calls are neither expired, however long they run, nor evicted
+	// by calls that finished since
+	time.Sleep(250 * time.Millisecond)
+	assert.Equal(t, http.StatusOK, callKey("slow.touch", "key-2", "touch-1").Code)
+	assert.Equal(t, http.StatusOK, callKey("slow.touch", "key-3", "touch-2").Code)
+	rec = call("retry")
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	assert.Equal(t, int32(1), executions.Load())
+
+	// Once the tool returns, retries get its result under their own request ID
+	close(release)
+	assert.Eventually(t, func() bool {
+		return call("third").Code == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+	rec = call("fourth")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get("Idempotent-Replayed"))
+	var result mcp.CallToolResult
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, "success", result.Status)
+	assert.Equal(t, "fourth", result.RequestID)
+	assert.Equal(t, int32(1), executions.Load())
+}
+
+func TestRequestLogging(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	var logOutput bytes.Buffer
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	mcpServer.Logger = slog.New(slog.NewJSONHandler(&logOutput, nil))
+	mcpServer.RegisterProvider(mcp.NewFilesystemProvider(mcp.WithRootDir(tempDir)))
+	mcpServer.RegisterRoutes(e)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	lastRecord := func() map[string]interface{} {
+		t.Helper()
+		lines := strings.Split(strings.TrimSpace(logOutput.String()), "\n")
+		var record map[string]interface{}
+		err := json.Unmarshal([]byte(lines[len(lines)-1]), &record)
+		assert.NoError(t, err)
+		return record
+	}
+
+	// The client's request ID is logged and echoed back
+	rec := post("/v1/call-tool", `{"tool_id": "filesystem.list", "request_id": "client-1", "params": {"arguments": {"path": "."}}}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "client-1", rec.Header().Get(echo.HeaderXRequestID))
+
+	record := lastRecord()
+	assert.Equal(t, "request", record["msg"])
+	assert.Equal(t, "/v1/call-tool", record["path"])
+	assert.Equal(t, "client-1", record["request_id"])
+	assert.Equal(t, "filesystem.list", record["tool_id"])
+	assert.Equal(t, float64(http.StatusOK), record["status"])
+	assert.Equal(t, "success", record["result_status"])
+	assert.Contains(t, record, "latency_ms")
+
+	// Requests without an ID get a generated one, also used for the result
+	rec = post("/v1/load-resource", `{"resource_id": "filesystem.file", "params": {"path": "missing.txt"}}`)
+	requestID := rec.Header().Get(echo.HeaderXRequestID)
+	assert.NotEmpty(t, requestID)
+
+	var resource mcp.LoadResourceResult
+	err = json.Unmarshal(rec.Body.Bytes(), &resource)
+	assert.NoError(t, err)
+	assert.Equal(t, requestID, resource.RequestID)
+
+	record = lastRecord()
+	assert.Equal(t, requestID, record["request_id"])
+	assert.Equal(t, "filesystem.file", record["resource_id"])
+	assert.Equal(t, "error", record["result_status"])
+
+	// JSON-RPC requests are correlated by their ID
+	rec = post("/rpc", `{"jsonrpc": "2.0", "id": 7, "method": "tools/call", "params": {"name": "filesystem.list", "arguments": {"path": "."}}}`)
+	assert.Equal(t, "7", rec.Header().Get(echo.HeaderXRequestID))
+
+	record = lastRecord()
+	assert.Equal(t, "7", record["request_id"])
+	assert.Equal(t, "tools/call", record["rpc_method"])
+	assert.Equal(t, "filesystem.list", record["name"])
+
+	// Failed requests are logged with their status
+	rec = post("/v1/call-tool", `{"tool_id": "missing.tool"}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	record = lastRecord()
+	assert.Equal(t, float64(http.StatusNotFound), record["status"])
+	assert.Equal(t, "INFO", record["level"])
+}
+
+func TestLoggerInjection(t *testing.T) {
+	// Servers log by default
+	assert.NotNil(t, server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation").Logger)
+
+	var logOutput bytes.Buffer
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	mcpServer.Logger = slog.New(slog.NewJSONHandler(&logOutput, nil))
+	mcpServer.RegisterProvider(failingProvider{})
+	mcpServer.RegisterRoutes(e)
+
+	// Provider failures are logged as errors through the injected logger
+	rec := callToolRaw(t, e, "failing.broken", map[string]interface{}{})
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	var record map[string]interface{}
+	assert.NoError(t, json.Unmarshal(logOutput.Bytes(), &record))
+	assert.Equal(t, "ERROR", record["level"])
+	assert.Equal(t, "request", record["msg"])
+	assert.Equal(t, "failing.broken", record["tool_id"])
+	assert.Equal(t, float64(http.StatusInternalServerError), record["status"])
+
+	// A nil logger disables logging
+	logOutput.Reset()
+	mcpServer.Logger = nil
+	rec = callToolRaw(t, e, "failing.invalid", map[string]interface{}{})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Zero(t, logOutput.Len())
+}
+
+func TestDeleteDryRun(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	for _, name := range []string{"tree/a.txt", "tree/sub/b.txt", "tree/sub/deeper/c.txt", "single.txt"} {
+		path := filepath.Join(tempDir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(name), 0644))
+	}
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	// A recursive dry run lists every path beneath the directory
+	response := callTool(t, e, "filesystem.delete", map[string]interface{}{"path": "tree", "recursive": true, "dry_run": true})
+	assert.Equal(t, "success", response.Status)
+	result := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, true, result["dry_run"])
+	assert.Equal(t, "delete", result["operation"])
+	assert.Equal(t, []interface{}{
+		"tree",
+		filepath.Join("tree", "a.txt"),
+		filepath.Join("tree", "sub"),
+		filepath.Join("tree", "sub", "b.txt"),
+		filepath.Join("tree", "sub", "deeper"),
+		filepath.Join("tree", "sub", "deeper", "c.txt"),
+	}, result["paths"])
+
+	response = callTool(t, e, "filesystem.delete", map[string]interface{}{"path": "single.txt", "dry_run": true})
+	result = response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"single.txt"}, result["paths"])
+
+	// Validation still applies
+	response = callTool(t, e, "filesystem.delete", map[string]interface{}{"path": "tree", "dry_run": true})
+	assert.Equal(t, mcp.ErrorCodeConflict, response.Error.Code)
+
+	response = callTool(t, e, "filesystem.delete", map[string]interface{}{"path": "missing", "dry_run": true})
+	assert.Equal(t, mcp.ErrorCodeNotFound, response.Error.Code)
+
+	// Nothing was deleted
+	for _, name := range []string{"tree/sub/deeper/c.txt", "single.txt"} {
+		_, err := os.Stat(filepath.Join(tempDir, name))
+		assert.NoError(t, err, name)
+	}
+
+	// Without dry_run the delete goes ahead
+	response = callTool(t, e, "filesystem.delete", map[string]interface{}{"path": "tree", "recursive": true, "dry_run": false})
+	assert.Equal(t, "success", response.Status)
+	_, err = os.Stat(filepath.Join(tempDir, "tree"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestTrash(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	trashDir, err := os.MkdirTemp("", "mcp-trash")
+	assert.NoError(t, err)
+	defer os.RemoveAll(trashDir)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes.txt"), []byte("Hello, MCP!"), 0640))
+	assert.NoError(t, os.MkdirAll(filepath.Join(tempDir, "project", "src"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "project", "src", "main.go"), []byte("package main"), 0644))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir), mcp.WithTrashDir(trashDir))
+
+	trash := func(arguments map[string]interface{}) string {
+		t.Helper()
+		response := callTool(t, e, "filesystem.delete", arguments)
+		if !assert.Equal(t, "success", response.Status) {
+			return ""
+		}
+		item := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+		assert.Equal(t, arguments["path"], item["path"])
+		return item["id"].(string)
+	}
+
+	// Deleting moves files and directories into the trash
+	fileID := trash(map[string]interface{}{"path": "notes.txt"})
+	dirID := trash(map[string]interface{}{"path": "project", "recursive": true})
+	for _, name := range []string{"notes.txt", "project"} {
+		_, err := os.Stat(filepath.Join(tempDir, name))
+		assert.True(t, os.IsNotExist(err), name)
+	}
+	entries, err := os.ReadDir(trashDir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	// Restoring brings them back with their content and mode
+	response := callTool(t, e, "filesystem.restore", map[string]interface{}{"id": fileID})
+	assert.Equal(t, "success", response.Status)
+	content, err := os.ReadFile(filepath.Join(tempDir, "notes.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello, MCP!", string(content))
+	info, err := os.Stat(filepath.Join(tempDir, "notes.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	response = callTool(t, e, "filesystem.restore", map[string]interface{}{"id": dirID})
+	assert.Equal(t, "success", response.Status)
+	content, err = os.ReadFile(filepath.Join(tempDir, "project", "src", "main.go"))
+	assert.NoError(t, err)
+	assert.Equal(t, "package main", string(content))
+
+	entries, err = os.ReadDir(trashDir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// Restored items are gone from the trash
+	response = callTool(t, e, "filesystem.restore", map[string]interface{}{"id": fileID})
+	assert.Equal(t, mcp.ErrorCodeNotFound, response.Error.Code)
+
+	// Restoring never overwrites
+	fileID = trash(map[string]interface{}{"path": "notes.txt"})
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes.txt"), []byte("new"), 0644))
+	response = callTool(t, e, "filesystem.restore", map[string]interface{}{"id": fileID})
+	assert.Equal(t, mcp.ErrorCodeAlreadyExists, response.Error.Code)
+
+	// IDs cannot point outside the trash
+	response = callTool(t, e, "filesystem.restore", map[string]interface{}{"id": "../" + filepath.Base(tempDir)})
+	assert.Equal(t, mcp.ErrorCodeInvalidArgument, response.Error.Code)
+
+	// Corrupt entries cannot be restored
+	assert.NoError(t, os.Remove(filepath.Join(tempDir, "notes.txt")))
+	assert.NoError(t, os.WriteFile(filepath.Join(trashDir, fileID, "info.json"), []byte("{"), 0644))
+	response = callTool(t, e, "filesystem.restore", map[string]interface{}{"id": fileID})
+	assert.Equal(t, mcp.ErrorCodeIO, response.Error.Code)
+	assert.Equal(t, "id", response.Error.Details["argument"])
+
+	// Nor can anything without a trash
+	e = setupTestServer(mcp.WithRootDir(tempDir))
+	response = callTool(t, e, "filesystem.restore", map[string]interface{}{"id": fileID})
+	assert.Equal(t, mcp.ErrorCodeUnavailable, response.Error.Code)
+}
+
+func TestZipRoundTrip(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]os.FileMode{
+		"project/README.md":       0644,
+		"project/bin/run.sh":      0755,
+		"project/src/main.go":     0600,
+		"project/src/lib/util.go": 0644,
+	}
+	for name, mode := range files {
+		path := filepath.Join(tempDir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte("content of "+name), mode))
+		assert.NoError(t, os.Chmod(path, mode))
+	}
+	assert.NoError(t, os.Mkdir(filepath.Join(tempDir, "project", "empty"), 0755))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	// Archive the directory
+	response := callTool(t, e, "filesystem.zip", map[string]interface{}{"path": "project", "destination": "export/project.zip"})
+	assert.Equal(t, "success", response.Status)
+	result := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, float64(4), result["files"])
+	assert.Equal(t, float64(4), result["directories"])
+
+	// The archive is never overwritten
+	response = callTool(t, e, "filesystem.zip", map[string]interface{}{"path": "project", "destination": "export/project.zip"})
+	assert.Equal(t, mcp.ErrorCodeAlreadyExists, response.Error.Code)
+
+	// Extract it elsewhere and compare
+	response = callTool(t, e, "filesystem.unzip", map[string]interface{}{"path": "export/project.zip", "destination": "restored"})
+	assert.Equal(t, "success", response.Status)
+	result = response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, float64(4), result["files"])
+
+	for name, mode := range files {
+		path := filepath.Join(tempDir, "restored", strings.TrimPrefix(name, "project/"))
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err, name)
+		assert.Equal(t, "content of "+name, string(content))
+		info, err := os.Stat(path)
+		assert.NoError(t, err, name)
+		assert.Equal(t, mode, info.Mode().Perm(), name)
+	}
+	info, err := os.Stat(filepath.Join(tempDir, "restored", "empty"))
+	assert.NoError(t, err)
+	assert.True(t, info.IsDir())
+
+	// Extracting again would overwrite files, so nothing is written
+	response = callTool(t, e, "filesystem.unzip", map[string]interface{}{"path": "export/project.zip", "destination": "restored"})
+	assert.Equal(t, mcp.ErrorCodeAlreadyExists, response.Error.Code)
+
+	// An archive written inside the directory does not include itself
+	response = callTool(t, e, "filesystem.zip", map[string]interface{}{"path": "project", "destination": "project/self.zip"})
+	assert.Equal(t, "success", response.Status)
+	result = response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, float64(4), result["files"])
+}
+
+func TestUnzipRejectsEscapingEntries(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	writeArchive := func(name string, entries ...string) {
+		t.Helper()
+		file, err := os.Create(filepath.Join(tempDir, name))
+		assert.NoError(t, err)
+		zipWriter := zip.NewWriter(file)
+		for _, entry := range entries {
+			w, err := zipWriter.Create(entry)
+			assert.NoError(t, err)
+			_, err = w.Write([]byte("payload"))
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, zipWriter.Close())
+		assert.NoError(t, file.Close())
+	}
+	writeArchive("slip.zip", "good.txt", "../evil.txt")
+	writeArchive("absolute.zip", "/tmp/evil.txt")
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	for _, archive := range []string{"slip.zip", "absolute.zip"} {
+		response := callTool(t, e, "filesystem.unzip", map[string]interface{}{"path": archive, "destination": "out"})
+		assert.Equal(t, "error", response.Status, archive)
+		assert.Equal(t, mcp.ErrorCodeInvalidArgument, response.Error.Code, archive)
+	}
+
+	// Nothing was extracted, not even the valid entries
+	_, err = os.Stat(filepath.Join(tempDir, "out"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(tempDir, "evil.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReadLines(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"lines.txt":  "one\ntwo\r\nthree\nfour\nfive",
+		"long.txt":   strings.Repeat("x", 100*1024) + "\nshort\n",
+		"binary.dat": "text\x00more\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644))
+	}
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+	readLines := func(arguments map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		response := callTool(t, e, "filesystem.readlines", arguments)
+		if !assert.Equal(t, "success", response.Status) {
+			return nil
+		}
+		return response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	}
+
+	// The whole file, with \n and \r\n endings removed
+	result := readLines(map[string]interface{}{"path": "lines.txt"})
+	assert.Equal(t, []interface{}{"one", "two", "three", "four", "five"}, result["lines"])
+	assert.Equal(t, float64(5), result["total_lines"])
+	assert.Equal(t, float64(1), result["start_line"])
+	assert.Equal(t, float64(5), result["end_line"])
+
+	// A slice
+	result = readLines(map[string]interface{}{"path": "lines.txt", "start_line": 2, "end_line": 3})
+	assert.Equal(t, []interface{}{"two", "three"}, result["lines"])
+	assert.Equal(t, float64(5), result["total_lines"])
+
+	// Ranges past the end are clamped
+	result = readLines(map[string]interface{}{"path": "lines.txt", "start_line": 4, "end_line": 100})
+	assert.Equal(t, []interface{}{"four", "five"}, result["lines"])
+	assert.Equal(t, float64(5), result["end_line"])
+	result = readLines(map[string]interface{}{"path": "lines.txt", "start_line": 10})
+	assert.Equal(t, []interface{}{}, result["lines"])
+	assert.Equal(t, float64(9), result["end_line"])
+
+	// Lines longer than the default scanner buffer are read whole
+	result = readLines(map[string]interface{}{"path": "long.txt", "end_line": 1})
+	assert.Len(t, result["lines"].([]interface{})[0], 100*1024)
+
+	response := callTool(t, e, "filesystem.readlines", map[string]interface{}{"path": "lines.txt", "start_line": 3, "end_line": 2})
+	assert.Equal(t, mcp.ErrorCodeInvalidArgument, response.Error.Code)
+	response = callTool(t, e, "filesystem.readlines", map[string]interface{}{"path": "binary.dat"})
+	assert.Equal(t, mcp.ErrorCodeNotText, response.Error.Code)
+}
+
+func TestHeadTail(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	// Long lines make tail read several chunks
+	var lines []string
+	for i := 1; i <= 25; i++ {
+		lines = append(lines, fmt.Sprintf("line %d %s", i, strings.Repeat("x", 500)))
+	}
+	files := map[string]string{
+		"log.txt":    strings.Join(lines, "\n") + "\n",
+		"no-eol.csv": "a,b\n1,2\n3,4",
+		"crlf.txt":   "one\r\ntwo\r\nthree\r\n",
+		"empty.txt":  "",
+		"binary.dat": "text\x00more\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644))
+	}
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	preview := func(tool, path string, n int) ([]interface{}, float64) {
+		t.Helper()
+		response := callTool(t, e, tool, map[string]interface{}{"path": path, "lines": n})
+		if !assert.Equal(t, "success", response.Status, path) {
+			return nil, 0
+		}
+		result := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+		return result["lines"].([]interface{}), result["total_lines"].(float64)
+	}
+	toInterfaces := func(lines []string) []interface{} {
+		values := make([]interface{}, len(lines))
+		for i, line := range lines {
+			values[i] = line
+		}
+		return values
+	}
+
+	got, total := preview("filesystem.head", "log.txt", 3)
+	assert.Equal(t, toInterfaces(lines[:3]), got)
+	assert.Equal(t, float64(25), total)
+
+	got, total = preview("filesystem.tail", "log.txt", 12)
+	assert.Equal(t, toInterfaces(lines[13:]), got)
+	assert.Equal(t, float64(25), total)
+
+	// Asking for more lines than there are returns them all
+	got, _ = preview("filesystem.tail", "log.txt", 100)
+	assert.Equal(t, toInterfaces(lines), got)
+	got, _ = preview("filesystem.head", "log.txt", 100)
+	assert.Equal(t, toInterfaces(lines), got)
+
+	got, total = preview("filesystem.tail", "no-eol.csv", 2)
+	assert.Equal(t, []interface{}{"1,2", "3,4"}, got)
+	assert.Equal(t, float64(3), total)
+
+	got, _ = preview("filesystem.head", "crlf.txt", 2)
+	assert.Equal(t, []interface{}{"one", "two"}, got)
+	got, _ = preview("filesystem.tail", "crlf.txt", 1)
+	assert.Equal(t, []interface{}{"three"}, got)
+
+	got, total = preview("filesystem.tail", "empty.txt", 5)
+	assert.Empty(t, got)
+	assert.Equal(t, float64(0), total)
+
+	got, _ = preview("filesystem.head", "log.txt", 0)
+	assert.Empty(t, got)
+
+	// Binary files are rejected
+	for _, tool := range []string{"filesystem.head", "filesystem.tail"} {
+		response := callTool(t, e, tool, map[string]interface{}{"path": "binary.dat"})
+		assert.Equal(t, mcp.ErrorCodeNotText, response.Error.Code, tool)
+	}
+}
+
+func TestFileResourceETag(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	testFile := filepath.Join(tempDir, "notes.txt")
+	assert.NoError(t, os.WriteFile(testFile, []byte("Hello, MCP!"), 0644))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	load := func(params map[string]interface{}) mcp.LoadResourceResult {
+		t.Helper()
+		params["path"] = "notes.txt"
+		body, err := json.Marshal(map[string]interface{}{"resource_id": "filesystem.file", "request_id": "test-etag", "params": params})
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/v1/load-resource", bytes.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var result mcp.LoadResourceResult
+		err = json.Unmarshal(rec.Body.Bytes(), &result)
+		assert.NoError(t, err)
+		return result
+	}
+
+	// Loads carry an ETag
+	result := load(map[string]interface{}{})
+	assert.Equal(t, "success", result.Status)
+	etag := result.ETag
+	assert.NotEmpty(t, etag)
+
+	// A matching ETag skips the content
+	result = load(map[string]interface{}{"if_none_match": etag})
+	assert.Equal(t, "not_modified", result.Status)
+	assert.Equal(t, etag, result.ETag)
+	assert.Nil(t, result.Content)
+	assert.Equal(t, "test-etag", result.RequestID)
+
+	// Once the file changes the ETag is stale and the content is returned
+	assert.NoError(t, os.WriteFile(testFile, []byte("Hello again, MCP!"), 0644))
+	result = load(map[string]interface{}{"if_none_match": etag})
+	assert.Equal(t, "success", result.Status)
+	assert.NotEqual(t, etag, result.ETag)
+	content := result.Content.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, "Hello again, MCP!", content["content"])
+
+	result = load(map[string]interface{}{"if_none_match": `W/"stale"`})
+	assert.Equal(t, "success", result.Status)
+	assert.NotNil(t, result.Content)
+}
+
+func TestConditionalWrite(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	testFile := filepath.Join(tempDir, "shared.txt")
+	assert.NoError(t, os.WriteFile(testFile, []byte("version 1"), 0644))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	// Reads report the ETag of the file
+	response := callTool(t, e, "filesystem.read", map[string]interface{}{"path": "shared.txt"})
+	etag := response.Result.(map[string]interface{})["json"].(map[string]interface{})["etag"].(string)
+	assert.NotEmpty(t, etag)
+
+	// A write against the current version succeeds and returns the new ETag
+	response = callTool(t, e, "filesystem.write", map[string]interface{}{"path": "shared.txt", "content": "version 2", "if_match": etag})
+	assert.Equal(t, "success", response.Status)
+	newETag := response.Result.(map[string]interface{})["json"].(map[string]interface{})["etag"].(string)
+	assert.NotEmpty(t, newETag)
+
+	response = callTool(t, e, "filesystem.read", map[string]interface{}{"path": "shared.txt"})
+	assert.Equal(t, newETag, response.Result.(map[string]interface{})["json"].(map[string]interface{})["etag"])
+
+	// Another agent modifies the file, so a write based on the old version fails
+	assert.NoError(t, os.WriteFile(testFile, []byte("someone else's version"), 0644))
+	response = callTool(t, e, "filesystem.write", map[string]interface{}{"path": "shared.txt", "content": "version 3", "if_match": newETag})
+	assert.Equal(t, "error", response.Status)
+	assert.Equal(t, mcp.ErrorCodeConflict, response.Error.Code)
+	assert.Equal(t, map[string]interface{}{"argument": "if_match"}, response.Error.Details)
+
+	content, err := os.ReadFile(testFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "someone else's version", string(content))
+
+	// "*" only writes files that do not exist yet
+	response = callTool(t, e, "filesystem.write", map[string]interface{}{"path": "shared.txt", "content": "new", "if_match": "*"})
+	assert.Equal(t, mcp.ErrorCodeConflict, response.Error.Code)
+
+	response = callTool(t, e, "filesystem.write", map[string]interface{}{"path": "fresh.txt", "content": "new", "if_match": "*"})
+	assert.Equal(t, "success", response.Status)
+
+	// An ETag for a file that has since been deleted conflicts too
+	response = callTool(t, e, "filesystem.write", map[string]interface{}{"path": "gone.txt", "content": "new", "if_match": etag})
+	assert.Equal(t, mcp.ErrorCodeConflict, response.Error.Code)
+}
+
+func TestDiscoverToolResultSchemas(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes.txt"), []byte("hello"), 0644))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/discover", nil)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Decode generically so the schemas can be compiled as sent
+	var response struct {
+		Providers []struct {
+			Tools []struct {
+				ID      string      `json:"id"`
+				Returns interface{} `json:"returns"`
+			} `json:"tools"`
+		} `json:"providers"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Len(t, response.Providers, 1)
+
+	returns := make(map[string]interface{})
+	for _, tool := range response.Providers[0].Tools {
+		assert.NotEmpty(t, tool.Returns, "tool %s has no result schema", tool.ID)
+		returns[tool.ID] = tool.Returns
+	}
+
+	// Actual results match the advertised schemas
+	calls := []struct {
+		toolID    string
+		arguments map[string]interface{}
+	}{
+		{"filesystem.list", map[string]interface{}{"path": "."}},
+		{"filesystem.read", map[string]interface{}{"path": "notes.txt"}},
+		{"filesystem.stat", map[string]interface{}{"path": "notes.txt"}},
+		{"filesystem.write", map[string]interface{}{"path": "new.txt", "content": "new"}},
+		{"filesystem.delete", map[string]interface{}{"path": "new.txt", "dry_run": true}},
+		{"filesystem.delete", map[string]interface{}{"path": "new.txt"}},
+	}
+	for _, call := range calls {
+		compiler := jsonschema.NewCompiler()
+		url := "mcp:///" + call.toolID
+		assert.NoError(t, compiler.AddResource(url, returns[call.toolID]))
+		schema, err := compiler.Compile(url)
+		if !assert.NoError(t, err, call.toolID) {
+			continue
+		}
+
+		response := callTool(t, e, call.toolID, call.arguments)
+		assert.Equal(t, "success", response.Status, call.toolID)
+		data, err := json.Marshal(response.Result)
+		assert.NoError(t, err)
+		result, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+		assert.NoError(t, err)
+		assert.NoError(t, schema.Validate(result), call.toolID)
+	}
+}
+
+func TestExists(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes.txt"), []byte("hello"), 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(tempDir, "docs"), 0755))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	tests := []struct {
+		path   string
+		exists bool
+		isDir  bool
+		isFile bool
+	}{
+		{"notes.txt", true, false, true},
+		{"docs", true, true, false},
+		{"missing.txt", false, false, false},
+	}
+	for _, tt := range tests {
+		response := callTool(t, e, "filesystem.exists", map[string]interface{}{"path": tt.path})
+		assert.Equal(t, "success", response.Status, tt.path)
+		result := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+		assert.Equal(t, tt.exists, result["exists"], tt.path)
+		assert.Equal(t, tt.isDir, result["is_dir"], tt.path)
+		assert.Equal(t, tt.isFile, result["is_file"], tt.path)
+	}
+
+	// Paths outside the root are still rejected
+	response := callTool(t, e, "filesystem.exists", map[string]interface{}{"path": "../outside"})
+	assert.Equal(t, "error", response.Status)
+}
+
+func TestAtomicWrite(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	testFile := filepath.Join(tempDir, "script.sh")
+	assert.NoError(t, os.WriteFile(testFile, []byte("echo old"), 0755))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	// Atomic writes keep the permissions of the file and leave no temporary files
+	response := callTool(t, e, "filesystem.write", map[string]interface{}{"path": "script.sh", "content": "echo new"})
+	assert.Equal(t, "success", response.Status)
+	content, err := os.ReadFile(testFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "echo new", string(content))
+	info, err := os.Stat(testFile)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	entries, err := os.ReadDir(tempDir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// Callers can opt out of the rename
+	response = callTool(t, e, "filesystem.write", map[string]interface{}{"path": "script.sh", "content": "echo in place", "atomic": false})
+	assert.Equal(t, "success", response.Status)
+	content, err = os.ReadFile(testFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "echo in place", string(content))
+}
+
+func TestCopyDirectory(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	fixture := map[string]string{
+		"project/README.md":           "# Project",
+		"project/main.go":             "package main",
+		"project/cmd/tool/tool.go":    "package tool",
+		"project/internal/a/a.go":     "package a",
+		"project/internal/a/b/b.go":   "package b",
+		"project/internal/a/b/c.json": "{}",
+	}
+	for path, content := range fixture {
+		fullPath := filepath.Join(tempDir, path)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		assert.NoError(t, os.WriteFile(fullPath, []byte(content), 0644))
+	}
+	assert.NoError(t, os.Mkdir(filepath.Join(tempDir, "project/empty"), 0700))
+	assert.NoError(t, os.Chmod(filepath.Join(tempDir, "project/main.go"), 0755))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	// A dry run lists what would be created without creating anything
+	response := callTool(t, e, "filesystem.copy", map[string]interface{}{"path": "project", "destination": "backup/project", "recursive": true, "dry_run": true})
+	assert.Equal(t, "success", response.Status)
+	dryRun := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, true, dryRun["dry_run"])
+	assert.Equal(t, "copy", dryRun["operation"])
+	assert.Equal(t, "backup/project", dryRun["destination"])
+	assert.Equal(t, []interface{}{
+		"backup/project",
+		"backup/project/README.md",
+		"backup/project/cmd",
+		"backup/project/cmd/tool",
+		"backup/project/cmd/tool/tool.go",
+		"backup/project/empty",
+		"backup/project/internal",
+		"backup/project/internal/a",
+		"backup/project/internal/a/a.go",
+		"backup/project/internal/a/b",
+		"backup/project/internal/a/b/b.go",
+		"backup/project/internal/a/b/c.json",
+		"backup/project/main.go",
+	}, dryRun["paths"])
+	_, err = os.Stat(filepath.Join(tempDir, "backup"))
+	assert.True(t, os.IsNotExist(err))
+
+	// and still validates the request
+	response = callTool(t, e, "filesystem.copy", map[string]interface{}{"path": "project", "destination": "other", "dry_run": true})
+	assert.Equal(t, mcp.ErrorCodeIsDirectory, response.Error.Code)
+
+	// Copy the tree as an event stream, with a progress event every two files
+	jsonBody, err := json.Marshal(map[string]interface{}{
+		"tool_id":    "filesystem.copy",
+		"request_id": "test-copy",
+		"params": map[string]interface{}{"arguments": map[string]interface{}{
+			"path": "project", "destination": "backup/project", "recursive": true, "progress_interval": 2,
+		}},
+	})
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/v1/call-tool", bytes.NewReader(jsonBody))
+	assert.NoError(t, err)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderAccept, "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var names []string
+	var payloads []map[string]interface{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			names = append(names, strings.TrimPrefix(line, "event: "))
+		case strings.HasPrefix(line, "data: "):
+			var data map[string]interface{}
+			assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &data))
+			payloads = append(payloads, data)
+		}
+	}
+	assert.NoError(t, scanner.Err())
+
+	if assert.Equal(t, []string{"chunk", "chunk", "chunk", "result"}, names) {
+		for i, files := range []float64{2, 4, 6} {
+			assert.Equal(t, files, payloads[i]["files"])
+		}
+		result := payloads[3]["result"].(map[string]interface{})["json"].(map[string]interface{})
+		assert.Equal(t, float64(6), result["files"])
+		assert.Equal(t, float64(6), result["directories"])
+		assert.Nil(t, result["errors"])
+	}
+
+	// The structure, contents and modes are preserved
+	for path, content := range fixture {
+		copied, err := os.ReadFile(filepath.Join(tempDir, "backup", path))
+		assert.NoError(t, err, path)
+		assert.Equal(t, content, string(copied), path)
+	}
+	info, err := os.Stat(filepath.Join(tempDir, "backup/project/main.go"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	info, err = os.Stat(filepath.Join(tempDir, "backup/project/empty"))
+	assert.NoError(t, err)
+	assert.True(t, info.IsDir())
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	// Single files are copied without recursive
+	response = callTool(t, e, "filesystem.copy", map[string]interface{}{"path": "project/README.md", "destination": "README.copy.md"})
+	assert.Equal(t, "success", response.Status)
+	copied, err := os.ReadFile(filepath.Join(tempDir, "README.copy.md"))
+	assert.NoError(t, err)
+	assert.Equal(t, "# Project", string(copied))
+
+	// Directories need recursive, existing destinations are never overwritten
+	// and a directory cannot be copied into itself
+	response = callTool(t, e, "filesystem.copy", map[string]interface{}{"path": "project", "destination": "other"})
+	assert.Equal(t, mcp.ErrorCodeIsDirectory, response.Error.Code)
+	response = callTool(t, e, "filesystem.copy", map[string]interface{}{"path": "project", "destination": "backup/project", "recursive": true})
+	assert.Equal(t, mcp.ErrorCodeAlreadyExists, response.Error.Code)
+	response = callTool(t, e, "filesystem.copy", map[string]interface{}{"path": "project", "destination": "project/nested", "recursive": true})
+	assert.Equal(t, mcp.ErrorCodeInvalidArgument, response.Error.Code)
+
+	// Files that cannot be copied are listed without aborting the copy
+	e = setupTestServer(mcp.WithRootDir(tempDir), mcp.WithAllowedExtensions(".go"))
+	response = callTool(t, e, "filesystem.copy", map[string]interface{}{"path": "project", "destination": "go-only", "recursive": true})
+	assert.Equal(t, "success", response.Status)
+	result := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, float64(4), result["files"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"path": "project/README.md", "error": "file extension is not allowed"},
+		map[string]interface{}{"path": "project/internal/a/b/c.json", "error": "file extension is not allowed"},
+	}, result["errors"])
+	_, err = os.Stat(filepath.Join(tempDir, "go-only/internal/a/b/b.go"))
+	assert.NoError(t, err)
+}
+
+func TestCORSAllowlist(t *testing.T) {
+	e := echo.New()
+	e.Use(middleware.CORSWithConfig(corsConfig("https://app.example.com, https://admin.example.com")))
+	mcpServer := server.NewMCPServer("Test Filesystem MCP Server", "1.0.0", "A test MCP server implementation")
+	mcpServer.RegisterProvider(mcp.NewFilesystemProvider())
+	mcpServer.RegisterRoutes(e)
+
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/v1/call-tool", nil)
+		req.Header.Set(echo.HeaderOrigin, origin)
+		req.Header.Set(echo.HeaderAccessControlRequestMethod, http.MethodPost)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Allowed origins are echoed back with the methods the server uses
+	rec := preflight("https://admin.example.com")
+	assert.Equal(t, "https://admin.example.com", rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "GET,POST", rec.Header().Get(echo.HeaderAccessControlAllowMethods))
+
+	// Other origins get no permissive header
+	rec = preflight("https://evil.example.com")
+	assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+
+	// Simple requests from allowed origins can read the correlation ID
+	req := httptest.NewRequest(http.MethodPost, "/v1/discover", nil)
+	req.Header.Set(echo.HeaderOrigin, "https://app.example.com")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, "https://app.example.com", rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, echo.HeaderXRequestID, rec.Header().Get(echo.HeaderAccessControlExposeHeaders))
+
+	// Without a configured list every origin is allowed
+	assert.Equal(t, []string{"*"}, corsConfig("").AllowOrigins)
+}
+
+func TestSymlink(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(tempDir, "config"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "config/app.yaml"), []byte("debug: true"), 0644))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	// Links are created relative to their own directory
+	response := callTool(t, e, "filesystem.symlink", map[string]interface{}{"target": "config/app.yaml", "link_path": "links/current.yaml"})
+	assert.Equal(t, "success", response.Status)
+	result := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, "../config/app.yaml", result["target"])
+	assert.Equal(t, false, result["broken"])
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "links/current.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "debug: true", string(content))
+
+	// stat reports the link's target and describes the file it points to
+	response = callTool(t, e, "filesystem.stat", map[string]interface{}{"path": "links/current.yaml"})
+	assert.Equal(t, "success", response.Status)
+	stat := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, "../config/app.yaml", stat["symlink_target"])
+	assert.Equal(t, float64(len("debug: true")), stat["size"])
+	assert.Equal(t, "current.yaml", stat["name"])
+
+	response = callTool(t, e, "filesystem.stat", map[string]interface{}{"path": "config/app.yaml"})
+	assert.NotContains(t, response.Result.(map[string]interface{})["json"], "symlink_target")
+
+	// Existing entries are never replaced
+	response = callTool(t, e, "filesystem.symlink", map[string]interface{}{"target": "config", "link_path": "config/app.yaml"})
+	assert.Equal(t, mcp.ErrorCodeAlreadyExists, response.Error.Code)
+
+	// Targets outside the root are rejected and no link is created
+	for _, target := range []string{"../outside", "/etc/passwd", "config/../../outside"} {
+		response = callTool(t, e, "filesystem.symlink", map[string]interface{}{"target": target, "link_path": "escape"})
+		assert.Equal(t, "error", response.Status, target)
+		assert.Equal(t, map[string]interface{}{"argument": "target"}, response.Error.Details, target)
+		_, err = os.Lstat(filepath.Join(tempDir, "escape"))
+		assert.True(t, os.IsNotExist(err), target)
+	}
+
+	// So are targets reached through a link that escapes
+	assert.NoError(t, os.Symlink(os.TempDir(), filepath.Join(tempDir, "tmp")))
+	response = callTool(t, e, "filesystem.symlink", map[string]interface{}{"target": "tmp/secret", "link_path": "escape"})
+	assert.Equal(t, "error", response.Status)
+
+	// A link created through a linked directory points at its target from
+	// the directory it really lands in, rather than outside the root
+	assert.NoError(t, os.MkdirAll(filepath.Join(tempDir, "x/y"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "x/y/f.txt"), []byte("f"), 0644))
+	assert.NoError(t, os.Symlink("../..", filepath.Join(tempDir, "x/y/alias")))
+	response = callTool(t, e, "filesystem.symlink", map[string]interface{}{"target": "x/y/f.txt", "link_path": "x/y/alias/f-link.txt"})
+	assert.Equal(t, "success", response.Status)
+	target, err := os.Readlink(filepath.Join(tempDir, "f-link.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "x/y/f.txt", target)
+	content, err = os.ReadFile(filepath.Join(tempDir, "f-link.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "f", string(content))
+}
+
+func TestListDirectorySymlinks(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "real.txt"), []byte("hello"), 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(tempDir, "dir"), 0755))
+	assert.NoError(t, os.Symlink("real.txt", filepath.Join(tempDir, "link.txt")))
+	assert.NoError(t, os.Symlink("dir", filepath.Join(tempDir, "dirlink")))
+	// A link pointing at itself would loop forever if it were followed
+	assert.NoError(t, os.Symlink("loop", filepath.Join(tempDir, "loop")))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	checkEntries := func(files []interface{}) {
+		entries := make(map[string]map[string]interface{})
+		for _, file := range files {
+			entry := file.(map[string]interface{})
+			entries[entry["name"].(string)] = entry
+		}
+		assert.Len(t, entries, 5)
+
+		assert.Equal(t, false, entries["real.txt"]["is_symlink"])
+		assert.NotContains(t, entries["real.txt"], "symlink_target")
+		assert.Equal(t, true, entries["dir"]["is_dir"])
+		assert.Equal(t, false, entries["dir"]["is_symlink"])
+
+		for name, target := range map[string]string{"link.txt": "real.txt", "dirlink": "dir", "loop": "loop"} {
+			assert.Equal(t, true, entries[name]["is_symlink"], name)
+			assert.Equal(t, false, entries[name]["is_dir"], name)
+			assert.Equal(t, target, entries[name]["symlink_target"], name)
+		}
+	}
+
+	response := callTool(t, e, "filesystem.list", map[string]interface{}{"path": "."})
+	assert.Equal(t, "success", response.Status)
+	checkEntries(response.Result.(map[string]interface{})["json"].(map[string]interface{})["files"].([]interface{}))
+
+	// The directory resource describes links the same way
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"resource_id": "filesystem.directory",
+		"request_id":  "test-symlinks",
+		"params":      map[string]interface{}{"path": "."},
+	})
+	assert.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/v1/load-resource", bytes.NewReader(requestBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resource mcp.LoadResourceResult
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resource))
+	assert.Equal(t, "success", resource.Status)
+	checkEntries(resource.Content.(map[string]interface{})["json"].(map[string]interface{})["files"].([]interface{}))
+}
+
+func TestStrictHTTPStatus(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes.txt"), []byte("hello"), 0644))
+
+	newServer := func(strict bool) *echo.Echo {
+		e := echo.New()
+		mcpServer := server.NewMCPServer("Test Filesystem MCP Server", "1.0.0", "A test MCP server implementation")
+		mcpServer.StrictHTTPStatus = strict
+		mcpServer.RegisterProvider(mcp.NewFilesystemProvider(mcp.WithRootDir(tempDir)))
+		mcpServer.RegisterRoutes(e)
+		return e
+	}
+	e := newServer(true)
+
+	tests := []struct {
+		toolID    string
+		arguments map[string]interface{}
+		status    int
+		code      string
+	}{
+		{"filesystem.read", map[string]interface{}{"path": "notes.txt"}, http.StatusOK, ""},
+		{"filesystem.read", map[string]interface{}{"path": "missing.txt"}, http.StatusNotFound, mcp.ErrorCodeNotFound},
+		{"filesystem.read", map[string]interface{}{"path": "../outside.txt"}, http.StatusBadRequest, mcp.ErrorCodeInvalidArgument},
+		{"filesystem.read", map[string]interface{}{"path": "."}, http.StatusUnprocessableEntity, mcp.ErrorCodeIsDirectory},
+		{"filesystem.write", map[string]interface{}{"path": "notes.txt", "content": "x", "if_match": "*"}, http.StatusConflict, mcp.ErrorCodeConflict},
+		{"filesystem.nonexistent", map[string]interface{}{}, http.StatusNotFound, mcp.ErrorCodeUnknownTool},
+	}
+	for _, tt := range tests {
+		rec := callToolRaw(t, e, tt.toolID, tt.arguments)
+		assert.Equal(t, tt.status, rec.Code, "%s %v", tt.toolID, tt.arguments)
+
+		// The body is the usual result
+		var response mcp.CallToolResult
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		if tt.code != "" {
+			assert.Equal(t, "error", response.Status)
+			assert.Equal(t, tt.code, response.Error.Code)
+		}
+	}
+
+	// Resource loads are mapped the same way
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"resource_id": "filesystem.file",
+		"params":      map[string]interface{}{"path": "missing.txt"},
+	})
+	assert.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/v1/load-resource", bytes.NewReader(requestBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Without the flag errors are still sent with 200
+	rec = callToolRaw(t, newServer(false), "filesystem.read", map[string]interface{}{"path": "missing.txt"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestPrettyJSON(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("content"), 0644))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+	post := func(target string, body map[string]interface{}) *httptest.ResponseRecorder {
+		jsonBody, err := json.Marshal(body)
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(jsonBody))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code, target)
+		return rec
+	}
+	callBody := map[string]interface{}{
+		"tool_id":    "filesystem.read",
+		"request_id": "test-pretty",
+		"params":     map[string]interface{}{"arguments": map[string]interface{}{"path": "test.txt"}},
+	}
+	loadBody := map[string]interface{}{
+		"resource_id": "filesystem.file",
+		"request_id":  "test-pretty",
+		"params":      map[string]interface{}{"path": "test.txt"},
+	}
+
+	// Responses are compact unless pretty output is asked for
+	for _, query := range []string{"", "?pretty=0", "?pretty=false"} {
+		rec := post("/v1/call-tool"+query, callBody)
+		assert.Equal(t, 1, strings.Count(rec.Body.String(), "\n"), query)
+		rec = post("/v1/load-resource"+query, loadBody)
+		assert.Equal(t, 1, strings.Count(rec.Body.String(), "\n"), query)
+	}
+
+	for _, query := range []string{"?pretty=1", "?pretty=true", "?pretty"} {
+		for target, body := range map[string]map[string]interface{}{
+			"/v1/call-tool":     callBody,
+			"/v1/load-resource": loadBody,
+		} {
+			rec := post(target+query, body)
+			assert.True(t, strings.HasPrefix(rec.Body.String(), "{\n  \""), "%s%s: %s", target, query, rec.Body.String())
+
+			// The content is the same either way
+			var indented, compact interface{}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &indented))
+			assert.NoError(t, json.Unmarshal(post(target, body).Body.Bytes(), &compact))
+			assert.Equal(t, compact, indented)
+		}
+	}
+}
+
+func TestToolInterceptors(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	for _, name := range []string{"protected.txt", "scratch.txt"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte("data"), 0644))
+	}
+
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test Filesystem MCP Server", "1.0.0", "A test MCP server implementation")
+	mcpServer.RegisterProvider(mcp.NewFilesystemProvider(mcp.WithRootDir(tempDir)))
+	mcpServer.RegisterRoutes(e)
+
+	// Count every call, including the denied ones
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	mcpServer.Use(func(ctx context.Context, request mcp.CallToolRequest, next func() (*mcp.CallToolResult, error)) (*mcp.CallToolResult, error) {
+		mu.Lock()
+		counts[request.ToolID]++
+		mu.Unlock()
+		return next()
+	})
+
+	// Refuse to delete the protected file
+	mcpServer.Use(func(ctx context.Context, request mcp.CallToolRequest, next func() (*mcp.CallToolResult, error)) (*mcp.CallToolResult, error) {
+		if request.ToolID == "filesystem.delete" && request.Params.Arguments["path"] == "protected.txt" {
+			result := mcp.NewToolResultErrorCode(mcp.ErrorCodePermissionDenied, "path", "protected.txt may not be deleted")
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		return next()
+	})
+
+	response := callTool(t, e, "filesystem.delete", map[string]interface{}{"path": "protected.txt"})
+	assert.Equal(t, "error", response.Status)
+	assert.Equal(t, mcp.ErrorCodePermissionDenied, response.Error.Code)
+	assert.Equal(t, "test-filesystem.delete", response.RequestID)
+	_, err = os.Stat(filepath.Join(tempDir, "protected.txt"))
+	assert.NoError(t, err)
+
+	response = callTool(t, e, "filesystem.delete", map[string]interface{}{"path": "scratch.txt"})
+	assert.Equal(t, "success", response.Status)
+	_, err = os.Stat(filepath.Join(tempDir, "scratch.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Interceptors also see calls made over JSON-RPC
+	rpcResponse := callRPC(t, e, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"filesystem.delete","arguments":{"path":"protected.txt"}}}`)
+	rpcResult := rpcResponse["result"].(map[string]interface{})
+	assert.Equal(t, "error", rpcResult["status"])
+	assert.Equal(t, mcp.ErrorCodePermissionDenied, rpcResult["error"].(map[string]interface{})["code"])
+
+	response = callTool(t, e, "filesystem.list", map[string]interface{}{"path": "."})
+	assert.Equal(t, "success", response.Status)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, map[string]int{"filesystem.delete": 3, "filesystem.list": 1}, counts)
+}
+
+func TestReplace(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	testFile := filepath.Join(tempDir, "config.ini")
+	writeFixture := func() {
+		assert.NoError(t, os.WriteFile(testFile, []byte("host=a.local\nport=80\nbackup_host=b.local\nbackup_port=81\n"), 0600))
+	}
+	readFixture := func() string {
+		content, err := os.ReadFile(testFile)
+		assert.NoError(t, err)
+		return string(content)
+	}
+	replacements := func(response mcp.CallToolResult) interface{} {
+		assert.Equal(t, "success", response.Status)
+		return response.Result.(map[string]interface{})["json"].(map[string]interface{})["replacements"]
+	}
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	// Literal replacements replace every occurrence by default
+	writeFixture()
+	response := callTool(t, e, "filesystem.replace", map[string]interface{}{"path": "config.ini", "search": ".local", "replacement": ".example.com"})
+	assert.Equal(t, float64(2), replacements(response))
+	assert.Equal(t, "host=a.example.com\nport=80\nbackup_host=b.example.com\nbackup_port=81\n", readFixture())
+	info, err := os.Stat(testFile)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Regular expressions can reference submatches; the pattern is not
+	// treated as a literal
+	writeFixture()
+	response = callTool(t, e, "filesystem.replace", map[string]interface{}{"path": "config.ini", "search": `(?m)^(\w*port)=(\d+)$`, "replacement": "${1}=80${2}", "regex": true})
+	assert.Equal(t, float64(2), replacements(response))
+	assert.Equal(t, "host=a.local\nport=8080\nbackup_host=b.local\nbackup_port=8081\n", readFixture())
+
+	// count limits the replacements to the first occurrences
+	writeFixture()
+	response = callTool(t, e, "filesystem.replace", map[string]interface{}{"path": "config.ini", "search": "local", "replacement": "internal", "count": 1})
+	assert.Equal(t, float64(1), replacements(response))
+	assert.Equal(t, "host=a.internal\nport=80\nbackup_host=b.local\nbackup_port=81\n", readFixture())
+
+	response = callTool(t, e, "filesystem.replace", map[string]interface{}{"path": "config.ini", "search": `\d+`, "replacement": "0", "regex": true, "count": 1})
+	assert.Equal(t, float64(1), replacements(response))
+	assert.Equal(t, "host=a.internal\nport=0\nbackup_host=b.local\nbackup_port=81\n", readFixture())
+
+	// No match leaves the file alone
+	response = callTool(t, e, "filesystem.replace", map[string]interface{}{"path": "config.ini", "search": "missing", "replacement": "x"})
+	assert.Equal(t, float64(0), replacements(response))
+
+	// Invalid expressions and binary files are refused
+	response = callTool(t, e, "filesystem.replace", map[string]interface{}{"path": "config.ini", "search": "(", "replacement": "x", "regex": true})
+	assert.Equal(t, mcp.ErrorCodeInvalidArgument, response.Error.Code)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "image.bin"), []byte{0x89, 'P', 'N', 'G', 0x00, 0x01}, 0644))
+	response = callTool(t, e, "filesystem.replace", map[string]interface{}{"path": "image.bin", "search": "PNG", "replacement": "GIF"})
+	assert.Equal(t, mcp.ErrorCodeNotText, response.Error.Code)
+}
+
+// failingProvider fails its "broken" tool and resource with an internal error
+// and its "invalid" tool with an error result
+type failingProvider struct {
+	mcp.NoHealthCheck
+}
+
+func (failingProvider) GetName() string { return "failing" }
+
+func (failingProvider) GetInfo() mcp.ProviderInfo { return mcp.ProviderInfo{Name: "failing"} }
+
+func (failingProvider) CallTool(ctx context.Context, toolName string, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if toolName == "invalid" {
+		result := mcp.NewToolResultErrorCode(mcp.ErrorCodeInvalidArgument, "path", "Path parameter is required")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	return nil, &mcp.InternalError{Code: mcp.ErrorCodeExecution, Message: "disk controller failure"}
+}
+
+func (failingProvider) LoadResource(ctx context.Context, resourceName string, request mcp.LoadResourceRequest) (*mcp.LoadResourceResult, error) {
+	return nil, &mcp.InternalError{Code: mcp.ErrorCodeResource, Message: "disk controller failure"}
+}
+
+// namesProvider echoes the tool or resource name it is called with
+type namesProvider struct {
+	mcp.NoHealthCheck
+}
+
+func (namesProvider) GetName() string { return "names" }
+
+func (namesProvider) GetInfo() mcp.ProviderInfo { return mcp.ProviderInfo{Name: "names"} }
+
+func (namesProvider) CallTool(ctx context.Context, toolName string, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	result := mcp.NewToolResultText(toolName)
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+func (namesProvider) LoadResource(ctx context.Context, resourceName string, request mcp.LoadResourceRequest) (*mcp.LoadResourceResult, error) {
+	return &mcp.LoadResourceResult{
+		Status:    "success",
+		RequestID: request.RequestID,
+		Content:   map[string]interface{}{"type": "text", "text": resourceName},
+	}, nil
+}
+
+func TestMultiDotIDs(t *testing.T) {
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	mcpServer.RegisterProvider(namesProvider{})
+	mcpServer.RegisterRoutes(e)
+
+	// Everything after the first dot is the tool name
+	for toolID, toolName := range map[string]string{
+		"names.read":        "read",
+		"names.read.raw":    "read.raw",
+		"names.v2.read.raw": "v2.read.raw",
+	} {
+		rec := callToolRaw(t, e, toolID, map[string]interface{}{})
+		assert.Equal(t, http.StatusOK, rec.Code, toolID)
+		var result mcp.CallToolResult
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		assert.Equal(t, toolName, result.Result.(map[string]interface{})["text"], toolID)
+	}
+
+	// The same goes for resources
+	req := httptest.NewRequest(http.MethodPost, "/v1/load-resource", strings.NewReader(`{"resource_id": "names.dir.sub"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resource mcp.LoadResourceResult
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resource))
+	assert.Equal(t, "dir.sub", resource.Content.(map[string]interface{})["text"])
+
+	rpcResponse := callRPC(t, e, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"names.read.raw","arguments":{}}}`)
+	assert.Nil(t, rpcResponse["error"])
+
+	// IDs without a provider or a tool name are rejected
+	for _, toolID := range []string{"names", "names.", ".read", ""} {
+		rec := callToolRaw(t, e, toolID, map[string]interface{}{})
+		assert.Equal(t, http.StatusBadRequest, rec.Code, toolID)
+	}
+}
+
+func TestProviderErrorContract(t *testing.T) {
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	mcpServer.RegisterProvider(failingProvider{})
+	mcpServer.RegisterRoutes(e)
+
+	// Error results are sent in the body with 200
+	rec := callToolRaw(t, e, "failing.invalid", map[string]interface{}{})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var result mcp.CallToolResult
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, mcp.ErrorCodeInvalidArgument, result.Error.Code)
+
+	// Provider errors fail the request
+	rec = callToolRaw(t, e, "failing.broken", map[string]interface{}{})
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	var errorResponse mcp.ErrorResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResponse))
+	assert.Equal(t, "tool_execution_error", errorResponse.Error)
+	assert.Equal(t, "disk controller failure", errorResponse.Message)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/load-resource", strings.NewReader(`{"resource_id": "failing.broken"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	// Over JSON-RPC they are internal errors
+	rpcResponse := callRPC(t, e, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"failing.broken","arguments":{}}}`)
+	assert.Equal(t, float64(mcp.JSONRPCInternalError), rpcResponse["error"].(map[string]interface{})["code"])
+}
+
+func TestFilesystemInternalErrors(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	// Without the option every failure is an error result
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+	response := callTool(t, e, "filesystem.restore", map[string]interface{}{"id": "missing"})
+	assert.Equal(t, mcp.ErrorCodeUnavailable, response.Error.Code)
+
+	// With it, failures the client cannot fix fail the request while
+	// client mistakes stay error results
+	e = setupTestServer(mcp.WithRootDir(tempDir), mcp.WithInternalErrors(true))
+	rec := callToolRaw(t, e, "filesystem.restore", map[string]interface{}{"id": "missing"})
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "The trash is not enabled on this server")
+
+	response = callTool(t, e, "filesystem.read", map[string]interface{}{"path": "missing.txt"})
+	assert.Equal(t, mcp.ErrorCodeNotFound, response.Error.Code)
+	response = callTool(t, e, "filesystem.read", map[string]interface{}{"path": "../outside.txt"})
+	assert.Equal(t, mcp.ErrorCodeInvalidArgument, response.Error.Code)
+}
+
+func TestListDirectoryTree(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	// Three levels: a/b/c.txt, plus a link back up that must not be followed
+	assert.NoError(t, os.MkdirAll(filepath.Join(tempDir, "a", "b"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "top.txt"), []byte("top"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "a", "mid.go"), []byte("mid"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "a", "b", "c.txt"), []byte("c"), 0644))
+	assert.NoError(t, os.Symlink("..", filepath.Join(tempDir, "a", "b", "up")))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	listTree := func(args map[string]interface{}) map[string]interface{} {
+		args["path"] = "."
+		args["recursive"] = true
+		response := callTool(t, e, "filesystem.list", args)
+		assert.Equal(t, "success", response.Status)
+		return response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	}
+	children := func(node map[string]interface{}) map[string]map[string]interface{} {
+		entries := make(map[string]map[string]interface{})
+		list, _ := node["children"].([]interface{})
+		if node["entries"] != nil {
+			list = node["entries"].([]interface{})
+		}
+		for _, entry := range list {
+			entries[entry.(map[string]interface{})["name"].(string)] = entry.(map[string]interface{})
+		}
+		return entries
+	}
+
+	tree := listTree(map[string]interface{}{})
+	assert.Equal(t, float64(6), tree["nodes"])
+	assert.Equal(t, false, tree["truncated"])
+	root := children(tree)
+	assert.Len(t, root, 2)
+	assert.NotContains(t, root["top.txt"], "children")
+	a := children(root["a"])
+	assert.Len(t, a, 2)
+	b := children(a["b"])
+	assert.Len(t, b, 2)
+	assert.Equal(t, "a/b/c.txt", b["c.txt"]["path"])
+	assert.Equal(t, true, b["up"]["is_symlink"])
+	assert.NotContains(t, b["up"], "children")
+
+	// max_depth stops the descent
+	tree = listTree(map[string]interface{}{"max_depth": 2})
+	assert.Equal(t, float64(4), tree["nodes"])
+	a = children(children(tree)["a"])
+	assert.Len(t, a, 2)
+	assert.NotContains(t, a["b"], "children")
+
+	// Patterns filter files but keep the directories leading to them
+	tree = listTree(map[string]interface{}{"pattern": "*.txt"})
+	root = children(tree)
+	assert.Contains(t, root, "top.txt")
+	a = children(root["a"])
+	assert.NotContains(t, a, "mid.go")
+	assert.Contains(t, children(a["b"]), "c.txt")
+
+	// type=dir leaves the files out
+	tree = listTree(map[string]interface{}{"type": "dir"})
+	assert.Equal(t, float64(2), tree["nodes"])
+
+	// Pagination does not apply to trees
+	response := callTool(t, e, "filesystem.list", map[string]interface{}{"path": ".", "recursive": true, "limit": 1})
+	assert.Equal(t, "error", response.Status)
+	assert.Equal(t, "invalid_argument", response.Error.Code)
+}
+
+func TestConcurrentWritesSerialize(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	// Non-atomic writes truncate and rewrite the file in place, so without
+	// locking the contents of different writers would interleave
+	const writers = 20
+	const size = 256 * 1024
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			content := strings.Repeat(string(rune('a'+i)), size)
+			rec := callToolRaw(t, e, "filesystem.write", map[string]interface{}{"path": "shared.txt", "content": content, "atomic": false})
+			assert.Equal(t, http.StatusOK, rec.Code)
+		}(i)
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "shared.txt"))
+	assert.NoError(t, err)
+	assert.Len(t, data, size)
+	assert.Equal(t, strings.Repeat(string(data[0]), size), string(data), "content was written by more than one writer")
+
+	// Edits read the file and write it back; serialized, none of them is lost
+	padding := strings.Repeat(".", size)
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "edits.txt"), []byte("x"+padding), 0644))
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := callToolRaw(t, e, "filesystem.replace", map[string]interface{}{"path": "edits.txt", "search": "x", "replacement": "xx", "count": 1})
+			assert.Equal(t, http.StatusOK, rec.Code)
+		}()
+	}
+	wg.Wait()
+
+	data, err = os.ReadFile(filepath.Join(tempDir, "edits.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Repeat("x", writers+1)+padding, string(data))
+}
+
+func TestWalkNDJSON(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(tempDir, "src", "pkg"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "src", "main.go"), []byte("package main"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "src", "README.md"), []byte("docs"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "src", "pkg", "util.go"), []byte("package pkg"), 0644))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	walk := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/walk?"+query, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	parseLines := func(rec *httptest.ResponseRecorder) []mcp.FileInfo {
+		var entries []mcp.FileInfo
+		scanner := bufio.NewScanner(rec.Body)
+		for scanner.Scan() {
+			var entry mcp.FileInfo
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), &entry), scanner.Text())
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+	paths := func(entries []mcp.FileInfo) []string {
+		var paths []string
+		for _, entry := range entries {
+			paths = append(paths, entry.Path)
+		}
+		return paths
+	}
+
+	rec := walk("path=src")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get(echo.HeaderContentType))
+	entries := parseLines(rec)
+	assert.Equal(t, []string{"src/README.md", "src/main.go", "src/pkg/util.go"}, paths(entries))
+	assert.Equal(t, int64(len("package main")), entries[1].Size)
+
+	// Filters and directories
+	assert.Equal(t, []string{"src/main.go", "src/pkg/util.go"}, paths(parseLines(walk("path=src&pattern=*.go"))))
+	assert.Equal(t, []string{"src/README.md", "src/main.go", "src/pkg"}, paths(parseLines(walk("path=src&include_dirs=true&ignore=util.go"))))
+	assert.Equal(t, []string{"src/README.md"}, paths(parseLines(walk("path=src&limit=1"))))
+
+	// Paths outside the root are refused before anything is streamed
+	rec = walk("path=../")
+	assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+	var result mcp.CallToolResult
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, "error", result.Status)
+
+	rec = walk("path=src&include_dirs=maybe")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = walk("path=src&provider=missing")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestWriteFileModes(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	mode := func(name string) os.FileMode {
+		info, err := os.Stat(filepath.Join(tempDir, name))
+		assert.NoError(t, err)
+		return info.Mode().Perm()
+	}
+
+	// Defaults are unchanged
+	response := callTool(t, e, "filesystem.write", map[string]interface{}{"path": "plain/default.txt", "content": "a"})
+	assert.Equal(t, "success", response.Status)
+	assert.Equal(t, os.FileMode(0644), mode("plain/default.txt")&^0022)
+	assert.Equal(t, os.FileMode(0755), mode("plain"))
+
+	for _, atomic := range []bool{true, false} {
+		name := fmt.Sprintf("private-%t/secret.txt", atomic)
+		response = callTool(t, e, "filesystem.write", map[string]interface{}{"path": name, "content": "a", "mode": "0600", "dir_mode": "0700", "atomic": atomic})
+		assert.Equal(t, "success", response.Status)
+		assert.Equal(t, os.FileMode(0600), mode(name))
+		assert.Equal(t, os.FileMode(0700), mode(filepath.Dir(name)))
+
+		// Overwriting keeps the mode unless a new one is given
+		response = callTool(t, e, "filesystem.write", map[string]interface{}{"path": name, "content": "b", "atomic": atomic})
+		assert.Equal(t, "success", response.Status)
+		assert.Equal(t, os.FileMode(0600), mode(name))
+		response = callTool(t, e, "filesystem.write", map[string]interface{}{"path": name, "content": "c", "mode": "640", "atomic": atomic})
+		assert.Equal(t, "success", response.Status)
+		assert.Equal(t, os.FileMode(0640), mode(name))
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"mode": "0999"},
+		{"mode": "10000"},
+		{"dir_mode": "rwx"},
+	} {
+		args["path"] = "invalid.txt"
+		args["content"] = "a"
+		response = callTool(t, e, "filesystem.write", args)
+		assert.Equal(t, "error", response.Status)
+		assert.Equal(t, "invalid_argument", response.Error.Code)
+	}
+	_, err = os.Stat(filepath.Join(tempDir, "invalid.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFileErrorCodes(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	loadResource := func(resourceID, path string) mcp.LoadResourceResult {
+		requestBody, err := json.Marshal(map[string]interface{}{
+			"resource_id": resourceID,
+			"request_id":  "test-error-codes",
+			"params":      map[string]interface{}{"path": path},
+		})
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/v1/load-resource", bytes.NewReader(requestBody))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var result mcp.LoadResourceResult
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		return result
+	}
+	checkToolCode := func(code, toolID string, args map[string]interface{}) {
+		t.Helper()
+		response := callTool(t, e, toolID, args)
+		assert.Equal(t, "error", response.Status, toolID)
+		if assert.NotNil(t, response.Error, toolID) {
+			assert.Equal(t, code, response.Error.Code, toolID)
+		}
+	}
+	checkResourceCode := func(code, resourceID, path string) {
+		t.Helper()
+		result := loadResource(resourceID, path)
+		assert.Equal(t, "error", result.Status, resourceID)
+		if assert.NotNil(t, result.Error, resourceID) {
+			assert.Equal(t, code, result.Error.Code, resourceID)
+		}
+	}
+
+	// Missing files and directories
+	checkToolCode("not_found", "filesystem.read", map[string]interface{}{"path": "missing.txt"})
+	checkToolCode("not_found", "filesystem.delete", map[string]interface{}{"path": "missing.txt"})
+	checkToolCode("not_found", "filesystem.list", map[string]interface{}{"path": "missing"})
+	checkResourceCode("not_found", "filesystem.file", "missing.txt")
+	checkResourceCode("not_found", "filesystem.directory", "missing")
+
+	if os.Geteuid() == 0 {
+		t.Skip("permission checks do not apply to root")
+	}
+
+	// Files and directories the server may not access
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "secret.txt"), []byte("secret"), 0644))
+	assert.NoError(t, os.Chmod(filepath.Join(tempDir, "secret.txt"), 0000))
+	assert.NoError(t, os.Mkdir(filepath.Join(tempDir, "locked"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "locked", "inner.txt"), nil, 0644))
+	assert.NoError(t, os.Chmod(filepath.Join(tempDir, "locked"), 0500))
+	defer os.Chmod(filepath.Join(tempDir, "locked"), 0755)
+	assert.NoError(t, os.Mkdir(filepath.Join(tempDir, "unreadable"), 0000))
+	defer os.Chmod(filepath.Join(tempDir, "unreadable"), 0755)
+
+	checkToolCode("permission_denied", "filesystem.read", map[string]interface{}{"path": "secret.txt"})
+	checkToolCode("permission_denied", "filesystem.write", map[string]interface{}{"path": "locked/new.txt", "content": "a"})
+	checkToolCode("permission_denied", "filesystem.delete", map[string]interface{}{"path": "locked/inner.txt"})
+	checkToolCode("permission_denied", "filesystem.list", map[string]interface{}{"path": "unreadable"})
+	checkResourceCode("permission_denied", "filesystem.file", "secret.txt")
+	checkResourceCode("permission_denied", "filesystem.directory", "unreadable")
+}
+
+func TestUpload(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	e := setupTestServer(mcp.WithRootDir(tempDir), mcp.WithMaxWriteBytes(8<<20))
+	upload := func(path, contentType string, body io.Reader) mcp.CallToolResult {
+		req := httptest.NewRequest(http.MethodPost, "/v1/upload?path="+url.QueryEscape(path), body)
+		req.Header.Set(echo.HeaderContentType, contentType)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var result mcp.CallToolResult
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		return result
+	}
+
+	// A raw body of several megabytes is written as is
+	data := make([]byte, 5<<20)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	result := upload("nested/big.bin", echo.MIMEOctetStream, bytes.NewReader(data))
+	assert.Equal(t, "success", result.Status)
+	content := result.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Equal(t, "nested/big.bin", content["path"])
+	assert.Equal(t, float64(len(data)), content["size"])
+	assert.NotEmpty(t, content["etag"])
+	written, err := os.ReadFile(filepath.Join(tempDir, "nested", "big.bin"))
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(data, written))
+
+	// Multipart uploads write the file part
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	assert.NoError(t, writer.WriteField("comment", "ignored"))
+	part, err := writer.CreateFormFile("file", "notes.txt")
+	assert.NoError(t, err)
+	part.Write([]byte("uploaded notes"))
+	assert.NoError(t, writer.Close())
+	result = upload("notes.txt", writer.FormDataContentType(), &form)
+	assert.Equal(t, "success", result.Status)
+	written, err = os.ReadFile(filepath.Join(tempDir, "notes.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "uploaded notes", string(written))
+
+	// Uploads over the write limit are aborted without leaving a file
+	result = upload("huge.bin", echo.MIMEOctetStream, io.LimitReader(zeroReader{}, 9<<20))
+	assert.Equal(t, "error", result.Status)
+	assert.Equal(t, mcp.ErrorCodeFileTooLarge, result.Error.Code)
+	entries, err := os.ReadDir(tempDir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	// The destination is confined to the root
+	result = upload("../escape.bin", echo.MIMEOctetStream, strings.NewReader("data"))
+	assert.Equal(t, "error", result.Status)
+	_, err = os.Stat(filepath.Join(filepath.Dir(tempDir), "escape.bin"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDownload(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	data := make([]byte, 1<<20)
+	for i := range data {
+		data[i] = byte(i * 13)
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "data.bin"), data, 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(tempDir, "dir"), 0755))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+	download := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/download?path="+url.QueryEscape(path), nil)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The whole file
+	rec := download("data.bin", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, bytes.Equal(data, rec.Body.Bytes()))
+	assert.Equal(t, "bytes", rec.Header().Get("Accept-Ranges"))
+	assert.Equal(t, `attachment; filename=data.bin`, rec.Header().Get(echo.HeaderContentDisposition))
+	etag := rec.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	// A range resumes the download where it stopped
+	rec = download("data.bin", http.Header{"Range": {"bytes=1000-1999"}})
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, fmt.Sprintf("bytes 1000-1999/%d", len(data)), rec.Header().Get("Content-Range"))
+	assert.True(t, bytes.Equal(data[1000:2000], rec.Body.Bytes()))
+
+	// Unchanged files are not sent again
+	rec = download("data.bin", http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	// Directories, missing files and paths outside the root are refused
+	assert.Equal(t, http.StatusBadRequest, download("dir", nil).Code)
+	assert.Equal(t, http.StatusNotFound, download("missing.bin", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, download("../outside.bin", nil).Code)
+}
+
+// zeroReader is an endless stream of zero bytes
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestRequestBodyLimit(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test Filesystem MCP Server", "1.0.0", "A test MCP server implementation")
+	assert.Equal(t, int64(server.DefaultMaxBodyBytes), mcpServer.MaxBodyBytes)
+	mcpServer.MaxBodyBytes = 1024
+	mcpServer.RegisterProvider(mcp.NewFilesystemProvider(mcp.WithRootDir(tempDir)))
+	mcpServer.RegisterRoutes(e)
+
+	post := func(path string, content string) *httptest.ResponseRecorder {
+		requestBody, err := json.Marshal(map[string]interface{}{
+			"tool_id":    "filesystem.write",
+			"request_id": "test-body-limit",
+			"params": map[string]interface{}{
+				"arguments": map[string]interface{}{"path": "big.txt", "content": content},
+			},
+		})
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(requestBody))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Bodies within the limit are handled as usual
+	rec := post("/v1/call-tool", strings.Repeat("a", 100))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Larger ones are rejected without writing anything
+	for _, path := range []string{"/v1/call-tool", "/v1/batch", "/rpc"} {
+		rec = post(path, strings.Repeat("b", 2048))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, path)
+	}
+	data, err := os.ReadFile(filepath.Join(tempDir, "big.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Repeat("a", 100), string(data))
+
+	// Bodies of unknown length are cut off at the limit rather than
+	// buffered, before and after authentication
+	mcpServer.AuthToken = "s3cret"
+	e = echo.New()
+	mcpServer.RegisterRoutes(e)
+	for _, token := range []string{"", "s3cret"} {
+		for _, path := range []string{"/v1/call-tool", "/rpc"} {
+			body := &countingReader{r: io.LimitReader(zeroReader{}, 200<<20)}
+			req := httptest.NewRequest(http.MethodPost, path, body)
+			req.ContentLength = -1
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if token != "" {
+				req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if token == "" {
+				assert.Equal(t, http.StatusUnauthorized, rec.Code, path)
+			} else {
+				assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, path)
+			}
+			assert.Less(t, body.n, int64(64<<10), path)
+		}
+	}
+}
+
+// countingReader counts the bytes read from r
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func TestProviderEndpoints(t *testing.T) {
+	e := setupTestServer()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/v1/providers")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var list mcp.ProviderList
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	assert.Equal(t, []string{"filesystem"}, list.Providers)
+
+	// A single provider is described as in discover
+	rec = get("/v1/providers/filesystem")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var info mcp.ProviderInfo
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+	assert.Equal(t, "filesystem", info.Name)
+	assert.NotEmpty(t, info.Tools)
+	assert.NotEmpty(t, info.Resources)
+
+	var discovered mcp.DiscoverResponse
+	req := httptest.NewRequest(http.MethodPost, "/v1/discover", nil)
+	discoverRec := httptest.NewRecorder()
+	e.ServeHTTP(discoverRec, req)
+	assert.NoError(t, json.Unmarshal(discoverRec.Body.Bytes(), &discovered))
+	assert.Len(t, discovered.Providers, 1)
+	assert.Equal(t, discovered.Providers[0], info)
+
+	rec = get("/v1/providers/missing")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	var errorResponse mcp.ErrorResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResponse))
+	assert.Equal(t, "provider_not_found", errorResponse.Error)
+}
+
+func TestDisableProvider(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("content"), 0644))
+
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	mcpServer.RegisterProvider(mcp.NewFilesystemProvider(mcp.WithRootDir(tempDir)))
+	mcpServer.RegisterRoutes(e)
+
+	discoveredProviders := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/discover", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		var response mcp.DiscoverResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		return len(response.Providers)
+	}
+	loadResource := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/load-resource", strings.NewReader(`{"resource_id": "filesystem.file", "params": {"path": "test.txt"}}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.False(t, mcpServer.DisableProvider("missing"))
+	assert.True(t, mcpServer.DisableProvider("filesystem"))
+	assert.False(t, mcpServer.ProviderEnabled("filesystem"))
+
+	// Disabled providers are left out of discovery and refuse calls
+	assert.Equal(t, 0, discoveredProviders())
+
+	rec := callToolRaw(t, e, "filesystem.list", map[string]interface{}{"path": "."})
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var errorResponse mcp.ErrorResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResponse))
+	assert.Equal(t, "provider_disabled", errorResponse.Error)
+
+	rec = loadResource()
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	rpcResponse := callRPC(t, e, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"filesystem.list","arguments":{"path":"."}}}`)
+	assert.Contains(t, rpcResponse["error"].(map[string]interface{})["message"], "disabled")
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/providers", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	var list mcp.ProviderList
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	assert.Empty(t, list.Providers)
+	assert.Equal(t, []string{"filesystem"}, list.Disabled)
+
+	// Enabling the provider again restores it
+	assert.True(t, mcpServer.EnableProvider("filesystem"))
+	assert.Equal(t, 1, discoveredProviders())
+	response := callTool(t, e, "filesystem.list", map[string]interface{}{"path": "."})
+	assert.Equal(t, "success", response.Status)
+	assert.Equal(t, http.StatusOK, loadResource().Code)
+}
+
+func TestReadCache(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	notesPath := filepath.Join(tempDir, "notes.txt")
+	assert.NoError(t, os.WriteFile(notesPath, []byte("first"), 0644))
+
+	e := echo.New()
+	mcpServer := server.NewMCPServer("Test", "1.0.0", "A test MCP server implementation")
+	mcpServer.Metrics = server.NewMetrics()
+	provider := mcp.NewFilesystemProvider(mcp.WithRootDir(tempDir), mcp.WithReadCache(2, 1024))
+	mcpServer.RegisterProvider(provider)
+	mcpServer.RegisterRoutes(e)
+
+	read := func(path string) string {
+		response := callTool(t, e, "filesystem.read", map[string]interface{}{"path": path})
+		assert.Equal(t, "success", response.Status)
+		return response.Result.(map[string]interface{})["json"].(map[string]interface{})["content"].(string)
+	}
+
+	// The first read fills the cache and the second is served from it
+	assert.Equal(t, "first", read("notes.txt"))
+	assert.Equal(t, "first", read("notes.txt"))
+	stats, enabled := provider.CacheStats()
+	assert.True(t, enabled)
+	assert.Equal(t, mcp.CacheStats{Hits: 1, Misses: 1, Entries: 1, Bytes: 5}, stats)
+
+	// A modified file is read again
+	assert.NoError(t, os.WriteFile(notesPath, []byte("second"), 0644))
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(notesPath, later, later))
+	assert.Equal(t, "second", read("notes.txt"))
+	stats, _ = provider.CacheStats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+
+	// The least recently used file is evicted once the cache is full, and
+	// files larger than the cache are not cached
+	for _, name := range []string{"a.txt", "b.txt"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte(name), 0644))
+		read(name)
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "big.txt"), bytes.Repeat([]byte("x"), 2048), 0644))
+	read("big.txt")
+	stats, _ = provider.CacheStats()
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, int64(len("a.txt")+len("b.txt")), stats.Bytes)
+
+	// The statistics are exported with the metrics
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, "# TYPE mcp_read_cache_hits_total counter")
+	assert.Contains(t, body, `mcp_read_cache_hits_total{provider="filesystem"} 1`)
+	assert.Contains(t, body, `mcp_read_cache_misses_total{provider="filesystem"} 5`)
+	assert.Contains(t, body, `mcp_read_cache_entries{provider="filesystem"} 2`)
+
+	// Providers without a cache report none
+	_, enabled = mcp.NewFilesystemProvider().CacheStats()
+	assert.False(t, enabled)
+}
+
+func TestGetTool(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	for _, name := range []string{"a.txt", "b.txt", "c.md"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte(name), 0644))
+	}
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Query parameters are converted to the types of the tool's parameters
+	rec := get("/v1/tool/filesystem.list?path=.&pattern=*.txt&limit=1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get(echo.HeaderXRequestID))
+	var response mcp.CallToolResult
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "success", response.Status)
+	content := response.Result.(map[string]interface{})["json"].(map[string]interface{})
+	assert.Len(t, content["files"], 1)
+	assert.Equal(t, true, content["has_more"])
+
+	rec = get("/v1/tool/filesystem.read?path=c.md")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "c.md", response.Result.(map[string]interface{})["json"].(map[string]interface{})["content"])
+
+	// Tools that modify the filesystem cannot be called with GET
+	rec = get("/v1/tool/filesystem.write?path=new.txt&content=hello")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	var errorResponse mcp.ErrorResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResponse))
+	assert.Equal(t, "method_not_allowed", errorResponse.Error)
+	_, err = os.Stat(filepath.Join(tempDir, "new.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	rec = get("/v1/tool/filesystem.delete?path=a.txt")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.FileExists(t, filepath.Join(tempDir, "a.txt"))
+
+	// Nor can arguments a read-only tool does not declare, such as asking a
+	// check to fix what it finds
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "crlf.txt"), []byte("a\r\nb\r\n"), 0644))
+	rec = get("/v1/tool/filesystem.check-line-endings?path=crlf.txt&fix=true")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = get("/v1/tool/filesystem.fix-line-endings?path=crlf.txt")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	data, err := os.ReadFile(filepath.Join(tempDir, "crlf.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "a\r\nb\r\n", string(data))
+	assert.Equal(t, http.StatusOK, get("/v1/tool/filesystem.check-line-endings?path=crlf.txt&pretty=1").Code)
+
+	// Bad arguments and unknown tools
+	assert.Equal(t, http.StatusBadRequest, get("/v1/tool/filesystem.list?path=.&limit=many").Code)
+	assert.Equal(t, http.StatusNotFound, get("/v1/tool/filesystem.missing").Code)
+	assert.Equal(t, http.StatusNotFound, get("/v1/tool/missing.list").Code)
+}
+
+func TestRawResource(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	// A small PNG, stored without an extension so its type must be sniffed
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	var encoded bytes.Buffer
+	assert.NoError(t, png.Encode(&encoded, img))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "picture"), encoded.Bytes(), 0644))
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+
+	get := func(query string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/raw-resource?"+query, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("resource_id=filesystem.file&path=picture", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/png", rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, encoded.Bytes(), rec.Body.Bytes())
+	etag := rec.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	// Unchanged content is not sent again
+	rec = get("resource_id=filesystem.file&path=picture", http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.Bytes())
+
+	// Errors are still reported as JSON
+	rec = get("resource_id=filesystem.file&path=missing.png", nil)
+	assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+	var result mcp.LoadResourceResult
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, "not_found", result.Error.Code)
+
+	// load-resource returns JSON unless raw is asked for
+	load := func(params map[string]interface{}) *httptest.ResponseRecorder {
+		requestBody, err := json.Marshal(map[string]interface{}{
+			"resource_id": "filesystem.file",
+			"request_id":  "test-raw",
+			"params":      params,
+		})
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/v1/load-resource", bytes.NewReader(requestBody))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	rec = load(map[string]interface{}{"path": "picture", "encoding": "base64"})
+	assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+	var loaded mcp.LoadResourceResult
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &loaded))
+	assert.Equal(t, "success", loaded.Status)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(encoded.Bytes()), loaded.Content.(map[string]interface{})["json"].(map[string]interface{})["content"])
+
+	rec = load(map[string]interface{}{"path": "picture", "raw": true})
+	assert.Equal(t, "image/png", rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, encoded.Bytes(), rec.Body.Bytes())
+}
+
+func TestDeleteMany(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	create := func() {
+		assert.NoError(t, os.MkdirAll(filepath.Join(tempDir, "logs"), 0755))
+		assert.NoError(t, os.MkdirAll(filepath.Join(tempDir, "config"), 0755))
+		for _, name := range []string{"a.txt", "b.txt", "logs/today.log", "config/app.yaml"} {
+			assert.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte(name), 0644))
+		}
+	}
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(tempDir, name))
+		return err == nil
+	}
+
+	e := setupTestServer(mcp.WithRootDir(tempDir), mcp.WithProtectedPaths("config"))
+	deleteMany := func(arguments map[string]interface{}) mcp.DeleteManyResult {
+		t.Helper()
+		response := callTool(t, e, "filesystem.delete-many", arguments)
+		assert.Equal(t, "success", response.Status)
+		data, err := json.Marshal(response.Result.(map[string]interface{})["json"])
+		assert.NoError(t, err)
+		var result mcp.DeleteManyResult
+		assert.NoError(t, json.Unmarshal(data, &result))
+		return result
+	}
+	assertOutcome := func(outcome mcp.DeleteOutcome, path, status, code string) {
+		t.Helper()
+		assert.Equal(t, path, outcome.Path)
+		assert.Equal(t, status, outcome.Status, path)
+		if code == "" {
+			assert.Nil(t, outcome.Error, path)
+		} else if assert.NotNil(t, outcome.Error, path) {
+			assert.Equal(t, code, outcome.Error.Code, path)
+		}
+	}
+
+	// With continue_on_error every path is tried and failures are reported
+	create()
+	result := deleteMany(map[string]interface{}{
+		"paths":             []string{"a.txt", "missing.txt", "logs", "config", "../outside", "b.txt"},
+		"recursive":         true,
+		"continue_on_error": true,
+	})
+	if assert.Len(t, result.Results, 6) {
+		assertOutcome(result.Results[0], "a.txt", "success", "")
+		assertOutcome(result.Results[1], "missing.txt", "error", mcp.ErrorCodeNotFound)
+		assertOutcome(result.Results[2], "logs", "success", "")
+		assertOutcome(result.Results[3], "config", "error", mcp.ErrorCodeProtectedPath)
+		assertOutcome(result.Results[4], "../outside", "error", mcp.ErrorCodeInvalidArgument)
+		assertOutcome(result.Results[5], "b.txt", "success", "")
+	}
+	assert.Equal(t, 3, result.Deleted)
+	assert.Equal(t, 3, result.Failed)
+	assert.Equal(t, 0, result.Skipped)
+	assert.False(t, exists("a.txt"))
+	assert.False(t, exists("logs"))
+	assert.False(t, exists("b.txt"))
+	assert.True(t, exists("config/app.yaml"))
+
+	// By default the first failure skips the remaining paths
+	create()
+	result = deleteMany(map[string]interface{}{
+		"paths": []string{"a.txt", "missing.txt", "b.txt", "logs"},
+	})
+	if assert.Len(t, result.Results, 4) {
+		assertOutcome(result.Results[0], "a.txt", "success", "")
+		assertOutcome(result.Results[1], "missing.txt", "error", mcp.ErrorCodeNotFound)
+		assertOutcome(result.Results[2], "b.txt", "skipped", "")
+		assertOutcome(result.Results[3], "logs", "skipped", "")
+	}
+	assert.Equal(t, 1, result.Deleted)
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, 2, result.Skipped)
+	assert.False(t, exists("a.txt"))
+	assert.True(t, exists("b.txt"))
+	assert.True(t, exists("logs/today.log"))
+
+	// Directories still need recursive
+	result = deleteMany(map[string]interface{}{"paths": []string{"logs"}})
+	if assert.Len(t, result.Results, 1) {
+		assertOutcome(result.Results[0], "logs", "error", mcp.ErrorCodeConflict)
+	}
+
+	// The paths must be a non-empty list of strings
+	for _, paths := range []interface{}{nil, []string{}, "a.txt", []interface{}{"b.txt", 1}} {
+		rec := callToolRaw(t, e, "filesystem.delete-many", map[string]interface{}{"paths": paths})
+		assert.Equal(t, http.StatusBadRequest, rec.Code, paths)
+	}
+	assert.True(t, exists("b.txt"))
+}
+
+func TestResourceURI(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mcp-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	outsideDir, err := os.MkdirTemp("", "mcp-outside")
+	assert.NoError(t, err)
+	defer os.RemoveAll(outsideDir)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(tempDir, "docs"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "docs", "read me.txt"), []byte("Hello, URI!"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(outsideDir, "secret.txt"), []byte("secret"), 0644))
+
+	fileURI := func(path string) string {
+		return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+	}
+	readmeURI := fileURI(filepath.Join(tempDir, "docs", "read me.txt"))
+	assert.Contains(t, readmeURI, "read%20me.txt")
+
+	e := setupTestServer(mcp.WithRootDir(tempDir))
+	load := func(body map[string]interface{}) (int, mcp.LoadResourceResult) {
+		t.Helper()
+		jsonBody, err := json.Marshal(body)
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/v1/load-resource", bytes.NewReader(jsonBody))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		var result mcp.LoadResourceResult
+		if rec.Code == http.StatusOK {
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		}
+		return rec.Code, result
+	}
+	content := func(result mcp.LoadResourceResult) interface{} {
+		t.Helper()
+		if !assert.Equal(t, "success", result.Status, result.Error) {
+			return nil
+		}
+		return result.Content.(map[string]interface{})["json"].(map[string]interface{})["content"]
+	}
+
+	// Discovery advertises the file URI template
+	req := httptest.NewRequest(http.MethodPost, "/v1/discover", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	var discovery mcp.DiscoverResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &discovery))
+	if assert.Len(t, discovery.Providers, 1) {
+		assert.Equal(t, []mcp.ResourceTemplate{{
+			URITemplate: "file:///{+path}",
+			Name:        "File",
+			Description: "A file in the filesystem, addressed by its absolute path",
+			ResourceID:  "filesystem.file",
+		}}, discovery.Providers[0].ResourceTemplates)
+	}
+
+	// A file URI can stand in for the resource ID, or for the path
+	code, result := load(map[string]interface{}{"resource_id": readmeURI, "params": map[string]interface{}{"encoding": "text"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Hello, URI!", content(result))
+
+	code, result = load(map[string]interface{}{"params": map[string]interface{}{"uri": readmeURI, "encoding": "text"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Hello, URI!", content(result))
+
+	code, result = load(map[string]interface{}{"resource_id": "filesystem.file", "params": map[string]interface{}{"uri": "file://localhost" + filepath.ToSlash(filepath.Join(tempDir, "docs", "read me.txt")), "encoding": "text"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Hello, URI!", content(result))
+
+	code, result = load(map[string]interface{}{"resource_id": "filesystem.directory", "params": map[string]interface{}{"uri": fileURI(filepath.Join(tempDir, "docs"))}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "success", result.Status)
+
+	// Raw loads take the URI as a query parameter
+	req = httptest.NewRequest(http.MethodGet, "/v1/raw-resource?uri="+url.QueryEscape(readmeURI), nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "Hello, URI!", rec.Body.String())
+
+	// So do JSON-RPC clients, which can also list the templates
+	response := callRPC(t, e, fmt.Sprintf(`{"jsonrpc": "2.0", "id": 1, "method": "resources/read", "params": {"uri": %q, "arguments": {"encoding": "text"}}}`, readmeURI))
+	assert.Nil(t, response["error"])
+	assert.Equal(t, "Hello, URI!", response["result"].(map[string]interface{})["content"].(map[string]interface{})["json"].(map[string]interface{})["content"])
+
+	response = callRPC(t, e, `{"jsonrpc": "2.0", "id": 2, "method": "resources/templates/list"}`)
+	templates := response["result"].(map[string]interface{})["resourceTemplates"].([]interface{})
+	if assert.Len(t, templates, 1) {
+		assert.Equal(t, "file:///{+path}", templates[0].(map[string]interface{})["uri_template"])
+	}
+
+	// URIs are held to the root like paths
+	for _, uri := range []string{
+		fileURI(filepath.Join(outsideDir, "secret.txt")),
+		fileURI(filepath.Join(tempDir, "..", filepath.Base(outsideDir), "secret.txt")),
+		"file://example.com" + filepath.ToSlash(filepath.Join(tempDir, "docs", "read me.txt")),
+		"file:docs/read%20me.txt",
+	} {
+		code, result = load(map[string]interface{}{"resource_id": "filesystem.file", "params": map[string]interface{}{"uri": uri}})
+		assert.Equal(t, http.StatusOK, code, uri)
+		assert.Equal(t, "error", result.Status, uri)
+		if assert.NotNil(t, result.Error, uri) {
+			assert.Equal(t, mcp.ErrorCodeInvalidArgument, result.Error.Code, uri)
+		}
+	}
+
+	// Only schemes with a template can be resolved
+	code, _ = load(map[string]interface{}{"resource_id": "https://example.com/notes.txt"})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// With mounts the host of the URI names the mount
+	mounted := echo.New()
+	mcpServer := server.NewMCPServer("Test MCP Server", "Test server for MCP", "1.0.0")
+	mcpServer.RegisterProvider(mcp.NewMountedFilesystemProvider(map[string]string{"docs": filepath.Join(tempDir, "docs")}))
+	mcpServer.RegisterRoutes(mounted)
+	req = httptest.NewRequest(http.MethodGet, "/v1/raw-resource?uri="+url.QueryEscape("file://docs/read%20me.txt"), nil)
+	rec = httptest.NewRecorder()
+	mounted.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "Hello, URI!", rec.Body.String())
+}
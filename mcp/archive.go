@@ -0,0 +1,375 @@
+package mcp
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// zipDirectory writes the tree beneath a directory into a new zip archive.
+// Files are streamed into the archive one at a time; symbolic links are skipped.
+func (p *FilesystemProvider) zipDirectory(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the destination parameter
+	destinationParam, ok := request.Params.Arguments["destination"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "destination", "Destination parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the on_permission_error parameter
+	policy, err := parsePermissionErrorPolicy(request.Params.Arguments)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, argumentOf(err), err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the paths
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	destinationPath, err := p.resolvePath(destinationParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "destination", fmt.Sprintf("Invalid destination: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Only write archives with an allowed extension; the size is checked as the archive is written
+	if result := p.checkWrite(destinationPath, destinationParam, "destination", -1); result != nil {
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Check if the path exists and is a directory
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			result := NewToolResultErrorCode(ErrorCodeNotFound, "path", fmt.Sprintf("Directory not found: %s", pathParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing directory: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	if !info.IsDir() {
+		result := NewToolResultErrorCode(ErrorCodeNotDirectory, "path", fmt.Sprintf("Path is not a directory: %s", pathParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Never overwrite an existing file with the archive
+	if _, err := os.Lstat(destinationPath); err == nil {
+		result := NewToolResultErrorCode(ErrorCodeAlreadyExists, "destination", fmt.Sprintf("Destination already exists: %s", destinationParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Create the parent directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(destinationPath), 0755); err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "destination", fmt.Sprintf("Error creating directory: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	archive, err := os.OpenFile(destinationPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "destination", fmt.Sprintf("Error creating archive: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	var out io.Writer = archive
+	if p.MaxWriteBytes > 0 {
+		out = &writeLimitWriter{w: archive, limit: p.MaxWriteBytes}
+	}
+
+	zipResult := ZipResult{Path: pathParam, Destination: destinationParam}
+	err = writeZip(ctx, out, fullPath, pathParam, destinationPath, policy, &zipResult)
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// Leave no partial archive behind
+		os.Remove(destinationPath)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(err, errWriteLimit) {
+			result := NewToolResultErrorCode(ErrorCodeFileTooLarge, "destination", fmt.Sprintf("Archive is larger than the %d byte write limit: %s", p.MaxWriteBytes, destinationParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error writing archive: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	if info, err := os.Stat(destinationPath); err == nil {
+		zipResult.Size = info.Size()
+	}
+
+	// Return the result
+	result := NewToolResultJSON(zipResult)
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// writeZip writes the tree beneath root, whose path as seen by the client is
+// displayRoot, to w as a zip archive, counting the entries in zipResult. The
+// archive itself is skipped if it lies within root. Entries that cannot be
+// read for lack of permission are handled according to policy.
+func writeZip(ctx context.Context, w io.Writer, root, displayRoot, archivePath string, policy PermissionErrorPolicy, zipResult *ZipResult) error {
+	zipWriter := zip.NewWriter(w)
+
+	inaccessible, err := walkTree(ctx, root, displayRoot, policy, func(path, displayPath string, entry fs.DirEntry) error {
+		if path == root || path == archivePath || entry.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+			_, err := zipWriter.CreateHeader(header)
+			zipResult.Directories++
+			return err
+		}
+		header.Method = zip.Deflate
+
+		// Open the file before adding its entry, so one that cannot be read is left out
+		file, err := os.Open(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrPermission) {
+				return policy.apply(displayPath, &zipResult.Inaccessible)
+			}
+			return err
+		}
+		defer file.Close()
+		entryWriter, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(entryWriter, newContextReader(ctx, file)); err != nil {
+			return err
+		}
+		zipResult.Files++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Merge the unreadable directories with the unreadable files
+	zipResult.Inaccessible = append(zipResult.Inaccessible, inaccessible...)
+	slices.Sort(zipResult.Inaccessible)
+	return zipWriter.Close()
+}
+
+// unzipArchive extracts a zip archive into a directory. Every entry is checked
+// before anything is written: entries that would land outside the destination,
+// symbolic links and existing files fail the whole extraction.
+func (p *FilesystemProvider) unzipArchive(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the destination parameter
+	destinationParam, ok := request.Params.Arguments["destination"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "destination", "Destination parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the paths
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	destinationPath, err := p.resolvePath(destinationParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "destination", fmt.Sprintf("Invalid destination: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Only read archives with an allowed extension
+	if !p.extensionAllowed(fullPath) {
+		result := NewToolResultErrorCode(ErrorCodeExtensionNotAllowed, "path", fmt.Sprintf("File extension is not allowed: %s", pathParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Check if the path exists and is a file
+	if _, errResult := statRegularFile(fullPath, pathParam, "path"); errResult != nil {
+		errResult.RequestID = request.RequestID
+		return errResult, nil
+	}
+
+	reader, err := zip.OpenReader(fullPath)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeParseError, "path", fmt.Sprintf("Error opening archive: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	defer reader.Close()
+
+	// Check every entry before extracting any of them
+	targets := make([]string, len(reader.File))
+	for i, file := range reader.File {
+		name := filepath.FromSlash(strings.TrimSuffix(file.Name, "/"))
+		if !filepath.IsLocal(name) {
+			result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", fmt.Sprintf("Archive entry escapes the destination: %s", file.Name))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		if file.Mode()&fs.ModeSymlink != 0 {
+			result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", fmt.Sprintf("Archive entry is a symbolic link: %s", file.Name))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+
+		// Resolve the target like any other path, so symlinks already in
+		// the destination cannot redirect the extraction either
+		target, err := p.resolvePath(filepath.Join(destinationParam, name))
+		if err != nil || !isWithinDir(destinationPath, target) {
+			result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", fmt.Sprintf("Archive entry escapes the destination: %s", file.Name))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+
+		if !file.FileInfo().IsDir() {
+			size := int64(min(file.UncompressedSize64, math.MaxInt64))
+			if result := p.checkWrite(target, file.Name, "path", size); result != nil {
+				result.RequestID = request.RequestID
+				return result, nil
+			}
+			if _, err := os.Lstat(target); err == nil {
+				result := NewToolResultErrorCode(ErrorCodeAlreadyExists, "destination", fmt.Sprintf("File already exists: %s", filepath.Join(destinationParam, name)))
+				result.RequestID = request.RequestID
+				return result, nil
+			}
+		}
+		targets[i] = target
+	}
+
+	unzipResult := UnzipResult{Path: pathParam, Destination: destinationParam}
+	for i, file := range reader.File {
+		if err := extractZipEntry(ctx, file, targets[i], p.MaxWriteBytes); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			code := errorCodeFor(err, ErrorCodeIO)
+			switch {
+			case errors.Is(err, errWriteLimit):
+				code = ErrorCodeFileTooLarge
+			case errors.Is(err, zip.ErrChecksum), errors.Is(err, zip.ErrFormat), errors.Is(err, zip.ErrAlgorithm):
+				code = ErrorCodeParseError
+			}
+			result := NewToolResultErrorCode(code, "destination", fmt.Sprintf("Error extracting %s: %s", file.Name, err.Error()))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		if file.FileInfo().IsDir() {
+			unzipResult.Directories++
+		} else {
+			unzipResult.Files++
+		}
+	}
+
+	// Return the result
+	result := NewToolResultJSON(unzipResult)
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// extractZipEntry writes a single archive entry to target. Files are written
+// with the mode recorded in the archive and at most maxBytes bytes, when positive.
+func extractZipEntry(ctx context.Context, file *zip.File, target string, maxBytes int64) error {
+	if file.FileInfo().IsDir() {
+		return os.MkdirAll(target, 0755)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	perm := file.Mode().Perm()
+	if perm == 0 {
+		perm = 0644
+	}
+
+	in, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	// The sizes in the archive may lie, so enforce the limit while copying
+	var source io.Reader = newContextReader(ctx, in)
+	if maxBytes > 0 {
+		source = io.LimitReader(source, maxBytes+1)
+	}
+	written, err := io.Copy(out, source)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && maxBytes > 0 && written > maxBytes {
+		err = fmt.Errorf("entry is larger than the %d byte write limit: %w", maxBytes, errWriteLimit)
+	}
+	if err != nil {
+		os.Remove(target)
+		return err
+	}
+
+	// Apply the mode exactly, regardless of the umask
+	return os.Chmod(target, perm)
+}
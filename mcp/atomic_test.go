@@ -0,0 +1,53 @@
+package mcp
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failingReader returns its content and then fails, like a copy whose
+// source becomes unreadable halfway through
+type failingReader struct {
+	r io.Reader
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF {
+		return n, errors.New("read failed")
+	}
+	return n, err
+}
+
+func TestWriteAtomicKeepsOriginalOnError(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "config.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("original content"), 0644))
+
+	err := writeAtomic(path, &failingReader{strings.NewReader("partial")}, 0644)
+	assert.EqualError(t, err, "read failed")
+
+	// The original file is untouched and the temporary file is gone
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "original content", string(content))
+
+	entries, err := os.ReadDir(tempDir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// A successful write replaces the file
+	assert.NoError(t, writeAtomic(path, strings.NewReader("new content"), 0600))
+	content, err = os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "new content", string(content))
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// casKey computes a content-addressable storage key for a file
+func (p *FilesystemProvider) casKey(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the algorithm parameter (default to sha256)
+	algorithm := "sha256"
+	if algorithmParam, ok := request.Params.Arguments["algorithm"].(string); ok {
+		algorithm = algorithmParam
+	}
+	hasher, err := newHasher(algorithm)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "algorithm", err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the prefix_length parameter (default to 2)
+	prefixLength := 2
+	if prefixParam, ok := request.Params.Arguments["prefix_length"].(float64); ok {
+		prefixLength = int(prefixParam)
+	}
+	if prefixLength < 0 || prefixLength >= hasher.Size()*2 {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "prefix_length", fmt.Sprintf("prefix_length must be between 0 and %d", hasher.Size()*2-1))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the include_size parameter (default to false)
+	includeSize := false
+	if includeSizeParam, ok := request.Params.Arguments["include_size"].(bool); ok {
+		includeSize = includeSizeParam
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Check if the path exists and is a file
+	if _, errResult := statRegularFile(fullPath, pathParam, "path"); errResult != nil {
+		errResult.RequestID = request.RequestID
+		return errResult, nil
+	}
+
+	// Stream the file through the hash
+	digest, size, err := hashFile(ctx, fullPath, hasher)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error reading file: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Shard the key into a directory prefix
+	key := digest
+	if prefixLength > 0 {
+		key = digest[:prefixLength] + "/" + digest[prefixLength:]
+	}
+	if includeSize {
+		key += "-" + strconv.FormatInt(size, 10)
+	}
+
+	casKey := CASKey{
+		Path:      pathParam,
+		Algorithm: algorithm,
+		Hash:      digest,
+		Size:      size,
+		Key:       key,
+	}
+
+	// Return the result
+	result := NewToolResultJSON(casKey)
+	result.RequestID = request.RequestID
+	return result, nil
+}
@@ -0,0 +1,110 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// chmodPath changes the permission bits of a file or directory
+func (p *FilesystemProvider) chmodPath(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the mode parameter and parse it before touching the file
+	modeParam, ok := request.Params.Arguments["mode"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "mode", "Mode parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	mode, err := parsePermission(modeParam)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "mode", err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Keep concurrent calls from modifying the path at the same time
+	unlock := p.pathLocks.lock(fullPath)
+	defer unlock()
+
+	// Check if the path exists
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			result := NewToolResultErrorCode(ErrorCodeNotFound, "path", fmt.Sprintf("File or directory not found: %s", pathParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Change the mode
+	if err := os.Chmod(fullPath, mode); err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error changing mode: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Return the result
+	result := NewToolResultJSON(ChmodResult{
+		Path:     pathParam,
+		Previous: fmt.Sprintf("%04o", info.Mode().Perm()),
+		Mode:     fmt.Sprintf("%04o", mode),
+	})
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// parsePermission parses octal permission bits such as "0644"
+func parsePermission(value string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("Invalid mode %q: expected an octal permission between 0000 and 0777", value)
+	}
+	return os.FileMode(mode), nil
+}
+
+// mkdirAllMode creates dir and any missing parents like os.MkdirAll, but
+// gives the directories it creates exactly perm, regardless of the umask
+func mkdirAllMode(dir string, perm os.FileMode) error {
+	// Find the directories that are missing, innermost first
+	var missing []string
+	for current := dir; ; current = filepath.Dir(current) {
+		if _, err := os.Stat(current); err == nil || !os.IsNotExist(err) {
+			break
+		}
+		missing = append(missing, current)
+		if filepath.Dir(current) == current {
+			break
+		}
+	}
+
+	if err := os.MkdirAll(dir, perm); err != nil {
+		return err
+	}
+	for _, created := range missing {
+		if err := os.Chmod(created, perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
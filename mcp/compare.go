@@ -0,0 +1,160 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// compareChunkSize is the number of bytes read from each file per comparison step
+const compareChunkSize = 64 * 1024
+
+// filesEqual compares two files byte by byte
+func (p *FilesystemProvider) filesEqual(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameters
+	pathA, ok := request.Params.Arguments["path_a"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path_a", "path_a parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	pathB, ok := request.Params.Arguments["path_b"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path_b", "path_b parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the paths
+	fullPathA, err := p.resolvePath(pathA)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path_a", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	fullPathB, err := p.resolvePath(pathB)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path_b", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Check that both paths exist and are files
+	infoA, errResult := statRegularFile(fullPathA, pathA, "path_a")
+	if errResult != nil {
+		errResult.RequestID = request.RequestID
+		return errResult, nil
+	}
+
+	infoB, errResult := statRegularFile(fullPathB, pathB, "path_b")
+	if errResult != nil {
+		errResult.RequestID = request.RequestID
+		return errResult, nil
+	}
+
+	compareResult := FilesEqualResult{
+		PathA: pathA,
+		PathB: pathB,
+		SizeA: infoA.Size(),
+		SizeB: infoB.Size(),
+	}
+
+	// Files of different sizes can never be equal
+	if infoA.Size() != infoB.Size() {
+		result := NewToolResultJSON(compareResult)
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Stream both files and stop at the first difference
+	offset, err := firstDifference(ctx, fullPathA, fullPathB)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "", fmt.Sprintf("Error comparing files: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	if offset < 0 {
+		compareResult.Equal = true
+	} else {
+		compareResult.FirstDifference = &offset
+	}
+
+	// Return the result
+	result := NewToolResultJSON(compareResult)
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// statRegularFile checks that a path exists and is a file, returning an error
+// result against the named argument otherwise
+func statRegularFile(fullPath, pathParam, argument string) (os.FileInfo, *CallToolResult) {
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, NewToolResultErrorCode(ErrorCodeNotFound, argument, fmt.Sprintf("File not found: %s", pathParam))
+		}
+		return nil, NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), argument, fmt.Sprintf("Error accessing file: %s", err.Error()))
+	}
+
+	if info.IsDir() {
+		return nil, NewToolResultErrorCode(ErrorCodeIsDirectory, argument, fmt.Sprintf("Path is a directory, not a file: %s", pathParam))
+	}
+
+	return info, nil
+}
+
+// firstDifference returns the offset of the first differing byte between two files, or -1 if they are equal
+func firstDifference(ctx context.Context, pathA, pathB string) (int64, error) {
+	fileA, err := os.Open(pathA)
+	if err != nil {
+		return 0, err
+	}
+	defer fileA.Close()
+
+	fileB, err := os.Open(pathB)
+	if err != nil {
+		return 0, err
+	}
+	defer fileB.Close()
+
+	bufA := make([]byte, compareChunkSize)
+	bufB := make([]byte, compareChunkSize)
+	var offset int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		nA, errA := io.ReadFull(fileA, bufA)
+		nB, errB := io.ReadFull(fileB, bufB)
+		if errA != nil && errA != io.EOF && errA != io.ErrUnexpectedEOF {
+			return 0, errA
+		}
+		if errB != nil && errB != io.EOF && errB != io.ErrUnexpectedEOF {
+			return 0, errB
+		}
+
+		n := min(nA, nB)
+		if !bytes.Equal(bufA[:n], bufB[:n]) {
+			for i := 0; i < n; i++ {
+				if bufA[i] != bufB[i] {
+					return offset + int64(i), nil
+				}
+			}
+		}
+		if nA != nB {
+			return offset + int64(n), nil
+		}
+
+		// Both readers are exhausted
+		if nA < compareChunkSize {
+			return -1, nil
+		}
+		offset += int64(n)
+	}
+}
@@ -0,0 +1,106 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrBusy is returned when no I/O slot became free in time, by the provider
+// methods that report failures as errors rather than results
+var ErrBusy = errors.New("too many filesystem operations in progress")
+
+// testHookIOSlot, when set by tests, is called right after an I/O slot is
+// acquired and right before it is released
+var testHookIOSlot func(acquired bool)
+
+// WithMaxConcurrency limits how many tool calls and resource loads may do
+// I/O at the same time. Calls over the limit wait for a slot for at most
+// waitTimeout and then fail with ErrorCodeBusy; a waitTimeout of zero waits
+// as long as the request allows. A limit of zero or less means no limit.
+func WithMaxConcurrency(limit int, waitTimeout time.Duration) FilesystemOption {
+	return func(p *FilesystemProvider) {
+		p.ioSlots = nil
+		if limit > 0 {
+			p.ioSlots = make(chan struct{}, limit)
+		}
+		p.ioWaitTimeout = waitTimeout
+	}
+}
+
+// acquireIOSlot waits for a free I/O slot and returns the function that
+// releases it. It fails with ErrBusy once the wait timeout has passed, or
+// with the context's error if the context is done first.
+func (p *FilesystemProvider) acquireIOSlot(ctx context.Context) (release func(), err error) {
+	if p.ioSlots == nil {
+		return func() {}, nil
+	}
+
+	var timeout <-chan time.Time
+	if p.ioWaitTimeout > 0 {
+		timer := time.NewTimer(p.ioWaitTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case p.ioSlots <- struct{}{}:
+	case <-timeout:
+		return nil, ErrBusy
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if testHookIOSlot != nil {
+		testHookIOSlot(true)
+	}
+	return func() {
+		if testHookIOSlot != nil {
+			testHookIOSlot(false)
+		}
+		<-p.ioSlots
+	}, nil
+}
+
+// busyMessage is the message of the error result of calls that got no I/O slot
+func (p *FilesystemProvider) busyMessage() string {
+	return fmt.Sprintf("Too many filesystem operations in progress (limit %d), try again later", cap(p.ioSlots))
+}
+
+// toolSlotError turns a failure to acquire an I/O slot into the outcome of a tool call
+func (p *FilesystemProvider) toolSlotError(request CallToolRequest, err error) (*CallToolResult, error) {
+	if !errors.Is(err, ErrBusy) {
+		return nil, err
+	}
+	result := NewToolResultErrorCode(ErrorCodeBusy, "", p.busyMessage())
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// resourceSlotError turns a failure to acquire an I/O slot into the outcome of a resource load
+func (p *FilesystemProvider) resourceSlotError(request LoadResourceRequest, err error) (*LoadResourceResult, error) {
+	if !errors.Is(err, ErrBusy) {
+		return nil, err
+	}
+	result := NewResourceResultErrorCode(ErrorCodeBusy, "", p.busyMessage())
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// slotCloser closes an open file and then releases the I/O slot held while
+// it is read, so the slot covers the whole transfer rather than the call
+// that opened the file
+type slotCloser struct {
+	io.Closer
+	release func()
+	once    sync.Once
+}
+
+func (c *slotCloser) Close() error {
+	err := c.Closer.Close()
+	c.once.Do(c.release)
+	return err
+}
@@ -0,0 +1,138 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxConcurrency(t *testing.T) {
+	tempDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("content"), 0644))
+
+	const limit = 3
+	p := NewFilesystemProvider(WithRootDir(tempDir), WithMaxConcurrency(limit, 0))
+
+	var inFlight, maxInFlight atomic.Int32
+	testHookIOSlot = func(acquired bool) {
+		if !acquired {
+			inFlight.Add(-1)
+			return
+		}
+		n := inFlight.Add(1)
+		for {
+			seen := maxInFlight.Load()
+			if n <= seen || maxInFlight.CompareAndSwap(seen, n) {
+				break
+			}
+		}
+		// Give the other callers a chance to run while the slot is held
+		runtime.Gosched()
+	}
+	defer func() { testHookIOSlot = nil }()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			request := CallToolRequest{Params: CallToolParams{Arguments: map[string]interface{}{"path": "test.txt"}}}
+			result, err := p.CallTool(context.Background(), "read", request)
+			assert.NoError(t, err)
+			assert.Equal(t, "success", result.Status)
+
+			resource, err := p.LoadResource(context.Background(), "file", LoadResourceRequest{Params: map[string]interface{}{"path": "test.txt"}})
+			assert.NoError(t, err)
+			assert.Equal(t, "success", resource.Status)
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(limit))
+	assert.Equal(t, int32(0), inFlight.Load())
+}
+
+func TestMaxConcurrencyBusy(t *testing.T) {
+	tempDir := t.TempDir()
+	p := NewFilesystemProvider(WithRootDir(tempDir), WithMaxConcurrency(1, 10*time.Millisecond))
+
+	release, err := p.acquireIOSlot(context.Background())
+	assert.NoError(t, err)
+
+	// Calls give up once the wait timeout has passed
+	request := CallToolRequest{RequestID: "busy", Params: CallToolParams{Arguments: map[string]interface{}{"path": "."}}}
+	result, err := p.CallTool(context.Background(), "list", request)
+	assert.NoError(t, err)
+	assert.Equal(t, "error", result.Status)
+	assert.Equal(t, ErrorCodeBusy, result.Error.Code)
+	assert.Equal(t, "busy", result.RequestID)
+
+	// or as soon as their context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = p.CallTool(ctx, "list", request)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	release()
+	result, err = p.CallTool(context.Background(), "list", request)
+	assert.NoError(t, err)
+	assert.Equal(t, "success", result.Status)
+}
+
+func TestTransfersHoldIOSlots(t *testing.T) {
+	tempDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("content"), 0644))
+	p := NewFilesystemProvider(WithRootDir(tempDir), WithMaxConcurrency(1, 10*time.Millisecond))
+
+	var held atomic.Int32
+	testHookIOSlot = func(acquired bool) {
+		if acquired {
+			held.Add(1)
+		} else {
+			held.Add(-1)
+		}
+	}
+	defer func() { testHookIOSlot = nil }()
+
+	// Uploads hold a slot while the body is written
+	result, err := p.UploadFile(context.Background(), "upload.txt", iotest.OneByteReader(strings.NewReader("uploaded")))
+	assert.NoError(t, err)
+	assert.Equal(t, "success", result.Status)
+	assert.Equal(t, int32(0), held.Load())
+
+	// Downloads and streams hold theirs until they are closed
+	download, err := p.DownloadFile(context.Background(), "test.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), held.Load())
+
+	_, err = p.StreamResource(context.Background(), "file", map[string]interface{}{"path": "test.txt"})
+	assert.ErrorIs(t, err, ErrBusy)
+	result, err = p.UploadFile(context.Background(), "upload.txt", strings.NewReader("again"))
+	assert.NoError(t, err)
+	assert.Equal(t, ErrorCodeBusy, result.Error.Code)
+
+	assert.NoError(t, download.Content.Close())
+	assert.Equal(t, int32(0), held.Load())
+
+	stream, err := p.StreamResource(context.Background(), "file", map[string]interface{}{"path": "test.txt"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), held.Load())
+	_, err = p.DownloadFile(context.Background(), "test.txt")
+	assert.ErrorIs(t, err, ErrBusy)
+	assert.NoError(t, stream.Reader.Close())
+	assert.Equal(t, int32(0), held.Load())
+
+	// Failing to open the file gives the slot back right away
+	_, err = p.DownloadFile(context.Background(), "missing.txt")
+	assert.Error(t, err)
+	assert.Equal(t, int32(0), held.Load())
+}
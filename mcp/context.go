@@ -0,0 +1,37 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// contextReader wraps a reader so that reads fail once the context is done
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+// newContextReader returns a reader that stops with the context's error once it is cancelled
+func newContextReader(ctx context.Context, reader io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, reader: reader}
+}
+
+// Read implements io.Reader
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
+// readFileContext reads a whole file, giving up once the context is done
+func readFileContext(ctx context.Context, path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(newContextReader(ctx, file))
+}
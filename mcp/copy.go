@@ -0,0 +1,321 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// DefaultCopyProgressInterval is how many files a directory copy copies
+// between two progress events
+const DefaultCopyProgressInterval = 100
+
+// copyFile copies a file, or with recursive a directory tree, to a destination
+// that does not exist yet. Directory copies carry on past files that cannot be
+// copied and list them in the result. If emit is not nil, a progress event is
+// emitted every progress_interval files. With dry_run, it only validates the
+// request and lists the paths the copy would create.
+func (p *FilesystemProvider) copyFile(ctx context.Context, request CallToolRequest, emit EmitFunc) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the destination parameter
+	destinationParam, ok := request.Params.Arguments["destination"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "destination", "Destination parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the recursive parameter (default to false)
+	recursive := false
+	if recursiveParam, ok := request.Params.Arguments["recursive"].(bool); ok {
+		recursive = recursiveParam
+	}
+
+	// Get the progress_interval parameter
+	progressInterval := DefaultCopyProgressInterval
+	if intervalParam, ok := request.Params.Arguments["progress_interval"].(float64); ok {
+		if intervalParam < 1 {
+			result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "progress_interval", "progress_interval must be at least 1")
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		progressInterval = int(intervalParam)
+	}
+
+	// Get the dry_run parameter (default to false)
+	dryRun := false
+	if dryRunParam, ok := request.Params.Arguments["dry_run"].(bool); ok {
+		dryRun = dryRunParam
+	}
+
+	// Get the on_permission_error parameter, which applies to the entries of a directory copy
+	policy, err := parsePermissionErrorPolicy(request.Params.Arguments)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, argumentOf(err), err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the paths
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	destinationPath, err := p.resolvePath(destinationParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "destination", fmt.Sprintf("Invalid destination: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Keep concurrent calls from modifying either path at the same time
+	unlock := p.pathLocks.lockAll(fullPath, destinationPath)
+	defer unlock()
+
+	// Check if the source exists
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			result := NewToolResultErrorCode(ErrorCodeNotFound, "path", fmt.Sprintf("Path not found: %s", pathParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	if info.IsDir() && !recursive {
+		result := NewToolResultErrorCode(ErrorCodeIsDirectory, "path", fmt.Sprintf("Path is a directory: %s. Use recursive=true to copy directories", pathParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// A directory cannot be copied into itself
+	if info.IsDir() {
+		if rel, err := filepath.Rel(fullPath, destinationPath); err == nil && filepath.IsLocal(rel) {
+			result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "destination", fmt.Sprintf("Destination is inside the directory being copied: %s", destinationParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+	}
+
+	// Never overwrite anything at the destination
+	if _, err := os.Lstat(destinationPath); err == nil {
+		result := NewToolResultErrorCode(ErrorCodeAlreadyExists, "destination", fmt.Sprintf("Destination already exists: %s", destinationParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// A single file must be readable and writable under the write rules
+	if !info.IsDir() {
+		if !p.extensionAllowed(fullPath) {
+			result := NewToolResultErrorCode(ErrorCodeExtensionNotAllowed, "path", fmt.Sprintf("File extension is not allowed: %s", pathParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		if result := p.checkWrite(destinationPath, destinationParam, "destination", info.Size()); result != nil {
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+	}
+
+	// Report what would be created without touching anything
+	if dryRun {
+		paths, err := affectedPaths(ctx, fullPath, destinationParam)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error reading directory: %s", err.Error()))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+
+		result := NewToolResultJSON(DryRunResult{
+			DryRun:      true,
+			Operation:   "copy",
+			Path:        pathParam,
+			Destination: destinationParam,
+			Paths:       paths,
+		})
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Create the parent directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(destinationPath), 0755); err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "destination", fmt.Sprintf("Error creating directory: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	copyResult := CopyResult{Path: pathParam, Destination: destinationParam}
+
+	// Copy a single file
+	if !info.IsDir() {
+		if err := copyFileContents(ctx, fullPath, destinationPath, info.Mode().Perm()); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error copying file: %s", err.Error()))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		copyResult.Files = 1
+		copyResult.Bytes = info.Size()
+
+		result := NewToolResultJSON(copyResult)
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Copy a directory tree
+	if err := p.copyDirectory(ctx, fullPath, destinationPath, pathParam, progressInterval, policy, emit, &copyResult); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error copying directory: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Return the result
+	result := NewToolResultJSON(copyResult)
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// copyDirectory recreates the tree beneath src at dst, counting what it copied in
+// copyResult. Symbolic links and special files are skipped. Source entries that
+// cannot be read for lack of permission are handled according to policy; other
+// failures are recorded in copyResult.Errors and the copy carries on. Only
+// failing to recreate the top directory always aborts it.
+func (p *FilesystemProvider) copyDirectory(ctx context.Context, src, dst, displayRoot string, progressInterval int, policy PermissionErrorPolicy, emit EmitFunc, copyResult *CopyResult) error {
+	// Directories are created writable and given their own mode once
+	// everything inside them has been copied
+	type directoryMode struct {
+		path string
+		mode fs.FileMode
+	}
+	var directories []directoryMode
+
+	recordError := func(displayPath string, err error) {
+		// Report the cause without the server-side path
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+		copyResult.Errors = append(copyResult.Errors, CopyError{Path: displayPath, Error: err.Error()})
+	}
+
+	// unreadable reports whether err is a lack of permission to read the source path
+	unreadable := func(path string, err error) bool {
+		var pathErr *fs.PathError
+		return errors.As(err, &pathErr) && pathErr.Path == path && errors.Is(err, fs.ErrPermission)
+	}
+
+	err := filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		rel, relErr := filepath.Rel(src, path)
+		if relErr != nil {
+			return relErr
+		}
+		displayPath := filepath.Join(displayRoot, rel)
+		target := filepath.Join(dst, rel)
+
+		if err != nil {
+			if path == src {
+				return err
+			}
+			if errors.Is(err, fs.ErrPermission) {
+				if err := policy.apply(displayPath, &copyResult.Inaccessible); err != nil {
+					return err
+				}
+			} else {
+				recordError(displayPath, err)
+			}
+			if entry != nil && entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			recordError(displayPath, err)
+			return nil
+		}
+
+		switch {
+		case info.IsDir():
+			if err := os.Mkdir(target, 0755); err != nil {
+				if path == src {
+					return err
+				}
+				recordError(displayPath, err)
+				return filepath.SkipDir
+			}
+			directories = append(directories, directoryMode{target, info.Mode().Perm()})
+			if path != src {
+				copyResult.Directories++
+			}
+			return nil
+		case !info.Mode().IsRegular():
+			return nil
+		case !p.extensionAllowed(path):
+			recordError(displayPath, fmt.Errorf("file extension is not allowed"))
+			return nil
+		}
+		if result := p.checkWrite(target, displayPath, "", info.Size()); result != nil {
+			recordError(displayPath, errors.New(result.Error.Message))
+			return nil
+		}
+
+		if err := copyFileContents(ctx, path, target, info.Mode().Perm()); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if unreadable(path, err) {
+				return policy.apply(displayPath, &copyResult.Inaccessible)
+			}
+			recordError(displayPath, err)
+			return nil
+		}
+		copyResult.Files++
+		copyResult.Bytes += info.Size()
+
+		// Report progress every progressInterval files
+		if emit != nil && copyResult.Files%progressInterval == 0 {
+			if err := emit(CopyProgress{Files: copyResult.Files, Bytes: copyResult.Bytes}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	// Apply directory modes deepest first, so that read-only directories
+	// are only locked once nothing more is written into them
+	for i := len(directories) - 1; i >= 0; i-- {
+		os.Chmod(directories[i].path, directories[i].mode)
+	}
+	return err
+}
@@ -0,0 +1,168 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// incrementCounter increments an integer stored in a file under an exclusive lock
+func (p *FilesystemProvider) incrementCounter(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the delta parameter (default to 1)
+	delta := int64(1)
+	if deltaParam, ok := request.Params.Arguments["delta"].(float64); ok {
+		if deltaParam != float64(int64(deltaParam)) {
+			result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "delta", "Delta parameter must be an integer")
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		delta = int64(deltaParam)
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Only touch files with an allowed extension
+	if result := p.checkWrite(fullPath, pathParam, "path", -1); result != nil {
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Keep concurrent calls from modifying the counter at the same time
+	unlock := p.pathLocks.lock(fullPath)
+	defer unlock()
+
+	// Create the parent directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error creating directory: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	previous, value, err := incrementCounterFile(fullPath, delta)
+	if argument := argumentOf(err); argument != "" {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, argument, err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	if errors.Is(err, errNotCounter) {
+		result := NewToolResultErrorCode(ErrorCodeParseError, "path", fmt.Sprintf("Error incrementing counter %s: %s", pathParam, err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error incrementing counter %s: %s", pathParam, err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	counterResult := CounterResult{
+		Path:     pathParam,
+		Previous: previous,
+		Value:    value,
+	}
+
+	// Return the result
+	result := NewToolResultJSON(counterResult)
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// errNotCounter reports a counter file whose content is not an integer
+var errNotCounter = errors.New("file does not contain an integer")
+
+// incrementCounterFile adds delta to the integer stored in a file and returns the
+// previous and new values. The counter itself is locked for the whole
+// read-modify-write cycle, so concurrent increments never lose an update, and
+// the new value is renamed into place, so a crash never leaves a truncated
+// counter behind. A missing or empty file counts as zero.
+func incrementCounterFile(path string, delta int64) (int64, int64, error) {
+	file, err := lockCounterFile(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+	defer unlockFile(file)
+
+	// Keep the permissions of an existing counter
+	info, err := file.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	if !info.Mode().IsRegular() {
+		return 0, 0, newArgumentError("path", "%s is not a regular file", filepath.Base(path))
+	}
+	perm := info.Mode().Perm()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var previous int64
+	if text := strings.TrimSpace(string(data)); text != "" {
+		previous, err = strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%w: %q", errNotCounter, text)
+		}
+	}
+
+	// Refuse to wrap around rather than repeat values
+	if (delta > 0 && previous > math.MaxInt64-delta) || (delta < 0 && previous < math.MinInt64-delta) {
+		return 0, 0, newArgumentError("delta", "adding %d to %d overflows the counter", delta, previous)
+	}
+	value := previous + delta
+
+	if err := writeFileAtomic(path, []byte(strconv.FormatInt(value, 10)+"\n"), perm); err != nil {
+		return 0, 0, err
+	}
+	return previous, value, nil
+}
+
+// lockCounterFile opens the counter at path, creating it if it does not exist,
+// and locks it. Updates rename a new file into place, so a counter replaced
+// while waiting for its lock is opened and locked again.
+func lockCounterFile(path string) (*os.File, error) {
+	for {
+		file, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+		if err := lockFile(file); err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		opened, err := file.Stat()
+		if err == nil {
+			var current os.FileInfo
+			current, err = os.Stat(path)
+			if err == nil && os.SameFile(opened, current) {
+				return file, nil
+			}
+		}
+		unlockFile(file)
+		file.Close()
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+}
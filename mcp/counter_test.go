@@ -0,0 +1,36 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterFileLockSurvivesRenames(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "counter")
+
+	// Without the provider's path locks, as for increments made by other
+	// processes, every update still holds the lock on the current file
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := incrementCounterFile(path, 1)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "50\n", string(data))
+
+	entries, err := os.ReadDir(tempDir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
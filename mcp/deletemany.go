@@ -0,0 +1,89 @@
+package mcp
+
+import (
+	"context"
+)
+
+// deleteMany deletes several paths in one call, each exactly as the delete
+// tool would, and reports the outcome for every path so that partial failures
+// are visible. Unless continue_on_error is set, the first failure stops the
+// call and the remaining paths are reported as skipped.
+func (p *FilesystemProvider) deleteMany(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the paths parameter
+	items, ok := request.Params.Arguments["paths"].([]interface{})
+	if !ok || len(items) == 0 {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "paths", "Paths parameter is required and must be a non-empty array of strings")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	paths := make([]string, 0, len(items))
+	for _, item := range items {
+		path, ok := item.(string)
+		if !ok {
+			result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "paths", "Paths parameter must be an array of strings")
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		paths = append(paths, path)
+	}
+
+	// Get the recursive parameter (default to false)
+	recursive := false
+	if recursiveParam, ok := request.Params.Arguments["recursive"].(bool); ok {
+		recursive = recursiveParam
+	}
+
+	// Get the continue_on_error parameter (default to false)
+	continueOnError := false
+	if continueParam, ok := request.Params.Arguments["continue_on_error"].(bool); ok {
+		continueOnError = continueParam
+	}
+
+	// Delete the paths one by one, so each is confined, guarded and locked
+	// like a single delete
+	deleteResult := DeleteManyResult{Results: make([]DeleteOutcome, 0, len(paths))}
+	stopped := false
+	for _, path := range paths {
+		if stopped {
+			deleteResult.Results = append(deleteResult.Results, DeleteOutcome{Path: path, Status: "skipped"})
+			deleteResult.Skipped++
+			continue
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		deleted, err := p.deleteFile(ctx, CallToolRequest{
+			RequestID: request.RequestID,
+			Params: CallToolParams{
+				Arguments: map[string]interface{}{
+					"path":      path,
+					"recursive": recursive,
+				},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		outcome := DeleteOutcome{Path: path, Status: deleted.Status}
+		if deleted.Status == "success" {
+			if content, ok := deleted.Result.(map[string]interface{}); ok {
+				if trashed, ok := content["json"].(TrashedItem); ok {
+					outcome.TrashID = trashed.ID
+				}
+			}
+			deleteResult.Deleted++
+		} else {
+			outcome.Error = deleted.Error
+			deleteResult.Failed++
+			stopped = !continueOnError
+		}
+		deleteResult.Results = append(deleteResult.Results, outcome)
+	}
+
+	// Return the result
+	result := NewToolResultJSON(deleteResult)
+	result.RequestID = request.RequestID
+	return result, nil
+}
@@ -0,0 +1,67 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// DownloadFile opens the file at pathParam for serving with ranges. The
+// caller must close the returned download, which holds an I/O slot until then.
+func (p *FilesystemProvider) DownloadFile(ctx context.Context, pathParam string) (*FileDownload, error) {
+	if pathParam == "" {
+		return nil, fmt.Errorf("path parameter is required")
+	}
+
+	release, err := p.acquireIOSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	download, err := p.openDownload(pathParam)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	download.Content = struct {
+		io.ReadSeeker
+		io.Closer
+	}{download.Content, &slotCloser{Closer: download.Content, release: release}}
+	return download, nil
+}
+
+// openDownload opens the file at pathParam for DownloadFile
+func (p *FilesystemProvider) openDownload(pathParam string) (*FileDownload, error) {
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+
+	// Only touch files with an allowed extension
+	if !p.extensionAllowed(fullPath) {
+		return nil, fmt.Errorf("file extension is not allowed: %s", pathParam)
+	}
+
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		file.Close()
+		return nil, fmt.Errorf("path is not a regular file: %s", pathParam)
+	}
+
+	return &FileDownload{
+		Name:    info.Name(),
+		ModTime: info.ModTime(),
+		ETag:    fileETag(info),
+		Content: file,
+	}, nil
+}
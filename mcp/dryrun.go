@@ -0,0 +1,32 @@
+package mcp
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+)
+
+// affectedPaths lists fullPath and, for a directory, every entry beneath it,
+// as the paths a client would use. Symbolic links are listed but not followed.
+func affectedPaths(ctx context.Context, fullPath, displayPath string) ([]string, error) {
+	paths := make([]string, 0)
+	err := filepath.WalkDir(fullPath, func(path string, entry fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(fullPath, path)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, filepath.Join(displayPath, rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
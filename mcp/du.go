@@ -0,0 +1,142 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// diskUsage reports how much space the files beneath a directory consume.
+// Directories that cannot be read for lack of permission are handled according
+// to on_permission_error, entries that cannot be stat'ed are skipped rather
+// than failing the whole walk, and symbolic links are not followed.
+func (p *FilesystemProvider) diskUsage(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the max_depth parameter (default to unlimited)
+	maxDepth := 0
+	if maxDepthParam, ok := request.Params.Arguments["max_depth"].(float64); ok {
+		maxDepth = int(maxDepthParam)
+	}
+
+	// Get the by_subdir parameter (default to false)
+	bySubdir := false
+	if bySubdirParam, ok := request.Params.Arguments["by_subdir"].(bool); ok {
+		bySubdir = bySubdirParam
+	}
+
+	// Get the on_permission_error parameter
+	policy, err := parsePermissionErrorPolicy(request.Params.Arguments)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, argumentOf(err), err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Check if the path exists and is a directory
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			result := NewToolResultErrorCode(ErrorCodeNotFound, "path", fmt.Sprintf("Directory not found: %s", pathParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing directory: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	if !info.IsDir() {
+		result := NewToolResultErrorCode(ErrorCodeNotDirectory, "path", fmt.Sprintf("Path is not a directory: %s", pathParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	usage := DiskUsageResult{Path: pathParam}
+	if bySubdir {
+		usage.Subdirs = make([]SubdirUsage, 0)
+	}
+	subdirIndex := make(map[string]int)
+
+	usage.Inaccessible, err = walkTree(ctx, fullPath, pathParam, policy, func(path, _ string, entry fs.DirEntry) error {
+		if path == fullPath {
+			return nil
+		}
+
+		rel, err := filepath.Rel(fullPath, path)
+		if err != nil {
+			return nil
+		}
+		subdir, _, nested := strings.Cut(rel, string(filepath.Separator))
+
+		// Find the immediate subdirectory the entry is part of, if any
+		var subdirUsage *DiskUsage
+		if bySubdir {
+			if !nested && entry.IsDir() {
+				subdirIndex[subdir] = len(usage.Subdirs)
+				usage.Subdirs = append(usage.Subdirs, SubdirUsage{Name: subdir, Path: filepath.Join(pathParam, subdir)})
+			} else if i, ok := subdirIndex[subdir]; ok && nested {
+				subdirUsage = &usage.Subdirs[i].DiskUsage
+			}
+		}
+
+		if entry.IsDir() {
+			usage.Directories++
+			if subdirUsage != nil {
+				subdirUsage.Directories++
+			}
+
+			// Stop descending once the maximum depth is reached
+			if maxDepth > 0 && walkDepth(fullPath, path) >= maxDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		entryInfo, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		usage.Bytes += entryInfo.Size()
+		usage.Files++
+		if subdirUsage != nil {
+			subdirUsage.Bytes += entryInfo.Size()
+			subdirUsage.Files++
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ctx.Err()) {
+			return nil, err
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error walking directory: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Return the result
+	result := NewToolResultJSON(usage)
+	result.RequestID = request.RequestID
+	return result, nil
+}
@@ -0,0 +1,137 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"syscall"
+)
+
+// Error codes carried by ErrorInfo.Code so clients can branch on the kind of
+// failure instead of parsing the message
+const (
+	// ErrorCodeInvalidArgument means an argument is missing or has a bad value
+	ErrorCodeInvalidArgument = "invalid_argument"
+	// ErrorCodeNotFound means the file, directory or staged write does not exist
+	ErrorCodeNotFound = "not_found"
+	// ErrorCodeAlreadyExists means the target of a create operation exists
+	ErrorCodeAlreadyExists = "already_exists"
+	// ErrorCodePermissionDenied means the operating system refused access
+	ErrorCodePermissionDenied = "permission_denied"
+	// ErrorCodeIsDirectory means a file was expected but the path is a directory
+	ErrorCodeIsDirectory = "is_directory"
+	// ErrorCodeNotDirectory means a directory was expected but the path is not one
+	ErrorCodeNotDirectory = "not_directory"
+	// ErrorCodeNotText means a text file was expected but the file is binary
+	ErrorCodeNotText = "not_text"
+	// ErrorCodeParseError means the content of a file could not be parsed
+	ErrorCodeParseError = "parse_error"
+	// ErrorCodeConflict means the filesystem is not in the state the operation requires
+	ErrorCodeConflict = "conflict"
+	// ErrorCodeExtensionNotAllowed means the file's extension is not in the allowlist
+	ErrorCodeExtensionNotAllowed = "extension_not_allowed"
+	// ErrorCodeFileTooLarge means a file or content exceeds a configured size limit
+	ErrorCodeFileTooLarge = "file_too_large"
+	// ErrorCodeLimitExceeded means a configured limit would be exceeded
+	ErrorCodeLimitExceeded = "limit_exceeded"
+	// ErrorCodeProtectedPath means the operation would delete or move the root
+	// or a protected path
+	ErrorCodeProtectedPath = "protected_path"
+	// ErrorCodeBusy means too many operations are in progress and the call
+	// should be retried later
+	ErrorCodeBusy = "busy"
+	// ErrorCodeStreamingRequired means the tool only works over Server-Sent Events
+	ErrorCodeStreamingRequired = "streaming_required"
+	// ErrorCodeUnavailable means the tool needs a feature that is not enabled
+	// on this server
+	ErrorCodeUnavailable = "unavailable"
+	// ErrorCodeReadOnly means the tool modifies the filesystem and the provider is read-only
+	ErrorCodeReadOnly = "read_only"
+	// ErrorCodeUnknownTool means the provider has no tool of that name
+	ErrorCodeUnknownTool = "unknown_tool"
+	// ErrorCodeUnknownResource means the provider has no resource of that name
+	ErrorCodeUnknownResource = "unknown_resource"
+	// ErrorCodeIO means reading or writing the filesystem failed for a reason
+	// other than a missing file or denied permission
+	ErrorCodeIO = "io_error"
+	// ErrorCodeExecution is the code of any other tool failure
+	ErrorCodeExecution = "execution_error"
+	// ErrorCodeResource is the code of any other resource failure
+	ErrorCodeResource = "resource_error"
+)
+
+// InternalError is returned by providers as the error of a tool call or
+// resource load that failed for a reason the client cannot fix, such as an
+// unexpected I/O error. Unlike an error result, the server reports it as a
+// failure of the request itself.
+type InternalError struct {
+	// Code is the error code the failure would have as an error result
+	Code    string
+	Message string
+}
+
+func (e *InternalError) Error() string {
+	return e.Message
+}
+
+// isInternalErrorCode reports whether an error code stands for a failure the
+// client cannot fix, as opposed to a problem with the request
+func isInternalErrorCode(code string) bool {
+	return code == ErrorCodeExecution || code == ErrorCodeResource || code == ErrorCodeIO || code == ErrorCodeUnavailable
+}
+
+// newErrorInfo creates an error with the given code. argument names the
+// offending argument, if any, and is reported in the details.
+func newErrorInfo(code, argument, message string) *ErrorInfo {
+	info := &ErrorInfo{
+		Code:    code,
+		Message: message,
+	}
+	if argument != "" {
+		info.Details = map[string]interface{}{"argument": argument}
+	}
+	return info
+}
+
+// errorCodeFor classifies a filesystem error, returning fallback for errors
+// that have no specific code
+func errorCodeFor(err error, fallback string) string {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return ErrorCodeNotFound
+	case errors.Is(err, fs.ErrExist):
+		return ErrorCodeAlreadyExists
+	case errors.Is(err, fs.ErrPermission):
+		return ErrorCodePermissionDenied
+	case errors.Is(err, syscall.EISDIR):
+		return ErrorCodeIsDirectory
+	case errors.Is(err, syscall.ENOTDIR):
+		return ErrorCodeNotDirectory
+	}
+	return fallback
+}
+
+// argumentError is returned by the helpers that parse an argument, naming the
+// argument whose value is invalid
+type argumentError struct {
+	argument string
+	message  string
+}
+
+func (e *argumentError) Error() string {
+	return e.message
+}
+
+// newArgumentError creates an error for an invalid argument
+func newArgumentError(argument, format string, args ...interface{}) error {
+	return &argumentError{argument: argument, message: fmt.Sprintf(format, args...)}
+}
+
+// argumentOf returns the name of the argument an error is about, if known
+func argumentOf(err error) string {
+	var argErr *argumentError
+	if errors.As(err, &argErr) {
+		return argErr.argument
+	}
+	return ""
+}
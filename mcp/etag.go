@@ -0,0 +1,12 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+)
+
+// fileETag returns a weak ETag for a file derived from its size and
+// modification time. It changes whenever a write changes either of them.
+func fileETag(info os.FileInfo) string {
+	return fmt.Sprintf(`W/"%x-%x"`, info.Size(), info.ModTime().UnixNano())
+}
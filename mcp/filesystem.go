@@ -1,25 +1,232 @@
 package mcp
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 // FilesystemProvider implements the Provider interface for filesystem operations
 type FilesystemProvider struct {
 	rootDir string
+
+	// mounts maps mount names to root directories. When set, paths are
+	// addressed as mount:relative/path and rootDir is not used.
+	mounts map[string]string
+
+	// staged holds writes waiting to be committed
+	staged     stagingArea
+	stagingTTL time.Duration
+
+	// lineIndexes caches line indexes until the indexed file changes or is removed
+	lineIndexes lineIndexCache
+
+	// pathLocks serializes writes, deletes and edits of the same path
+	pathLocks pathLocks
+
+	// readCache, when enabled, keeps the contents of recently read files
+	readCache *readCache
+
+	// lifecycleMu guards the background janitor started by Start
+	lifecycleMu sync.Mutex
+	stopJanitor chan struct{}
+	janitorDone chan struct{}
+
+	// watchersMu guards the number of running watches
+	watchersMu     sync.Mutex
+	activeWatchers int
+	maxWatchers    int
+
+	// ioSlots, when set, holds a token for every tool call or resource load
+	// doing I/O, limiting how many run at the same time
+	ioSlots       chan struct{}
+	ioWaitTimeout time.Duration
+
+	// ReadOnly blocks every tool that modifies the filesystem
+	ReadOnly bool
+
+	// AllowedExtensions, when not empty, restricts reading and writing files
+	// to these extensions (e.g. ".txt"), compared case-insensitively
+	AllowedExtensions []string
+
+	// DefaultEncoding is the encoding read and the file resource use when
+	// none is given: "text", "base64", or "auto" to send text as is and
+	// anything else base64-encoded
+	DefaultEncoding string
+
+	// MaxReadBytes and MaxWriteBytes limit the size of files read and content
+	// written whole. Zero means no limit.
+	MaxReadBytes  int64
+	MaxWriteBytes int64
+
+	// TrashDir, when set, is where deleted files and directories are moved
+	// to, so the restore tool can bring them back
+	TrashDir string
+
+	// ProtectedPaths lists paths that, like the root itself, can never be
+	// deleted, moved or renamed, either directly or along with a directory
+	// containing them
+	ProtectedPaths []string
+
+	// InternalErrors returns failures the client cannot fix, those with the
+	// codes io_error, execution_error and resource_error, as *InternalError
+	// errors instead of error results
+	InternalErrors bool
+}
+
+// mutatingTools lists the tools that modify the filesystem
+var mutatingTools = map[string]bool{
+	"write":                true,
+	"delete":               true,
+	"delete-many":          true,
+	"move":                 true,
+	"rename":               true,
+	"copy":                 true,
+	"scaffold":             true,
+	"counter-increment":    true,
+	"normalize-whitespace": true,
+	"fix-line-endings":     true,
+	"replace":              true,
+	"stage-write":          true,
+	"commit-write":         true,
+	"chmod":                true,
+	"symlink":              true,
+	"restore":              true,
+	"zip":                  true,
+	"unzip":                true,
+}
+
+// FilesystemOption configures a FilesystemProvider
+type FilesystemOption func(*FilesystemProvider)
+
+// WithRootDir confines the provider to the given directory
+func WithRootDir(rootDir string) FilesystemOption {
+	return func(p *FilesystemProvider) {
+		p.rootDir = rootDir
+	}
+}
+
+// WithMounts exposes several root directories under the given names. Paths are
+// then addressed as mount:relative/path and confined to the root of their mount.
+func WithMounts(mounts map[string]string) FilesystemOption {
+	return func(p *FilesystemProvider) {
+		p.mounts = mounts
+	}
+}
+
+// WithReadOnly blocks all tools that modify the filesystem
+func WithReadOnly(readOnly bool) FilesystemOption {
+	return func(p *FilesystemProvider) {
+		p.ReadOnly = readOnly
+	}
+}
+
+// WithStagingTTL sets how long a staged write stays available for commit
+func WithStagingTTL(ttl time.Duration) FilesystemOption {
+	return func(p *FilesystemProvider) {
+		p.stagingTTL = ttl
+	}
+}
+
+// WithStagingLimits limits how many writes may be staged at once and their
+// total size in bytes, counting both the new and the original content (zero
+// means no limit)
+func WithStagingLimits(maxWrites int, maxBytes int64) FilesystemOption {
+	return func(p *FilesystemProvider) {
+		p.staged.maxWrites = maxWrites
+		p.staged.maxBytes = maxBytes
+	}
+}
+
+// WithAllowedExtensions restricts reading and writing files to the given extensions
+func WithAllowedExtensions(extensions ...string) FilesystemOption {
+	return func(p *FilesystemProvider) {
+		p.AllowedExtensions = extensions
+	}
+}
+
+// WithDefaultEncoding sets the encoding files are read with when the request
+// does not name one
+func WithDefaultEncoding(encoding string) FilesystemOption {
+	return func(p *FilesystemProvider) {
+		p.DefaultEncoding = encoding
+	}
+}
+
+// WithMaxReadBytes limits the size of the files that can be read (zero means no limit)
+func WithMaxReadBytes(maxBytes int64) FilesystemOption {
+	return func(p *FilesystemProvider) {
+		p.MaxReadBytes = maxBytes
+	}
+}
+
+// WithMaxWriteBytes limits the size of the content that can be written (zero means no limit)
+func WithMaxWriteBytes(maxBytes int64) FilesystemOption {
+	return func(p *FilesystemProvider) {
+		p.MaxWriteBytes = maxBytes
+	}
+}
+
+// WithTrashDir moves deleted files and directories into trashDir instead of removing them
+func WithTrashDir(trashDir string) FilesystemOption {
+	return func(p *FilesystemProvider) {
+		p.TrashDir = trashDir
+	}
+}
+
+// WithInternalErrors returns unexpected failures as Go errors, which the
+// server reports as HTTP 500, instead of as error results
+func WithInternalErrors(enabled bool) FilesystemOption {
+	return func(p *FilesystemProvider) {
+		p.InternalErrors = enabled
+	}
+}
+
+// WithReadCache caches the contents of up to maxEntries recently read files,
+// maxBytes in total, until they are modified. A maxEntries of zero means
+// DefaultReadCacheEntries.
+func WithReadCache(maxEntries int, maxBytes int64) FilesystemOption {
+	return func(p *FilesystemProvider) {
+		p.readCache = newReadCache(maxEntries, maxBytes)
+	}
+}
+
+// WithMaxWatchers limits how many watches may run at the same time (zero means no limit)
+func WithMaxWatchers(maxWatchers int) FilesystemOption {
+	return func(p *FilesystemProvider) {
+		p.maxWatchers = maxWatchers
+	}
 }
 
 // NewFilesystemProvider creates a new filesystem provider
-func NewFilesystemProvider() *FilesystemProvider {
-	// Default to current directory, but this could be configurable
-	return &FilesystemProvider{
-		rootDir: ".",
+func NewFilesystemProvider(opts ...FilesystemOption) *FilesystemProvider {
+	// Default to current directory
+	p := &FilesystemProvider{
+		rootDir:         ".",
+		staged:          stagingArea{maxWrites: DefaultMaxStagedWrites, maxBytes: DefaultMaxStagedBytes},
+		lineIndexes:     lineIndexCache{maxEntries: DefaultLineIndexEntries, maxBytes: DefaultLineIndexBytes},
+		stagingTTL:      DefaultStagingTTL,
+		maxWatchers:     DefaultMaxWatchers,
+		DefaultEncoding: EncodingAuto,
+	}
+	for _, opt := range opts {
+		opt(p)
 	}
+	return p
+}
+
+// NewMountedFilesystemProvider creates a filesystem provider exposing each of
+// the given root directories under its mount name
+func NewMountedFilesystemProvider(mounts map[string]string, opts ...FilesystemOption) *FilesystemProvider {
+	return NewFilesystemProvider(append([]FilesystemOption{WithMounts(mounts)}, opts...)...)
 }
 
 // GetName returns the name of the provider
@@ -29,6 +236,74 @@ func (p *FilesystemProvider) GetName() string {
 
 // GetInfo returns information about the provider
 func (p *FilesystemProvider) GetInfo() ProviderInfo {
+	info := p.allInfo()
+
+	// Mark the tools that leave the filesystem untouched
+	for i, tool := range info.Tools {
+		if parts := ParseID(tool.ID); len(parts) == 2 && !mutatingTools[parts[1]] {
+			info.Tools[i].ReadOnly = true
+		}
+	}
+
+	// Hide the mutating tools from clients of a read-only provider
+	if p.ReadOnly {
+		tools := make([]ToolInfo, 0, len(info.Tools))
+		for _, tool := range info.Tools {
+			if parts := ParseID(tool.ID); len(parts) == 2 && mutatingTools[parts[1]] {
+				continue
+			}
+			tools = append(tools, tool)
+		}
+		info.Tools = tools
+	}
+
+	// Advertise the mounts paths can be addressed by, and the URIs resources can be
+	info.Mounts = p.mountNames()
+	info.ResourceTemplates = p.resourceTemplates()
+
+	return info
+}
+
+// mountNames returns the names of the mounts in sorted order
+func (p *FilesystemProvider) mountNames() []string {
+	if len(p.mounts) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(p.mounts))
+	for name := range p.mounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HealthCheck verifies that the root directory, or the root of every mount, is still accessible
+func (p *FilesystemProvider) HealthCheck() error {
+	if len(p.mounts) == 0 {
+		return checkRootDir(p.rootDir)
+	}
+	for name, rootDir := range p.mounts {
+		if err := checkRootDir(rootDir); err != nil {
+			return fmt.Errorf("mount %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// checkRootDir verifies that a root directory exists and is a directory
+func checkRootDir(rootDir string) error {
+	info, err := os.Stat(rootDir)
+	if err != nil {
+		return fmt.Errorf("root directory is not accessible: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("root directory is not a directory: %s", rootDir)
+	}
+	return nil
+}
+
+// allInfo returns information about every tool and resource of the provider
+func (p *FilesystemProvider) allInfo() ProviderInfo {
 	return ProviderInfo{
 		Name:        "filesystem",
 		Description: "Provides access to the local filesystem",
@@ -44,9 +319,62 @@ func (p *FilesystemProvider) GetInfo() ProviderInfo {
 							"type":        "string",
 							"description": "Path to the directory to list",
 						},
+						"offset": map[string]interface{}{
+							"type":        "integer",
+							"description": "Number of entries to skip, in listing order",
+							"default":     0,
+						},
+						"limit": map[string]interface{}{
+							"type":        "integer",
+							"description": "Maximum number of entries to return (0 means unlimited)",
+							"default":     0,
+						},
+						"page_token": map[string]interface{}{
+							"type":        "string",
+							"description": "next_token of the previous page, to resume the listing after its last entry",
+						},
+						"sort_by": map[string]interface{}{
+							"type":        "string",
+							"description": "Key to sort the entries by",
+							"enum":        directorySortKeys,
+							"default":     "name",
+						},
+						"sort_desc": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Sort in descending order",
+							"default":     false,
+						},
+						"dirs_first": map[string]interface{}{
+							"type":        "boolean",
+							"description": "List directories ahead of files regardless of the sort key",
+							"default":     false,
+						},
+						"pattern": map[string]interface{}{
+							"type":        "string",
+							"description": "Glob pattern the entry names must match, e.g. *.go",
+						},
+						"type": map[string]interface{}{
+							"type":        "string",
+							"description": "Kind of entries to list",
+							"enum":        listEntryTypes,
+							"default":     "all",
+						},
+						"recursive": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Return the whole tree, each directory with its children. Directories are then always listed; pattern and type only filter files",
+							"default":     false,
+						},
+						"max_depth": map[string]interface{}{
+							"type":        "integer",
+							"description": "Number of levels a recursive listing descends, where 1 lists only the directory itself (0 means unlimited)",
+							"minimum":     0,
+							"default":     0,
+						},
+						"on_permission_error": permissionErrorParameter,
 					},
 					"required": []string{"path"},
 				},
+				Returns: oneOfResultSchemas(jsonResultSchema(DirectoryContent{}), jsonResultSchema(DirectoryTree{})),
 			},
 			{
 				ID:          "filesystem.read",
@@ -61,13 +389,19 @@ func (p *FilesystemProvider) GetInfo() ProviderInfo {
 						},
 						"encoding": map[string]interface{}{
 							"type":        "string",
-							"description": "Encoding of the file (text or base64)",
-							"enum":        []string{"text", "base64"},
-							"default":     "text",
+							"description": "Encoding of the content: text, base64, auto to send text as is and anything else base64-encoded, or data_url for a base64 data URL",
+							"enum":        readEncodings,
+							"default":     p.DefaultEncoding,
+						},
+						"git_blob_hash": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Include the git blob SHA-1 of the content",
+							"default":     false,
 						},
 					},
 					"required": []string{"path"},
 				},
+				Returns: jsonResultSchema(FileContent{}),
 			},
 			{
 				ID:          "filesystem.write",
@@ -87,12 +421,31 @@ func (p *FilesystemProvider) GetInfo() ProviderInfo {
 						"encoding": map[string]interface{}{
 							"type":        "string",
 							"description": "Encoding of the content (text or base64)",
-							"enum":        []string{"text", "base64"},
-							"default":     "text",
+							"enum":        writeEncodings,
+							"default":     EncodingText,
+						},
+						"if_match": map[string]interface{}{
+							"type":        "string",
+							"description": "ETag from a previous read; the write fails with a conflict if the file changed since. Use \"*\" to only write a file that does not exist yet",
+						},
+						"atomic": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Write to a temporary file and rename it into place; disable for filesystems that cannot rename over an existing file",
+							"default":     true,
+						},
+						"mode": map[string]interface{}{
+							"type":        "string",
+							"description": "Octal permission bits of the file, e.g. \"0600\". Defaults to the mode of the file being overwritten, or 0644",
+						},
+						"dir_mode": map[string]interface{}{
+							"type":        "string",
+							"description": "Octal permission bits of parent directories created for the file",
+							"default":     "0755",
 						},
 					},
 					"required": []string{"path", "content"},
 				},
+				Returns: jsonResultSchema(WriteResult{}),
 			},
 			{
 				ID:          "filesystem.delete",
@@ -110,106 +463,1203 @@ func (p *FilesystemProvider) GetInfo() ProviderInfo {
 							"description": "Whether to recursively delete directories",
 							"default":     false,
 						},
+						"dry_run": map[string]interface{}{
+							"type":        "boolean",
+							"description": "List the paths that would be deleted without deleting anything",
+							"default":     false,
+						},
 					},
 					"required": []string{"path"},
 				},
+				Returns: oneOfResultSchemas(textResultSchema(), jsonResultSchema(DryRunResult{}), jsonResultSchema(TrashedItem{})),
 			},
-		},
-		Resources: []ResourceInfo{
 			{
-				ID:          "filesystem.file",
-				Name:        "File",
-				Description: "A file in the filesystem",
+				ID:          "filesystem.delete-many",
+				Name:        "Delete Many",
+				Description: "Deletes several files or directories, reporting the outcome for each path",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"paths": map[string]interface{}{
+							"type":        "array",
+							"description": "Paths to the files or directories to delete",
+							"items":       map[string]interface{}{"type": "string"},
+							"minItems":    1,
+						},
+						"recursive": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Whether to recursively delete directories",
+							"default":     false,
+						},
+						"continue_on_error": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Keep deleting the remaining paths after one fails instead of skipping them",
+							"default":     false,
+						},
+					},
+					"required": []string{"paths"},
+				},
+				Returns: jsonResultSchema(DeleteManyResult{}),
+			},
+			{
+				ID:          "filesystem.copy",
+				Name:        "Copy",
+				Description: "Copies a file, or a directory tree, to a new location. Streaming clients receive progress events while directories are copied",
 				Parameters: map[string]interface{}{
 					"type": "object",
 					"properties": map[string]interface{}{
 						"path": map[string]interface{}{
 							"type":        "string",
-							"description": "Path to the file",
+							"description": "Path to the file or directory to copy",
 						},
-						"encoding": map[string]interface{}{
+						"destination": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to copy to; must not exist yet",
+						},
+						"recursive": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Whether to copy directories",
+							"default":     false,
+						},
+						"progress_interval": map[string]interface{}{
+							"type":        "integer",
+							"description": "Number of files copied between two progress events",
+							"minimum":     1,
+							"default":     DefaultCopyProgressInterval,
+						},
+						"on_permission_error": permissionErrorParameter,
+						"dry_run": map[string]interface{}{
+							"type":        "boolean",
+							"description": "List the paths that would be created without copying anything",
+							"default":     false,
+						},
+					},
+					"required": []string{"path", "destination"},
+				},
+				Returns: oneOfResultSchemas(jsonResultSchema(CopyResult{}), jsonResultSchema(DryRunResult{})),
+			},
+			{
+				ID:          "filesystem.move",
+				Name:        "Move",
+				Description: "Moves a file or directory. A destination that is an existing directory receives the source under its own name",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the file or directory to move",
+						},
+						"destination": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to move to, or an existing directory to move into",
+						},
+						"dry_run": map[string]interface{}{
+							"type":        "boolean",
+							"description": "List the paths that would be moved without moving anything",
+							"default":     false,
+						},
+					},
+					"required": []string{"path", "destination"},
+				},
+				Returns: oneOfResultSchemas(jsonResultSchema(MoveResult{}), jsonResultSchema(DryRunResult{})),
+			},
+			{
+				ID:          "filesystem.scaffold",
+				Name:        "Scaffold Directory Structure",
+				Description: "Creates a tree of directories and files from a nested spec in a single call",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Base directory in which to create the structure",
+						},
+						"spec": map[string]interface{}{
+							"type":        "array",
+							"description": "Nodes to create. Each node has a name, an optional type (file or directory), optional content for files and optional children for directories",
+							"items": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"name": map[string]interface{}{
+										"type": "string",
+									},
+									"type": map[string]interface{}{
+										"type": "string",
+										"enum": []string{"file", "directory"},
+									},
+									"content": map[string]interface{}{
+										"type": "string",
+									},
+									"children": map[string]interface{}{
+										"type": "array",
+									},
+								},
+								"required": []string{"name"},
+							},
+						},
+						"dry_run": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Report the paths that would be created without touching the filesystem",
+							"default":     false,
+						},
+					},
+					"required": []string{"path", "spec"},
+				},
+				Returns: jsonResultSchema(ScaffoldResult{}),
+			},
+			{
+				ID:          "filesystem.files-equal",
+				Name:        "Compare Files",
+				Description: "Checks whether two files have identical contents, stopping at the first difference",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path_a": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the first file",
+						},
+						"path_b": map[string]interface{}{
 							"type":        "string",
-							"description": "Encoding of the file (text or base64)",
-							"enum":        []string{"text", "base64"},
-							"default":     "text",
+							"description": "Path to the second file",
+						},
+					},
+					"required": []string{"path_a", "path_b"},
+				},
+				Returns: jsonResultSchema(FilesEqualResult{}),
+			},
+			{
+				ID:          "filesystem.diff",
+				Name:        "Diff",
+				Description: "Compares two files, returning a unified diff of their text, or two directories, returning the relative paths of the files added, removed and changed between them",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path_a": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the original file or directory",
+						},
+						"path_b": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the file or directory to compare it with",
+						},
+						"on_permission_error": permissionErrorParameter,
+					},
+					"required": []string{"path_a", "path_b"},
+				},
+				Returns: jsonResultSchema(DiffResult{}),
+			},
+			{
+				ID:          "filesystem.stat",
+				Name:        "Stat Path",
+				Description: "Returns metadata about a file or directory",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the file or directory",
 						},
 					},
 					"required": []string{"path"},
 				},
+				Returns: jsonResultSchema(FileStat{}),
 			},
 			{
-				ID:          "filesystem.directory",
-				Name:        "Directory",
-				Description: "A directory in the filesystem",
+				ID:          "filesystem.exists",
+				Name:        "Path Exists",
+				Description: "Checks whether a path exists and whether it is a file or a directory",
 				Parameters: map[string]interface{}{
 					"type": "object",
 					"properties": map[string]interface{}{
 						"path": map[string]interface{}{
 							"type":        "string",
-							"description": "Path to the directory",
+							"description": "Path to check",
 						},
 					},
 					"required": []string{"path"},
 				},
+				Returns: jsonResultSchema(ExistsResult{}),
 			},
-		},
-	}
-}
-
-// CallTool calls a tool provided by this provider
-func (p *FilesystemProvider) CallTool(toolName string, request CallToolRequest) (*CallToolResult, error) {
-	// Set the request ID in the result
-	result := &CallToolResult{
-		RequestID: request.RequestID,
+			{
+				ID:          "filesystem.search",
+				Name:        "Search Files",
+				Description: "Recursively searches a directory for entries whose name matches a glob pattern",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the directory to search",
+						},
+						"pattern": map[string]interface{}{
+							"type":        "string",
+							"description": "Glob pattern matched against entry names, e.g. *.go",
+						},
+						"max_depth": map[string]interface{}{
+							"type":        "integer",
+							"description": "Maximum depth to descend, where 1 only searches the directory itself (0 means unlimited)",
+							"default":     0,
+						},
+						"max_results": map[string]interface{}{
+							"type":        "integer",
+							"description": "Maximum number of entries to return (0 means unlimited)",
+							"default":     0,
+						},
+						"on_permission_error": permissionErrorParameter,
+					},
+					"required": []string{"path", "pattern"},
+				},
+				Returns: jsonResultSchema(SearchResult{}),
+			},
+			{
+				ID:          "filesystem.list-symlinks",
+				Name:        "List Symlinks",
+				Description: "Recursively lists the symbolic links in a directory with their targets, flagging broken links and links that point outside the root",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the directory to scan",
+						},
+						"on_permission_error": permissionErrorParameter,
+					},
+					"required": []string{"path"},
+				},
+				Returns: jsonResultSchema(SymlinkList{}),
+			},
+			{
+				ID:          "filesystem.grep",
+				Name:        "Grep Files",
+				Description: "Searches the contents of a file, or of every text file under a directory, for a string or regular expression",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the file or directory to search",
+						},
+						"query": map[string]interface{}{
+							"type":        "string",
+							"description": "Text or regular expression to search for",
+						},
+						"regex": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Treat the query as a regular expression",
+							"default":     false,
+						},
+						"case_insensitive": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Ignore case when matching",
+							"default":     false,
+						},
+						"max_matches": map[string]interface{}{
+							"type":        "integer",
+							"description": "Maximum number of matching lines to return (0 means unlimited)",
+							"default":     0,
+						},
+						"on_permission_error": permissionErrorParameter,
+					},
+					"required": []string{"path", "query"},
+				},
+				Returns: jsonResultSchema(GrepResult{}),
+			},
+			{
+				ID:          "filesystem.cas-key",
+				Name:        "Content-Addressable Key",
+				Description: "Computes a content-addressable storage key for a file, optionally sharded into a directory prefix",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the file",
+						},
+						"algorithm": map[string]interface{}{
+							"type":        "string",
+							"description": "Hash algorithm to use",
+							"enum":        hashAlgorithms,
+							"default":     "sha256",
+						},
+						"prefix_length": map[string]interface{}{
+							"type":        "integer",
+							"description": "Number of leading hash characters used as a shard directory (0 disables sharding)",
+							"default":     2,
+						},
+						"include_size": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Append the file size to the key",
+							"default":     false,
+						},
+					},
+					"required": []string{"path"},
+				},
+				Returns: jsonResultSchema(CASKey{}),
+			},
+			{
+				ID:          "filesystem.hash",
+				Name:        "Hash File",
+				Description: "Computes the checksum of a file",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the file",
+						},
+						"algorithm": map[string]interface{}{
+							"type":        "string",
+							"description": "Hash algorithm to use",
+							"enum":        hashAlgorithms,
+							"default":     "sha256",
+						},
+					},
+					"required": []string{"path"},
+				},
+				Returns: jsonResultSchema(FileHash{}),
+			},
+			{
+				ID:          "filesystem.counter-increment",
+				Name:        "Increment Counter",
+				Description: "Atomically increments an integer stored in a file and returns the new value. A missing file starts at zero",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the counter file",
+						},
+						"delta": map[string]interface{}{
+							"type":        "integer",
+							"description": "Amount to add to the counter",
+							"default":     1,
+						},
+					},
+					"required": []string{"path"},
+				},
+				Returns: jsonResultSchema(CounterResult{}),
+			},
+			{
+				ID:          "filesystem.build-line-index",
+				Name:        "Build Line Index",
+				Description: "Scans a file once and returns the byte offset of each line. The index is cached until the file changes",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the file to index",
+						},
+						"include_offsets": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Return the offsets rather than only the line count",
+							"default":     true,
+						},
+					},
+					"required": []string{"path"},
+				},
+				Returns: jsonResultSchema(LineIndex{}),
+			},
+			{
+				ID:          "filesystem.normalize-whitespace",
+				Name:        "Normalize Whitespace",
+				Description: "Trims trailing whitespace from every line of a text file, ends it with a single newline and optionally expands tabs to spaces",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the text file",
+						},
+						"expand_tabs": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Convert tabs to spaces",
+							"default":     false,
+						},
+						"tab_width": map[string]interface{}{
+							"type":        "integer",
+							"description": "Number of columns between tab stops when expanding tabs",
+							"default":     4,
+						},
+						"dry_run": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Report the lines that would change without modifying the file",
+							"default":     false,
+						},
+					},
+					"required": []string{"path"},
+				},
+				Returns: jsonResultSchema(WhitespaceResult{}),
+			},
+			{
+				ID:          "filesystem.replace",
+				Name:        "Find and Replace",
+				Description: "Replaces occurrences of a string or regular expression in a text file",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the file to edit",
+						},
+						"search": map[string]interface{}{
+							"type":        "string",
+							"description": "Text to search for, or a regular expression if regex is true",
+							"minLength":   1,
+						},
+						"replacement": map[string]interface{}{
+							"type":        "string",
+							"description": "Text to replace each occurrence with. With regex, $1 or ${name} insert submatches",
+						},
+						"regex": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Whether search is a regular expression",
+							"default":     false,
+						},
+						"count": map[string]interface{}{
+							"type":        "integer",
+							"description": "Maximum number of occurrences to replace, starting from the beginning of the file (default all)",
+							"minimum":     1,
+						},
+					},
+					"required": []string{"path", "search", "replacement"},
+				},
+				Returns: jsonResultSchema(ReplaceResult{}),
+			},
+			{
+				ID:          "filesystem.walk",
+				Name:        "Walk Directory",
+				Description: "Returns a flat, paginated list of the files beneath a directory in a stable lexical order. Streaming clients receive each entry as it is found and the whole tree unless a limit is given",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the directory to walk",
+						},
+						"pattern": map[string]interface{}{
+							"type":        "string",
+							"description": "Glob pattern matched against entry names, e.g. *.go",
+						},
+						"ignore": map[string]interface{}{
+							"type":        "array",
+							"description": "Glob patterns of entry names to skip. Ignored directories are not descended into",
+							"items": map[string]interface{}{
+								"type": "string",
+							},
+						},
+						"include_dirs": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Include directories in the list",
+							"default":     false,
+						},
+						"limit": map[string]interface{}{
+							"type":        "integer",
+							"description": "Maximum number of entries per page",
+							"default":     defaultWalkLimit,
+						},
+						"cursor": map[string]interface{}{
+							"type":        "string",
+							"description": "next_cursor of the previous page",
+						},
+						"on_permission_error": permissionErrorParameter,
+					},
+					"required": []string{"path"},
+				},
+				Returns: jsonResultSchema(WalkResult{}),
+			},
+			{
+				ID:          "filesystem.stage-write",
+				Name:        "Stage Write",
+				Description: "Stages new content for a file without writing it and returns a token and a unified diff against the current content. The write is applied with filesystem.commit-write",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the file to write",
+						},
+						"content": map[string]interface{}{
+							"type":        "string",
+							"description": "Content to write to the file",
+						},
+						"encoding": map[string]interface{}{
+							"type":        "string",
+							"description": "Encoding of the content (text or base64)",
+							"enum":        writeEncodings,
+							"default":     EncodingText,
+						},
+					},
+					"required": []string{"path", "content"},
+				},
+				Returns: jsonResultSchema(StageWriteResult{}),
+			},
+			{
+				ID:          "filesystem.commit-write",
+				Name:        "Commit Write",
+				Description: "Atomically writes staged content to its file. Fails if the token expired or the file changed since it was staged",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"token": map[string]interface{}{
+							"type":        "string",
+							"description": "Token returned by filesystem.stage-write",
+						},
+					},
+					"required": []string{"token"},
+				},
+				Returns: textResultSchema(),
+			},
+			{
+				ID:          "filesystem.read-structured",
+				Name:        "Read Structured File",
+				Description: "Reads a JSON, YAML or TOML file and returns its parsed content as JSON",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the file to read",
+						},
+						"format": map[string]interface{}{
+							"type":        "string",
+							"description": "Format of the file. Detected from the extension when omitted",
+							"enum":        structuredFormats,
+						},
+					},
+					"required": []string{"path"},
+				},
+				Returns: jsonResultSchema(StructuredContent{}),
+			},
+			{
+				ID:          "filesystem.check-line-endings",
+				Name:        "Check Line Endings",
+				Description: "Reports whether a text file uses LF, CRLF or mixed line endings. Use filesystem.fix-line-endings to normalize them",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the text file",
+						},
+					},
+					"required": []string{"path"},
+				},
+				Returns: jsonResultSchema(LineEndingReport{}),
+			},
+			{
+				ID:          "filesystem.fix-line-endings",
+				Name:        "Fix Line Endings",
+				Description: "Rewrites a text file so every line ends with the target style, reporting the line endings it used before",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the text file",
+						},
+						"target": map[string]interface{}{
+							"type":        "string",
+							"description": "Line ending style to normalize to",
+							"enum":        lineEndingStyles,
+							"default":     "lf",
+						},
+					},
+					"required": []string{"path"},
+				},
+				Returns: jsonResultSchema(LineEndingReport{}),
+			},
+			{
+				ID:          "filesystem.watch",
+				Name:        "Watch Directory",
+				Description: "Streams create, write, remove and rename events for everything beneath a directory until the client disconnects or the request times out. Requires Accept: text/event-stream",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the directory to watch",
+						},
+					},
+					"required": []string{"path"},
+				},
+				Returns: jsonResultSchema(WatchResult{}),
+			},
+			{
+				ID:          "filesystem.chmod",
+				Name:        "Change Mode",
+				Description: "Changes the permission bits of a file or directory",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the file or directory",
+						},
+						"mode": map[string]interface{}{
+							"type":        "string",
+							"description": "Octal permission bits, e.g. \"0644\"",
+						},
+					},
+					"required": []string{"path", "mode"},
+				},
+				Returns: jsonResultSchema(ChmodResult{}),
+			},
+			{
+				ID:          "filesystem.rename",
+				Name:        "Rename",
+				Description: "Renames a file or directory within the directory it is in, without overwriting an existing entry",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the file or directory to rename",
+						},
+						"new_name": map[string]interface{}{
+							"type":        "string",
+							"description": "New name of the entry, without any path separators",
+						},
+					},
+					"required": []string{"path", "new_name"},
+				},
+				Returns: jsonResultSchema(RenameResult{}),
+			},
+			{
+				ID:          "filesystem.symlink",
+				Name:        "Create Symbolic Link",
+				Description: "Creates a symbolic link to a file or directory within the root",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"target": map[string]interface{}{
+							"type":        "string",
+							"description": "Path the link points to",
+						},
+						"link_path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path of the link to create",
+						},
+					},
+					"required": []string{"target", "link_path"},
+				},
+				Returns: jsonResultSchema(SymlinkInfo{}),
+			},
+			{
+				ID:          "filesystem.restore",
+				Name:        "Restore From Trash",
+				Description: "Moves an item deleted into the trash back to where it was deleted from",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"id": map[string]interface{}{
+							"type":        "string",
+							"description": "ID of the trashed item, as returned by filesystem.delete",
+						},
+					},
+					"required": []string{"id"},
+				},
+				Returns: textResultSchema(),
+			},
+			{
+				ID:          "filesystem.head",
+				Name:        "Head",
+				Description: "Returns the first lines of a text file and its total line count",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the text file",
+						},
+						"lines": map[string]interface{}{
+							"type":        "integer",
+							"description": "Number of lines to return",
+							"minimum":     0,
+							"default":     DefaultPreviewLines,
+						},
+					},
+					"required": []string{"path"},
+				},
+				Returns: jsonResultSchema(FileLines{}),
+			},
+			{
+				ID:          "filesystem.tail",
+				Name:        "Tail",
+				Description: "Returns the last lines of a text file and its total line count",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the text file",
+						},
+						"lines": map[string]interface{}{
+							"type":        "integer",
+							"description": "Number of lines to return",
+							"minimum":     0,
+							"default":     DefaultPreviewLines,
+						},
+					},
+					"required": []string{"path"},
+				},
+				Returns: jsonResultSchema(FileLines{}),
+			},
+			{
+				ID:          "filesystem.readlines",
+				Name:        "Read Lines",
+				Description: "Returns the lines of a text file as an array, or the lines from start_line to end_line, along with the total line count. Line endings, \\n or \\r\\n, are removed",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the text file",
+						},
+						"start_line": map[string]interface{}{
+							"type":        "integer",
+							"description": "1-based number of the first line to return",
+							"minimum":     1,
+							"default":     1,
+						},
+						"end_line": map[string]interface{}{
+							"type":        "integer",
+							"description": "1-based number of the last line to return, inclusive (defaults to the last line of the file)",
+							"minimum":     1,
+						},
+					},
+					"required": []string{"path"},
+				},
+				Returns: jsonResultSchema(FileLineRange{}),
+			},
+			{
+				ID:          "filesystem.zip",
+				Name:        "Zip Directory",
+				Description: "Bundles the tree beneath a directory into a new zip archive, keeping relative paths and file modes. Symbolic links are skipped",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the directory to archive",
+						},
+						"destination": map[string]interface{}{
+							"type":        "string",
+							"description": "Path of the archive to create; it must not exist yet",
+						},
+						"on_permission_error": permissionErrorParameter,
+					},
+					"required": []string{"path", "destination"},
+				},
+				Returns: jsonResultSchema(ZipResult{}),
+			},
+			{
+				ID:          "filesystem.unzip",
+				Name:        "Unzip Archive",
+				Description: "Extracts a zip archive into a directory. Archives with entries that would land outside the directory, symbolic links or files that already exist are rejected before anything is written",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the zip archive",
+						},
+						"destination": map[string]interface{}{
+							"type":        "string",
+							"description": "Directory to extract the archive into; it is created if missing",
+						},
+					},
+					"required": []string{"path", "destination"},
+				},
+				Returns: jsonResultSchema(UnzipResult{}),
+			},
+			{
+				ID:          "filesystem.du",
+				Name:        "Disk Usage",
+				Description: "Sums the sizes of the files beneath a directory",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the directory to measure",
+						},
+						"max_depth": map[string]interface{}{
+							"type":        "integer",
+							"description": "Maximum depth to descend, where 1 only counts the entries directly inside the directory (0 means unlimited)",
+							"default":     0,
+						},
+						"by_subdir": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Also report the usage of each immediate subdirectory",
+							"default":     false,
+						},
+						"on_permission_error": permissionErrorParameter,
+					},
+					"required": []string{"path"},
+				},
+				Returns: jsonResultSchema(DiskUsageResult{}),
+			},
+		},
+		Resources: []ResourceInfo{
+			{
+				ID:          "filesystem.file",
+				Name:        "File",
+				Description: "A file in the filesystem",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the file",
+						},
+						"uri": map[string]interface{}{
+							"type":        "string",
+							"description": "file:// URI of the file, in place of path",
+						},
+						"encoding": map[string]interface{}{
+							"type":        "string",
+							"description": "Encoding of the content: text, base64, auto to send text as is and anything else base64-encoded, or data_url for a base64 data URL",
+							"enum":        readEncodings,
+							"default":     p.DefaultEncoding,
+						},
+						"if_none_match": map[string]interface{}{
+							"type":        "string",
+							"description": "ETag of a previous load; if the file is unchanged the result has status not_modified and no content",
+						},
+						"raw": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Send the file itself over HTTP, with its detected media type as Content-Type, instead of the JSON result",
+							"default":     false,
+						},
+					},
+					"anyOf": []interface{}{
+						map[string]interface{}{"required": []string{"path"}},
+						map[string]interface{}{"required": []string{"uri"}},
+					},
+				},
+			},
+			{
+				ID:          "filesystem.directory",
+				Name:        "Directory",
+				Description: "A directory in the filesystem",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path to the directory",
+						},
+						"uri": map[string]interface{}{
+							"type":        "string",
+							"description": "file:// URI of the directory, in place of path",
+						},
+						"offset": map[string]interface{}{
+							"type":        "integer",
+							"description": "Number of entries to skip, in listing order",
+							"default":     0,
+						},
+						"limit": map[string]interface{}{
+							"type":        "integer",
+							"description": "Maximum number of entries to return (0 means unlimited)",
+							"default":     0,
+						},
+					},
+					"anyOf": []interface{}{
+						map[string]interface{}{"required": []string{"path"}},
+						map[string]interface{}{"required": []string{"uri"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+// CallTool calls a tool provided by this provider
+func (p *FilesystemProvider) CallTool(ctx context.Context, toolName string, request CallToolRequest) (*CallToolResult, error) {
+	// Watches only wait for events and have their own limit
+	if toolName != "watch" {
+		release, err := p.acquireIOSlot(ctx)
+		if err != nil {
+			return p.toolSlotError(request, err)
+		}
+		defer release()
+	}
+	return p.toolOutcome(p.callTool(ctx, toolName, request))
+}
+
+// toolOutcome turns a failed tool call the client cannot fix into an
+// *InternalError if InternalErrors is set
+func (p *FilesystemProvider) toolOutcome(result *CallToolResult, err error) (*CallToolResult, error) {
+	if p.InternalErrors && err == nil && result.Status == "error" && result.Error != nil && isInternalErrorCode(result.Error.Code) {
+		return nil, &InternalError{Code: result.Error.Code, Message: result.Error.Message}
+	}
+	return result, err
+}
+
+// callTool dispatches a tool call to the method implementing the tool
+func (p *FilesystemProvider) callTool(ctx context.Context, toolName string, request CallToolRequest) (*CallToolResult, error) {
+	// Set the request ID in the result
+	result := &CallToolResult{
+		RequestID: request.RequestID,
+	}
+
+	// Refuse to modify anything in read-only mode
+	if p.ReadOnly && mutatingTools[toolName] {
+		result.Status = "error"
+		result.Error = &ErrorInfo{
+			Code:    ErrorCodeReadOnly,
+			Message: fmt.Sprintf("Tool %s is not available: the filesystem is read-only", toolName),
+		}
+		return result, nil
+	}
+
+	switch toolName {
+	case "list":
+		return p.listDirectory(ctx, request)
+	case "read":
+		return p.readFile(ctx, request)
+	case "write":
+		return p.writeFile(ctx, request)
+	case "delete":
+		return p.deleteFile(ctx, request)
+	case "delete-many":
+		return p.deleteMany(ctx, request)
+	case "copy":
+		return p.copyFile(ctx, request, nil)
+	case "move":
+		return p.moveFile(ctx, request)
+	case "scaffold":
+		return p.scaffold(ctx, request)
+	case "files-equal":
+		return p.filesEqual(ctx, request)
+	case "diff":
+		return p.diffPaths(ctx, request)
+	case "stat":
+		return p.statPath(ctx, request)
+	case "exists":
+		return p.pathExists(ctx, request)
+	case "search":
+		return p.searchFiles(ctx, request, nil)
+	case "list-symlinks":
+		return p.listSymlinks(ctx, request)
+	case "grep":
+		return p.grepFiles(ctx, request, nil)
+	case "cas-key":
+		return p.casKey(ctx, request)
+	case "hash":
+		return p.hashPath(ctx, request)
+	case "counter-increment":
+		return p.incrementCounter(ctx, request)
+	case "build-line-index":
+		return p.buildLineIndex(ctx, request)
+	case "replace":
+		return p.replaceInFile(ctx, request)
+	case "normalize-whitespace":
+		return p.normalizeWhitespace(ctx, request)
+	case "walk":
+		return p.walkFiles(ctx, request, nil)
+	case "stage-write":
+		return p.stageWrite(ctx, request)
+	case "commit-write":
+		return p.commitWrite(ctx, request)
+	case "read-structured":
+		return p.readStructured(ctx, request)
+	case "check-line-endings":
+		return p.checkLineEndings(ctx, request)
+	case "fix-line-endings":
+		return p.fixLineEndings(ctx, request)
+	case "watch":
+		return p.watch(ctx, request, nil)
+	case "chmod":
+		return p.chmodPath(ctx, request)
+	case "symlink":
+		return p.createSymlink(ctx, request)
+	case "rename":
+		return p.renamePath(ctx, request)
+	case "du":
+		return p.diskUsage(ctx, request)
+	case "restore":
+		return p.restoreFromTrash(ctx, request)
+	case "readlines":
+		return p.readFileLines(ctx, request)
+	case "head":
+		return p.headFile(ctx, request)
+	case "tail":
+		return p.tailFile(ctx, request)
+	case "zip":
+		return p.zipDirectory(ctx, request)
+	case "unzip":
+		return p.unzipArchive(ctx, request)
+	default:
+		result.Status = "error"
+		result.Error = &ErrorInfo{
+			Code:    ErrorCodeUnknownTool,
+			Message: fmt.Sprintf("Unknown tool: %s", toolName),
+		}
+		return result, nil
+	}
+}
+
+// CallToolStream calls a tool, emitting partial results as they become available.
+// search emits each matching entry, grep the matches of each file and copy its
+// progress; every other tool runs like CallTool without emitting anything.
+func (p *FilesystemProvider) CallToolStream(ctx context.Context, toolName string, request CallToolRequest, emit EmitFunc) (*CallToolResult, error) {
+	if toolName != "watch" {
+		release, err := p.acquireIOSlot(ctx)
+		if err != nil {
+			return p.toolSlotError(request, err)
+		}
+		defer release()
+	}
+	return p.toolOutcome(p.callToolStream(ctx, toolName, request, emit))
+}
+
+// callToolStream dispatches a streaming tool call to the method implementing the tool
+func (p *FilesystemProvider) callToolStream(ctx context.Context, toolName string, request CallToolRequest, emit EmitFunc) (*CallToolResult, error) {
+	// Let callTool refuse mutating tools in read-only mode
+	if p.ReadOnly && mutatingTools[toolName] {
+		return p.callTool(ctx, toolName, request)
+	}
+
+	switch toolName {
+	case "search":
+		return p.searchFiles(ctx, request, emit)
+	case "copy":
+		return p.copyFile(ctx, request, emit)
+	case "grep":
+		return p.grepFiles(ctx, request, emit)
+	case "watch":
+		return p.watch(ctx, request, emit)
+	case "walk":
+		return p.walkFiles(ctx, request, emit)
+	default:
+		return p.callTool(ctx, toolName, request)
+	}
+}
+
+// LoadResource loads a resource provided by this provider
+func (p *FilesystemProvider) LoadResource(ctx context.Context, resourceName string, request LoadResourceRequest) (*LoadResourceResult, error) {
+	release, err := p.acquireIOSlot(ctx)
+	if err != nil {
+		return p.resourceSlotError(request, err)
+	}
+	defer release()
+
+	result, err := p.loadResource(ctx, resourceName, request)
+	if p.InternalErrors && err == nil && result.Status == "error" && result.Error != nil && isInternalErrorCode(result.Error.Code) {
+		return nil, &InternalError{Code: result.Error.Code, Message: result.Error.Message}
+	}
+	return result, err
+}
+
+// loadResource dispatches a resource load to the method implementing the resource
+func (p *FilesystemProvider) loadResource(ctx context.Context, resourceName string, request LoadResourceRequest) (*LoadResourceResult, error) {
+	// Set the request ID in the result
+	result := &LoadResourceResult{
+		RequestID: request.RequestID,
+	}
+
+	switch resourceName {
+	case "file":
+		return p.loadFile(ctx, request)
+	case "directory":
+		return p.loadDirectory(ctx, request)
+	default:
+		result.Status = "error"
+		result.Error = &ErrorInfo{
+			Code:    ErrorCodeUnknownResource,
+			Message: fmt.Sprintf("Unknown resource: %s", resourceName),
+		}
+		return result, nil
+	}
+}
+
+// listDirectory lists the contents of a directory
+func (p *FilesystemProvider) listDirectory(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the offset and limit parameters
+	offset, limit, err := parsePagination(request.Params.Arguments)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, argumentOf(err), err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the sort parameters
+	order, err := parseDirectorySort(request.Params.Arguments)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, argumentOf(err), err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the pattern parameter (default to every entry)
+	pattern := ""
+	if patternParam, ok := request.Params.Arguments["pattern"].(string); ok {
+		pattern = patternParam
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "pattern", fmt.Sprintf("Invalid pattern: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the type parameter (default to all entries)
+	entryType := "all"
+	if typeParam, ok := request.Params.Arguments["type"].(string); ok {
+		entryType = typeParam
+	}
+	if !slices.Contains(listEntryTypes, entryType) {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "type", fmt.Sprintf("Unknown type value: %s (expected one of %s)", entryType, strings.Join(listEntryTypes, ", ")))
+		result.RequestID = request.RequestID
+		return result, nil
 	}
 
-	switch toolName {
-	case "list":
-		return p.listDirectory(request)
-	case "read":
-		return p.readFile(request)
-	case "write":
-		return p.writeFile(request)
-	case "delete":
-		return p.deleteFile(request)
-	default:
-		result.Status = "error"
-		result.Error = &ErrorInfo{
-			Code:    "unknown_tool",
-			Message: fmt.Sprintf("Unknown tool: %s", toolName),
-		}
+	// Get the recursive and max_depth parameters (default to a flat listing)
+	recursive := false
+	if recursiveParam, ok := request.Params.Arguments["recursive"].(bool); ok {
+		recursive = recursiveParam
+	}
+	maxDepth := 0
+	if maxDepthParam, ok := request.Params.Arguments["max_depth"].(float64); ok {
+		maxDepth = int(maxDepthParam)
+	}
+	if maxDepth < 0 {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "max_depth", "max_depth must not be negative")
+		result.RequestID = request.RequestID
 		return result, nil
 	}
-}
-
-// LoadResource loads a resource provided by this provider
-func (p *FilesystemProvider) LoadResource(resourceName string, request LoadResourceRequest) (*LoadResourceResult, error) {
-	// Set the request ID in the result
-	result := &LoadResourceResult{
-		RequestID: request.RequestID,
+	if recursive && (offset > 0 || limit > 0) {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "recursive", "offset and limit cannot be combined with recursive")
+		result.RequestID = request.RequestID
+		return result, nil
 	}
 
-	switch resourceName {
-	case "file":
-		return p.loadFile(request)
-	case "directory":
-		return p.loadDirectory(request)
-	default:
-		result.Status = "error"
-		result.Error = &ErrorInfo{
-			Code:    "unknown_resource",
-			Message: fmt.Sprintf("Unknown resource: %s", resourceName),
-		}
+	// Get the on_permission_error parameter, which applies to the subdirectories of a recursive listing
+	policy, err := parsePermissionErrorPolicy(request.Params.Arguments)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, argumentOf(err), err.Error())
+		result.RequestID = request.RequestID
 		return result, nil
 	}
-}
 
-// listDirectory lists the contents of a directory
-func (p *FilesystemProvider) listDirectory(request CallToolRequest) (*CallToolResult, error) {
-	// Get the path parameter
-	pathParam, ok := request.Params.Arguments["path"].(string)
-	if !ok {
-		result := NewToolResultError("Path parameter is required and must be a string")
+	// Get the page_token parameter, which resumes a listing after the last entry of a previous page
+	pageToken, err := parseListPageToken(request.Params.Arguments, pathParam, order)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, argumentOf(err), err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	if pageToken != nil && (offset > 0 || recursive) {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "page_token", "page_token cannot be combined with offset or recursive")
 		result.RequestID = request.RequestID
 		return result, nil
 	}
@@ -217,7 +1667,7 @@ func (p *FilesystemProvider) listDirectory(request CallToolRequest) (*CallToolRe
 	// Sanitize and resolve the path
 	fullPath, err := p.resolvePath(pathParam)
 	if err != nil {
-		result := NewToolResultError(fmt.Sprintf("Invalid path: %s", err.Error()))
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
 		result.RequestID = request.RequestID
 		return result, nil
 	}
@@ -226,17 +1676,35 @@ func (p *FilesystemProvider) listDirectory(request CallToolRequest) (*CallToolRe
 	info, err := os.Stat(fullPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			result := NewToolResultError(fmt.Sprintf("Directory not found: %s", pathParam))
+			result := NewToolResultErrorCode(ErrorCodeNotFound, "path", fmt.Sprintf("Directory not found: %s", pathParam))
 			result.RequestID = request.RequestID
 			return result, nil
 		}
-		result := NewToolResultError(fmt.Sprintf("Error accessing directory: %s", err.Error()))
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing directory: %s", err.Error()))
 		result.RequestID = request.RequestID
 		return result, nil
 	}
 
 	if !info.IsDir() {
-		result := NewToolResultError(fmt.Sprintf("Path is not a directory: %s", pathParam))
+		result := NewToolResultErrorCode(ErrorCodeNotDirectory, "path", fmt.Sprintf("Path is not a directory: %s", pathParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// List the whole tree in one nested result
+	if recursive {
+		tree := DirectoryTree{Path: pathParam}
+		tree.Entries, err = buildTree(ctx, fullPath, pathParam, 1, maxDepth, order, pattern, entryType, policy, &tree)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error reading directory: %s", err.Error()))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+
+		result := NewToolResultJSON(tree)
 		result.RequestID = request.RequestID
 		return result, nil
 	}
@@ -244,32 +1712,39 @@ func (p *FilesystemProvider) listDirectory(request CallToolRequest) (*CallToolRe
 	// Read the directory contents
 	entries, err := os.ReadDir(fullPath)
 	if err != nil {
-		result := NewToolResultError(fmt.Sprintf("Error reading directory: %s", err.Error()))
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error reading directory: %s", err.Error()))
 		result.RequestID = request.RequestID
 		return result, nil
 	}
 
+	// Drop the entries that do not match the filters, then sort them and select the requested page
+	entries = filterEntries(entries, pattern, entryType)
+	total := len(entries)
+	sortEntries(entries, order)
+	if pageToken != nil {
+		entries = entriesAfter(entries, pageToken)
+	}
+	entries, hasMore := paginateEntries(entries, offset, limit)
+
 	// Convert entries to FileInfo objects
 	files := make([]FileInfo, 0, len(entries))
 	for _, entry := range entries {
-		entryInfo, err := entry.Info()
+		fileInfo, err := entryFileInfo(filepath.Join(fullPath, entry.Name()), filepath.Join(pathParam, entry.Name()), entry)
 		if err != nil {
 			continue
 		}
-
-		files = append(files, FileInfo{
-			Name:    entry.Name(),
-			Path:    filepath.Join(pathParam, entry.Name()),
-			Size:    entryInfo.Size(),
-			IsDir:   entry.IsDir(),
-			ModTime: entryInfo.ModTime(),
-		})
+		files = append(files, fileInfo)
 	}
 
 	// Create the directory content object
 	dirContent := DirectoryContent{
-		Path:  pathParam,
-		Files: files,
+		Path:    pathParam,
+		Files:   files,
+		Total:   total,
+		HasMore: hasMore,
+	}
+	if hasMore && order.by == "name" {
+		dirContent.NextToken = encodeListPageToken(pathParam, order, entries[len(entries)-1])
 	}
 
 	// Return the result
@@ -278,26 +1753,66 @@ func (p *FilesystemProvider) listDirectory(request CallToolRequest) (*CallToolRe
 	return result, nil
 }
 
+// entryFileInfo describes a directory entry found at entryPath, whose path as
+// seen by the client is displayPath. Symbolic links are described themselves
+// rather than followed, so a link loop cannot stall a listing.
+func entryFileInfo(entryPath, displayPath string, entry os.DirEntry) (FileInfo, error) {
+	entryInfo, err := entry.Info()
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	fileInfo := FileInfo{
+		Name:    entry.Name(),
+		Path:    displayPath,
+		Size:    entryInfo.Size(),
+		IsDir:   entry.IsDir(),
+		ModTime: entryInfo.ModTime(),
+	}
+	if entry.Type()&os.ModeSymlink != 0 {
+		fileInfo.IsSymlink = true
+		if target, err := os.Readlink(entryPath); err == nil {
+			fileInfo.SymlinkTarget = target
+		}
+	}
+	return fileInfo, nil
+}
+
 // readFile reads the contents of a file
-func (p *FilesystemProvider) readFile(request CallToolRequest) (*CallToolResult, error) {
+func (p *FilesystemProvider) readFile(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
 	// Get the path parameter
 	pathParam, ok := request.Params.Arguments["path"].(string)
 	if !ok {
-		result := NewToolResultError("Path parameter is required and must be a string")
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
 		result.RequestID = request.RequestID
 		return result, nil
 	}
 
-	// Get the encoding parameter (default to text)
-	encoding := "text"
-	if encodingParam, ok := request.Params.Arguments["encoding"].(string); ok {
-		encoding = encodingParam
+	// Get the encoding parameter (default to the provider's default encoding)
+	encoding, err := parseEncoding(request.Params.Arguments, p.DefaultEncoding, readEncodings)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, argumentOf(err), err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the git_blob_hash parameter (default to false)
+	includeBlobHash := false
+	if blobHashParam, ok := request.Params.Arguments["git_blob_hash"].(bool); ok {
+		includeBlobHash = blobHashParam
 	}
 
 	// Sanitize and resolve the path
 	fullPath, err := p.resolvePath(pathParam)
 	if err != nil {
-		result := NewToolResultError(fmt.Sprintf("Invalid path: %s", err.Error()))
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Only touch files with an allowed extension
+	if !p.extensionAllowed(fullPath) {
+		result := NewToolResultErrorCode(ErrorCodeExtensionNotAllowed, "path", fmt.Sprintf("File extension is not allowed: %s", pathParam))
 		result.RequestID = request.RequestID
 		return result, nil
 	}
@@ -306,43 +1821,46 @@ func (p *FilesystemProvider) readFile(request CallToolRequest) (*CallToolResult,
 	info, err := os.Stat(fullPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			result := NewToolResultError(fmt.Sprintf("File not found: %s", pathParam))
+			result := NewToolResultErrorCode(ErrorCodeNotFound, "path", fmt.Sprintf("File not found: %s", pathParam))
 			result.RequestID = request.RequestID
 			return result, nil
 		}
-		result := NewToolResultError(fmt.Sprintf("Error accessing file: %s", err.Error()))
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing file: %s", err.Error()))
 		result.RequestID = request.RequestID
 		return result, nil
 	}
 
 	if info.IsDir() {
-		result := NewToolResultError(fmt.Sprintf("Path is a directory, not a file: %s", pathParam))
+		result := NewToolResultErrorCode(ErrorCodeIsDirectory, "path", fmt.Sprintf("Path is a directory, not a file: %s", pathParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	if p.MaxReadBytes > 0 && info.Size() > p.MaxReadBytes {
+		result := NewToolResultErrorCode(ErrorCodeFileTooLarge, "path", fmt.Sprintf("File is larger than the %d byte read limit: %s", p.MaxReadBytes, pathParam))
 		result.RequestID = request.RequestID
 		return result, nil
 	}
 
 	// Read the file contents
-	data, err := os.ReadFile(fullPath)
+	data, err := p.readFileCached(ctx, fullPath, info)
 	if err != nil {
-		result := NewToolResultError(fmt.Sprintf("Error reading file: %s", err.Error()))
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error reading file: %s", err.Error()))
 		result.RequestID = request.RequestID
 		return result, nil
 	}
 
 	// Create the file content object
-	var content string
-	isText := true
-	if encoding == "base64" {
-		content = base64.StdEncoding.EncodeToString(data)
-		isText = false
-	} else {
-		content = string(data)
-	}
-
+	mimeType := detectMimeType(fullPath, data)
 	fileContent := FileContent{
-		Path:    pathParam,
-		Content: content,
-		IsText:  isText,
+		Path:     pathParam,
+		MimeType: mimeType,
+		IsText:   isTextMimeType(mimeType),
+		ETag:     fileETag(info),
+	}
+	encodeFileContent(&fileContent, data, encoding)
+	if includeBlobHash {
+		fileContent.GitBlobHash = gitBlobHash(data)
 	}
 
 	// Return the result
@@ -352,11 +1870,11 @@ func (p *FilesystemProvider) readFile(request CallToolRequest) (*CallToolResult,
 }
 
 // writeFile writes content to a file
-func (p *FilesystemProvider) writeFile(request CallToolRequest) (*CallToolResult, error) {
+func (p *FilesystemProvider) writeFile(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
 	// Get the path parameter
 	pathParam, ok := request.Params.Arguments["path"].(string)
 	if !ok {
-		result := NewToolResultError("Path parameter is required and must be a string")
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
 		result.RequestID = request.RequestID
 		return result, nil
 	}
@@ -364,39 +1882,108 @@ func (p *FilesystemProvider) writeFile(request CallToolRequest) (*CallToolResult
 	// Get the content parameter
 	contentParam, ok := request.Params.Arguments["content"].(string)
 	if !ok {
-		result := NewToolResultError("Content parameter is required and must be a string")
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "content", "Content parameter is required and must be a string")
 		result.RequestID = request.RequestID
 		return result, nil
 	}
 
 	// Get the encoding parameter (default to text)
-	encoding := "text"
-	if encodingParam, ok := request.Params.Arguments["encoding"].(string); ok {
-		encoding = encodingParam
+	encoding, err := parseEncoding(request.Params.Arguments, EncodingText, writeEncodings)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, argumentOf(err), err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the if_match parameter
+	ifMatch, _ := request.Params.Arguments["if_match"].(string)
+
+	// Get the atomic parameter (default to true)
+	atomic := true
+	if atomicParam, ok := request.Params.Arguments["atomic"].(bool); ok {
+		atomic = atomicParam
+	}
+
+	// Get the mode and dir_mode parameters (default to keeping the mode of
+	// an existing file, 0644 for new files and 0755 for new directories)
+	var mode os.FileMode
+	modeGiven := false
+	if modeParam, ok := request.Params.Arguments["mode"].(string); ok {
+		parsed, err := parsePermission(modeParam)
+		if err != nil {
+			result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "mode", err.Error())
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		mode, modeGiven = parsed, true
+	}
+	dirMode := os.FileMode(0755)
+	if dirModeParam, ok := request.Params.Arguments["dir_mode"].(string); ok {
+		parsed, err := parsePermission(dirModeParam)
+		if err != nil {
+			result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "dir_mode", err.Error())
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		dirMode = parsed
 	}
 
 	// Sanitize and resolve the path
 	fullPath, err := p.resolvePath(pathParam)
 	if err != nil {
-		result := NewToolResultError(fmt.Sprintf("Invalid path: %s", err.Error()))
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
 		result.RequestID = request.RequestID
 		return result, nil
 	}
 
+	// Keep concurrent calls from modifying the file at the same time
+	unlock := p.pathLocks.lock(fullPath)
+	defer unlock()
+
+	// Only touch files with an allowed extension
+	if result := p.checkWrite(fullPath, pathParam, "path", -1); result != nil {
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Refuse to overwrite changes made since the client read the file.
+	// "*" means the client expects the file not to exist yet.
+	if ifMatch != "" {
+		info, err := os.Stat(fullPath)
+		switch {
+		case err == nil:
+			if ifMatch != fileETag(info) {
+				result := NewToolResultErrorCode(ErrorCodeConflict, "if_match", fmt.Sprintf("File changed since it was read: %s", pathParam))
+				result.RequestID = request.RequestID
+				return result, nil
+			}
+		case os.IsNotExist(err):
+			if ifMatch != "*" {
+				result := NewToolResultErrorCode(ErrorCodeConflict, "if_match", fmt.Sprintf("File no longer exists: %s", pathParam))
+				result.RequestID = request.RequestID
+				return result, nil
+			}
+		default:
+			result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing file: %s", err.Error()))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+	}
+
 	// Create the parent directory if it doesn't exist
 	parentDir := filepath.Dir(fullPath)
-	if err := os.MkdirAll(parentDir, 0755); err != nil {
-		result := NewToolResultError(fmt.Sprintf("Error creating directory: %s", err.Error()))
+	if err := mkdirAllMode(parentDir, dirMode); err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error creating directory: %s", err.Error()))
 		result.RequestID = request.RequestID
 		return result, nil
 	}
 
 	// Decode the content if necessary
 	var data []byte
-	if encoding == "base64" {
+	if encoding == EncodingBase64 {
 		data, err = base64.StdEncoding.DecodeString(contentParam)
 		if err != nil {
-			result := NewToolResultError(fmt.Sprintf("Error decoding base64 content: %s", err.Error()))
+			result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "content", fmt.Sprintf("Error decoding base64 content: %s", err.Error()))
 			result.RequestID = request.RequestID
 			return result, nil
 		}
@@ -404,25 +1991,48 @@ func (p *FilesystemProvider) writeFile(request CallToolRequest) (*CallToolResult
 		data = []byte(contentParam)
 	}
 
-	// Write the file
-	if err := os.WriteFile(fullPath, data, 0644); err != nil {
-		result := NewToolResultError(fmt.Sprintf("Error writing file: %s", err.Error()))
+	if result := p.checkWrite(fullPath, pathParam, "content", int64(len(data))); result != nil {
 		result.RequestID = request.RequestID
 		return result, nil
 	}
 
-	// Return success
-	result := NewToolResultText(fmt.Sprintf("File written successfully: %s", pathParam))
+	// Keep the permissions of a file being overwritten unless a mode is given
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(fullPath); err == nil {
+		perm = info.Mode().Perm()
+	}
+	if modeGiven {
+		perm = mode
+	}
+
+	// Write the file, by default through a temporary file renamed into
+	// place so a failed write never leaves a truncated file
+	write := writeFileAtomic
+	if !atomic {
+		write = writeFileInPlace
+	}
+	if err := write(fullPath, data, perm); err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error writing file: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Return the new version of the file
+	writeResult := WriteResult{Path: pathParam}
+	if info, err := os.Stat(fullPath); err == nil {
+		writeResult.ETag = fileETag(info)
+	}
+	result := NewToolResultJSON(writeResult)
 	result.RequestID = request.RequestID
 	return result, nil
 }
 
 // deleteFile deletes a file or directory
-func (p *FilesystemProvider) deleteFile(request CallToolRequest) (*CallToolResult, error) {
+func (p *FilesystemProvider) deleteFile(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
 	// Get the path parameter
 	pathParam, ok := request.Params.Arguments["path"].(string)
 	if !ok {
-		result := NewToolResultError("Path parameter is required and must be a string")
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
 		result.RequestID = request.RequestID
 		return result, nil
 	}
@@ -433,23 +2043,91 @@ func (p *FilesystemProvider) deleteFile(request CallToolRequest) (*CallToolResul
 		recursive = recursiveParam
 	}
 
+	// Get the dry_run parameter (default to false)
+	dryRun := false
+	if dryRunParam, ok := request.Params.Arguments["dry_run"].(bool); ok {
+		dryRun = dryRunParam
+	}
+
 	// Sanitize and resolve the path
 	fullPath, err := p.resolvePath(pathParam)
 	if err != nil {
-		result := NewToolResultError(fmt.Sprintf("Invalid path: %s", err.Error()))
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Never delete the root or a protected path
+	if p.isProtected(fullPath) {
+		result := NewToolResultErrorCode(ErrorCodeProtectedPath, "path", fmt.Sprintf("Path is protected and cannot be deleted: %s", pathParam))
 		result.RequestID = request.RequestID
 		return result, nil
 	}
 
+	// Keep concurrent calls from modifying the file at the same time
+	unlock := p.pathLocks.lock(fullPath)
+	defer unlock()
+
 	// Check if the path exists
 	info, err := os.Stat(fullPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			result := NewToolResultError(fmt.Sprintf("File or directory not found: %s", pathParam))
+			result := NewToolResultErrorCode(ErrorCodeNotFound, "path", fmt.Sprintf("File or directory not found: %s", pathParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Only delete non-empty directories when asked to
+	if info.IsDir() && !recursive {
+		entries, err := os.ReadDir(fullPath)
+		if err != nil {
+			result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error reading directory: %s", err.Error()))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		if len(entries) > 0 {
+			result := NewToolResultErrorCode(ErrorCodeConflict, "path", fmt.Sprintf("Directory is not empty: %s. Use recursive=true to delete non-empty directories", pathParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+	}
+
+	// Report what would be deleted without touching anything
+	if dryRun {
+		paths, err := affectedPaths(ctx, fullPath, pathParam)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error reading directory: %s", err.Error()))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+
+		result := NewToolResultJSON(DryRunResult{
+			DryRun:    true,
+			Operation: "delete",
+			Path:      pathParam,
+			Paths:     paths,
+		})
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Move the file or directory to the trash if there is one
+	if p.TrashDir != "" {
+		trashed, err := p.moveToTrash(fullPath, pathParam)
+		if err != nil {
+			result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error moving to trash: %s", err.Error()))
 			result.RequestID = request.RequestID
 			return result, nil
 		}
-		result := NewToolResultError(fmt.Sprintf("Error accessing path: %s", err.Error()))
+
+		result := NewToolResultJSON(trashed)
 		result.RequestID = request.RequestID
 		return result, nil
 	}
@@ -458,33 +2136,20 @@ func (p *FilesystemProvider) deleteFile(request CallToolRequest) (*CallToolResul
 	if info.IsDir() {
 		if recursive {
 			if err := os.RemoveAll(fullPath); err != nil {
-				result := NewToolResultError(fmt.Sprintf("Error deleting directory: %s", err.Error()))
+				result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error deleting directory: %s", err.Error()))
 				result.RequestID = request.RequestID
 				return result, nil
 			}
 		} else {
-			// Check if the directory is empty
-			entries, err := os.ReadDir(fullPath)
-			if err != nil {
-				result := NewToolResultError(fmt.Sprintf("Error reading directory: %s", err.Error()))
-				result.RequestID = request.RequestID
-				return result, nil
-			}
-			if len(entries) > 0 {
-				result := NewToolResultError(fmt.Sprintf("Directory is not empty: %s. Use recursive=true to delete non-empty directories", pathParam))
-				result.RequestID = request.RequestID
-				return result, nil
-			}
-
 			if err := os.Remove(fullPath); err != nil {
-				result := NewToolResultError(fmt.Sprintf("Error deleting directory: %s", err.Error()))
+				result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error deleting directory: %s", err.Error()))
 				result.RequestID = request.RequestID
 				return result, nil
 			}
 		}
 	} else {
 		if err := os.Remove(fullPath); err != nil {
-			result := NewToolResultError(fmt.Sprintf("Error deleting file: %s", err.Error()))
+			result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error deleting file: %s", err.Error()))
 			result.RequestID = request.RequestID
 			return result, nil
 		}
@@ -497,25 +2162,40 @@ func (p *FilesystemProvider) deleteFile(request CallToolRequest) (*CallToolResul
 }
 
 // loadFile loads a file resource
-func (p *FilesystemProvider) loadFile(request LoadResourceRequest) (*LoadResourceResult, error) {
-	// Get the path parameter
-	pathParam, ok := request.Params["path"].(string)
-	if !ok {
-		result := NewResourceResultError("Path parameter is required and must be a string")
+func (p *FilesystemProvider) loadFile(ctx context.Context, request LoadResourceRequest) (*LoadResourceResult, error) {
+	// Get the path parameter, or the file URI standing in for it
+	pathParam, err := p.resourcePath(request.Params)
+	if err != nil {
+		result := NewResourceResultErrorCode(ErrorCodeInvalidArgument, argumentOf(err), err.Error())
 		result.RequestID = request.RequestID
 		return result, nil
 	}
 
-	// Get the encoding parameter (default to text)
-	encoding := "text"
-	if encodingParam, ok := request.Params["encoding"].(string); ok {
-		encoding = encodingParam
+	// Get the encoding parameter (default to the provider's default encoding)
+	encoding, err := parseEncoding(request.Params, p.DefaultEncoding, readEncodings)
+	if err != nil {
+		result := NewResourceResultErrorCode(ErrorCodeInvalidArgument, argumentOf(err), err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
 	}
 
+	// Get the if_none_match parameter
+	ifNoneMatch, _ := request.Params["if_none_match"].(string)
+
+	// Get the raw parameter (default to false)
+	raw, _ := request.Params["raw"].(bool)
+
 	// Sanitize and resolve the path
 	fullPath, err := p.resolvePath(pathParam)
 	if err != nil {
-		result := NewResourceResultError(fmt.Sprintf("Invalid path: %s", err.Error()))
+		result := NewResourceResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Only touch files with an allowed extension
+	if !p.extensionAllowed(fullPath) {
+		result := NewResourceResultErrorCode(ErrorCodeExtensionNotAllowed, "path", fmt.Sprintf("File extension is not allowed: %s", pathParam))
 		result.RequestID = request.RequestID
 		return result, nil
 	}
@@ -524,57 +2204,78 @@ func (p *FilesystemProvider) loadFile(request LoadResourceRequest) (*LoadResourc
 	info, err := os.Stat(fullPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			result := NewResourceResultError(fmt.Sprintf("File not found: %s", pathParam))
+			result := NewResourceResultErrorCode(ErrorCodeNotFound, "path", fmt.Sprintf("File not found: %s", pathParam))
 			result.RequestID = request.RequestID
 			return result, nil
 		}
-		result := NewResourceResultError(fmt.Sprintf("Error accessing file: %s", err.Error()))
+		result := NewResourceResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing file: %s", err.Error()))
 		result.RequestID = request.RequestID
 		return result, nil
 	}
 
 	if info.IsDir() {
-		result := NewResourceResultError(fmt.Sprintf("Path is a directory, not a file: %s", pathParam))
+		result := NewResourceResultErrorCode(ErrorCodeIsDirectory, "path", fmt.Sprintf("Path is a directory, not a file: %s", pathParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Skip the content if the client already has the current version
+	etag := fileETag(info)
+	if ifNoneMatch != "" && ifNoneMatch == etag {
+		return &LoadResourceResult{
+			RequestID: request.RequestID,
+			Status:    "not_modified",
+			ETag:      etag,
+		}, nil
+	}
+
+	if p.MaxReadBytes > 0 && info.Size() > p.MaxReadBytes {
+		result := NewResourceResultErrorCode(ErrorCodeFileTooLarge, "path", fmt.Sprintf("File is larger than the %d byte read limit: %s", p.MaxReadBytes, pathParam))
 		result.RequestID = request.RequestID
 		return result, nil
 	}
 
 	// Read the file contents
-	data, err := os.ReadFile(fullPath)
+	data, err := p.readFileCached(ctx, fullPath, info)
 	if err != nil {
-		result := NewResourceResultError(fmt.Sprintf("Error reading file: %s", err.Error()))
+		result := NewResourceResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error reading file: %s", err.Error()))
 		result.RequestID = request.RequestID
 		return result, nil
 	}
 
 	// Create the file content object
-	var content string
-	isText := true
-	if encoding == "base64" {
-		content = base64.StdEncoding.EncodeToString(data)
-		isText = false
-	} else {
-		content = string(data)
-	}
-
+	mimeType := detectMimeType(fullPath, data)
 	fileContent := FileContent{
-		Path:    pathParam,
-		Content: content,
-		IsText:  isText,
+		Path:     pathParam,
+		MimeType: mimeType,
+		IsText:   isTextMimeType(mimeType),
 	}
+	encodeFileContent(&fileContent, data, encoding)
 
 	// Return the result
 	result := NewResourceResultJSON(fileContent)
 	result.RequestID = request.RequestID
+	result.ETag = etag
+	if raw {
+		result.Raw = &RawContent{ContentType: mimeType, Data: data}
+	}
 	return result, nil
 }
 
 // loadDirectory loads a directory resource
-func (p *FilesystemProvider) loadDirectory(request LoadResourceRequest) (*LoadResourceResult, error) {
-	// Get the path parameter
-	pathParam, ok := request.Params["path"].(string)
-	if !ok {
-		result := NewResourceResultError("Path parameter is required and must be a string")
+func (p *FilesystemProvider) loadDirectory(ctx context.Context, request LoadResourceRequest) (*LoadResourceResult, error) {
+	// Get the path parameter, or the file URI standing in for it
+	pathParam, err := p.resourcePath(request.Params)
+	if err != nil {
+		result := NewResourceResultErrorCode(ErrorCodeInvalidArgument, argumentOf(err), err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the offset and limit parameters
+	offset, limit, err := parsePagination(request.Params)
+	if err != nil {
+		result := NewResourceResultErrorCode(ErrorCodeInvalidArgument, argumentOf(err), err.Error())
 		result.RequestID = request.RequestID
 		return result, nil
 	}
@@ -582,7 +2283,7 @@ func (p *FilesystemProvider) loadDirectory(request LoadResourceRequest) (*LoadRe
 	// Sanitize and resolve the path
 	fullPath, err := p.resolvePath(pathParam)
 	if err != nil {
-		result := NewResourceResultError(fmt.Sprintf("Invalid path: %s", err.Error()))
+		result := NewResourceResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
 		result.RequestID = request.RequestID
 		return result, nil
 	}
@@ -591,17 +2292,17 @@ func (p *FilesystemProvider) loadDirectory(request LoadResourceRequest) (*LoadRe
 	info, err := os.Stat(fullPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			result := NewResourceResultError(fmt.Sprintf("Directory not found: %s", pathParam))
+			result := NewResourceResultErrorCode(ErrorCodeNotFound, "path", fmt.Sprintf("Directory not found: %s", pathParam))
 			result.RequestID = request.RequestID
 			return result, nil
 		}
-		result := NewResourceResultError(fmt.Sprintf("Error accessing directory: %s", err.Error()))
+		result := NewResourceResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing directory: %s", err.Error()))
 		result.RequestID = request.RequestID
 		return result, nil
 	}
 
 	if !info.IsDir() {
-		result := NewResourceResultError(fmt.Sprintf("Path is not a directory: %s", pathParam))
+		result := NewResourceResultErrorCode(ErrorCodeNotDirectory, "path", fmt.Sprintf("Path is not a directory: %s", pathParam))
 		result.RequestID = request.RequestID
 		return result, nil
 	}
@@ -609,32 +2310,32 @@ func (p *FilesystemProvider) loadDirectory(request LoadResourceRequest) (*LoadRe
 	// Read the directory contents
 	entries, err := os.ReadDir(fullPath)
 	if err != nil {
-		result := NewResourceResultError(fmt.Sprintf("Error reading directory: %s", err.Error()))
+		result := NewResourceResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error reading directory: %s", err.Error()))
 		result.RequestID = request.RequestID
 		return result, nil
 	}
 
+	// Sort the entries and select the requested page
+	total := len(entries)
+	sortEntries(entries, directorySort{by: "name"})
+	entries, hasMore := paginateEntries(entries, offset, limit)
+
 	// Convert entries to FileInfo objects
 	files := make([]FileInfo, 0, len(entries))
 	for _, entry := range entries {
-		entryInfo, err := entry.Info()
+		fileInfo, err := entryFileInfo(filepath.Join(fullPath, entry.Name()), filepath.Join(pathParam, entry.Name()), entry)
 		if err != nil {
 			continue
 		}
-
-		files = append(files, FileInfo{
-			Name:    entry.Name(),
-			Path:    filepath.Join(pathParam, entry.Name()),
-			Size:    entryInfo.Size(),
-			IsDir:   entry.IsDir(),
-			ModTime: entryInfo.ModTime(),
-		})
+		files = append(files, fileInfo)
 	}
 
 	// Create the directory content object
 	dirContent := DirectoryContent{
-		Path:  pathParam,
-		Files: files,
+		Path:    pathParam,
+		Files:   files,
+		Total:   total,
+		HasMore: hasMore,
 	}
 
 	// Return the result
@@ -643,42 +2344,155 @@ func (p *FilesystemProvider) loadDirectory(request LoadResourceRequest) (*LoadRe
 	return result, nil
 }
 
-// resolvePath resolves and sanitizes a path
+// parsePagination reads the offset and limit arguments of a directory listing.
+// A limit of zero means no limit.
+func parsePagination(arguments map[string]interface{}) (offset, limit int, err error) {
+	if offsetParam, exists := arguments["offset"]; exists {
+		value, ok := offsetParam.(float64)
+		if !ok || value != float64(int(value)) {
+			return 0, 0, newArgumentError("offset", "offset parameter must be an integer")
+		}
+		offset = max(int(value), 0)
+	}
+	if limitParam, exists := arguments["limit"]; exists {
+		value, ok := limitParam.(float64)
+		if !ok || value != float64(int(value)) || value < 0 {
+			return 0, 0, newArgumentError("limit", "limit parameter must be a non-negative integer")
+		}
+		limit = int(value)
+	}
+	return offset, limit, nil
+}
+
+// paginateEntries returns the page of sorted directory entries starting at
+// offset, along with whether more entries follow it. Offsets past the end
+// yield an empty page.
+func paginateEntries(entries []os.DirEntry, offset, limit int) ([]os.DirEntry, bool) {
+	if offset >= len(entries) {
+		return nil, false
+	}
+	entries = entries[offset:]
+	if limit > 0 && len(entries) > limit {
+		return entries[:limit], true
+	}
+	return entries, false
+}
+
+// extensionAllowed reports whether a file may be read or written given the
+// allowed extensions. Extensions may be listed with or without the leading dot.
+func (p *FilesystemProvider) extensionAllowed(path string) bool {
+	if len(p.AllowedExtensions) == 0 {
+		return true
+	}
+
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if ext == "" {
+		return false
+	}
+	for _, allowed := range p.AllowedExtensions {
+		if strings.EqualFold(strings.TrimPrefix(allowed, "."), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath resolves and sanitizes a path. Relative paths are resolved against
+// the root directory; absolute paths are only accepted when they already lie
+// within it. Any path that ends up outside of the root is rejected.
 func (p *FilesystemProvider) resolvePath(path string) (string, error) {
-	// If the path is absolute, use it directly
-	if filepath.IsAbs(path) {
-		return path, nil
+	// Select the root directory, which depends on the mount when there are any
+	rootDir := p.rootDir
+	mounted := len(p.mounts) > 0
+	if mounted {
+		name, rel, ok := strings.Cut(path, ":")
+		if !ok {
+			return "", fmt.Errorf("path must be addressed as mount:path (mounts: %s)", strings.Join(p.mountNames(), ", "))
+		}
+		if rootDir, ok = p.mounts[name]; !ok {
+			return "", fmt.Errorf("unknown mount: %s", name)
+		}
+		path = rel
 	}
 
-	// Clean the path to remove any ".." or "." components
-	cleanPath := filepath.Clean(path)
+	// Resolve the root directory
+	rootAbs, err := filepath.Abs(rootDir)
+	if err != nil {
+		return "", err
+	}
 
-	// Ensure the path doesn't try to escape the root directory
-	if strings.HasPrefix(cleanPath, "..") || strings.Contains(cleanPath, "/../") {
-		return "", errors.New("path attempts to access parent directory outside of root")
+	// Resolve the full path, cleaning any ".." or "." components. Paths within
+	// a mount are always relative to its root.
+	var absPath string
+	if filepath.IsAbs(path) && !mounted {
+		absPath = filepath.Clean(path)
+	} else {
+		absPath = filepath.Join(rootAbs, path)
 	}
 
-	// Resolve the full path
-	fullPath := filepath.Join(p.rootDir, cleanPath)
+	// Ensure the path is within the root directory
+	if !isWithinDir(rootAbs, absPath) {
+		return "", errors.New("path is outside of root directory")
+	}
 
-	// Convert to absolute path
-	absPath, err := filepath.Abs(fullPath)
+	// Resolve symlinks and ensure the real path is still within the root
+	realRoot, err := resolveSymlinks(rootAbs)
+	if err != nil {
+		return "", err
+	}
+	realPath, err := resolveSymlinks(absPath)
 	if err != nil {
 		return "", err
 	}
+	if !isWithinDir(realRoot, realPath) {
+		return "", errors.New("path is outside of root directory")
+	}
+
+	return absPath, nil
+}
+
+// resolveSymlinks evaluates the symlinks in a path that may not fully exist yet.
+// The deepest existing ancestor is evaluated and the remaining components are
+// appended to it. Dangling symlinks are followed to their target, so a write
+// through one cannot land outside of the evaluated location.
+func resolveSymlinks(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return resolved, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
 
-	// If we're using a relative root directory, don't enforce the prefix check
-	if filepath.IsAbs(p.rootDir) {
-		// Ensure the path is within the root directory
-		rootAbs, err := filepath.Abs(p.rootDir)
+	// Follow a dangling symlink to wherever it points
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
 		if err != nil {
 			return "", err
 		}
-
-		if !strings.HasPrefix(absPath, rootAbs) {
-			return "", errors.New("path is outside of root directory")
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
 		}
+		return resolveSymlinks(target)
 	}
 
-	return absPath, nil
+	// Otherwise evaluate the parent directory instead
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	resolvedParent, err := resolveSymlinks(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(resolvedParent, filepath.Base(path)), nil
+}
+
+// isWithinDir reports whether path is dir itself or lies beneath it
+func isWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
 }
@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"net/url"
+	"strings"
+)
+
+// resourceTemplates returns the URI templates of the provider's resources.
+// Without mounts a file URI carries the absolute path of the file, as on the
+// local machine; with mounts its host names the mount and its path is
+// relative to the mount's root.
+func (p *FilesystemProvider) resourceTemplates() []ResourceTemplate {
+	uriTemplate := "file:///{+path}"
+	description := "A file in the filesystem, addressed by its absolute path"
+	if len(p.mounts) > 0 {
+		uriTemplate = "file://{mount}/{+path}"
+		description = "A file in the filesystem, addressed by its mount and its path within the mount"
+	}
+	return []ResourceTemplate{
+		{
+			URITemplate: uriTemplate,
+			Name:        "File",
+			Description: description,
+			ResourceID:  "filesystem.file",
+		},
+	}
+}
+
+// resourcePath reads the path a resource is loaded from, given either as the
+// path parameter or as a file URI in the uri parameter
+func (p *FilesystemProvider) resourcePath(params map[string]interface{}) (string, error) {
+	if pathParam, ok := params["path"].(string); ok {
+		return pathParam, nil
+	}
+	uriParam, ok := params["uri"].(string)
+	if !ok {
+		return "", newArgumentError("path", "Path parameter is required and must be a string")
+	}
+	return p.pathFromURI(uriParam)
+}
+
+// pathFromURI converts a file URI to the path it addresses. The path is only
+// converted, not resolved, so it is held to the root like any other.
+func (p *FilesystemProvider) pathFromURI(uri string) (string, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", newArgumentError("uri", "invalid URI: %s", err.Error())
+	}
+	if !strings.EqualFold(parsed.Scheme, "file") {
+		return "", newArgumentError("uri", "URI must use the file scheme: %s", uri)
+	}
+	if parsed.Opaque != "" || parsed.Path == "" {
+		return "", newArgumentError("uri", "URI must have an absolute path, as in file:///path: %s", uri)
+	}
+
+	if len(p.mounts) > 0 {
+		if parsed.Host == "" {
+			return "", newArgumentError("uri", "URI must name a mount as its host, as in file://mount/path (mounts: %s)", strings.Join(p.mountNames(), ", "))
+		}
+		return parsed.Host + ":" + strings.TrimPrefix(parsed.Path, "/"), nil
+	}
+	if parsed.Host != "" && parsed.Host != "localhost" {
+		return "", newArgumentError("uri", "URI must not name a remote host: %s", parsed.Host)
+	}
+	return parsed.Path, nil
+}
@@ -0,0 +1,127 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ToolHandler implements a tool registered with a FunctionProvider
+type ToolHandler func(ctx context.Context, request CallToolRequest) (*CallToolResult, error)
+
+// FunctionProvider is a provider whose tools are plain functions registered
+// with RegisterTool, for adding tools without implementing Provider. It has
+// no resources.
+type FunctionProvider struct {
+	NoHealthCheck
+
+	name        string
+	description string
+
+	// mu guards tools and order, so tools can be registered while the
+	// provider serves requests
+	mu    sync.RWMutex
+	tools map[string]functionTool
+	order []string
+}
+
+// functionTool is a tool registered with a FunctionProvider
+type functionTool struct {
+	info    ToolInfo
+	handler ToolHandler
+}
+
+// NewFunctionProvider creates a provider without any tools
+func NewFunctionProvider(name, description string) *FunctionProvider {
+	return &FunctionProvider{
+		name:        name,
+		description: description,
+		tools:       make(map[string]functionTool),
+	}
+}
+
+// RegisterTool adds a tool with the given ID, of the form provider.name,
+// described by info and implemented by handler. The ID replaces info.ID. It
+// fails if the ID is malformed, names another provider or is already taken.
+func (p *FunctionProvider) RegisterTool(id string, info ToolInfo, handler ToolHandler) error {
+	providerName, toolName, ok := strings.Cut(id, ".")
+	if !ok || toolName == "" {
+		return fmt.Errorf("invalid tool ID %q: expected %s.name", id, p.name)
+	}
+	if providerName != p.name {
+		return fmt.Errorf("tool ID %q does not belong to provider %s", id, p.name)
+	}
+	if handler == nil {
+		return errors.New("tool handler must not be nil")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, exists := p.tools[toolName]; exists {
+		return fmt.Errorf("tool %s is already registered", id)
+	}
+	info.ID = id
+	if info.Name == "" {
+		info.Name = toolName
+	}
+	p.tools[toolName] = functionTool{info: info, handler: handler}
+	p.order = append(p.order, toolName)
+	return nil
+}
+
+// GetName returns the name of the provider
+func (p *FunctionProvider) GetName() string {
+	return p.name
+}
+
+// GetInfo lists the registered tools in the order they were registered
+func (p *FunctionProvider) GetInfo() ProviderInfo {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	tools := make([]ToolInfo, 0, len(p.order))
+	for _, toolName := range p.order {
+		tools = append(tools, p.tools[toolName].info)
+	}
+	return ProviderInfo{
+		Name:        p.name,
+		Description: p.description,
+		Tools:       tools,
+		Resources:   []ResourceInfo{},
+	}
+}
+
+// CallTool calls the handler registered for the tool
+func (p *FunctionProvider) CallTool(ctx context.Context, toolName string, request CallToolRequest) (*CallToolResult, error) {
+	p.mu.RLock()
+	tool, exists := p.tools[toolName]
+	p.mu.RUnlock()
+
+	if !exists {
+		result := NewToolResultErrorCode(ErrorCodeUnknownTool, "", fmt.Sprintf("Unknown tool: %s", toolName))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	result, err := tool.handler(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("tool %s returned no result", tool.info.ID)
+	}
+	if result.RequestID == "" {
+		result.RequestID = request.RequestID
+	}
+	return result, nil
+}
+
+// LoadResource fails for every resource, since the provider has none
+func (p *FunctionProvider) LoadResource(ctx context.Context, resourceName string, request LoadResourceRequest) (*LoadResourceResult, error) {
+	result := NewResourceResultErrorCode(ErrorCodeUnknownResource, "", fmt.Sprintf("Unknown resource: %s", resourceName))
+	result.RequestID = request.RequestID
+	return result, nil
+}
@@ -0,0 +1,223 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+const (
+	// binarySniffSize is how much of a file is checked for NUL bytes to detect binary content
+	binarySniffSize = 8 * 1024
+
+	// maxGrepLineSize is the longest line grep will scan
+	maxGrepLineSize = 1024 * 1024
+)
+
+// errMaxMatches stops a search once the requested number of matches was found
+var errMaxMatches = errors.New("maximum number of matches reached")
+
+// grepFiles searches file contents for a string or regular expression.
+// If emit is not nil, the matches of every file are also emitted as soon as the file was searched.
+func (p *FilesystemProvider) grepFiles(ctx context.Context, request CallToolRequest, emit EmitFunc) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the query parameter
+	query, ok := request.Params.Arguments["query"].(string)
+	if !ok || query == "" {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "query", "Query parameter is required and must be a non-empty string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the matching options (default to a case-sensitive literal match)
+	useRegex := false
+	if regexParam, ok := request.Params.Arguments["regex"].(bool); ok {
+		useRegex = regexParam
+	}
+	caseInsensitive := false
+	if caseParam, ok := request.Params.Arguments["case_insensitive"].(bool); ok {
+		caseInsensitive = caseParam
+	}
+	maxMatches := 0
+	if maxMatchesParam, ok := request.Params.Arguments["max_matches"].(float64); ok {
+		maxMatches = int(maxMatchesParam)
+	}
+
+	// Get the on_permission_error parameter
+	policy, err := parsePermissionErrorPolicy(request.Params.Arguments)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, argumentOf(err), err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Build the matcher
+	expr := query
+	if !useRegex {
+		expr = regexp.QuoteMeta(query)
+	}
+	if caseInsensitive {
+		expr = "(?i)" + expr
+	}
+	matcher, err := regexp.Compile(expr)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "query", fmt.Sprintf("Invalid regular expression: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Check if the path exists
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			result := NewToolResultErrorCode(ErrorCodeNotFound, "path", fmt.Sprintf("File or directory not found: %s", pathParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	grepResult := GrepResult{
+		Path:  pathParam,
+		Query: query,
+		Files: make([]GrepFileMatches, 0),
+	}
+	totalMatches := 0
+
+	// searchFile greps a single file, appending any matches to the result
+	searchFile := func(filePath, displayPath string) error {
+		remaining := -1
+		if maxMatches > 0 {
+			remaining = maxMatches - totalMatches
+		}
+
+		matches, truncated, err := grepFile(ctx, filePath, matcher, remaining)
+		if err != nil {
+			return err
+		}
+		if len(matches) > 0 {
+			fileMatches := GrepFileMatches{
+				Path:    displayPath,
+				Matches: matches,
+			}
+			grepResult.Files = append(grepResult.Files, fileMatches)
+			totalMatches += len(matches)
+			if emit != nil {
+				if err := emit(fileMatches); err != nil {
+					return err
+				}
+			}
+		}
+		if truncated {
+			grepResult.Truncated = true
+			return errMaxMatches
+		}
+		return nil
+	}
+
+	if !info.IsDir() {
+		err = searchFile(fullPath, pathParam)
+	} else {
+		var inaccessible []string
+		inaccessible, err = walkTree(ctx, fullPath, pathParam, policy, func(entryPath, displayPath string, entry fs.DirEntry) error {
+			// Only search regular files, so symlinks can't lead outside the root
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+
+			err := searchFile(entryPath, displayPath)
+			if errors.Is(err, errMaxMatches) {
+				return filepath.SkipAll
+			}
+			if errors.Is(err, fs.ErrPermission) {
+				switch policy {
+				case PermissionErrorFail:
+					return fmt.Errorf("%w: %s", fs.ErrPermission, displayPath)
+				case PermissionErrorReport:
+					grepResult.Inaccessible = append(grepResult.Inaccessible, displayPath)
+				}
+				return nil
+			}
+			return err
+		})
+		grepResult.Inaccessible = append(grepResult.Inaccessible, inaccessible...)
+	}
+	if err != nil && !errors.Is(err, errMaxMatches) {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error searching files: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Return the result
+	result := NewToolResultJSON(grepResult)
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// grepFile scans a file line by line for matches. Binary files are skipped.
+// If limit is not negative, at most limit matches are returned and truncated
+// reports whether more matches were found.
+func grepFile(ctx context.Context, path string, matcher *regexp.Regexp, limit int) (matches []GrepMatch, truncated bool, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	// Skip binary files
+	reader := bufio.NewReaderSize(newContextReader(ctx, file), binarySniffSize)
+	head, err := reader.Peek(binarySniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, false, err
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil, false, nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxGrepLineSize)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if !matcher.Match(scanner.Bytes()) {
+			continue
+		}
+		if limit >= 0 && len(matches) >= limit {
+			return matches, true, nil
+		}
+		matches = append(matches, GrepMatch{
+			Line: lineNumber,
+			Text: scanner.Text(),
+		})
+	}
+
+	// Lines that are too long are not searched
+	if err := scanner.Err(); err != nil && err != bufio.ErrTooLong {
+		return matches, false, err
+	}
+	return matches, false, nil
+}
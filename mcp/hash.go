@@ -0,0 +1,114 @@
+package mcp
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+)
+
+// hashAlgorithms lists the supported hash algorithms
+var hashAlgorithms = []string{"sha256", "sha512", "sha1", "md5"}
+
+// newHasher returns a hash for the named algorithm
+func newHasher(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "md5":
+		return md5.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm: %s", algorithm)
+	}
+}
+
+// hashFile streams a file through the hasher and returns the hex digest and the number of bytes read
+func hashFile(ctx context.Context, path string, hasher hash.Hash) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	size, err := io.Copy(hasher, newContextReader(ctx, file))
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), size, nil
+}
+
+// gitBlobHash computes the object ID git assigns to a blob with the given content
+func gitBlobHash(data []byte) string {
+	hasher := sha1.New()
+	hasher.Write([]byte("blob " + strconv.Itoa(len(data)) + "\x00"))
+	hasher.Write(data)
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// hashPath computes the digest of a file
+func (p *FilesystemProvider) hashPath(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the algorithm parameter (default to sha256)
+	algorithm := "sha256"
+	if algorithmParam, ok := request.Params.Arguments["algorithm"].(string); ok {
+		algorithm = algorithmParam
+	}
+	hasher, err := newHasher(algorithm)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "algorithm", err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Check if the path exists and is a file
+	if _, errResult := statRegularFile(fullPath, pathParam, "path"); errResult != nil {
+		errResult.RequestID = request.RequestID
+		return errResult, nil
+	}
+
+	// Stream the file through the hash
+	digest, size, err := hashFile(ctx, fullPath, hasher)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error reading file: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	fileHash := FileHash{
+		Path:      pathParam,
+		Algorithm: algorithm,
+		Hash:      digest,
+		Size:      size,
+	}
+
+	// Return the result
+	result := NewToolResultJSON(fileHash)
+	result.RequestID = request.RequestID
+	return result, nil
+}
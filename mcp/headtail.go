@@ -0,0 +1,204 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DefaultPreviewLines is the number of lines head and tail return by default
+const DefaultPreviewLines = 10
+
+// tailChunkSize is how many bytes tail reads at a time going backwards from the end
+const tailChunkSize = 4096
+
+// headFile returns the first lines of a text file
+func (p *FilesystemProvider) headFile(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	return p.previewFile(ctx, request, readHeadLines)
+}
+
+// tailFile returns the last lines of a text file, reading backwards from the
+// end so only the returned lines are held in memory
+func (p *FilesystemProvider) tailFile(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	return p.previewFile(ctx, request, readTailLines)
+}
+
+// previewFile does the work shared by head and tail; readLines selects the lines to return
+func (p *FilesystemProvider) previewFile(ctx context.Context, request CallToolRequest, readLines func(file *os.File, size int64, n int) ([]string, error)) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the lines parameter (default to DefaultPreviewLines)
+	n := DefaultPreviewLines
+	if linesParam, ok := request.Params.Arguments["lines"].(float64); ok {
+		n = int(linesParam)
+	}
+	if n < 0 {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "lines", "Lines parameter must not be negative")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Only touch files with an allowed extension
+	if !p.extensionAllowed(fullPath) {
+		result := NewToolResultErrorCode(ErrorCodeExtensionNotAllowed, "path", fmt.Sprintf("File extension is not allowed: %s", pathParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Check if the path exists and is a file
+	info, errResult := statRegularFile(fullPath, pathParam, "path")
+	if errResult != nil {
+		errResult.RequestID = request.RequestID
+		return errResult, nil
+	}
+
+	file, err := os.Open(fullPath)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error opening file: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	defer file.Close()
+
+	// Count the lines, which also tells whether the file is text
+	total, binary, err := countLines(ctx, file)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error reading file: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	if binary {
+		result := NewToolResultErrorCode(ErrorCodeNotText, "path", fmt.Sprintf("File is not a text file: %s", pathParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	lines, err := readLines(file, info.Size(), n)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error reading file: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Return the result
+	result := NewToolResultJSON(FileLines{
+		Path:       pathParam,
+		Lines:      lines,
+		TotalLines: total,
+	})
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// countLines counts the lines of a file, a last line without a newline
+// included. It stops early, reporting binary, at the first NUL byte.
+func countLines(ctx context.Context, file *os.File) (lines int, binary bool, err error) {
+	reader := newContextReader(ctx, file)
+	buf := make([]byte, 32*1024)
+	var last byte
+	for {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			chunk := buf[:n]
+			if bytes.IndexByte(chunk, 0) >= 0 {
+				return 0, true, nil
+			}
+			lines += bytes.Count(chunk, []byte("\n"))
+			last = chunk[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, false, err
+		}
+	}
+	if last != 0 && last != '\n' {
+		lines++
+	}
+	return lines, false, nil
+}
+
+// readHeadLines reads the first n lines of a file
+func readHeadLines(file *os.File, size int64, n int) ([]string, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, 0, min(n, DefaultPreviewLines*10))
+	reader := bufio.NewReader(file)
+	for len(lines) < n {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			lines = append(lines, trimLineEnding(line))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return lines, nil
+}
+
+// readTailLines reads the last n lines of a file by reading chunks backwards
+// from the end until enough lines have been seen
+func readTailLines(file *os.File, size int64, n int) ([]string, error) {
+	if n == 0 || size == 0 {
+		return []string{}, nil
+	}
+
+	var data []byte
+	offset := size
+	for offset > 0 {
+		chunkSize := min(int64(tailChunkSize), offset)
+		offset -= chunkSize
+		chunk := make([]byte, chunkSize)
+		if _, err := file.ReadAt(chunk, offset); err != nil && err != io.EOF {
+			return nil, err
+		}
+		data = append(chunk, data...)
+
+		// A newline ending the file does not start another line, so n full
+		// lines need n newlines before them besides that one
+		if bytes.Count(bytes.TrimSuffix(data, []byte("\n")), []byte("\n")) >= n {
+			break
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines, nil
+}
+
+// trimLineEnding removes the \n or \r\n ending a line
+func trimLineEnding(line string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+}
@@ -0,0 +1,53 @@
+package mcp
+
+import "encoding/json"
+
+// JSONRPCVersion is the only JSON-RPC version the server speaks
+const JSONRPCVersion = "2.0"
+
+// Standard JSON-RPC 2.0 error codes
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+	JSONRPCInvalidParams  = -32602
+	JSONRPCInternalError  = -32603
+)
+
+// JSONRPCRequest is a JSON-RPC 2.0 request or notification. Notifications have no ID.
+type JSONRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// JSONRPCResponse is a JSON-RPC 2.0 response carrying either a result or an error
+type JSONRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+}
+
+// JSONRPCError is the error object of a JSON-RPC 2.0 response
+type JSONRPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// JSONRPCCallParams are the parameters of the tools/call and resources/read
+// methods. resources/read may name the resource by URI instead.
+type JSONRPCCallParams struct {
+	Name      string                 `json:"name"`
+	URI       string                 `json:"uri,omitempty"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+}
+
+// InitializeResult is the result of the initialize method
+type InitializeResult struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	ServerInfo      ServerInfo             `json:"serverInfo"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+}
@@ -0,0 +1,72 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// stagingJanitorInterval bounds how often expired staged writes are swept
+const stagingJanitorInterval = time.Minute
+
+// Start launches the provider's background work: a janitor that discards
+// expired staged writes and the line indexes of removed files
+func (p *FilesystemProvider) Start(ctx context.Context) error {
+	p.lifecycleMu.Lock()
+	defer p.lifecycleMu.Unlock()
+
+	if p.stopJanitor != nil {
+		return errors.New("filesystem provider already started")
+	}
+
+	interval := min(p.stagingTTL, stagingJanitorInterval)
+	if interval <= 0 {
+		interval = stagingJanitorInterval
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				p.staged.prune(now)
+				p.lineIndexes.prune()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	p.stopJanitor = stop
+	p.janitorDone = done
+	return nil
+}
+
+// Stop halts the background work started by Start and drops the provider's
+// caches and pending staged writes
+func (p *FilesystemProvider) Stop(ctx context.Context) error {
+	p.lifecycleMu.Lock()
+	defer p.lifecycleMu.Unlock()
+
+	if p.stopJanitor == nil {
+		return nil
+	}
+
+	close(p.stopJanitor)
+	select {
+	case <-p.janitorDone:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	p.stopJanitor = nil
+	p.janitorDone = nil
+
+	p.staged.clear()
+	p.lineIndexes.clear()
+	return nil
+}
@@ -0,0 +1,198 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+)
+
+// lineEndingStyles lists the line ending styles files can be normalized to
+var lineEndingStyles = []string{"lf", "crlf"}
+
+// checkLineEndings reports the line endings used by a text file. Normalizing
+// them modifies the file, so it is the separate fix-line-endings tool.
+func (p *FilesystemProvider) checkLineEndings(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Refuse requests to fix the file rather than ignoring them
+	if fixParam, exists := request.Params.Arguments["fix"]; exists && fixParam != false {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "fix", "check-line-endings only reports line endings; use filesystem.fix-line-endings to normalize them")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Count the line endings
+	report, _, _, errResult := p.readLineEndings(ctx, fullPath, pathParam)
+	if errResult != nil {
+		errResult.RequestID = request.RequestID
+		return errResult, nil
+	}
+
+	// Return the result
+	result := NewToolResultJSON(report)
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// fixLineEndings rewrites a text file so every line ends with the target
+// style, reporting the line endings it used before
+func (p *FilesystemProvider) fixLineEndings(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the target parameter (default to LF)
+	target := "lf"
+	if targetParam, ok := request.Params.Arguments["target"].(string); ok {
+		target = targetParam
+	}
+	if target != "lf" && target != "crlf" {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "target", fmt.Sprintf("Unknown target line ending: %s", target))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Keep concurrent calls from modifying the file at the same time
+	unlock := p.pathLocks.lock(fullPath)
+	defer unlock()
+
+	// Only touch files with an allowed extension
+	if result := p.checkWrite(fullPath, pathParam, "path", -1); result != nil {
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Count the line endings
+	report, data, info, errResult := p.readLineEndings(ctx, fullPath, pathParam)
+	if errResult != nil {
+		errResult.RequestID = request.RequestID
+		return errResult, nil
+	}
+
+	// Normalize the line endings if they differ from the target
+	if report.Style != target && report.Style != "none" {
+		fixed := convertLineEndings(data, target)
+		if result := p.checkWrite(fullPath, pathParam, "path", int64(len(fixed))); result != nil {
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		if err := writeFileAtomic(fullPath, fixed, info.Mode().Perm()); err != nil {
+			result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error writing file: %s", err.Error()))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		report.Fixed = true
+		report.Target = target
+	}
+
+	// Return the result
+	result := NewToolResultJSON(report)
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// readLineEndings reads a text file, within the read limit, and counts its
+// line endings
+func (p *FilesystemProvider) readLineEndings(ctx context.Context, fullPath, pathParam string) (LineEndingReport, []byte, os.FileInfo, *CallToolResult) {
+	// Check if the path exists and is a file
+	info, errResult := statRegularFile(fullPath, pathParam, "path")
+	if errResult != nil {
+		return LineEndingReport{}, nil, nil, errResult
+	}
+	if p.MaxReadBytes > 0 && info.Size() > p.MaxReadBytes {
+		return LineEndingReport{}, nil, nil, NewToolResultErrorCode(ErrorCodeFileTooLarge, "path", fmt.Sprintf("File is larger than the %d byte read limit: %s", p.MaxReadBytes, pathParam))
+	}
+
+	// Read the file contents
+	data, err := readFileContext(ctx, fullPath)
+	if err != nil {
+		return LineEndingReport{}, nil, nil, NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error reading file: %s", err.Error()))
+	}
+
+	// Only text files have line endings
+	if bytes.IndexByte(data, 0) >= 0 {
+		return LineEndingReport{}, nil, nil, NewToolResultErrorCode(ErrorCodeNotText, "path", fmt.Sprintf("File is not a text file: %s", pathParam))
+	}
+
+	report := LineEndingReport{Path: pathParam}
+	report.LF, report.CRLF, report.CR = countLineEndings(data)
+	report.Style = lineEndingStyle(report.LF, report.CRLF, report.CR)
+	return report, data, info, nil
+}
+
+// countLineEndings counts the LF, CRLF and lone CR line endings in data
+func countLineEndings(data []byte) (lf, crlf, cr int) {
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '\n':
+			lf++
+		case '\r':
+			if i+1 < len(data) && data[i+1] == '\n' {
+				crlf++
+				i++
+			} else {
+				cr++
+			}
+		}
+	}
+	return lf, crlf, cr
+}
+
+// lineEndingStyle names the line ending style implied by the counts
+func lineEndingStyle(lf, crlf, cr int) string {
+	styles := 0
+	style := "none"
+	if lf > 0 {
+		styles++
+		style = "lf"
+	}
+	if crlf > 0 {
+		styles++
+		style = "crlf"
+	}
+	if cr > 0 {
+		styles++
+		style = "cr"
+	}
+	if styles > 1 {
+		return "mixed"
+	}
+	return style
+}
+
+// convertLineEndings rewrites every line ending in data to the target style
+func convertLineEndings(data []byte, target string) []byte {
+	normalized := bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	normalized = bytes.ReplaceAll(normalized, []byte("\r"), []byte("\n"))
+	if target == "crlf" {
+		normalized = bytes.ReplaceAll(normalized, []byte("\n"), []byte("\r\n"))
+	}
+	return normalized
+}
@@ -0,0 +1,221 @@
+package mcp
+
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Defaults for the number of line indexes a provider caches and their total
+// size, at 8 bytes per line
+const (
+	DefaultLineIndexEntries = 256
+	DefaultLineIndexBytes   = 64 << 20
+)
+
+// lineIndexEntry is a cached line index along with the file version it was built from
+type lineIndexEntry struct {
+	path    string
+	modTime time.Time
+	size    int64
+	offsets []int64
+}
+
+// bytes is the memory the offsets of the entry take up
+func (e *lineIndexEntry) bytes() int64 {
+	return int64(len(e.offsets)) * 8
+}
+
+// lineIndexCache is a least recently used cache of line indexes keyed by
+// resolved path, bounded by both its number of entries and their total size
+type lineIndexCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	bytes      int64
+	order      *list.List // of *lineIndexEntry, most recently used first
+	entries    map[string]*list.Element
+}
+
+// get returns the cached offsets for a path if the file has not changed since they were built
+func (c *lineIndexCache) get(path string, info os.FileInfo) ([]int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[path]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*lineIndexEntry)
+	if !entry.modTime.Equal(info.ModTime()) || entry.size != info.Size() {
+		c.remove(element)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.offsets, true
+}
+
+// put stores the offsets for a path, evicting the least recently used
+// indexes to stay within the limits. Indexes larger than the whole cache are
+// not cached.
+func (c *lineIndexCache) put(path string, info os.FileInfo, offsets []int64) {
+	entry := &lineIndexEntry{
+		path:    path,
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		offsets: offsets,
+	}
+	if entry.bytes() > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.order = list.New()
+		c.entries = make(map[string]*list.Element)
+	}
+	if element, ok := c.entries[path]; ok {
+		c.remove(element)
+	}
+	for c.order.Len() > 0 && (c.order.Len() >= c.maxEntries || c.bytes+entry.bytes() > c.maxBytes) {
+		c.remove(c.order.Back())
+	}
+
+	c.entries[path] = c.order.PushFront(entry)
+	c.bytes += entry.bytes()
+}
+
+// prune drops the indexes of files that no longer exist
+func (c *lineIndexCache) prune() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for path, element := range c.entries {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			c.remove(element)
+		}
+	}
+}
+
+// clear drops every cached index
+func (c *lineIndexCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order = nil
+	c.entries = nil
+	c.bytes = 0
+}
+
+// remove drops an entry; the caller must hold mu
+func (c *lineIndexCache) remove(element *list.Element) {
+	entry := c.order.Remove(element).(*lineIndexEntry)
+	delete(c.entries, entry.path)
+	c.bytes -= entry.bytes()
+}
+
+// buildLineIndex scans a file and returns the byte offset of each line
+func (p *FilesystemProvider) buildLineIndex(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the include_offsets parameter (default to true)
+	includeOffsets := true
+	if includeOffsetsParam, ok := request.Params.Arguments["include_offsets"].(bool); ok {
+		includeOffsets = includeOffsetsParam
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Check if the path exists and is a file
+	info, errResult := statRegularFile(fullPath, pathParam, "path")
+	if errResult != nil {
+		errResult.RequestID = request.RequestID
+		return errResult, nil
+	}
+
+	offsets, cached, err := p.lineOffsets(ctx, fullPath, info)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error indexing file: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	lineIndex := LineIndex{
+		Path:      pathParam,
+		Size:      info.Size(),
+		LineCount: len(offsets),
+		Cached:    cached,
+	}
+	if includeOffsets {
+		lineIndex.Offsets = offsets
+	}
+
+	// Return the result
+	result := NewToolResultJSON(lineIndex)
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// lineOffsets returns the line index of a file, reusing the cached index while
+// the file's modification time and size are unchanged. It reports whether the
+// index came from the cache.
+func (p *FilesystemProvider) lineOffsets(ctx context.Context, fullPath string, info os.FileInfo) ([]int64, bool, error) {
+	if offsets, ok := p.lineIndexes.get(fullPath, info); ok {
+		return offsets, true, nil
+	}
+
+	offsets, err := scanLineOffsets(ctx, fullPath)
+	if err != nil {
+		return nil, false, err
+	}
+	p.lineIndexes.put(fullPath, info, offsets)
+	return offsets, false, nil
+}
+
+// scanLineOffsets returns the byte offset at which each line of a file starts
+func scanLineOffsets(ctx context.Context, path string) ([]int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	offsets := make([]int64, 0)
+	reader := bufio.NewReader(newContextReader(ctx, file))
+	var offset int64
+	atLineStart := true
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if len(chunk) > 0 && atLineStart {
+			offsets = append(offsets, offset)
+		}
+		offset += int64(len(chunk))
+		atLineStart = err == nil
+
+		if err == io.EOF {
+			return offsets, nil
+		}
+		if err != nil && err != bufio.ErrBufferFull {
+			return nil, err
+		}
+	}
+}
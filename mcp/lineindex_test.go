@@ -0,0 +1,56 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineIndexCacheLimits(t *testing.T) {
+	tempDir := t.TempDir()
+	info := func(name string) (string, os.FileInfo) {
+		path := filepath.Join(tempDir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(name), 0644))
+		info, err := os.Stat(path)
+		assert.NoError(t, err)
+		return path, info
+	}
+
+	cache := lineIndexCache{maxEntries: 2, maxBytes: 64}
+	pathA, infoA := info("a")
+	pathB, infoB := info("b")
+	pathC, infoC := info("c")
+
+	// The least recently used index is evicted past the entry limit
+	cache.put(pathA, infoA, []int64{0})
+	cache.put(pathB, infoB, []int64{0})
+	_, ok := cache.get(pathA, infoA)
+	assert.True(t, ok)
+	cache.put(pathC, infoC, []int64{0})
+	_, ok = cache.get(pathB, infoB)
+	assert.False(t, ok)
+	_, ok = cache.get(pathA, infoA)
+	assert.True(t, ok)
+
+	// and past the byte limit, at 8 bytes per line
+	cache.maxEntries = 3
+	cache.put(pathB, infoB, make([]int64, 7))
+	_, ok = cache.get(pathC, infoC)
+	assert.False(t, ok)
+	_, ok = cache.get(pathA, infoA)
+	assert.True(t, ok)
+	assert.Equal(t, int64(64), cache.bytes)
+
+	// Indexes larger than the whole cache are not kept
+	cache.put(pathC, infoC, make([]int64, 9))
+	_, ok = cache.get(pathC, infoC)
+	assert.False(t, ok)
+
+	// Indexes of removed files are dropped
+	assert.NoError(t, os.Remove(pathA))
+	cache.prune()
+	assert.Len(t, cache.entries, 1)
+	assert.Equal(t, int64(56), cache.bytes)
+}
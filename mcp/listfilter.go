@@ -0,0 +1,35 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// listEntryTypes lists the kinds of entries a directory listing can be limited to
+var listEntryTypes = []string{"file", "dir", "all"}
+
+// filterEntries returns the entries whose name matches pattern and whose kind
+// matches entryType. An empty pattern matches every name. The pattern must
+// already have been validated.
+func filterEntries(entries []os.DirEntry, pattern string, entryType string) []os.DirEntry {
+	if pattern == "" && entryType == "all" {
+		return entries
+	}
+
+	filtered := entries[:0]
+	for _, entry := range entries {
+		switch {
+		case entryType == "file" && entry.IsDir():
+			continue
+		case entryType == "dir" && !entry.IsDir():
+			continue
+		}
+		if pattern != "" {
+			if matched, _ := filepath.Match(pattern, entry.Name()); !matched {
+				continue
+			}
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
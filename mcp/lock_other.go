@@ -0,0 +1,27 @@
+//go:build !unix
+
+package mcp
+
+import (
+	"os"
+	"sync"
+)
+
+// fileLocks serializes access to files on platforms without advisory locks.
+// It only protects against concurrent callers within this process.
+var fileLocks sync.Map
+
+// lockFile takes an exclusive lock on an open file, blocking until it is available
+func lockFile(file *os.File) error {
+	mu, _ := fileLocks.LoadOrStore(file.Name(), &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return nil
+}
+
+// unlockFile releases a lock taken by lockFile
+func unlockFile(file *os.File) error {
+	if mu, ok := fileLocks.Load(file.Name()); ok {
+		mu.(*sync.Mutex).Unlock()
+	}
+	return nil
+}
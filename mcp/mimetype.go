@@ -0,0 +1,168 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// sniffLen is the number of leading bytes used to detect a MIME type
+const sniffLen = 512
+
+// textSniffLen is the number of leading bytes the auto encoding checks to
+// tell text from binary content
+const textSniffLen = 8 << 10
+
+// Encodings of file content
+const (
+	EncodingText   = "text"
+	EncodingBase64 = "base64"
+	// EncodingAuto sends text as is and anything else base64-encoded
+	EncodingAuto = "auto"
+	// EncodingDataURL sends the content as a data URL carrying its MIME type,
+	// ready to be embedded by clients
+	EncodingDataURL = "data_url"
+)
+
+// readEncodings and writeEncodings are the encodings content can be read and
+// written with
+var (
+	readEncodings  = []string{EncodingText, EncodingBase64, EncodingAuto, EncodingDataURL}
+	writeEncodings = []string{EncodingText, EncodingBase64}
+)
+
+// parseEncoding reads the encoding argument (default to fallback), which
+// must be one of allowed
+func parseEncoding(arguments map[string]interface{}, fallback string, allowed []string) (string, error) {
+	value, exists := arguments["encoding"]
+	if !exists {
+		return fallback, nil
+	}
+
+	encoding, ok := value.(string)
+	if !ok {
+		return "", newArgumentError("encoding", "encoding parameter must be a string")
+	}
+	for _, name := range allowed {
+		if encoding == name {
+			return encoding, nil
+		}
+	}
+	return "", newArgumentError("encoding", "unknown encoding: %s (expected one of %s)", encoding, strings.Join(allowed, ", "))
+}
+
+// textualMimeTypes are MIME types outside text/* whose content is text
+var textualMimeTypes = map[string]bool{
+	"application/json":       true,
+	"application/javascript": true,
+	"application/xml":        true,
+	"application/x-yaml":     true,
+	"application/yaml":       true,
+	"application/toml":       true,
+	"application/x-sh":       true,
+	"image/svg+xml":          true,
+}
+
+// detectMimeType determines the MIME type of a file from its leading bytes.
+// Sniffing cannot tell text formats apart, so content sniffed as plain text is
+// refined by the file extension when that names a textual type.
+func detectMimeType(name string, head []byte) string {
+	detected := http.DetectContentType(head[:min(len(head), sniffLen)])
+	if mediaType(detected) != "text/plain" {
+		return detected
+	}
+
+	if byExtension := mime.TypeByExtension(filepath.Ext(name)); byExtension != "" && isTextMimeType(byExtension) {
+		return byExtension
+	}
+	return detected
+}
+
+// detectFileMimeType determines the MIME type of a file by sniffing its first bytes
+func detectFileMimeType(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return detectMimeType(path, head[:n]), nil
+}
+
+// isTextMimeType reports whether content of the given MIME type is text
+func isTextMimeType(mimeType string) bool {
+	base := mediaType(mimeType)
+	return strings.HasPrefix(base, "text/") ||
+		textualMimeTypes[base] ||
+		strings.HasSuffix(base, "+json") ||
+		strings.HasSuffix(base, "+xml")
+}
+
+// mediaType strips the parameters, such as the charset, from a MIME type
+func mediaType(mimeType string) string {
+	base, _, _ := strings.Cut(mimeType, ";")
+	return strings.ToLower(strings.TrimSpace(base))
+}
+
+// encodeFileContent sets the content of a file read with the given encoding.
+// With EncodingAuto, content that looks like text is sent as is and marked as
+// text; anything else is base64-encoded and marked as binary. With
+// EncodingDataURL, the content is a base64 data URL of the file's MIME type.
+func encodeFileContent(fileContent *FileContent, data []byte, encoding string) {
+	if encoding == EncodingAuto {
+		fileContent.IsText = looksLikeText(data)
+		encoding = EncodingText
+		if !fileContent.IsText {
+			encoding = EncodingBase64
+		}
+	}
+
+	fileContent.Encoding = encoding
+	switch encoding {
+	case EncodingBase64:
+		fileContent.Content = base64.StdEncoding.EncodeToString(data)
+	case EncodingDataURL:
+		fileContent.Content = dataURL(fileContent.MimeType, data)
+	default:
+		fileContent.Content = string(data)
+	}
+}
+
+// dataURL returns data as a base64 data URL of the given MIME type
+func dataURL(mimeType string, data []byte) string {
+	return "data:" + strings.ReplaceAll(mimeType, " ", "") + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// looksLikeText reports whether the first textSniffLen bytes of data are
+// valid UTF-8 without NUL bytes
+func looksLikeText(data []byte) bool {
+	head := data[:min(len(data), textSniffLen)]
+	if bytes.IndexByte(head, 0) >= 0 {
+		return false
+	}
+
+	// Do not hold a character cut off by the end of the sniffed bytes
+	// against the content
+	if len(head) < len(data) {
+		for i := 1; i < utf8.UTFMax && i <= len(head); i++ {
+			if utf8.RuneStart(head[len(head)-i]) {
+				if !utf8.FullRune(head[len(head)-i:]) {
+					head = head[:len(head)-i]
+				}
+				break
+			}
+		}
+	}
+	return utf8.Valid(head)
+}
@@ -0,0 +1,154 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// moveFile moves a file or directory to a destination that does not exist
+// yet. Like mv, a destination that is an existing directory receives the
+// source under its base name instead of being replaced. With dry_run, it only
+// validates the request and lists the paths that would be moved.
+func (p *FilesystemProvider) moveFile(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the destination parameter
+	destinationParam, ok := request.Params.Arguments["destination"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "destination", "Destination parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the dry_run parameter (default to false)
+	dryRun := false
+	if dryRunParam, ok := request.Params.Arguments["dry_run"].(bool); ok {
+		dryRun = dryRunParam
+	}
+
+	// Sanitize and resolve the paths
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	destinationPath, err := p.resolvePath(destinationParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "destination", fmt.Sprintf("Invalid destination: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Never move away the root or a protected path
+	if p.isProtected(fullPath) {
+		result := NewToolResultErrorCode(ErrorCodeProtectedPath, "path", fmt.Sprintf("Path is protected and cannot be moved: %s", pathParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Check if the source exists
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			result := NewToolResultErrorCode(ErrorCodeNotFound, "path", fmt.Sprintf("Path not found: %s", pathParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Move into an existing directory, keeping the base name. The computed
+	// path is resolved again so it is held to the root like any other.
+	if destinationInfo, err := os.Stat(destinationPath); err == nil && destinationInfo.IsDir() {
+		destinationParam = filepath.Join(destinationParam, filepath.Base(fullPath))
+		destinationPath, err = p.resolvePath(destinationParam)
+		if err != nil {
+			result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "destination", fmt.Sprintf("Invalid destination: %s", err.Error()))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+	}
+
+	// Keep concurrent calls from modifying either path at the same time
+	unlock := p.pathLocks.lockAll(fullPath, destinationPath)
+	defer unlock()
+
+	// A directory cannot be moved into itself
+	if info.IsDir() {
+		if rel, err := filepath.Rel(fullPath, destinationPath); err == nil && filepath.IsLocal(rel) {
+			result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "destination", fmt.Sprintf("Destination is inside the directory being moved: %s", destinationParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+	}
+
+	// A file must not change to or from a disallowed extension
+	if !info.IsDir() && (!p.extensionAllowed(fullPath) || !p.extensionAllowed(destinationPath)) {
+		result := NewToolResultErrorCode(ErrorCodeExtensionNotAllowed, "path", fmt.Sprintf("File extension is not allowed: %s", pathParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Never overwrite anything at the destination
+	if _, err := os.Lstat(destinationPath); err == nil {
+		result := NewToolResultErrorCode(ErrorCodeAlreadyExists, "destination", fmt.Sprintf("Destination already exists: %s", destinationParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Report what would be moved without touching anything
+	if dryRun {
+		paths, err := affectedPaths(ctx, fullPath, pathParam)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error reading directory: %s", err.Error()))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+
+		result := NewToolResultJSON(DryRunResult{
+			DryRun:      true,
+			Operation:   "move",
+			Path:        pathParam,
+			Destination: destinationParam,
+			Paths:       paths,
+		})
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Create the parent directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(destinationPath), 0755); err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "destination", fmt.Sprintf("Error creating directory: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Move the entry, copying it if it has to cross devices
+	if err := movePath(fullPath, destinationPath); err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error moving path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Return the result
+	result := NewToolResultJSON(MoveResult{
+		Path:        pathParam,
+		Destination: destinationParam,
+	})
+	result.RequestID = request.RequestID
+	return result, nil
+}
@@ -0,0 +1,84 @@
+package mcp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// listPageToken is the position a directory listing resumes from, handed to
+// clients as next_token. It records the last entry returned rather than an
+// offset, so entries added or removed in the meantime neither repeat nor skip
+// the entries still to come.
+type listPageToken struct {
+	Path      string `json:"path"`
+	After     string `json:"after"`
+	IsDir     bool   `json:"is_dir,omitempty"`
+	Desc      bool   `json:"desc,omitempty"`
+	DirsFirst bool   `json:"dirs_first,omitempty"`
+}
+
+// encodeListPageToken returns the token resuming a listing of pathParam in the
+// given order after last
+func encodeListPageToken(pathParam string, order directorySort, last os.DirEntry) string {
+	data, _ := json.Marshal(listPageToken{
+		Path:      filepath.Clean(pathParam),
+		After:     last.Name(),
+		IsDir:     last.IsDir(),
+		Desc:      order.desc,
+		DirsFirst: order.dirsFirst,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// parseListPageToken reads the page_token argument of a listing of pathParam,
+// returning nil if there is none. Tokens only resume listings of the same
+// directory in the same order, which must be by name.
+func parseListPageToken(arguments map[string]interface{}, pathParam string, order directorySort) (*listPageToken, error) {
+	tokenParam, exists := arguments["page_token"]
+	if !exists || tokenParam == nil || tokenParam == "" {
+		return nil, nil
+	}
+	encoded, ok := tokenParam.(string)
+	if !ok {
+		return nil, newArgumentError("page_token", "page_token parameter must be a string")
+	}
+
+	var token listPageToken
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || json.Unmarshal(data, &token) != nil || token.After == "" {
+		return nil, newArgumentError("page_token", "invalid page_token")
+	}
+	if token.Path != filepath.Clean(pathParam) {
+		return nil, newArgumentError("page_token", "page_token belongs to a listing of %s, not %s", token.Path, pathParam)
+	}
+	if order.by != "name" || token.Desc != order.desc || token.DirsFirst != order.dirsFirst {
+		return nil, newArgumentError("page_token", "page_token must be used with the sort order it was returned for, by name")
+	}
+	return &token, nil
+}
+
+// follows reports whether entry comes after the token's position in the
+// listing order
+func (t *listPageToken) follows(entry os.DirEntry) bool {
+	if t.DirsFirst && entry.IsDir() != t.IsDir {
+		return !entry.IsDir()
+	}
+	c := strings.Compare(entry.Name(), t.After)
+	if t.Desc {
+		return c < 0
+	}
+	return c > 0
+}
+
+// entriesAfter returns the sorted entries that come after the token's position
+func entriesAfter(entries []os.DirEntry, token *listPageToken) []os.DirEntry {
+	for i, entry := range entries {
+		if token.follows(entry) {
+			return entries[i:]
+		}
+	}
+	return nil
+}
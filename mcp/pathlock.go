@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// pathLocks serializes the tools that modify the same file within this
+// process. Locks are keyed by resolved path and dropped once nobody holds or
+// waits for them, so the map only holds paths being modified right now.
+type pathLocks struct {
+	locks sync.Map // resolved path -> *pathLock
+}
+
+// pathLock is the mutex of one path. refs counts the callers holding or
+// waiting for it; -1 marks a lock that has been released for good and is
+// being removed from the map.
+type pathLock struct {
+	mu   sync.Mutex
+	refs atomic.Int32
+}
+
+// lock blocks until no other caller holds the lock for path and returns the
+// function that releases it
+func (l *pathLocks) lock(path string) func() {
+	for {
+		value, _ := l.locks.LoadOrStore(path, &pathLock{})
+		lock := value.(*pathLock)
+
+		refs := lock.refs.Load()
+		if refs < 0 {
+			// The last holder is removing this lock; help it out and retry
+			// with a fresh one
+			l.locks.CompareAndDelete(path, lock)
+			continue
+		}
+		if !lock.refs.CompareAndSwap(refs, refs+1) {
+			continue
+		}
+
+		lock.mu.Lock()
+		return func() {
+			lock.mu.Unlock()
+			if lock.refs.Add(-1) == 0 && lock.refs.CompareAndSwap(0, -1) {
+				l.locks.CompareAndDelete(path, lock)
+			}
+		}
+	}
+}
+
+// lockAll locks every one of paths, such as the source and destination of a
+// move, and returns the function that releases them. The locks are taken in
+// sorted order so two callers locking the same paths cannot deadlock.
+func (l *pathLocks) lockAll(paths ...string) func() {
+	paths = slices.Compact(slices.Sorted(slices.Values(paths)))
+	unlocks := make([]func(), 0, len(paths))
+	for _, path := range paths {
+		unlocks = append(unlocks, l.lock(path))
+	}
+	return func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathLocksExcludeAndCleanUp(t *testing.T) {
+	var locks pathLocks
+	var wg sync.WaitGroup
+	holders := map[string]int{}
+	var holdersMu sync.Mutex
+
+	for i := 0; i < 100; i++ {
+		path := []string{"/a", "/b"}[i%2]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := locks.lock(path)
+			defer unlock()
+
+			holdersMu.Lock()
+			holders[path]++
+			assert.Equal(t, 1, holders[path], "two callers hold the lock for %s", path)
+			holdersMu.Unlock()
+
+			// Give the other callers a chance to run while the lock is held
+			runtime.Gosched()
+
+			holdersMu.Lock()
+			holders[path]--
+			holdersMu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// Released locks are removed from the map
+	locks.locks.Range(func(key, value any) bool {
+		t.Errorf("lock for %v left behind", key)
+		return true
+	})
+}
+
+func TestPathLocksLockAllInAnyOrder(t *testing.T) {
+	var locks pathLocks
+	var wg sync.WaitGroup
+
+	// Callers locking the same paths in opposite orders must not deadlock
+	for i := 0; i < 100; i++ {
+		paths := []string{"/a", "/b"}
+		if i%2 == 1 {
+			paths = []string{"/b", "/a", "/b"}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := locks.lockAll(paths...)
+			runtime.Gosched()
+			unlock()
+		}()
+	}
+	wg.Wait()
+
+	locks.locks.Range(func(key, value any) bool {
+		t.Errorf("lock for %v left behind", key)
+		return true
+	})
+}
+
+func TestRelocatingToolsLockBothPaths(t *testing.T) {
+	tests := []struct {
+		tool      string
+		arguments map[string]interface{}
+		locked    string
+	}{
+		{"move", map[string]interface{}{"path": "a.txt", "destination": "b.txt"}, "b.txt"},
+		{"rename", map[string]interface{}{"path": "a.txt", "new_name": "b.txt"}, "b.txt"},
+		{"copy", map[string]interface{}{"path": "a.txt", "destination": "b.txt"}, "b.txt"},
+		{"symlink", map[string]interface{}{"target": "a.txt", "link_path": "b.txt"}, "a.txt"},
+		{"chmod", map[string]interface{}{"path": "a.txt", "mode": "0600"}, "a.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			tempDir := t.TempDir()
+			assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("content"), 0644))
+			p := NewFilesystemProvider(WithRootDir(tempDir))
+
+			lockedPath, err := p.resolvePath(tt.locked)
+			assert.NoError(t, err)
+			unlock := p.pathLocks.lock(lockedPath)
+
+			done := make(chan *CallToolResult)
+			go func() {
+				result, err := p.CallTool(context.Background(), tt.tool, CallToolRequest{Params: CallToolParams{Arguments: tt.arguments}})
+				assert.NoError(t, err)
+				done <- result
+			}()
+
+			// The call waits while another caller holds the lock
+			select {
+			case <-done:
+				t.Fatal("call did not wait for the path lock")
+			case <-time.After(50 * time.Millisecond):
+			}
+
+			unlock()
+			result := <-done
+			assert.Equal(t, "success", result.Status)
+		})
+	}
+}
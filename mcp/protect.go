@@ -0,0 +1,51 @@
+package mcp
+
+import (
+	"path/filepath"
+)
+
+// WithProtectedPaths keeps the given paths, and the directories containing
+// them, from being deleted, moved or renamed
+func WithProtectedPaths(paths ...string) FilesystemOption {
+	return func(p *FilesystemProvider) {
+		p.ProtectedPaths = paths
+	}
+}
+
+// isProtected reports whether deleting, moving or renaming fullPath would
+// take away the root directory, the root of a mount or one of ProtectedPaths.
+// Paths are compared once resolved, both as given and with their symbolic
+// links evaluated, so no spelling of a protected path gets past the check.
+func (p *FilesystemProvider) isProtected(fullPath string) bool {
+	var protected []string
+	if len(p.mounts) == 0 {
+		protected = append(protected, p.rootDir)
+	}
+	for _, rootDir := range p.mounts {
+		protected = append(protected, rootDir)
+	}
+	for i, rootDir := range protected {
+		if abs, err := filepath.Abs(rootDir); err == nil {
+			protected[i] = abs
+		}
+	}
+	for _, path := range p.ProtectedPaths {
+		if resolved, err := p.resolvePath(path); err == nil {
+			protected = append(protected, resolved)
+		}
+	}
+
+	realPath, err := resolveSymlinks(fullPath)
+	if err != nil {
+		realPath = fullPath
+	}
+	for _, path := range protected {
+		if isWithinDir(fullPath, path) {
+			return true
+		}
+		if realProtected, err := resolveSymlinks(path); err == nil && isWithinDir(realPath, realProtected) {
+			return true
+		}
+	}
+	return false
+}
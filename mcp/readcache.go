@@ -0,0 +1,159 @@
+package mcp
+
+import (
+	"container/list"
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultReadCacheEntries is the number of files the read cache holds when
+// WithReadCache is given no entry limit
+const DefaultReadCacheEntries = 1024
+
+// CacheStats describes the use of a provider's cache
+type CacheStats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+	Bytes   int64
+}
+
+// CacheStatsProvider is implemented by providers that cache results, so the
+// server can report how well the cache works
+type CacheStatsProvider interface {
+	// CacheStats returns the statistics of the cache and whether caching is enabled
+	CacheStats() (CacheStats, bool)
+}
+
+// readCacheEntry is the cached content of a file, valid as long as the file
+// keeps its modification time and size
+type readCacheEntry struct {
+	path    string
+	modTime time.Time
+	size    int64
+	data    []byte
+}
+
+// readCache is a least recently used cache of file contents keyed by
+// resolved path, bounded by both its number of entries and their total size
+type readCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	bytes      int64
+	order      *list.List // of *readCacheEntry, most recently used first
+	entries    map[string]*list.Element
+	hits       uint64
+	misses     uint64
+}
+
+func newReadCache(maxEntries int, maxBytes int64) *readCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultReadCacheEntries
+	}
+	return &readCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached content of a file if it has not changed since it was cached
+func (c *readCache) get(path string, info os.FileInfo) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[path]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	entry := element.Value.(*readCacheEntry)
+	if !entry.modTime.Equal(info.ModTime()) || entry.size != info.Size() {
+		c.remove(element)
+		c.misses++
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	c.hits++
+	return entry.data, true
+}
+
+// put caches the content of a file, evicting the least recently used files
+// to stay within the limits. Files larger than the whole cache are not cached.
+func (c *readCache) put(path string, info os.FileInfo, data []byte) {
+	size := int64(len(data))
+	if size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[path]; ok {
+		c.remove(element)
+	}
+	for c.order.Len() > 0 && (c.order.Len() >= c.maxEntries || c.bytes+size > c.maxBytes) {
+		c.remove(c.order.Back())
+	}
+
+	c.entries[path] = c.order.PushFront(&readCacheEntry{
+		path:    path,
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		data:    data,
+	})
+	c.bytes += size
+}
+
+// remove drops an entry; the caller must hold mu
+func (c *readCache) remove(element *list.Element) {
+	entry := c.order.Remove(element).(*readCacheEntry)
+	delete(c.entries, entry.path)
+	c.bytes -= int64(len(entry.data))
+}
+
+// stats returns the current statistics of the cache
+func (c *readCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{
+		Hits:    c.hits,
+		Misses:  c.misses,
+		Entries: c.order.Len(),
+		Bytes:   c.bytes,
+	}
+}
+
+// readFileCached reads a file whose stat is info, from the read cache if it
+// is enabled and holds the file unchanged. The returned data must not be modified.
+func (p *FilesystemProvider) readFileCached(ctx context.Context, fullPath string, info os.FileInfo) ([]byte, error) {
+	if p.readCache == nil {
+		return readFileContext(ctx, fullPath)
+	}
+	if data, ok := p.readCache.get(fullPath, info); ok {
+		return data, nil
+	}
+
+	data, err := readFileContext(ctx, fullPath)
+	if err != nil {
+		return nil, err
+	}
+	// Only cache what matches the stat, not a file that changed while it was read
+	if int64(len(data)) == info.Size() {
+		p.readCache.put(fullPath, info, data)
+	}
+	return data, nil
+}
+
+// CacheStats returns the statistics of the read cache and whether it is enabled
+func (p *FilesystemProvider) CacheStats() (CacheStats, bool) {
+	if p.readCache == nil {
+		return CacheStats{}, false
+	}
+	return p.readCache.stats(), true
+}
@@ -0,0 +1,126 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// MaxLineBytes is the longest line readlines can return
+const MaxLineBytes = 16 << 20
+
+// readFileLines returns the lines of a text file, or those from start_line to
+// end_line (1-based, inclusive), along with the total line count. Ranges
+// reaching past the end of the file are clamped to it.
+func (p *FilesystemProvider) readFileLines(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the start_line and end_line parameters (default to the whole file)
+	startLine := 1
+	if startParam, ok := request.Params.Arguments["start_line"].(float64); ok {
+		startLine = int(startParam)
+	}
+	if startLine < 1 {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "start_line", "start_line must be at least 1")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	endLine := 0
+	if endParam, ok := request.Params.Arguments["end_line"].(float64); ok {
+		endLine = int(endParam)
+		if endLine < startLine {
+			result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "end_line", "end_line must not be before start_line")
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Only touch files with an allowed extension
+	if !p.extensionAllowed(fullPath) {
+		result := NewToolResultErrorCode(ErrorCodeExtensionNotAllowed, "path", fmt.Sprintf("File extension is not allowed: %s", pathParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Check if the path exists and is a file
+	info, errResult := statRegularFile(fullPath, pathParam, "path")
+	if errResult != nil {
+		errResult.RequestID = request.RequestID
+		return errResult, nil
+	}
+	if p.MaxReadBytes > 0 && info.Size() > p.MaxReadBytes {
+		result := NewToolResultErrorCode(ErrorCodeFileTooLarge, "path", fmt.Sprintf("File is larger than the %d byte read limit: %s", p.MaxReadBytes, pathParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	file, err := os.Open(fullPath)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error opening file: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	defer file.Close()
+
+	// Scan every line to count them, keeping those in the range. ScanLines
+	// drops the \r of \r\n endings.
+	lines := []string{}
+	total := 0
+	scanner := bufio.NewScanner(newContextReader(ctx, file))
+	scanner.Buffer(make([]byte, 64*1024), MaxLineBytes)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if bytes.IndexByte(line, 0) >= 0 {
+			result := NewToolResultErrorCode(ErrorCodeNotText, "path", fmt.Sprintf("File is not a text file: %s", pathParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		total++
+		if total >= startLine && (endLine == 0 || total <= endLine) {
+			lines = append(lines, string(line))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(err, bufio.ErrTooLong) {
+			result := NewToolResultErrorCode(ErrorCodeLimitExceeded, "path", fmt.Sprintf("File has a line longer than %d bytes: %s", MaxLineBytes, pathParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error reading file: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Return the result
+	result := NewToolResultJSON(FileLineRange{
+		FileLines: FileLines{
+			Path:       pathParam,
+			Lines:      lines,
+			TotalLines: total,
+		},
+		StartLine: startLine,
+		EndLine:   startLine + len(lines) - 1,
+	})
+	result.RequestID = request.RequestID
+	return result, nil
+}
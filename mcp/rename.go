@@ -0,0 +1,110 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// renamePath gives a file or directory a new name within the directory it is
+// in. Unlike a move, it can never relocate the entry, since new_name is a
+// base name rather than a path.
+func (p *FilesystemProvider) renamePath(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the new_name parameter, which must be a plain name
+	newName, ok := request.Params.Arguments["new_name"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "new_name", "new_name parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	if newName == "" || newName == "." || newName == ".." || strings.ContainsAny(newName, `/\`) {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "new_name", fmt.Sprintf("new_name must be a file name without path separators: %q", newName))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Address the new path the same way as the old one, within its mount if any
+	mountPrefix, relPath := "", pathParam
+	if len(p.mounts) > 0 {
+		name, rel, _ := strings.Cut(pathParam, ":")
+		mountPrefix, relPath = name+":", rel
+	}
+	newParam := mountPrefix + filepath.Join(filepath.Dir(filepath.Clean(relPath)), newName)
+	newPath, err := p.resolvePath(newParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "new_name", fmt.Sprintf("Invalid new name: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Never rename the root or a protected path
+	if p.isProtected(fullPath) || filepath.Dir(newPath) != filepath.Dir(fullPath) {
+		result := NewToolResultErrorCode(ErrorCodeProtectedPath, "path", fmt.Sprintf("Path is protected and cannot be renamed: %s", pathParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Keep concurrent calls from modifying either path at the same time
+	unlock := p.pathLocks.lockAll(fullPath, newPath)
+	defer unlock()
+
+	// Check if the path exists
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			result := NewToolResultErrorCode(ErrorCodeNotFound, "path", fmt.Sprintf("File or directory not found: %s", pathParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// A file must not change to or from a disallowed extension
+	if !info.IsDir() && (!p.extensionAllowed(fullPath) || !p.extensionAllowed(newPath)) {
+		result := NewToolResultErrorCode(ErrorCodeExtensionNotAllowed, "new_name", fmt.Sprintf("File extension is not allowed: %s", newName))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Never replace an existing sibling
+	if _, err := os.Lstat(newPath); err == nil {
+		result := NewToolResultErrorCode(ErrorCodeAlreadyExists, "new_name", fmt.Sprintf("Path already exists: %s", newParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Rename the entry
+	if err := os.Rename(fullPath, newPath); err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error renaming path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Return the result
+	result := NewToolResultJSON(RenameResult{
+		Path:    pathParam,
+		NewPath: newParam,
+	})
+	result.RequestID = request.RequestID
+	return result, nil
+}
@@ -0,0 +1,155 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// replaceInFile replaces occurrences of a string or regular expression in a
+// text file, at most count of them if count is given, and writes the file
+// back atomically if anything was replaced
+func (p *FilesystemProvider) replaceInFile(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the search parameter
+	search, ok := request.Params.Arguments["search"].(string)
+	if !ok || search == "" {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "search", "Search parameter is required and must be a non-empty string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the replacement parameter
+	replacement, ok := request.Params.Arguments["replacement"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "replacement", "Replacement parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the regex parameter (default to a literal search)
+	var matcher *regexp.Regexp
+	if regex, _ := request.Params.Arguments["regex"].(bool); regex {
+		var err error
+		matcher, err = regexp.Compile(search)
+		if err != nil {
+			result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "search", fmt.Sprintf("Invalid regular expression: %s", err.Error()))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+	}
+
+	// Get the count parameter (default to replacing every occurrence)
+	count := -1
+	if countParam, ok := request.Params.Arguments["count"].(float64); ok {
+		if countParam < 1 {
+			result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "count", "count must be at least 1")
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		count = int(countParam)
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Keep concurrent calls from modifying the file at the same time
+	unlock := p.pathLocks.lock(fullPath)
+	defer unlock()
+
+	// Only touch files with an allowed extension
+	if result := p.checkWrite(fullPath, pathParam, "path", -1); result != nil {
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Check if the path exists and is a file
+	info, errResult := statRegularFile(fullPath, pathParam, "path")
+	if errResult != nil {
+		errResult.RequestID = request.RequestID
+		return errResult, nil
+	}
+
+	// Read the file contents
+	data, err := readFileContext(ctx, fullPath)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error reading file: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Only text files can be edited
+	if bytes.IndexByte(data, 0) >= 0 {
+		result := NewToolResultErrorCode(ErrorCodeNotText, "path", fmt.Sprintf("File is not a text file: %s", pathParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	var replaced string
+	var replacements int
+	if matcher != nil {
+		replaced, replacements = replaceRegexp(string(data), matcher, replacement, count)
+	} else {
+		replacements = strings.Count(string(data), search)
+		if count >= 0 && replacements > count {
+			replacements = count
+		}
+		replaced = strings.Replace(string(data), search, replacement, count)
+	}
+
+	if result := p.checkWrite(fullPath, pathParam, "replacement", int64(len(replaced))); result != nil {
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Write the file back unless nothing was replaced
+	if replacements > 0 {
+		if err := writeFileAtomic(fullPath, []byte(replaced), info.Mode().Perm()); err != nil {
+			result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error writing file: %s", err.Error()))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+	}
+
+	// Return the result
+	result := NewToolResultJSON(ReplaceResult{
+		Path:         pathParam,
+		Replacements: replacements,
+	})
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// replaceRegexp replaces the first count matches of matcher in text, or all of
+// them if count is negative. $1-style references in replacement are expanded.
+// It returns the new text and the number of replacements.
+func replaceRegexp(text string, matcher *regexp.Regexp, replacement string, count int) (string, int) {
+	matches := matcher.FindAllStringSubmatchIndex(text, count)
+	if len(matches) == 0 {
+		return text, 0
+	}
+
+	var builder strings.Builder
+	last := 0
+	for _, match := range matches {
+		builder.WriteString(text[last:match[0]])
+		builder.Write(matcher.ExpandString(nil, replacement, text, match))
+		last = match[1]
+	}
+	builder.WriteString(text[last:])
+	return builder.String(), len(matches)
+}
@@ -0,0 +1,140 @@
+package mcp
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// textResultSchema returns the JSON Schema of a tool result with text content
+func textResultSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"type": map[string]interface{}{"const": "text"},
+			"text": map[string]interface{}{"type": "string"},
+		},
+		"required": []string{"type", "text"},
+	}
+}
+
+// jsonResultSchema returns the JSON Schema of a tool result whose JSON content
+// is a value of the same type as v
+func jsonResultSchema(v interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"type": map[string]interface{}{"const": "json"},
+			"json": typeSchema(reflect.TypeOf(v), map[reflect.Type]bool{}),
+		},
+		"required": []string{"type", "json"},
+	}
+}
+
+// oneOfResultSchemas returns the JSON Schema of a tool whose result takes one
+// of several shapes
+func oneOfResultSchemas(schemas ...map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"oneOf": schemas}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// typeSchema derives the JSON Schema of the encoding/json representation of
+// values of type t. seen holds the struct types being described, so that
+// recursive types end in an unconstrained schema instead of looping.
+func typeSchema(t reflect.Type, seen map[reflect.Type]bool) map[string]interface{} {
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Pointer:
+		return nullable(typeSchema(t.Elem(), seen))
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// Byte slices are encoded as base64 strings
+			return map[string]interface{}{"type": []string{"string", "null"}}
+		}
+		return nullable(map[string]interface{}{
+			"type":  "array",
+			"items": typeSchema(t.Elem(), seen),
+		})
+	case reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": typeSchema(t.Elem(), seen),
+		}
+	case reflect.Map:
+		return nullable(map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": typeSchema(t.Elem(), seen),
+		})
+	case reflect.Struct:
+		if seen[t] {
+			return map[string]interface{}{}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		properties := map[string]interface{}{}
+		required := []string{}
+		addStructFields(t, seen, properties, &required)
+		return map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+			"required":   required,
+		}
+	default:
+		// Interfaces hold any JSON value
+		return map[string]interface{}{}
+	}
+}
+
+// addStructFields adds the schemas of the encoded fields of struct type t to
+// properties, flattening embedded structs the way encoding/json does. Fields
+// without omitempty are always present and so are listed in required.
+func addStructFields(t reflect.Type, seen map[reflect.Type]bool, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			addStructFields(field.Type, seen, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = typeSchema(field.Type, seen)
+		if !strings.Contains(","+options+",", ",omitempty,") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// nullable extends a schema to also accept null, which encoding/json
+// produces for nil pointers, slices and maps
+func nullable(schema map[string]interface{}) map[string]interface{} {
+	if typ, ok := schema["type"].(string); ok {
+		schema["type"] = []string{typ, "null"}
+		return schema
+	}
+	return map[string]interface{}{"anyOf": []interface{}{schema, map[string]interface{}{"type": "null"}}}
+}
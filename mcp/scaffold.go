@@ -0,0 +1,212 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// scaffoldEntry is a single flattened node of a scaffold spec
+type scaffoldEntry struct {
+	path     string
+	fullPath string
+	isDir    bool
+	content  string
+	parent   int
+}
+
+// scaffold creates a directory structure from a nested spec
+func (p *FilesystemProvider) scaffold(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the spec parameter
+	specParam, ok := request.Params.Arguments["spec"].([]interface{})
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "spec", "Spec parameter is required and must be an array")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the dry_run parameter (default to false)
+	dryRun := false
+	if dryRunParam, ok := request.Params.Arguments["dry_run"].(bool); ok {
+		dryRun = dryRunParam
+	}
+
+	// Decode the spec into nodes
+	var nodes []ScaffoldNode
+	specJSON, err := json.Marshal(specParam)
+	if err == nil {
+		err = json.Unmarshal(specJSON, &nodes)
+	}
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "spec", fmt.Sprintf("Invalid spec: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the base path
+	if _, err := p.resolvePath(pathParam); err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Validate the whole spec before touching the filesystem
+	entries, err := p.flattenScaffold(pathParam, nodes, -1, nil)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "spec", fmt.Sprintf("Invalid spec: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	for _, entry := range entries {
+		if entry.isDir {
+			continue
+		}
+		if result := p.checkWrite(entry.fullPath, entry.path, "spec", int64(len(entry.content))); result != nil {
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+	}
+
+	scaffoldResult := ScaffoldResult{
+		Path:    pathParam,
+		DryRun:  dryRun,
+		Created: make([]string, 0, len(entries)),
+	}
+
+	// Materialize the entries, skipping anything beneath a failed directory
+	failed := make([]bool, len(entries))
+	for i, entry := range entries {
+		if entry.parent >= 0 && failed[entry.parent] {
+			failed[i] = true
+			continue
+		}
+
+		created, err := createScaffoldEntry(entry, dryRun)
+		if err != nil {
+			failed[i] = true
+			scaffoldResult.Failed = append(scaffoldResult.Failed, ScaffoldFailure{
+				Path:    entry.path,
+				Message: err.Error(),
+			})
+			continue
+		}
+
+		if created {
+			scaffoldResult.Created = append(scaffoldResult.Created, entry.path)
+		}
+	}
+
+	// Return the result
+	result := NewToolResultJSON(scaffoldResult)
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// flattenScaffold validates a list of nodes and flattens them in creation order
+func (p *FilesystemProvider) flattenScaffold(base string, nodes []ScaffoldNode, parent int, entries []scaffoldEntry) ([]scaffoldEntry, error) {
+	for _, node := range nodes {
+		if node.Name == "" {
+			return nil, fmt.Errorf("node under %s has no name", base)
+		}
+
+		// Node names must stay beneath their parent
+		cleanName := filepath.Clean(node.Name)
+		if filepath.IsAbs(cleanName) || cleanName == ".." || strings.HasPrefix(cleanName, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("node %s escapes its parent directory", node.Name)
+		}
+
+		nodePath := filepath.Join(base, cleanName)
+		fullPath, err := p.resolvePath(nodePath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", nodePath, err.Error())
+		}
+
+		isDir := len(node.Children) > 0
+		switch node.Type {
+		case "":
+		case "directory":
+			isDir = true
+		case "file":
+			if isDir {
+				return nil, fmt.Errorf("file %s cannot have children", nodePath)
+			}
+		default:
+			return nil, fmt.Errorf("node %s has unknown type: %s", nodePath, node.Type)
+		}
+
+		if isDir && node.Content != "" {
+			return nil, fmt.Errorf("directory %s cannot have content", nodePath)
+		}
+
+		entries = append(entries, scaffoldEntry{
+			path:     nodePath,
+			fullPath: fullPath,
+			isDir:    isDir,
+			content:  node.Content,
+			parent:   parent,
+		})
+
+		if isDir {
+			entries, err = p.flattenScaffold(nodePath, node.Children, len(entries)-1, entries)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return entries, nil
+}
+
+// createScaffoldEntry creates a single scaffold entry and reports whether anything was created.
+// In dry-run mode it only checks that the entry could be created.
+func createScaffoldEntry(entry scaffoldEntry, dryRun bool) (bool, error) {
+	info, err := os.Stat(entry.fullPath)
+	if err == nil {
+		if entry.isDir && info.IsDir() {
+			return false, nil
+		}
+		if entry.isDir {
+			return false, fmt.Errorf("a file already exists at this path")
+		}
+		return false, fmt.Errorf("path already exists")
+	}
+
+	if dryRun {
+		return true, nil
+	}
+
+	if entry.isDir {
+		if err := os.MkdirAll(entry.fullPath, 0755); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(entry.fullPath), 0755); err != nil {
+		return false, err
+	}
+
+	file, err := os.OpenFile(entry.fullPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return false, err
+	}
+	if _, err := file.WriteString(entry.content); err != nil {
+		file.Close()
+		return false, err
+	}
+	if err := file.Close(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
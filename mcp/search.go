@@ -0,0 +1,129 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// searchFiles recursively searches a directory for entries matching a glob pattern.
+// If emit is not nil, every match is also emitted as soon as it is found.
+func (p *FilesystemProvider) searchFiles(ctx context.Context, request CallToolRequest, emit EmitFunc) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the pattern parameter
+	pattern, ok := request.Params.Arguments["pattern"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "pattern", "Pattern parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "pattern", fmt.Sprintf("Invalid pattern: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the max_depth and max_results parameters (default to unlimited)
+	maxDepth := 0
+	if maxDepthParam, ok := request.Params.Arguments["max_depth"].(float64); ok {
+		maxDepth = int(maxDepthParam)
+	}
+	maxResults := 0
+	if maxResultsParam, ok := request.Params.Arguments["max_results"].(float64); ok {
+		maxResults = int(maxResultsParam)
+	}
+
+	// Get the on_permission_error parameter
+	policy, err := parsePermissionErrorPolicy(request.Params.Arguments)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, argumentOf(err), err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Check if the path exists and is a directory
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			result := NewToolResultErrorCode(ErrorCodeNotFound, "path", fmt.Sprintf("Directory not found: %s", pathParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing directory: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	if !info.IsDir() {
+		result := NewToolResultErrorCode(ErrorCodeNotDirectory, "path", fmt.Sprintf("Path is not a directory: %s", pathParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	searchResult := SearchResult{
+		Path:    pathParam,
+		Pattern: pattern,
+		Files:   make([]FileInfo, 0),
+	}
+
+	// Walk the tree collecting matching entries
+	inaccessible, err := walkTree(ctx, fullPath, pathParam, policy, func(entryPath, displayPath string, entry fs.DirEntry) error {
+		if entryPath == fullPath {
+			return nil
+		}
+
+		if matched, _ := filepath.Match(pattern, entry.Name()); matched {
+			// Only report entries that are still within the root
+			if _, err := p.resolvePath(displayPath); err == nil {
+				if maxResults > 0 && len(searchResult.Files) >= maxResults {
+					searchResult.Truncated = true
+					return filepath.SkipAll
+				}
+
+				fileInfo, err := entryFileInfo(entryPath, displayPath, entry)
+				if err == nil {
+					searchResult.Files = append(searchResult.Files, fileInfo)
+					if emit != nil {
+						if err := emit(fileInfo); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+
+		// Stop descending once the maximum depth is reached
+		if entry.IsDir() && maxDepth > 0 && walkDepth(fullPath, entryPath) >= maxDepth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error searching directory: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	searchResult.Inaccessible = inaccessible
+
+	// Return the result
+	result := NewToolResultJSON(searchResult)
+	result.RequestID = request.RequestID
+	return result, nil
+}
@@ -0,0 +1,102 @@
+package mcp
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// directorySortKeys lists the keys a directory listing can be sorted by
+var directorySortKeys = []string{"name", "size", "mod_time"}
+
+// directorySort describes the order of a directory listing
+type directorySort struct {
+	by        string
+	desc      bool
+	dirsFirst bool
+}
+
+// parseDirectorySort reads the sort_by, sort_desc and dirs_first arguments (default to ascending by name)
+func parseDirectorySort(arguments map[string]interface{}) (directorySort, error) {
+	order := directorySort{by: "name"}
+
+	if sortByParam, exists := arguments["sort_by"]; exists {
+		sortBy, ok := sortByParam.(string)
+		if !ok {
+			return order, newArgumentError("sort_by", "sort_by parameter must be a string")
+		}
+		switch sortBy {
+		case "name", "size", "mod_time":
+			order.by = sortBy
+		default:
+			return order, newArgumentError("sort_by", "unknown sort_by value: %s (expected one of %s)", sortBy, strings.Join(directorySortKeys, ", "))
+		}
+	}
+	if sortDescParam, ok := arguments["sort_desc"].(bool); ok {
+		order.desc = sortDescParam
+	}
+	if dirsFirstParam, ok := arguments["dirs_first"].(bool); ok {
+		order.dirsFirst = dirsFirstParam
+	}
+
+	return order, nil
+}
+
+// sortEntries orders directory entries in place. Entries that compare equal
+// by the sort key are ordered by name so pagination stays stable.
+func sortEntries(entries []os.DirEntry, order directorySort) {
+	// Look up the metadata once rather than on every comparison
+	infos := make(map[string]os.FileInfo, len(entries))
+	if order.by != "name" {
+		for _, entry := range entries {
+			if info, err := entry.Info(); err == nil {
+				infos[entry.Name()] = info
+			}
+		}
+	}
+
+	compare := func(a, b os.DirEntry) int {
+		switch order.by {
+		case "size":
+			if sizeA, sizeB := entrySize(infos, a), entrySize(infos, b); sizeA != sizeB {
+				if sizeA < sizeB {
+					return -1
+				}
+				return 1
+			}
+		case "mod_time":
+			if c := entryModTime(infos, a).Compare(entryModTime(infos, b)); c != 0 {
+				return c
+			}
+		}
+		return strings.Compare(a.Name(), b.Name())
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if order.dirsFirst && entries[i].IsDir() != entries[j].IsDir() {
+			return entries[i].IsDir()
+		}
+		c := compare(entries[i], entries[j])
+		if order.desc {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+// entrySize returns the size of an entry, or zero if it is unknown
+func entrySize(infos map[string]os.FileInfo, entry os.DirEntry) int64 {
+	if info, ok := infos[entry.Name()]; ok {
+		return info.Size()
+	}
+	return 0
+}
+
+// entryModTime returns the modification time of an entry, or the zero time if it is unknown
+func entryModTime(infos map[string]os.FileInfo, entry os.DirEntry) time.Time {
+	if info, ok := infos[entry.Name()]; ok {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
@@ -0,0 +1,290 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultStagingTTL is how long a staged write can be committed before it expires
+const DefaultStagingTTL = 15 * time.Minute
+
+// Defaults for the number of writes a provider keeps staged at once and
+// their total size, counting both the new and the original content
+const (
+	DefaultMaxStagedWrites = 100
+	DefaultMaxStagedBytes  = 256 << 20
+)
+
+// stagedWrite is content waiting to be committed to a file
+type stagedWrite struct {
+	path     string
+	fullPath string
+	data     []byte
+	existed  bool
+	original []byte
+	expires  time.Time
+}
+
+// size is the memory a staged write holds on to
+func (w stagedWrite) size() int64 {
+	return int64(len(w.data) + len(w.original))
+}
+
+// stagingArea holds staged writes keyed by token, bounded by both their
+// number and their total size. A zero limit means no limit.
+type stagingArea struct {
+	mu        sync.Mutex
+	maxWrites int
+	maxBytes  int64
+	bytes     int64
+	writes    map[string]stagedWrite
+}
+
+// add stores a staged write and returns its token. It fails if the write
+// would take the staging area over one of its limits.
+func (s *stagingArea) add(write stagedWrite) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked(time.Now())
+	if s.maxWrites > 0 && len(s.writes) >= s.maxWrites {
+		return "", fmt.Errorf("too many staged writes (limit %d)", s.maxWrites)
+	}
+	if s.maxBytes > 0 && s.bytes+write.size() > s.maxBytes {
+		return "", fmt.Errorf("staged writes would exceed %d bytes", s.maxBytes)
+	}
+	if s.writes == nil {
+		s.writes = make(map[string]stagedWrite)
+	}
+	token := uuid.New().String()
+	s.writes[token] = write
+	s.bytes += write.size()
+	return token, nil
+}
+
+// take removes and returns the staged write for a token if it has not expired
+func (s *stagingArea) take(token string) (stagedWrite, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked(time.Now())
+	write, ok := s.writes[token]
+	if ok {
+		delete(s.writes, token)
+		s.bytes -= write.size()
+	}
+	return write, ok
+}
+
+// prune drops every staged write that expired before now
+func (s *stagingArea) prune(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked(now)
+}
+
+// clear drops every staged write
+func (s *stagingArea) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.writes = nil
+	s.bytes = 0
+}
+
+// pruneLocked drops every expired staged write. The caller must hold the lock.
+func (s *stagingArea) pruneLocked(now time.Time) {
+	for token, write := range s.writes {
+		if now.After(write.expires) {
+			delete(s.writes, token)
+			s.bytes -= write.size()
+		}
+	}
+}
+
+// stageWrite stages new content for a file and returns a token and a diff against the current content
+func (p *FilesystemProvider) stageWrite(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the content parameter
+	contentParam, ok := request.Params.Arguments["content"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "content", "Content parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the encoding parameter (default to text)
+	encoding, err := parseEncoding(request.Params.Arguments, EncodingText, writeEncodings)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, argumentOf(err), err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Only touch files with an allowed extension
+	if result := p.checkWrite(fullPath, pathParam, "path", -1); result != nil {
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Decode the content if necessary
+	var data []byte
+	if encoding == EncodingBase64 {
+		data, err = base64.StdEncoding.DecodeString(contentParam)
+		if err != nil {
+			result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "content", fmt.Sprintf("Error decoding base64 content: %s", err.Error()))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+	} else {
+		data = []byte(contentParam)
+	}
+
+	if result := p.checkWrite(fullPath, pathParam, "content", int64(len(data))); result != nil {
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Read the current content, if any
+	var original []byte
+	existed := false
+	info, err := os.Stat(fullPath)
+	if err == nil {
+		if info.IsDir() {
+			result := NewToolResultErrorCode(ErrorCodeIsDirectory, "path", fmt.Sprintf("Path is a directory, not a file: %s", pathParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		original, err = readFileContext(ctx, fullPath)
+		if err != nil {
+			result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error reading file: %s", err.Error()))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		existed = true
+	} else if !os.IsNotExist(err) {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing file: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	expires := time.Now().Add(p.stagingTTL)
+	token, err := p.staged.add(stagedWrite{
+		path:     pathParam,
+		fullPath: fullPath,
+		data:     data,
+		existed:  existed,
+		original: original,
+		expires:  expires,
+	})
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeLimitExceeded, "", fmt.Sprintf("Cannot stage write: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	stageResult := StageWriteResult{
+		Path:      pathParam,
+		Token:     token,
+		ExpiresAt: expires,
+		NewFile:   !existed,
+		Changed:   !existed || !bytes.Equal(original, data),
+	}
+	if bytes.IndexByte(original, 0) >= 0 || bytes.IndexByte(data, 0) >= 0 {
+		stageResult.Binary = true
+	} else {
+		oldName := "a/" + filepath.ToSlash(pathParam)
+		if !existed {
+			oldName = "/dev/null"
+		}
+		stageResult.Diff = unifiedDiff(oldName, "b/"+filepath.ToSlash(pathParam), string(original), string(data))
+	}
+
+	// Return the result
+	result := NewToolResultJSON(stageResult)
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// commitWrite atomically writes staged content to its file
+func (p *FilesystemProvider) commitWrite(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the token parameter
+	token, ok := request.Params.Arguments["token"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "token", "Token parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	write, ok := p.staged.take(token)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeNotFound, "token", "Unknown or expired staging token")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Keep concurrent calls from modifying the file at the same time
+	unlock := p.pathLocks.lock(write.fullPath)
+	defer unlock()
+
+	// Refuse to overwrite changes made since the write was staged
+	current, err := readFileContext(ctx, write.fullPath)
+	if err != nil && !os.IsNotExist(err) {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "", fmt.Sprintf("Error reading file: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	if exists := err == nil; exists != write.existed || !bytes.Equal(current, write.original) {
+		result := NewToolResultErrorCode(ErrorCodeConflict, "token", fmt.Sprintf("File changed since the write was staged: %s", write.path))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Keep the permissions of the file being replaced
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(write.fullPath); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	// Create the parent directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(write.fullPath), 0755); err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "", fmt.Sprintf("Error creating directory: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	if err := writeFileAtomic(write.fullPath, write.data, perm); err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "", fmt.Sprintf("Error writing file: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Return success
+	result := NewToolResultText(fmt.Sprintf("Staged write committed: %s", write.path))
+	result.RequestID = request.RequestID
+	return result, nil
+}
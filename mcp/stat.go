@@ -0,0 +1,120 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// statPath returns metadata about a file or directory
+func (p *FilesystemProvider) statPath(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// A missing path is reported rather than treated as an error
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			result := NewToolResultJSON(FileStat{
+				Path:   pathParam,
+				Exists: false,
+			})
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Report where a symbolic link points, and describe what it points to
+	// unless the link is broken
+	var symlinkTarget string
+	if info.Mode()&os.ModeSymlink != 0 {
+		symlinkTarget, err = os.Readlink(fullPath)
+		if err != nil {
+			result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error reading link: %s", err.Error()))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		if targetInfo, err := os.Stat(fullPath); err == nil {
+			info = targetInfo
+		}
+	}
+
+	fileStat := FileStat{
+		Path:    pathParam,
+		Exists:  true,
+		Name:    info.Name(),
+		Size:    info.Size(),
+		IsDir:   info.IsDir(),
+		ModTime: info.ModTime(),
+		Mode:    fmt.Sprintf("%04o", info.Mode().Perm()),
+
+		SymlinkTarget: symlinkTarget,
+	}
+
+	// Sniff the content of regular files; failing to do so is not an error
+	if info.Mode().IsRegular() {
+		if mimeType, err := detectFileMimeType(fullPath); err == nil {
+			fileStat.MimeType = mimeType
+		}
+	}
+
+	// Return the result
+	result := NewToolResultJSON(fileStat)
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// pathExists reports whether a path exists and whether it is a regular file
+// or a directory. Unlike stat, it returns nothing else about the path.
+func (p *FilesystemProvider) pathExists(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// A missing path is an answer, not an error
+	existsResult := ExistsResult{Path: pathParam}
+	info, err := os.Stat(fullPath)
+	switch {
+	case err == nil:
+		existsResult.Exists = true
+		existsResult.IsDir = info.IsDir()
+		existsResult.IsFile = info.Mode().IsRegular()
+	case !os.IsNotExist(err):
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Return the result
+	result := NewToolResultJSON(existsResult)
+	result.RequestID = request.RequestID
+	return result, nil
+}
@@ -0,0 +1,84 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+)
+
+// StreamResource opens a file resource for streaming. The caller must close
+// the returned stream, which holds an I/O slot until then. Only the file
+// resource can be streamed.
+func (p *FilesystemProvider) StreamResource(ctx context.Context, resourceName string, params map[string]interface{}) (*ResourceStream, error) {
+	if resourceName != "file" {
+		return nil, fmt.Errorf("resource %s cannot be streamed", resourceName)
+	}
+
+	release, err := p.acquireIOSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := p.openStream(ctx, params)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	stream.Reader = struct {
+		io.Reader
+		io.Closer
+	}{stream.Reader, &slotCloser{Closer: stream.Reader, release: release}}
+	return stream, nil
+}
+
+// openStream opens the file resource for StreamResource
+func (p *FilesystemProvider) openStream(ctx context.Context, params map[string]interface{}) (*ResourceStream, error) {
+	// Get the path parameter
+	pathParam, ok := params["path"].(string)
+	if !ok || pathParam == "" {
+		return nil, fmt.Errorf("path parameter is required and must be a string")
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+
+	// Only touch files with an allowed extension
+	if !p.extensionAllowed(fullPath) {
+		return nil, fmt.Errorf("file extension is not allowed: %s", pathParam)
+	}
+
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, fmt.Errorf("path is a directory, not a file: %s", pathParam)
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(fullPath))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	return &ResourceStream{
+		Name:        info.Name(),
+		ContentType: contentType,
+		Size:        info.Size(),
+		Reader: struct {
+			io.Reader
+			io.Closer
+		}{newContextReader(ctx, file), file},
+	}, nil
+}
@@ -0,0 +1,176 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// structuredFormats lists the formats understood by filesystem.read-structured
+var structuredFormats = []string{"json", "yaml", "toml"}
+
+// readStructured reads a JSON, YAML or TOML file and returns its parsed content
+func (p *FilesystemProvider) readStructured(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the format parameter (default to detecting it from the extension)
+	format := ""
+	if formatParam, ok := request.Params.Arguments["format"].(string); ok {
+		format = formatParam
+	}
+	if format == "" {
+		format = structuredFormatFromExt(pathParam)
+		if format == "" {
+			result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "format", fmt.Sprintf("Cannot detect the format of %s; pass the format parameter", pathParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Check if the path exists and is a file
+	if _, errResult := statRegularFile(fullPath, pathParam, "path"); errResult != nil {
+		errResult.RequestID = request.RequestID
+		return errResult, nil
+	}
+
+	// Read the file contents
+	data, err := readFileContext(ctx, fullPath)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error reading file: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	parsed, err := parseStructured(format, data)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeParseError, "path", fmt.Sprintf("Error parsing %s: %s", pathParam, err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	structuredContent := StructuredContent{
+		Path:   pathParam,
+		Format: format,
+		Data:   parsed,
+	}
+
+	// Return the result
+	result := NewToolResultJSON(structuredContent)
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// structuredFormatFromExt returns the structured format implied by a file extension
+func structuredFormatFromExt(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".toml":
+		return "toml"
+	default:
+		return ""
+	}
+}
+
+// parseStructured parses data in the given format into JSON-compatible values.
+// Parse errors include the line and column of the problem where known.
+func parseStructured(format string, data []byte) (interface{}, error) {
+	var parsed interface{}
+	switch format {
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&parsed); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				// The offset points just past the offending byte
+				line, column := offsetToLineColumn(data, syntaxErr.Offset-1)
+				return nil, fmt.Errorf("line %d, column %d: %s", line, column, syntaxErr.Error())
+			}
+			return nil, err
+		}
+		if decoder.More() {
+			return nil, errors.New("unexpected data after the top-level value")
+		}
+		return parsed, nil
+
+	case "yaml":
+		// yaml errors already carry the line number
+		if err := yaml.Unmarshal(data, &parsed); err != nil {
+			return nil, err
+		}
+		return normalizeYAML(parsed), nil
+
+	case "toml":
+		var table map[string]interface{}
+		if err := toml.Unmarshal(data, &table); err != nil {
+			var parseErr toml.ParseError
+			if errors.As(err, &parseErr) {
+				line, column := offsetToLineColumn(data, int64(parseErr.Position.Start))
+				return nil, fmt.Errorf("line %d, column %d: %s", line, column, parseErr.Message)
+			}
+			return nil, err
+		}
+		return table, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// offsetToLineColumn returns the 1-based line and column of the byte at offset
+func offsetToLineColumn(data []byte, offset int64) (int, int) {
+	offset = min(max(offset, 0), int64(len(data)))
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// normalizeYAML converts the maps with non-string keys that YAML allows into
+// string-keyed maps so the value can be encoded as JSON
+func normalizeYAML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeYAML(item)
+		}
+		return v
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[fmt.Sprint(key)] = normalizeYAML(item)
+		}
+		return converted
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeYAML(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,121 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// createSymlink creates a symbolic link at link_path pointing to target. Both
+// are confined to the root, and the link is stored relative to its own
+// directory so it keeps working wherever the root is mounted.
+func (p *FilesystemProvider) createSymlink(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the target parameter
+	targetParam, ok := request.Params.Arguments["target"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "target", "Target parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the link_path parameter
+	linkParam, ok := request.Params.Arguments["link_path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "link_path", "link_path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the paths
+	targetPath, err := p.resolvePath(targetParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "target", fmt.Sprintf("Invalid target: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	linkPath, err := p.resolvePath(linkParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "link_path", fmt.Sprintf("Invalid link path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// A link into another mount would lead outside the root of its own mount
+	if len(p.mounts) > 0 {
+		targetMount, _, _ := strings.Cut(targetParam, ":")
+		linkMount, _, _ := strings.Cut(linkParam, ":")
+		if targetMount != linkMount {
+			result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "target", fmt.Sprintf("Target must be in the same mount as the link: %s", targetParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+	}
+
+	// Keep concurrent calls from modifying either path at the same time
+	unlock := p.pathLocks.lockAll(targetPath, linkPath)
+	defer unlock()
+
+	// A link to a file must not give access to a file with a disallowed
+	// extension through an allowed name, or the other way round
+	if info, err := os.Stat(targetPath); err != nil || !info.IsDir() {
+		if !p.extensionAllowed(targetPath) || !p.extensionAllowed(linkPath) {
+			result := NewToolResultErrorCode(ErrorCodeExtensionNotAllowed, "link_path", fmt.Sprintf("File extension is not allowed: %s", linkParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+	}
+
+	// Never replace an existing entry
+	if _, err := os.Lstat(linkPath); err == nil {
+		result := NewToolResultErrorCode(ErrorCodeAlreadyExists, "link_path", fmt.Sprintf("Path already exists: %s", linkParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Create the parent directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(linkPath), 0755); err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "link_path", fmt.Sprintf("Error creating directory: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Point the link at the target relative to the link's directory. The
+	// link is followed from the real directory it ends up in, which differs
+	// from the one named by link_path if a directory on the way is a link.
+	linkDir, err := resolveSymlinks(filepath.Dir(linkPath))
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "link_path", fmt.Sprintf("Error resolving link path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	targetDir, err := resolveSymlinks(filepath.Dir(targetPath))
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "target", fmt.Sprintf("Error resolving target: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	linkTarget, err := filepath.Rel(linkDir, filepath.Join(targetDir, filepath.Base(targetPath)))
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "target", fmt.Sprintf("Invalid target: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	if err := os.Symlink(linkTarget, linkPath); err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "link_path", fmt.Sprintf("Error creating symbolic link: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Return the result; the target may not exist yet
+	_, statErr := os.Stat(linkPath)
+	result := NewToolResultJSON(SymlinkInfo{
+		Path:       linkParam,
+		Target:     linkTarget,
+		Broken:     statErr != nil,
+		InsideRoot: true,
+	})
+	result.RequestID = request.RequestID
+	return result, nil
+}
@@ -0,0 +1,96 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// listSymlinks recursively lists the symbolic links in a directory
+func (p *FilesystemProvider) listSymlinks(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the on_permission_error parameter
+	policy, err := parsePermissionErrorPolicy(request.Params.Arguments)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, argumentOf(err), err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Check if the path exists and is a directory
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			result := NewToolResultErrorCode(ErrorCodeNotFound, "path", fmt.Sprintf("Directory not found: %s", pathParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing directory: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	if !info.IsDir() {
+		result := NewToolResultErrorCode(ErrorCodeNotDirectory, "path", fmt.Sprintf("Path is not a directory: %s", pathParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	symlinkList := SymlinkList{
+		Path:     pathParam,
+		Symlinks: make([]SymlinkInfo, 0),
+	}
+
+	// Walk the tree collecting symbolic links
+	inaccessible, err := walkTree(ctx, fullPath, pathParam, policy, func(entryPath, displayPath string, entry fs.DirEntry) error {
+		if entry.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+
+		target, err := os.Readlink(entryPath)
+		if err != nil {
+			return err
+		}
+
+		// A link is broken if following it fails
+		_, statErr := os.Stat(entryPath)
+
+		// resolvePath rejects links whose real location is outside the root
+		_, resolveErr := p.resolvePath(displayPath)
+
+		symlinkList.Symlinks = append(symlinkList.Symlinks, SymlinkInfo{
+			Path:       displayPath,
+			Target:     target,
+			Broken:     statErr != nil,
+			InsideRoot: resolveErr == nil,
+		})
+		return nil
+	})
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error scanning directory: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	symlinkList.Inaccessible = inaccessible
+
+	// Return the result
+	result := NewToolResultJSON(symlinkList)
+	result.RequestID = request.RequestID
+	return result, nil
+}
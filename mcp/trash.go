@@ -0,0 +1,206 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Names of the files kept in each entry of the trash directory
+const (
+	trashInfoFile = "info.json"
+	trashDataName = "data"
+)
+
+// trashInfo records where a trashed item came from so it can be restored
+type trashInfo struct {
+	Path      string    `json:"path"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// moveToTrash moves fullPath into a new entry of the trash directory, recording
+// pathParam as its original location. It returns the entry.
+func (p *FilesystemProvider) moveToTrash(fullPath, pathParam string) (TrashedItem, error) {
+	deletedAt := time.Now().UTC()
+	id := deletedAt.Format("20060102T150405.000000000Z") + "-" + uuid.New().String()[:8]
+	entryDir := filepath.Join(p.TrashDir, id)
+
+	if err := os.MkdirAll(entryDir, 0700); err != nil {
+		return TrashedItem{}, err
+	}
+
+	info, err := json.Marshal(trashInfo{Path: pathParam, DeletedAt: deletedAt})
+	if err != nil {
+		return TrashedItem{}, err
+	}
+	if err := os.WriteFile(filepath.Join(entryDir, trashInfoFile), info, 0600); err != nil {
+		os.RemoveAll(entryDir)
+		return TrashedItem{}, err
+	}
+
+	if err := movePath(fullPath, filepath.Join(entryDir, trashDataName)); err != nil {
+		os.RemoveAll(entryDir)
+		return TrashedItem{}, err
+	}
+
+	return TrashedItem{ID: id, Path: pathParam, DeletedAt: deletedAt}, nil
+}
+
+// restoreFromTrash moves a trashed item back to the location it was deleted from
+func (p *FilesystemProvider) restoreFromTrash(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	if p.TrashDir == "" {
+		result := NewToolResultErrorCode(ErrorCodeUnavailable, "", "The trash is not enabled on this server")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the id parameter
+	id, ok := request.Params.Arguments["id"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "id", "ID parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	if id == "" || id != filepath.Base(id) || !filepath.IsLocal(id) {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "id", fmt.Sprintf("Invalid trash ID: %s", id))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Read where the item came from
+	entryDir := filepath.Join(p.TrashDir, id)
+	data, err := os.ReadFile(filepath.Join(entryDir, trashInfoFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			result := NewToolResultErrorCode(ErrorCodeNotFound, "id", fmt.Sprintf("Trashed item not found: %s", id))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "id", fmt.Sprintf("Error reading trash: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	var info trashInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "id", fmt.Sprintf("Error reading trash: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// The original location must still be within the root and free
+	fullPath, err := p.resolvePath(info.Path)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "id", fmt.Sprintf("Invalid original path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Keep concurrent calls from modifying the item or its original location at the same time
+	unlock := p.pathLocks.lockAll(entryDir, fullPath)
+	defer unlock()
+	if _, err := os.Lstat(entryDir); err != nil {
+		result := NewToolResultErrorCode(ErrorCodeNotFound, "id", fmt.Sprintf("Trashed item not found: %s", id))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	if _, err := os.Lstat(fullPath); err == nil {
+		result := NewToolResultErrorCode(ErrorCodeAlreadyExists, "id", fmt.Sprintf("Path already exists: %s", info.Path))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Create the parent directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "id", fmt.Sprintf("Error creating directory: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	if err := movePath(filepath.Join(entryDir, trashDataName), fullPath); err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "id", fmt.Sprintf("Error restoring item: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	os.RemoveAll(entryDir)
+
+	// Return success
+	result := NewToolResultText(fmt.Sprintf("Restored: %s", info.Path))
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// movePath moves a file or directory, copying it and removing the original
+// when it has to cross devices
+func movePath(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if err := copyTree(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyTree copies a file or directory tree, keeping the permissions of its
+// entries and recreating symbolic links rather than following them
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case entry.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			return copyFileContents(context.Background(), path, target, info.Mode().Perm())
+		}
+	})
+}
+
+// copyFileContents copies a regular file to dst, which must not exist yet,
+// with the given permissions. The copy stops once ctx is done.
+func copyFileContents(ctx context.Context, src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// Never overwrite an existing file
+	if _, err := os.Lstat(dst); err == nil {
+		return &os.PathError{Op: "copy", Path: dst, Err: os.ErrExist}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	// Copy through a temporary file so an interrupted copy leaves no
+	// truncated file behind
+	return writeAtomic(dst, newContextReader(ctx, in), perm)
+}
@@ -0,0 +1,81 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// MaxTreeNodes is the largest number of entries a recursive listing returns.
+// Larger trees are cut off and reported as truncated.
+const MaxTreeNodes = 10000
+
+// buildTree lists the tree beneath dir, whose path as seen by the client is
+// displayDir, down to maxDepth levels (0 means no limit). Directories are
+// always included so that the files below them can be reached; pattern and
+// entryType=file filter the files, entryType=dir leaves them out. Symbolic
+// links are listed but never followed, so link loops cannot recurse forever.
+// Subdirectories that cannot be read for lack of permission are handled
+// according to policy.
+func buildTree(ctx context.Context, dir, displayDir string, depth, maxDepth int, order directorySort, pattern, entryType string, policy PermissionErrorPolicy, tree *DirectoryTree) ([]TreeNode, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		// Only the top directory failing to be read fails the listing
+		if depth == 1 {
+			return nil, err
+		}
+		if errors.Is(err, fs.ErrPermission) {
+			return nil, policy.apply(displayDir, &tree.Inaccessible)
+		}
+		return nil, nil
+	}
+	sortEntries(entries, order)
+
+	nodes := make([]TreeNode, 0, len(entries))
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if !entry.IsDir() {
+			if entryType == "dir" {
+				continue
+			}
+			if pattern != "" {
+				if matched, _ := filepath.Match(pattern, entry.Name()); !matched {
+					continue
+				}
+			}
+		}
+
+		if tree.Nodes >= MaxTreeNodes {
+			tree.Truncated = true
+			break
+		}
+
+		entryPath := filepath.Join(dir, entry.Name())
+		fileInfo, err := entryFileInfo(entryPath, filepath.Join(displayDir, entry.Name()), entry)
+		if err != nil {
+			continue
+		}
+		tree.Nodes++
+		node := TreeNode{FileInfo: fileInfo}
+
+		// Descend into subdirectories until the depth limit; ones that
+		// cannot be read are listed without children
+		if entry.IsDir() && (maxDepth == 0 || depth < maxDepth) {
+			children, err := buildTree(ctx, entryPath, fileInfo.Path, depth+1, maxDepth, order, pattern, entryType, policy, tree)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			if err != nil {
+				return nil, err
+			}
+			node.Children = children
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
@@ -0,0 +1,240 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// diffPaths compares two files, returning a unified diff of their text, or two
+// directory trees, returning the files added, removed and changed between them
+func (p *FilesystemProvider) diffPaths(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameters
+	pathA, ok := request.Params.Arguments["path_a"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path_a", "path_a parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	pathB, ok := request.Params.Arguments["path_b"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path_b", "path_b parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the on_permission_error parameter, which applies to the entries of compared directories
+	policy, err := parsePermissionErrorPolicy(request.Params.Arguments)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, argumentOf(err), err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the paths
+	fullPathA, err := p.resolvePath(pathA)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path_a", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	fullPathB, err := p.resolvePath(pathB)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path_b", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Check that both paths exist and are of the same kind
+	infoA, err := os.Stat(fullPathA)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path_a", fmt.Sprintf("Error accessing path: %s", err.Error()))
+		if os.IsNotExist(err) {
+			result = NewToolResultErrorCode(ErrorCodeNotFound, "path_a", fmt.Sprintf("File or directory not found: %s", pathA))
+		}
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	infoB, err := os.Stat(fullPathB)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path_b", fmt.Sprintf("Error accessing path: %s", err.Error()))
+		if os.IsNotExist(err) {
+			result = NewToolResultErrorCode(ErrorCodeNotFound, "path_b", fmt.Sprintf("File or directory not found: %s", pathB))
+		}
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	if infoA.IsDir() != infoB.IsDir() {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path_b", "Cannot compare a file with a directory")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Two files must both be readable under the extension and size limits
+	if !infoA.IsDir() {
+		for _, file := range []struct {
+			argument, fullPath, path string
+			size                     int64
+		}{
+			{"path_a", fullPathA, pathA, infoA.Size()},
+			{"path_b", fullPathB, pathB, infoB.Size()},
+		} {
+			if !p.extensionAllowed(file.fullPath) {
+				result := NewToolResultErrorCode(ErrorCodeExtensionNotAllowed, file.argument, fmt.Sprintf("File extension is not allowed: %s", file.path))
+				result.RequestID = request.RequestID
+				return result, nil
+			}
+			if p.MaxReadBytes > 0 && file.size > p.MaxReadBytes {
+				result := NewToolResultErrorCode(ErrorCodeFileTooLarge, file.argument, fmt.Sprintf("File is larger than the %d byte read limit: %s", p.MaxReadBytes, file.path))
+				result.RequestID = request.RequestID
+				return result, nil
+			}
+		}
+	}
+
+	diffResult := DiffResult{PathA: pathA, PathB: pathB}
+	if infoA.IsDir() {
+		diffResult.Type = "directory"
+		err = p.diffTrees(ctx, fullPathA, fullPathB, policy, &diffResult)
+	} else {
+		diffResult.Type = "file"
+		err = p.diffFiles(ctx, fullPathA, fullPathB, &diffResult)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "", err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Return the result
+	result := NewToolResultJSON(diffResult)
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// diffFiles compares two files into diffResult. Binary files are only
+// reported as differing, without a diff.
+func (p *FilesystemProvider) diffFiles(ctx context.Context, fullPathA, fullPathB string, diffResult *DiffResult) error {
+	dataA, err := readFileContext(ctx, fullPathA)
+	if err != nil {
+		return fmt.Errorf("Error reading file: %w", err)
+	}
+	dataB, err := readFileContext(ctx, fullPathB)
+	if err != nil {
+		return fmt.Errorf("Error reading file: %w", err)
+	}
+
+	diffResult.Identical = bytes.Equal(dataA, dataB)
+	if diffResult.Identical {
+		return nil
+	}
+	if bytes.IndexByte(dataA, 0) >= 0 || bytes.IndexByte(dataB, 0) >= 0 {
+		diffResult.Binary = true
+		return nil
+	}
+	diffResult.Diff = unifiedDiff("a/"+filepath.ToSlash(diffResult.PathA), "b/"+filepath.ToSlash(diffResult.PathB), string(dataA), string(dataB))
+	return nil
+}
+
+// diffTrees compares the files beneath two directories into diffResult.
+// Files present on both sides are changed if their sizes or SHA-256 digests
+// differ. Symbolic links and files with extensions that are not allowed are
+// left out, as are entries that cannot be read for lack of permission, which
+// are handled according to policy.
+func (p *FilesystemProvider) diffTrees(ctx context.Context, fullPathA, fullPathB string, policy PermissionErrorPolicy, diffResult *DiffResult) error {
+	filesA, inaccessibleA, err := p.treeFiles(ctx, fullPathA, diffResult.PathA, policy)
+	if err != nil {
+		return fmt.Errorf("Error walking directory: %w", err)
+	}
+	filesB, inaccessibleB, err := p.treeFiles(ctx, fullPathB, diffResult.PathB, policy)
+	if err != nil {
+		return fmt.Errorf("Error walking directory: %w", err)
+	}
+	diffResult.Inaccessible = append(inaccessibleA, inaccessibleB...)
+
+	diffResult.Added = []string{}
+	diffResult.Removed = []string{}
+	diffResult.Changed = []string{}
+	for rel, sizeA := range filesA {
+		sizeB, ok := filesB[rel]
+		if !ok {
+			diffResult.Removed = append(diffResult.Removed, rel)
+			continue
+		}
+		if sizeA != sizeB {
+			diffResult.Changed = append(diffResult.Changed, rel)
+			continue
+		}
+
+		hashA, _, err := hashFile(ctx, filepath.Join(fullPathA, rel), sha256.New())
+		if errors.Is(err, fs.ErrPermission) {
+			if err := policy.apply(filepath.Join(diffResult.PathA, rel), &diffResult.Inaccessible); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return fmt.Errorf("Error hashing file: %w", err)
+		}
+		hashB, _, err := hashFile(ctx, filepath.Join(fullPathB, rel), sha256.New())
+		if errors.Is(err, fs.ErrPermission) {
+			if err := policy.apply(filepath.Join(diffResult.PathB, rel), &diffResult.Inaccessible); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return fmt.Errorf("Error hashing file: %w", err)
+		}
+		if hashA != hashB {
+			diffResult.Changed = append(diffResult.Changed, rel)
+		}
+	}
+	for rel := range filesB {
+		if _, ok := filesA[rel]; !ok {
+			diffResult.Added = append(diffResult.Added, rel)
+		}
+	}
+
+	slices.Sort(diffResult.Added)
+	slices.Sort(diffResult.Removed)
+	slices.Sort(diffResult.Changed)
+	slices.Sort(diffResult.Inaccessible)
+	diffResult.Identical = len(diffResult.Added) == 0 && len(diffResult.Removed) == 0 && len(diffResult.Changed) == 0
+	return nil
+}
+
+// treeFiles returns the sizes of the regular files beneath root, keyed by
+// their slash-separated path relative to root, and under the report policy
+// the directories that could not be read, as seen from displayRoot
+func (p *FilesystemProvider) treeFiles(ctx context.Context, root, displayRoot string, policy PermissionErrorPolicy) (map[string]int64, []string, error) {
+	files := make(map[string]int64)
+	inaccessible, err := walkTree(ctx, root, displayRoot, policy, func(path, _ string, entry fs.DirEntry) error {
+		if !entry.Type().IsRegular() || !p.extensionAllowed(path) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = info.Size()
+		return nil
+	})
+	return files, inaccessible, err
+}
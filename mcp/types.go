@@ -1,16 +1,89 @@
 package mcp
 
 import (
+	"context"
+	"io"
 	"strings"
 	"time"
 )
 
-// Provider interface defines the methods that a provider must implement
+// Provider interface defines the methods that a provider must implement.
+//
+// CallTool and LoadResource report failures in one of two ways. Failures the
+// client can act on, such as invalid arguments, missing files or denied
+// permissions, are returned as a result with status "error" and a nil error;
+// the server sends them in the response body. Failures of the provider itself, such
+// as an unexpected I/O error or a cancelled context, are returned as a non-nil
+// error, preferably an *InternalError; the server answers those with HTTP 500
+// (504 if the request timed out) and never sends a partial result.
 type Provider interface {
 	GetName() string
 	GetInfo() ProviderInfo
-	CallTool(toolName string, request CallToolRequest) (*CallToolResult, error)
-	LoadResource(resourceName string, request LoadResourceRequest) (*LoadResourceResult, error)
+	CallTool(ctx context.Context, toolName string, request CallToolRequest) (*CallToolResult, error)
+	LoadResource(ctx context.Context, resourceName string, request LoadResourceRequest) (*LoadResourceResult, error)
+
+	// HealthCheck reports whether the provider can serve requests. It must be
+	// cheap enough to run on every readiness probe.
+	HealthCheck() error
+}
+
+// NoHealthCheck can be embedded by providers that have nothing to check.
+// Its HealthCheck always succeeds.
+type NoHealthCheck struct{}
+
+// HealthCheck always returns nil
+func (NoHealthCheck) HealthCheck() error { return nil }
+
+// ProviderLifecycle is implemented by providers that hold resources or run
+// background work. The server calls Start before serving requests and Stop
+// when it shuts down.
+type ProviderLifecycle interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// EmitFunc receives a partial result of a streaming tool call. Returning an
+// error aborts the call.
+type EmitFunc func(chunk interface{}) error
+
+// StreamingProvider is implemented by providers that can emit partial results
+// while a tool call is running
+type StreamingProvider interface {
+	CallToolStream(ctx context.Context, toolName string, request CallToolRequest, emit EmitFunc) (*CallToolResult, error)
+}
+
+// ResourceStreamer is implemented by providers whose resources can be streamed
+// as raw bytes instead of being loaded into a JSON response
+type ResourceStreamer interface {
+	StreamResource(ctx context.Context, resourceName string, params map[string]interface{}) (*ResourceStream, error)
+}
+
+// ResourceStream is an open resource ready to be copied to a client
+type ResourceStream struct {
+	Name        string
+	ContentType string
+	Size        int64
+	Reader      io.ReadCloser
+}
+
+// FileDownloader is implemented by providers whose files can be served with
+// support for byte ranges, for resumable downloads
+type FileDownloader interface {
+	DownloadFile(ctx context.Context, path string) (*FileDownload, error)
+}
+
+// FileDownload is an open file ready to be served to a client
+type FileDownload struct {
+	Name    string
+	ModTime time.Time
+	ETag    string
+	Content io.ReadSeekCloser
+}
+
+// FileUploader is implemented by providers that can write a file from a
+// stream of raw bytes, without holding the content in memory
+type FileUploader interface {
+	UploadFile(ctx context.Context, path string, body io.Reader) (*CallToolResult, error)
 }
 
 // ServerInfo represents information about the MCP server
@@ -26,6 +99,12 @@ type ProviderInfo struct {
 	Description string         `json:"description"`
 	Tools       []ToolInfo     `json:"tools"`
 	Resources   []ResourceInfo `json:"resources"`
+
+	// Mounts lists the named roots paths are addressed by, for providers that have them
+	Mounts []string `json:"mounts,omitempty"`
+
+	// ResourceTemplates lists the URI templates resources can be addressed by
+	ResourceTemplates []ResourceTemplate `json:"resource_templates,omitempty"`
 }
 
 // ToolInfo represents information about a tool
@@ -35,6 +114,10 @@ type ToolInfo struct {
 	Description string      `json:"description"`
 	Parameters  interface{} `json:"parameters,omitempty"`
 	Returns     interface{} `json:"returns,omitempty"`
+
+	// ReadOnly marks tools that do not modify anything, which may therefore
+	// also be called with GET /v1/tool/:id
+	ReadOnly bool `json:"read_only,omitempty"`
 }
 
 // ResourceInfo represents information about a resource
@@ -45,17 +128,60 @@ type ResourceInfo struct {
 	Parameters  interface{} `json:"parameters,omitempty"`
 }
 
+// ResourceTemplate represents a URI template, like the resourceTemplates of
+// the MCP specification, addressing instances of a resource. A URI with the
+// template's scheme is loaded from ResourceID, with the URI as its uri
+// parameter.
+type ResourceTemplate struct {
+	URITemplate string `json:"uri_template"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ResourceID  string `json:"resource_id"`
+}
+
+// InitializeRequest is the request to the initialize endpoint
+type InitializeRequest struct {
+	ProtocolVersion string `json:"protocol_version"`
+}
+
+// InitializeResponse is the response from the initialize endpoint
+type InitializeResponse struct {
+	ProtocolVersion string       `json:"protocol_version"`
+	ServerInfo      ServerInfo   `json:"server_info"`
+	Capabilities    Capabilities `json:"capabilities"`
+}
+
+// Capabilities lists the features a server supports
+type Capabilities struct {
+	Tools         bool `json:"tools"`
+	Resources     bool `json:"resources"`
+	Streaming     bool `json:"streaming"`
+	Subscriptions bool `json:"subscriptions"`
+}
+
 // DiscoverResponse is the response from the discover endpoint
 type DiscoverResponse struct {
 	ServerInfo ServerInfo     `json:"server_info"`
 	Providers  []ProviderInfo `json:"providers"`
 }
 
+// ProviderList is the response of the providers endpoint
+type ProviderList struct {
+	Providers []string `json:"providers"`
+
+	// Disabled lists the registered providers that are out of service
+	Disabled []string `json:"disabled,omitempty"`
+}
+
 // CallToolRequest is the request to call a tool
 type CallToolRequest struct {
 	ToolID    string         `json:"tool_id"`
 	RequestID string         `json:"request_id"`
 	Params    CallToolParams `json:"params"`
+
+	// IdempotencyKey, when set, makes retries of the call with the same key
+	// return the result of the first call instead of calling the tool again
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
 }
 
 // CallToolParams contains the parameters for a tool call
@@ -84,20 +210,49 @@ type LoadResourceResult struct {
 	Status    string      `json:"status"`
 	Content   interface{} `json:"content,omitempty"`
 	Error     *ErrorInfo  `json:"error,omitempty"`
+
+	// ETag identifies the version of the loaded content, for resources that support it
+	ETag string `json:"etag,omitempty"`
+
+	// Raw, when set, holds the resource as bytes for HTTP clients that
+	// asked for the content itself rather than the JSON envelope
+	Raw *RawContent `json:"-"`
+}
+
+// RawContent is a resource's content as bytes with its detected media type
+type RawContent struct {
+	ContentType string
+	Data        []byte
 }
 
 // ErrorInfo represents error information
 type ErrorInfo struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
 }
 
 // ErrorResponse is a generic error response
 type ErrorResponse struct {
-	Error   string `json:"error"`
+	Error   string       `json:"error"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// FieldError describes why the value of a single argument is invalid
+type FieldError struct {
+	Field   string `json:"field"`
 	Message string `json:"message"`
 }
 
+// HealthResponse represents the response of the health and readiness endpoints
+type HealthResponse struct {
+	Status string `json:"status"`
+
+	// Failures maps the name of each provider whose health check failed to its error
+	Failures map[string]string `json:"failures,omitempty"`
+}
+
 // FileInfo represents information about a file
 type FileInfo struct {
 	Name    string    `json:"name"`
@@ -105,24 +260,437 @@ type FileInfo struct {
 	Size    int64     `json:"size"`
 	IsDir   bool      `json:"is_dir"`
 	ModTime time.Time `json:"mod_time"`
+
+	// IsSymlink marks symbolic links, which are listed without following
+	// them; SymlinkTarget is where they point
+	IsSymlink     bool   `json:"is_symlink"`
+	SymlinkTarget string `json:"symlink_target,omitempty"`
+}
+
+// FileStat represents metadata about a single path
+type FileStat struct {
+	Path    string    `json:"path"`
+	Exists  bool      `json:"exists"`
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	IsDir   bool      `json:"is_dir"`
+	ModTime time.Time `json:"mod_time"`
+	Mode    string    `json:"mode"`
+
+	// MimeType is detected from the content of regular files
+	MimeType string `json:"mime_type,omitempty"`
+
+	// SymlinkTarget is where the path points if it is a symbolic link. The
+	// other fields then describe the target, unless the link is broken.
+	SymlinkTarget string `json:"symlink_target,omitempty"`
+}
+
+// ExistsResult represents whether a path exists and what kind of entry it is
+type ExistsResult struct {
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+	IsDir  bool   `json:"is_dir"`
+	IsFile bool   `json:"is_file"`
 }
 
 // FileContent represents the content of a file
 type FileContent struct {
 	Path    string `json:"path"`
 	Content string `json:"content"`
-	IsText  bool   `json:"is_text"`
+
+	// Encoding is how Content is encoded, "text", "base64" or "data_url"
+	Encoding    string `json:"encoding"`
+	MimeType    string `json:"mime_type"`
+	IsText      bool   `json:"is_text"`
+	GitBlobHash string `json:"git_blob_hash,omitempty"`
+	ETag        string `json:"etag,omitempty"`
+}
+
+// WriteResult represents a file written by the write tool
+type WriteResult struct {
+	Path string `json:"path"`
+	ETag string `json:"etag"`
+}
+
+// StructuredContent represents the parsed content of a JSON, YAML or TOML file
+type StructuredContent struct {
+	Path   string      `json:"path"`
+	Format string      `json:"format"`
+	Data   interface{} `json:"data"`
 }
 
 // DirectoryContent represents the content of a directory
 type DirectoryContent struct {
-	Path  string     `json:"path"`
-	Files []FileInfo `json:"files"`
+	Path    string     `json:"path"`
+	Files   []FileInfo `json:"files"`
+	Total   int        `json:"total"`
+	HasMore bool       `json:"has_more"`
+
+	// NextToken, set when more entries sorted by name follow, resumes the
+	// listing after the last entry of this page when passed as page_token
+	NextToken string `json:"next_token,omitempty"`
+}
+
+// TreeNode represents an entry of a directory tree. Directories carry the
+// entries beneath them.
+type TreeNode struct {
+	FileInfo
+	Children []TreeNode `json:"children,omitempty"`
+}
+
+// DirectoryTree represents a directory and the tree beneath it
+type DirectoryTree struct {
+	Path    string     `json:"path"`
+	Entries []TreeNode `json:"entries"`
+	Nodes   int        `json:"nodes"`
+
+	// Truncated is set when the tree has more than MaxTreeNodes entries
+	Truncated bool `json:"truncated"`
+
+	Inaccessible []string `json:"inaccessible,omitempty"`
+}
+
+// SearchResult represents the entries matched by a search
+type SearchResult struct {
+	Path         string     `json:"path"`
+	Pattern      string     `json:"pattern"`
+	Files        []FileInfo `json:"files"`
+	Truncated    bool       `json:"truncated"`
+	Inaccessible []string   `json:"inaccessible,omitempty"`
+}
+
+// WalkResult represents a page of the entries beneath a directory
+type WalkResult struct {
+	Path         string     `json:"path"`
+	Files        []FileInfo `json:"files"`
+	HasMore      bool       `json:"has_more"`
+	NextCursor   string     `json:"next_cursor,omitempty"`
+	Inaccessible []string   `json:"inaccessible,omitempty"`
+}
+
+// GrepMatch represents a single matching line
+type GrepMatch struct {
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// GrepFileMatches represents the matching lines of a single file
+type GrepFileMatches struct {
+	Path    string      `json:"path"`
+	Matches []GrepMatch `json:"matches"`
+}
+
+// GrepResult represents the outcome of a content search
+type GrepResult struct {
+	Path         string            `json:"path"`
+	Query        string            `json:"query"`
+	Files        []GrepFileMatches `json:"files"`
+	Truncated    bool              `json:"truncated"`
+	Inaccessible []string          `json:"inaccessible,omitempty"`
+}
+
+// SymlinkInfo represents a symbolic link and where it points
+type SymlinkInfo struct {
+	Path       string `json:"path"`
+	Target     string `json:"target"`
+	Broken     bool   `json:"broken"`
+	InsideRoot bool   `json:"inside_root"`
 }
 
-// ParseID parses a dot-separated ID into its components
+// SymlinkList represents the symbolic links found in a tree
+type SymlinkList struct {
+	Path         string        `json:"path"`
+	Symlinks     []SymlinkInfo `json:"symlinks"`
+	Inaccessible []string      `json:"inaccessible,omitempty"`
+}
+
+// FileHash represents the digest of a file
+type FileHash struct {
+	Path      string `json:"path"`
+	Algorithm string `json:"algorithm"`
+	Hash      string `json:"hash"`
+	Size      int64  `json:"size"`
+}
+
+// CounterResult represents the outcome of incrementing a counter file
+type CounterResult struct {
+	Path     string `json:"path"`
+	Previous int64  `json:"previous"`
+	Value    int64  `json:"value"`
+}
+
+// LineIndex represents the byte offset at which each line of a file starts
+type LineIndex struct {
+	Path      string  `json:"path"`
+	Size      int64   `json:"size"`
+	LineCount int     `json:"line_count"`
+	Offsets   []int64 `json:"offsets,omitempty"`
+	Cached    bool    `json:"cached"`
+}
+
+// WhitespaceResult represents the outcome of normalizing whitespace in a file
+type WhitespaceResult struct {
+	Path         string `json:"path"`
+	DryRun       bool   `json:"dry_run"`
+	Changed      bool   `json:"changed"`
+	LinesChanged int    `json:"lines_changed"`
+	ChangedLines []int  `json:"changed_lines"`
+}
+
+// ReplaceResult represents the outcome of a find-and-replace edit
+type ReplaceResult struct {
+	Path         string `json:"path"`
+	Replacements int    `json:"replacements"`
+}
+
+// StageWriteResult represents a write that was staged for review
+type StageWriteResult struct {
+	Path      string    `json:"path"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	NewFile   bool      `json:"new_file"`
+	Changed   bool      `json:"changed"`
+	Binary    bool      `json:"binary"`
+	Diff      string    `json:"diff,omitempty"`
+}
+
+// ChmodResult represents the outcome of changing the mode of a path
+type ChmodResult struct {
+	Path     string `json:"path"`
+	Previous string `json:"previous"`
+	Mode     string `json:"mode"`
+}
+
+// MoveResult represents a file or directory that was moved. Destination is
+// where it ended up, beneath the requested destination if that was a directory.
+type MoveResult struct {
+	Path        string `json:"path"`
+	Destination string `json:"destination"`
+}
+
+// DeleteManyResult represents the outcome of deleting each of several paths,
+// in the order they were given
+type DeleteManyResult struct {
+	Results []DeleteOutcome `json:"results"`
+	Deleted int             `json:"deleted"`
+	Failed  int             `json:"failed"`
+	Skipped int             `json:"skipped"`
+}
+
+// DeleteOutcome represents the outcome of deleting one path of a delete-many
+// call. Status is success, error or skipped; TrashID is the trash entry the
+// path was moved to, if there is a trash.
+type DeleteOutcome struct {
+	Path    string     `json:"path"`
+	Status  string     `json:"status"`
+	Error   *ErrorInfo `json:"error,omitempty"`
+	TrashID string     `json:"trash_id,omitempty"`
+}
+
+// RenameResult represents a file or directory that was renamed in place
+type RenameResult struct {
+	Path    string `json:"path"`
+	NewPath string `json:"new_path"`
+}
+
+// CopyResult represents a file or directory tree that was copied
+type CopyResult struct {
+	Path         string      `json:"path"`
+	Destination  string      `json:"destination"`
+	Files        int         `json:"files"`
+	Directories  int         `json:"directories"`
+	Bytes        int64       `json:"bytes"`
+	Errors       []CopyError `json:"errors,omitempty"`
+	Inaccessible []string    `json:"inaccessible,omitempty"`
+}
+
+// CopyError represents an entry that could not be copied
+type CopyError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// CopyProgress represents the progress of a directory copy, streamed while it runs
+type CopyProgress struct {
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+// DryRunResult represents the paths an operation would affect, reported
+// instead of performing it
+type DryRunResult struct {
+	DryRun      bool     `json:"dry_run"`
+	Operation   string   `json:"operation"`
+	Path        string   `json:"path"`
+	Destination string   `json:"destination,omitempty"`
+	Paths       []string `json:"paths"`
+}
+
+// TrashedItem represents an item moved to the trash instead of being deleted
+type TrashedItem struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// ZipResult represents an archive written from a directory
+type ZipResult struct {
+	Path         string   `json:"path"`
+	Destination  string   `json:"destination"`
+	Files        int      `json:"files"`
+	Directories  int      `json:"directories"`
+	Size         int64    `json:"size"`
+	Inaccessible []string `json:"inaccessible,omitempty"`
+}
+
+// UnzipResult represents an archive extracted into a directory
+type UnzipResult struct {
+	Path        string `json:"path"`
+	Destination string `json:"destination"`
+	Files       int    `json:"files"`
+	Directories int    `json:"directories"`
+}
+
+// FileLines represents lines taken from the start or end of a text file
+type FileLines struct {
+	Path       string   `json:"path"`
+	Lines      []string `json:"lines"`
+	TotalLines int      `json:"total_lines"`
+}
+
+// FileLineRange represents a range of lines read from a text file. StartLine
+// and EndLine are the 1-based numbers of the first and last line returned;
+// EndLine is StartLine - 1 if the range lies past the end of the file.
+type FileLineRange struct {
+	FileLines
+	StartLine int `json:"start_line"`
+	EndLine   int `json:"end_line"`
+}
+
+// DiskUsage represents the space consumed by the files beneath a directory
+type DiskUsage struct {
+	Bytes       int64 `json:"bytes"`
+	Files       int   `json:"files"`
+	Directories int   `json:"directories"`
+}
+
+// SubdirUsage represents the disk usage of an immediate subdirectory
+type SubdirUsage struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	DiskUsage
+}
+
+// DiskUsageResult represents the disk usage of a directory tree
+type DiskUsageResult struct {
+	Path string `json:"path"`
+	DiskUsage
+	Subdirs      []SubdirUsage `json:"subdirs,omitempty"`
+	Inaccessible []string      `json:"inaccessible,omitempty"`
+}
+
+// WatchEvent represents a change to a watched path
+type WatchEvent struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+}
+
+// WatchResult summarizes a finished watch
+type WatchResult struct {
+	Path   string `json:"path"`
+	Events int    `json:"events"`
+}
+
+// LineEndingReport represents the line endings found in a file
+type LineEndingReport struct {
+	Path   string `json:"path"`
+	Style  string `json:"style"`
+	LF     int    `json:"lf"`
+	CRLF   int    `json:"crlf"`
+	CR     int    `json:"cr"`
+	Fixed  bool   `json:"fixed"`
+	Target string `json:"target,omitempty"`
+}
+
+// CASKey represents a content-addressable storage key for a file
+type CASKey struct {
+	Path      string `json:"path"`
+	Algorithm string `json:"algorithm"`
+	Hash      string `json:"hash"`
+	Size      int64  `json:"size"`
+	Key       string `json:"key"`
+}
+
+// ScaffoldNode describes a file or directory to be created by the scaffold tool
+type ScaffoldNode struct {
+	Name     string         `json:"name"`
+	Type     string         `json:"type,omitempty"`
+	Content  string         `json:"content,omitempty"`
+	Children []ScaffoldNode `json:"children,omitempty"`
+}
+
+// ScaffoldFailure describes a node that could not be created
+type ScaffoldFailure struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ScaffoldResult represents the outcome of a scaffold operation
+type ScaffoldResult struct {
+	Path    string            `json:"path"`
+	DryRun  bool              `json:"dry_run"`
+	Created []string          `json:"created"`
+	Failed  []ScaffoldFailure `json:"failed,omitempty"`
+}
+
+// FilesEqualResult represents the outcome of comparing two files
+type FilesEqualResult struct {
+	PathA           string `json:"path_a"`
+	PathB           string `json:"path_b"`
+	Equal           bool   `json:"equal"`
+	SizeA           int64  `json:"size_a"`
+	SizeB           int64  `json:"size_b"`
+	FirstDifference *int64 `json:"first_difference,omitempty"`
+}
+
+// UploadResult represents the result of uploading a file
+type UploadResult struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	ETag string `json:"etag,omitempty"`
+}
+
+// DiffResult represents the differences between two files or two directories
+type DiffResult struct {
+	PathA string `json:"path_a"`
+	PathB string `json:"path_b"`
+
+	// Type is "file" or "directory"
+	Type      string `json:"type"`
+	Identical bool   `json:"identical"`
+
+	// Diff is the unified diff of two text files; Binary is set instead when
+	// either file is binary
+	Diff   string `json:"diff,omitempty"`
+	Binary bool   `json:"binary,omitempty"`
+
+	// Added, Removed and Changed list the paths, relative to the compared
+	// directories, of the files only in path_b, only in path_a, and in both
+	// with different contents
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+
+	// Inaccessible lists the paths that could not be read when
+	// on_permission_error is report
+	Inaccessible []string `json:"inaccessible,omitempty"`
+}
+
+// ParseID splits a tool or resource ID into the provider name before its
+// first dot and the tool or resource name after it, which may contain further
+// dots. IDs without a dot are returned whole.
 func ParseID(id string) []string {
-	return strings.Split(id, ".")
+	return strings.SplitN(id, ".", 2)
 }
 
 // NewToolResultText creates a new tool result with text content
@@ -147,14 +715,17 @@ func NewToolResultJSON(data interface{}) *CallToolResult {
 	}
 }
 
-// NewToolResultError creates a new tool result with an error
+// NewToolResultError creates a new tool result with an execution error
 func NewToolResultError(message string) *CallToolResult {
+	return NewToolResultErrorCode(ErrorCodeExecution, "", message)
+}
+
+// NewToolResultErrorCode creates a new tool result with an error of the given
+// code. argument names the offending argument, or is empty.
+func NewToolResultErrorCode(code, argument, message string) *CallToolResult {
 	return &CallToolResult{
 		Status: "error",
-		Error: &ErrorInfo{
-			Code:    "execution_error",
-			Message: message,
-		},
+		Error:  newErrorInfo(code, argument, message),
 	}
 }
 
@@ -180,13 +751,16 @@ func NewResourceResultJSON(data interface{}) *LoadResourceResult {
 	}
 }
 
-// NewResourceResultError creates a new resource result with an error
+// NewResourceResultError creates a new resource result with a resource error
 func NewResourceResultError(message string) *LoadResourceResult {
+	return NewResourceResultErrorCode(ErrorCodeResource, "", message)
+}
+
+// NewResourceResultErrorCode creates a new resource result with an error of the
+// given code. argument names the offending parameter, or is empty.
+func NewResourceResultErrorCode(code, argument, message string) *LoadResourceResult {
 	return &LoadResourceResult{
 		Status: "error",
-		Error: &ErrorInfo{
-			Code:    "resource_error",
-			Message: message,
-		},
+		Error:  newErrorInfo(code, argument, message),
 	}
 }
@@ -0,0 +1,122 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// errWriteLimit is returned by a writeLimitReader or writeLimitWriter once its
+// limit is exceeded
+var errWriteLimit = errors.New("content exceeds the write limit")
+
+// writeLimitReader fails with errWriteLimit as soon as more than limit bytes
+// have been read, so oversized uploads are cut off while streaming
+type writeLimitReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func (r *writeLimitReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	if r.read > r.limit {
+		return n, errWriteLimit
+	}
+	return n, err
+}
+
+// writeLimitWriter fails with errWriteLimit, writing nothing more, once more
+// than limit bytes would have been written to w
+type writeLimitWriter struct {
+	w       io.Writer
+	limit   int64
+	written int64
+}
+
+func (w *writeLimitWriter) Write(p []byte) (int, error) {
+	if w.written+int64(len(p)) > w.limit {
+		return 0, errWriteLimit
+	}
+	n, err := w.w.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+// UploadFile writes the content read from body to the file at pathParam. The
+// content is streamed into a temporary file that is renamed into place once
+// complete, so it is never held in memory and a failed upload leaves any
+// existing file untouched. Uploads larger than MaxWriteBytes are aborted.
+func (p *FilesystemProvider) UploadFile(ctx context.Context, pathParam string, body io.Reader) (*CallToolResult, error) {
+	release, err := p.acquireIOSlot(ctx)
+	if err != nil {
+		return p.toolSlotError(CallToolRequest{}, err)
+	}
+	defer release()
+	return p.toolOutcome(p.uploadFile(ctx, pathParam, body))
+}
+
+func (p *FilesystemProvider) uploadFile(ctx context.Context, pathParam string, body io.Reader) (*CallToolResult, error) {
+	// Refuse to modify anything in read-only mode
+	if p.ReadOnly {
+		return NewToolResultErrorCode(ErrorCodeReadOnly, "", "Uploads are not available: the filesystem is read-only"), nil
+	}
+
+	if pathParam == "" {
+		return NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required"), nil
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		return NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error())), nil
+	}
+
+	// Keep concurrent calls from modifying the file at the same time
+	unlock := p.pathLocks.lock(fullPath)
+	defer unlock()
+
+	// Only touch files with an allowed extension; the size is checked as the body is read
+	if result := p.checkWrite(fullPath, pathParam, "path", -1); result != nil {
+		return result, nil
+	}
+
+	// Keep the permissions of a file being overwritten
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(fullPath); err == nil {
+		if info.IsDir() {
+			return NewToolResultErrorCode(ErrorCodeIsDirectory, "path", fmt.Sprintf("Path is a directory: %s", pathParam)), nil
+		}
+		perm = info.Mode().Perm()
+	}
+
+	// Create the parent directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error creating directory: %s", err.Error())), nil
+	}
+
+	var source io.Reader = newContextReader(ctx, body)
+	if p.MaxWriteBytes > 0 {
+		source = &writeLimitReader{r: source, limit: p.MaxWriteBytes}
+	}
+	if err := writeAtomic(fullPath, source, perm); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(err, errWriteLimit) {
+			return NewToolResultErrorCode(ErrorCodeFileTooLarge, "", fmt.Sprintf("Upload is larger than the %d byte write limit", p.MaxWriteBytes)), nil
+		}
+		return NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error writing file: %s", err.Error())), nil
+	}
+
+	// Return the size and new version of the file
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing file: %s", err.Error())), nil
+	}
+	return NewToolResultJSON(UploadResult{Path: pathParam, Size: info.Size(), ETag: fileETag(info)}), nil
+}
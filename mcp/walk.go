@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// PermissionErrorPolicy controls how tree walks handle paths they are not allowed to read
+type PermissionErrorPolicy string
+
+const (
+	// PermissionErrorSkip silently skips inaccessible paths
+	PermissionErrorSkip PermissionErrorPolicy = "skip"
+	// PermissionErrorFail aborts the walk at the first inaccessible path
+	PermissionErrorFail PermissionErrorPolicy = "fail"
+	// PermissionErrorReport skips inaccessible paths and lists them in the result
+	PermissionErrorReport PermissionErrorPolicy = "report"
+)
+
+// permissionErrorParameter is the parameter schema shared by all tools that walk a tree
+var permissionErrorParameter = map[string]interface{}{
+	"type":        "string",
+	"description": "How to handle paths that cannot be read: skip them, fail the call, or report them in the result",
+	"enum":        []string{string(PermissionErrorSkip), string(PermissionErrorFail), string(PermissionErrorReport)},
+	"default":     string(PermissionErrorSkip),
+}
+
+// parsePermissionErrorPolicy reads the on_permission_error argument (default to skip)
+func parsePermissionErrorPolicy(arguments map[string]interface{}) (PermissionErrorPolicy, error) {
+	value, exists := arguments["on_permission_error"]
+	if !exists {
+		return PermissionErrorSkip, nil
+	}
+
+	policy, ok := value.(string)
+	if !ok {
+		return "", newArgumentError("on_permission_error", "on_permission_error parameter must be a string")
+	}
+
+	switch PermissionErrorPolicy(policy) {
+	case PermissionErrorSkip, PermissionErrorFail, PermissionErrorReport:
+		return PermissionErrorPolicy(policy), nil
+	default:
+		return "", newArgumentError("on_permission_error", "unknown on_permission_error policy: %s", policy)
+	}
+}
+
+// apply handles displayPath, which could not be read for lack of permission,
+// according to the policy: under fail it returns the error to abort with,
+// under report it adds the path to inaccessible.
+func (policy PermissionErrorPolicy) apply(displayPath string, inaccessible *[]string) error {
+	switch policy {
+	case PermissionErrorFail:
+		return fmt.Errorf("%w: %s", fs.ErrPermission, displayPath)
+	case PermissionErrorReport:
+		*inaccessible = append(*inaccessible, displayPath)
+	}
+	return nil
+}
+
+// walkFunc is called for every entry of a tree walk with its resolved path and
+// its path as seen by the client
+type walkFunc func(fullPath, displayPath string, entry fs.DirEntry) error
+
+// walkTree walks the tree rooted at fullRoot, applying the permission error
+// policy to anything that cannot be read. The walk stops with the context's
+// error once ctx is done. Display paths are built from
+// displayRoot so results mirror the path the client asked for. When the policy
+// is report, the inaccessible display paths are returned.
+func walkTree(ctx context.Context, fullRoot, displayRoot string, policy PermissionErrorPolicy, fn walkFunc) ([]string, error) {
+	var inaccessible []string
+
+	err := filepath.WalkDir(fullRoot, func(fullPath string, entry fs.DirEntry, err error) error {
+		// Abort the walk once the request is cancelled
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		displayPath := displayRoot
+		if rel, relErr := filepath.Rel(fullRoot, fullPath); relErr == nil && rel != "." {
+			displayPath = filepath.Join(displayRoot, rel)
+		}
+
+		if err != nil {
+			if !errors.Is(err, fs.ErrPermission) {
+				return err
+			}
+			if err := policy.apply(displayPath, &inaccessible); err != nil {
+				return err
+			}
+
+			// Don't descend into a directory we failed to read
+			if entry != nil && entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		return fn(fullPath, displayPath, entry)
+	})
+
+	return inaccessible, err
+}
+
+// walkDepth returns how many levels below root a path is, where the entries
+// directly inside root are at depth 1
+func walkDepth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
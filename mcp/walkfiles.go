@@ -0,0 +1,254 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultWalkLimit is the page size of filesystem.walk when no limit is given
+const defaultWalkLimit = 1000
+
+// errWalkPageFull stops a walk once a page of results has been collected
+var errWalkPageFull = errors.New("walk page is full")
+
+// walkFiles returns a flat, paginated list of the entries beneath a directory.
+// If emit is not nil, each entry is emitted as it is found instead of being
+// collected in the result, and the walk is only paginated when a limit is given.
+func (p *FilesystemProvider) walkFiles(ctx context.Context, request CallToolRequest, emit EmitFunc) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the pattern parameter (default to every entry)
+	pattern := ""
+	if patternParam, ok := request.Params.Arguments["pattern"].(string); ok {
+		pattern = patternParam
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "pattern", fmt.Sprintf("Invalid pattern: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the ignore parameter
+	ignore, err := parseGlobList(request.Params.Arguments, "ignore")
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, argumentOf(err), err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the include_dirs parameter (default to false)
+	includeDirs := false
+	if includeDirsParam, ok := request.Params.Arguments["include_dirs"].(bool); ok {
+		includeDirs = includeDirsParam
+	}
+
+	// Get the limit parameter
+	limit := defaultWalkLimit
+	if emit != nil {
+		limit = math.MaxInt
+	}
+	if limitParam, ok := request.Params.Arguments["limit"].(float64); ok {
+		limit = int(limitParam)
+	}
+	if limit < 1 {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "limit", "limit must be at least 1")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the cursor parameter
+	cursor := ""
+	if cursorParam, ok := request.Params.Arguments["cursor"].(string); ok && cursorParam != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(cursorParam)
+		if err != nil {
+			result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "cursor", "Invalid cursor")
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		cursor = string(decoded)
+	}
+
+	// Get the on_permission_error parameter
+	policy, err := parsePermissionErrorPolicy(request.Params.Arguments)
+	if err != nil {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, argumentOf(err), err.Error())
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Check if the path exists and is a directory
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			result := NewToolResultErrorCode(ErrorCodeNotFound, "path", fmt.Sprintf("Directory not found: %s", pathParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing directory: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	if !info.IsDir() {
+		result := NewToolResultErrorCode(ErrorCodeNotDirectory, "path", fmt.Sprintf("Path is not a directory: %s", pathParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	walkResult := WalkResult{
+		Path:  pathParam,
+		Files: make([]FileInfo, 0),
+	}
+	lastPath := ""
+	found := 0
+
+	// Walk the tree in lexical order, resuming after the cursor
+	inaccessible, err := walkTree(ctx, fullPath, pathParam, policy, func(entryPath, displayPath string, entry fs.DirEntry) error {
+		if entryPath == fullPath {
+			return nil
+		}
+
+		rel, err := filepath.Rel(fullPath, entryPath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if matchesAny(ignore, entry.Name()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Skip everything up to and including the cursor, pruning
+		// directories that lie entirely before it
+		if cursor != "" && compareWalkOrder(rel, cursor) <= 0 {
+			if entry.IsDir() && !strings.HasPrefix(cursor, rel+"/") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if entry.IsDir() && !includeDirs {
+			return nil
+		}
+		if pattern != "" {
+			if matched, _ := filepath.Match(pattern, entry.Name()); !matched {
+				return nil
+			}
+		}
+
+		// Only report entries that are still within the root
+		if _, err := p.resolvePath(displayPath); err != nil {
+			return nil
+		}
+
+		if found >= limit {
+			walkResult.HasMore = true
+			return errWalkPageFull
+		}
+
+		fileInfo, err := entryFileInfo(entryPath, displayPath, entry)
+		if err != nil {
+			return nil
+		}
+		if emit != nil {
+			if err := emit(fileInfo); err != nil {
+				return err
+			}
+		} else {
+			walkResult.Files = append(walkResult.Files, fileInfo)
+		}
+		found++
+		lastPath = rel
+		return nil
+	})
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil && !errors.Is(err, errWalkPageFull) {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error walking directory: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	walkResult.Inaccessible = inaccessible
+	if walkResult.HasMore {
+		walkResult.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(lastPath))
+	}
+
+	// Return the result
+	result := NewToolResultJSON(walkResult)
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// compareWalkOrder compares two slash-separated relative paths in the order a
+// lexical depth-first walk visits them, where a directory precedes its contents
+func compareWalkOrder(a, b string) int {
+	partsA := strings.Split(a, "/")
+	partsB := strings.Split(b, "/")
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		if c := strings.Compare(partsA[i], partsB[i]); c != 0 {
+			return c
+		}
+	}
+	return len(partsA) - len(partsB)
+}
+
+// parseGlobList reads an optional array of glob patterns from the arguments
+func parseGlobList(arguments map[string]interface{}, name string) ([]string, error) {
+	value, exists := arguments[name]
+	if !exists || value == nil {
+		return nil, nil
+	}
+
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, newArgumentError(name, "%s parameter must be an array of strings", name)
+	}
+
+	patterns := make([]string, 0, len(items))
+	for _, item := range items {
+		pattern, ok := item.(string)
+		if !ok {
+			return nil, newArgumentError(name, "%s parameter must be an array of strings", name)
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, newArgumentError(name, "invalid %s pattern %q: %s", name, pattern, err.Error())
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// matchesAny reports whether name matches any of the glob patterns
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultMaxWatchers is the number of watches a provider allows at the same time
+const DefaultMaxWatchers = 8
+
+// watchOps maps the fsnotify operations reported by watch to their names.
+// Permission changes are not reported.
+var watchOps = []struct {
+	op   fsnotify.Op
+	name string
+}{
+	{fsnotify.Create, "create"},
+	{fsnotify.Write, "write"},
+	{fsnotify.Remove, "remove"},
+	{fsnotify.Rename, "rename"},
+}
+
+// watch streams the changes made beneath a directory until the request ends.
+// Every change is emitted as a WatchEvent; the result summarizes the watch.
+func (p *FilesystemProvider) watch(ctx context.Context, request CallToolRequest, emit EmitFunc) (*CallToolResult, error) {
+	// Changes can only be delivered as partial results
+	if emit == nil {
+		result := NewToolResultErrorCode(ErrorCodeStreamingRequired, "", "The watch tool streams its events; call it with Accept: text/event-stream")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Check if the path exists and is a directory
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			result := NewToolResultErrorCode(ErrorCodeNotFound, "path", fmt.Sprintf("Directory not found: %s", pathParam))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error accessing directory: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	if !info.IsDir() {
+		result := NewToolResultErrorCode(ErrorCodeNotDirectory, "path", fmt.Sprintf("Path is not a directory: %s", pathParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Reserve a watcher slot
+	if !p.acquireWatcher() {
+		result := NewToolResultErrorCode(ErrorCodeLimitExceeded, "", fmt.Sprintf("Too many active watches (limit %d)", p.maxWatchers))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	defer p.releaseWatcher()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		result := NewToolResultError(fmt.Sprintf("Error creating watcher: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+	defer watcher.Close()
+
+	// Watch every directory of the tree; fsnotify is not recursive
+	if err := addWatchTree(ctx, watcher, fullPath); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error watching directory: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	watchResult := WatchResult{Path: pathParam}
+	for {
+		select {
+		case <-ctx.Done():
+			// The client went away or the request timed out
+			result := NewToolResultJSON(watchResult)
+			result.RequestID = request.RequestID
+			return result, nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil, errors.New("watcher closed unexpectedly")
+			}
+
+			// Start watching directories created inside the tree
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Lstat(event.Name); err == nil && info.IsDir() {
+					addWatchTree(ctx, watcher, event.Name)
+				}
+			}
+
+			rel, err := filepath.Rel(fullPath, event.Name)
+			if err != nil {
+				continue
+			}
+			displayPath := filepath.Join(pathParam, rel)
+
+			for _, op := range watchOps {
+				if !event.Has(op.op) {
+					continue
+				}
+				if err := emit(WatchEvent{Op: op.name, Path: displayPath}); err != nil {
+					return nil, err
+				}
+				watchResult.Events++
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil, errors.New("watcher closed unexpectedly")
+			}
+			return nil, fmt.Errorf("watching %s: %w", pathParam, err)
+		}
+	}
+}
+
+// addWatchTree adds root and every directory beneath it to the watcher.
+// Symbolic links are not followed, so the watch stays inside the tree.
+func addWatchTree(ctx context.Context, watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			// Directories we cannot read cannot be watched either
+			if errors.Is(err, fs.ErrPermission) && path != root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		return watcher.Add(path)
+	})
+}
+
+// acquireWatcher reserves a watcher slot, reporting false if all are in use.
+// A limit of zero or less means no limit.
+func (p *FilesystemProvider) acquireWatcher() bool {
+	p.watchersMu.Lock()
+	defer p.watchersMu.Unlock()
+
+	if p.maxWatchers > 0 && p.activeWatchers >= p.maxWatchers {
+		return false
+	}
+	p.activeWatchers++
+	return true
+}
+
+// releaseWatcher frees a slot reserved by acquireWatcher
+func (p *FilesystemProvider) releaseWatcher() {
+	p.watchersMu.Lock()
+	defer p.watchersMu.Unlock()
+
+	p.activeWatchers--
+}
@@ -0,0 +1,195 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// utf8BOM is the byte order mark some editors put at the start of UTF-8 files
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// normalizeWhitespace trims trailing whitespace, fixes the final newline and optionally expands tabs in a text file
+func (p *FilesystemProvider) normalizeWhitespace(ctx context.Context, request CallToolRequest) (*CallToolResult, error) {
+	// Get the path parameter
+	pathParam, ok := request.Params.Arguments["path"].(string)
+	if !ok {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "path", "Path parameter is required and must be a string")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the tab options (default to leaving tabs alone)
+	expandTabs := false
+	if expandTabsParam, ok := request.Params.Arguments["expand_tabs"].(bool); ok {
+		expandTabs = expandTabsParam
+	}
+	tabWidth := 4
+	if tabWidthParam, ok := request.Params.Arguments["tab_width"].(float64); ok {
+		tabWidth = int(tabWidthParam)
+	}
+	if tabWidth < 1 {
+		result := NewToolResultErrorCode(ErrorCodeInvalidArgument, "tab_width", "tab_width must be at least 1")
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Get the dry_run parameter (default to false)
+	dryRun := false
+	if dryRunParam, ok := request.Params.Arguments["dry_run"].(bool); ok {
+		dryRun = dryRunParam
+	}
+
+	// Sanitize and resolve the path
+	fullPath, err := p.resolvePath(pathParam)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeInvalidArgument), "path", fmt.Sprintf("Invalid path: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Keep concurrent calls from modifying the file at the same time
+	unlock := p.pathLocks.lock(fullPath)
+	defer unlock()
+
+	// Only touch files with an allowed extension
+	if result := p.checkWrite(fullPath, pathParam, "path", -1); result != nil {
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Check if the path exists and is a file
+	info, errResult := statRegularFile(fullPath, pathParam, "path")
+	if errResult != nil {
+		errResult.RequestID = request.RequestID
+		return errResult, nil
+	}
+
+	// Read the file contents
+	data, err := readFileContext(ctx, fullPath)
+	if err != nil {
+		result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error reading file: %s", err.Error()))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	// Only text files can be normalized
+	if bytes.IndexByte(data, 0) >= 0 {
+		result := NewToolResultErrorCode(ErrorCodeNotText, "path", fmt.Sprintf("File is not a text file: %s", pathParam))
+		result.RequestID = request.RequestID
+		return result, nil
+	}
+
+	normalized, changedLines := normalizeText(data, expandTabs, tabWidth)
+	whitespaceResult := WhitespaceResult{
+		Path:         pathParam,
+		DryRun:       dryRun,
+		Changed:      !bytes.Equal(data, normalized),
+		LinesChanged: len(changedLines),
+		ChangedLines: changedLines,
+	}
+
+	// Write the result back unless nothing changed
+	if whitespaceResult.Changed && !dryRun {
+		if result := p.checkWrite(fullPath, pathParam, "path", int64(len(normalized))); result != nil {
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+		if err := writeFileAtomic(fullPath, normalized, info.Mode().Perm()); err != nil {
+			result := NewToolResultErrorCode(errorCodeFor(err, ErrorCodeIO), "path", fmt.Sprintf("Error writing file: %s", err.Error()))
+			result.RequestID = request.RequestID
+			return result, nil
+		}
+	}
+
+	// Return the result
+	result := NewToolResultJSON(whitespaceResult)
+	result.RequestID = request.RequestID
+	return result, nil
+}
+
+// normalizeText trims trailing whitespace from every line, optionally expands
+// tabs and ends the text with exactly one newline. A byte order mark and CRLF
+// line endings are preserved. It returns the normalized text and the 1-based
+// numbers of the lines that changed, including trailing blank lines that were
+// removed.
+func normalizeText(data []byte, expandTabs bool, tabWidth int) ([]byte, []int) {
+	if len(data) == 0 {
+		return data, nil
+	}
+
+	text := string(data)
+	bom := ""
+	if bytes.HasPrefix(data, utf8BOM) {
+		bom = string(utf8BOM)
+		text = text[len(utf8BOM):]
+	}
+
+	newline := "\n"
+	if strings.Contains(text, "\r\n") {
+		newline = "\r\n"
+	}
+
+	lines := strings.Split(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	changedLines := make([]int, 0)
+	normalized := make([]string, len(lines))
+	for i, line := range lines {
+		clean := strings.TrimRight(strings.TrimSuffix(line, "\r"), " \t\f\v\r")
+		if expandTabs {
+			clean = expandTabStops(clean, tabWidth)
+		}
+		normalized[i] = clean
+
+		// Lines whose ending is rewritten count as changed as well
+		expected := clean
+		if newline == "\r\n" {
+			expected += "\r"
+		}
+		if expected != line {
+			changedLines = append(changedLines, i+1)
+		}
+	}
+
+	// Drop trailing blank lines
+	end := len(normalized)
+	for end > 0 && normalized[end-1] == "" {
+		end--
+	}
+	for i := end; i < len(normalized); i++ {
+		if len(changedLines) == 0 || changedLines[len(changedLines)-1] != i+1 {
+			changedLines = append(changedLines, i+1)
+		}
+	}
+	normalized = normalized[:end]
+
+	if len(normalized) == 0 {
+		return []byte(bom), changedLines
+	}
+	return []byte(bom + strings.Join(normalized, newline) + newline), changedLines
+}
+
+// expandTabStops replaces each tab with spaces up to the next tab stop
+func expandTabStops(line string, tabWidth int) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+
+	var builder strings.Builder
+	column := 0
+	for _, r := range line {
+		if r == '\t' {
+			spaces := tabWidth - column%tabWidth
+			builder.WriteString(strings.Repeat(" ", spaces))
+			column += spaces
+			continue
+		}
+		builder.WriteRune(r)
+		column++
+	}
+	return builder.String()
+}
@@ -0,0 +1,18 @@
+package mcp
+
+import "fmt"
+
+// checkWrite holds a file about to be created or rewritten with size bytes to
+// the provider's write rules: its extension must be allowed and size must not
+// exceed MaxWriteBytes. A negative size, for content whose size is not known
+// yet, only checks the extension. It returns the error result to fail with,
+// naming argument, or nil if the write may go ahead.
+func (p *FilesystemProvider) checkWrite(fullPath, pathParam, argument string, size int64) *CallToolResult {
+	if !p.extensionAllowed(fullPath) {
+		return NewToolResultErrorCode(ErrorCodeExtensionNotAllowed, argument, fmt.Sprintf("File extension is not allowed: %s", pathParam))
+	}
+	if p.MaxWriteBytes > 0 && size > p.MaxWriteBytes {
+		return NewToolResultErrorCode(ErrorCodeFileTooLarge, argument, fmt.Sprintf("Content is larger than the %d byte write limit: %s", p.MaxWriteBytes, pathParam))
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/loag/mcp-server-test/mcp"
+)
+
+// authMiddleware rejects requests that don't carry the server's bearer token.
+// It lets every request through when no token is configured.
+func (s *MCPServer) authMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if s.AuthToken == "" {
+			return next(c)
+		}
+
+		token, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.AuthToken)) != 1 {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="mcp"`)
+			return c.JSON(http.StatusUnauthorized, mcp.ErrorResponse{
+				Error:   "unauthorized",
+				Message: "A valid bearer token is required",
+			})
+		}
+
+		return next(c)
+	}
+}
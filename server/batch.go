@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/loag/mcp-server-test/mcp"
+)
+
+// DefaultBatchConcurrency is the number of calls of a batch that run at the same time
+const DefaultBatchConcurrency = 4
+
+// handleBatch handles the batch endpoint. It runs every tool call of the
+// request, up to BatchConcurrency at a time, and returns their results in the
+// order of the calls. A failing call does not affect the others.
+func (s *MCPServer) handleBatch(c echo.Context) error {
+	var requests []mcp.CallToolRequest
+	if err := c.Bind(&requests); err != nil {
+		return c.JSON(http.StatusBadRequest, mcp.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Failed to parse request body: expected an array of tool calls",
+		})
+	}
+
+	concurrency := s.BatchConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	concurrency = min(concurrency, len(requests))
+
+	ctx := c.Request().Context()
+	results := make([]*mcp.CallToolResult, len(requests))
+
+	// Feed the calls to a fixed pool of workers
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = s.batchCall(ctx, requests[i])
+			}
+		}()
+	}
+	for i := range requests {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return c.JSON(http.StatusOK, results)
+}
+
+// batchCall runs a single call of a batch. Every failure, including an
+// unknown tool or invalid arguments, is reported as an error result.
+func (s *MCPServer) batchCall(ctx context.Context, request mcp.CallToolRequest) *mcp.CallToolResult {
+	result := s.runBatchCall(ctx, request)
+
+	// Tag the result with the request ID of its call
+	if result.RequestID == "" {
+		result.RequestID = request.RequestID
+	}
+	return result
+}
+
+// runBatchCall does the work of batchCall, returning a result that may lack a request ID
+func (s *MCPServer) runBatchCall(ctx context.Context, request mcp.CallToolRequest) *mcp.CallToolResult {
+	errorResult := func(code, message string, details map[string]interface{}) *mcp.CallToolResult {
+		return &mcp.CallToolResult{
+			Status: "error",
+			Error:  &mcp.ErrorInfo{Code: code, Message: message, Details: details},
+		}
+	}
+
+	// Find the provider and tool
+	providerName, toolName, err := parseToolID(request.ToolID)
+	if err != nil {
+		return errorResult("invalid_tool_id", "Invalid tool ID format. Expected: provider.tool", nil)
+	}
+
+	provider, exists := s.provider(providerName)
+	if !exists {
+		if s.providerDisabled(providerName) {
+			return errorResult("provider_disabled", "Provider is disabled: "+providerName, nil)
+		}
+		return errorResult("provider_not_found", "Provider not found: "+providerName, nil)
+	}
+
+	// Validate the arguments against the tool's parameter schema
+	fieldErrors, err := s.validateArguments(provider, request.ToolID, request.Params.Arguments)
+	if err != nil {
+		return errorResult("invalid_tool_schema", err.Error(), nil)
+	}
+	if len(fieldErrors) > 0 {
+		return errorResult("invalid_arguments", "Arguments do not match the parameters of "+request.ToolID, map[string]interface{}{"fields": fieldErrors})
+	}
+
+	// Skip the call if the batch has already run out of time
+	if err := ctx.Err(); err != nil {
+		return errorResult("timeout", "Batch exceeded the request timeout of "+s.RequestTimeout.String(), nil)
+	}
+
+	// Call the tool
+	result, _, err := s.callOnce(ctx, request, func() (*mcp.CallToolResult, error) {
+		return s.interceptToolCall(ctx, request, func() (*mcp.CallToolResult, error) {
+			return provider.CallTool(ctx, toolName, request)
+		})
+	})
+	if errors.Is(err, errIdempotencyConflict) {
+		return errorResult("idempotency_key_conflict", err.Error(), nil)
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return errorResult("timeout", "Batch exceeded the request timeout of "+s.RequestTimeout.String(), nil)
+	}
+	if err != nil {
+		return errorResult("tool_execution_error", err.Error(), nil)
+	}
+	return result
+}
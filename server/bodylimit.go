@@ -0,0 +1,45 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultMaxBodyBytes is the largest request body a newly created server
+// accepts. It leaves room for writes of files of several megabytes, which
+// grow by a third when sent base64-encoded.
+const DefaultMaxBodyBytes = 32 << 20
+
+// bodyLimitMiddleware returns a middleware that rejects request bodies larger
+// than MaxBodyBytes with HTTP 413. Bodies of unknown length are read up to
+// the limit and no further, so they are rejected before the handler sees them.
+func (s *MCPServer) bodyLimitMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if s.MaxBodyBytes <= 0 {
+			return next
+		}
+		return func(c echo.Context) error {
+			request := c.Request()
+			if request.ContentLength > s.MaxBodyBytes {
+				return echo.ErrStatusRequestEntityTooLarge
+			}
+			if request.Body == nil {
+				return next(c)
+			}
+
+			body, err := io.ReadAll(io.LimitReader(request.Body, s.MaxBodyBytes+1))
+			request.Body.Close()
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "Failed to read request body").SetInternal(err)
+			}
+			if int64(len(body)) > s.MaxBodyBytes {
+				return echo.ErrStatusRequestEntityTooLarge
+			}
+			request.Body = io.NopCloser(bytes.NewReader(body))
+			return next(c)
+		}
+	}
+}
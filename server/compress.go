@@ -0,0 +1,18 @@
+package server
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// compressMiddleware returns a middleware that gzips responses for clients
+// that accept it. Resource streams and downloads are sent as they are since
+// they are often already compressed and byte ranges refer to the file itself,
+// and Server-Sent Events must reach the client as soon as they are written.
+func (s *MCPServer) compressMiddleware() echo.MiddlewareFunc {
+	return middleware.GzipWithConfig(middleware.GzipConfig{
+		Skipper: func(c echo.Context) bool {
+			return !s.Compress || c.Path() == "/v1/stream-resource" || c.Path() == "/v1/download" || wantsEventStream(c)
+		},
+	})
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/loag/mcp-server-test/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes a server and its filesystem provider, as read from a
+// configuration file by LoadConfig. Limits left at zero keep their defaults.
+type Config struct {
+	Name        string `json:"name" yaml:"name"`
+	Version     string `json:"version" yaml:"version"`
+	Description string `json:"description" yaml:"description"`
+
+	// RootDir is the directory the filesystem provider serves (default ".")
+	RootDir string `json:"root_dir" yaml:"root_dir"`
+
+	// ReadOnly blocks every tool that modifies the filesystem
+	ReadOnly bool `json:"read_only" yaml:"read_only"`
+
+	// MaxReadBytes and MaxWriteBytes limit the size of files read and written,
+	// MaxBodyBytes the size of request bodies
+	MaxReadBytes  int64 `json:"max_read_bytes" yaml:"max_read_bytes"`
+	MaxWriteBytes int64 `json:"max_write_bytes" yaml:"max_write_bytes"`
+	MaxBodyBytes  int64 `json:"max_body_bytes" yaml:"max_body_bytes"`
+
+	// AuthToken, when set, is the bearer token clients must send
+	AuthToken string `json:"auth_token" yaml:"auth_token"`
+}
+
+// LoadConfig reads a configuration file, in JSON if its name ends in .json
+// or in YAML if it ends in .yaml or .yml, and validates it. Unknown settings
+// are rejected so that typos do not go unnoticed.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+
+	var config Config
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&config)
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&config)
+	default:
+		return nil, fmt.Errorf("config file %s must have a .json, .yaml or .yml extension", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// Validate checks that the configuration is complete and that its root
+// directory exists
+func (c *Config) Validate() error {
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	if c.Version == "" {
+		return errors.New("version is required")
+	}
+
+	rootDir := c.RootDir
+	if rootDir == "" {
+		rootDir = "."
+	}
+	info, err := os.Stat(rootDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("root_dir %s does not exist", rootDir)
+		}
+		return fmt.Errorf("root_dir %s is not accessible: %w", rootDir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("root_dir %s is not a directory", rootDir)
+	}
+
+	limits := []struct {
+		name  string
+		value int64
+	}{
+		{"max_read_bytes", c.MaxReadBytes},
+		{"max_write_bytes", c.MaxWriteBytes},
+		{"max_body_bytes", c.MaxBodyBytes},
+	}
+	for _, limit := range limits {
+		if limit.value < 0 {
+			return fmt.Errorf("%s must not be negative, got %d", limit.name, limit.value)
+		}
+	}
+	return nil
+}
+
+// NewMCPServerFromConfig creates a server as described by config, serving a
+// filesystem provider created with opts followed by the settings of config,
+// which take precedence
+func NewMCPServerFromConfig(config *Config, opts ...mcp.FilesystemOption) (*MCPServer, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := NewMCPServer(config.Name, config.Version, config.Description)
+	s.AuthToken = config.AuthToken
+	if config.MaxBodyBytes > 0 {
+		s.MaxBodyBytes = config.MaxBodyBytes
+	}
+
+	fsOptions := append([]mcp.FilesystemOption{}, opts...)
+	if config.RootDir != "" {
+		fsOptions = append(fsOptions, mcp.WithRootDir(config.RootDir))
+	}
+	if config.ReadOnly {
+		fsOptions = append(fsOptions, mcp.WithReadOnly(true))
+	}
+	if config.MaxReadBytes > 0 {
+		fsOptions = append(fsOptions, mcp.WithMaxReadBytes(config.MaxReadBytes))
+	}
+	if config.MaxWriteBytes > 0 {
+		fsOptions = append(fsOptions, mcp.WithMaxWriteBytes(config.MaxWriteBytes))
+	}
+	if err := s.RegisterProvider(mcp.NewFilesystemProvider(fsOptions...)); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"errors"
+	"io/fs"
+	"mime"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/loag/mcp-server-test/mcp"
+)
+
+// defaultDownloadProvider is the provider GET /v1/download reads from when
+// no provider is given
+const defaultDownloadProvider = "filesystem"
+
+// handleDownload serves the file named by the path query parameter with
+// http.ServeContent, which answers Range, If-Range, If-Modified-Since and
+// If-None-Match requests, so interrupted downloads can be resumed.
+func (s *MCPServer) handleDownload(c echo.Context) error {
+	providerName := c.QueryParam("provider")
+	if providerName == "" {
+		providerName = defaultDownloadProvider
+	}
+	provider, exists := s.provider(providerName)
+	if !exists {
+		return s.providerUnavailable(c, providerName)
+	}
+	downloader, ok := provider.(mcp.FileDownloader)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, mcp.ErrorResponse{
+			Error:   "download_not_supported",
+			Message: "Provider does not support downloads: " + providerName,
+		})
+	}
+
+	download, err := downloader.DownloadFile(c.Request().Context(), c.QueryParam("path"))
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			status = http.StatusNotFound
+		case errors.Is(err, mcp.ErrBusy):
+			status = http.StatusServiceUnavailable
+		}
+		return c.JSON(status, mcp.ErrorResponse{
+			Error:   "download_error",
+			Message: err.Error(),
+		})
+	}
+	defer download.Content.Close()
+
+	// Have browsers save the file rather than render it
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": download.Name}))
+	header.Set("X-Content-Type-Options", "nosniff")
+	if download.ETag != "" {
+		header.Set("ETag", download.ETag)
+	}
+
+	http.ServeContent(c.Response(), c.Request(), download.Name, download.ModTime, download.Content)
+	return nil
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/loag/mcp-server-test/mcp"
+)
+
+// handleGetTool calls a read-only tool with its arguments taken from the query
+// string, for quick debugging from a browser or curl. Tools that modify
+// anything can only be called with POST, so a link or prefetch cannot
+// trigger them.
+func (s *MCPServer) handleGetTool(c echo.Context) error {
+	toolID := c.Param("id")
+	providerName, _, err := parseToolID(toolID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, mcp.ErrorResponse{
+			Error:   "invalid_tool_id",
+			Message: err.Error(),
+		})
+	}
+
+	provider, exists := s.provider(providerName)
+	if !exists {
+		return s.providerUnavailable(c, providerName)
+	}
+
+	var tool *mcp.ToolInfo
+	for _, info := range provider.GetInfo().Tools {
+		if info.ID == toolID {
+			tool = &info
+			break
+		}
+	}
+	if tool == nil {
+		return c.JSON(http.StatusNotFound, mcp.ErrorResponse{
+			Error:   "tool_not_found",
+			Message: "Tool not found: " + toolID,
+		})
+	}
+	if !tool.ReadOnly {
+		return c.JSON(http.StatusMethodNotAllowed, mcp.ErrorResponse{
+			Error:   "method_not_allowed",
+			Message: "Tool is not read-only and must be called with POST /v1/call-tool: " + toolID,
+		})
+	}
+
+	arguments, err := queryArguments(c.QueryParams(), tool.Parameters)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, mcp.ErrorResponse{
+			Error:   "invalid_arguments",
+			Message: err.Error(),
+		})
+	}
+
+	request := mcp.CallToolRequest{
+		ToolID:    toolID,
+		RequestID: correlationID(c),
+	}
+	request.Params.Arguments = arguments
+	return s.callTool(c, request)
+}
+
+// queryArguments converts query parameters to tool arguments, using the types
+// of the tool's parameter schema: booleans and numbers are parsed, arrays take
+// every value of a repeated parameter and objects are decoded from JSON.
+// Parameters the schema does not describe are refused, so a GET cannot pass
+// a tool arguments it handles without advertising them, such as ones that
+// would make it modify files. The pretty parameter formats the response and
+// is not passed on.
+func queryArguments(query url.Values, parameters interface{}) (map[string]interface{}, error) {
+	var schema struct {
+		Properties map[string]struct {
+			Type interface{} `json:"type"`
+		} `json:"properties"`
+	}
+	if parameters != nil {
+		document, err := json.Marshal(parameters)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(document, &schema); err != nil {
+			return nil, err
+		}
+	}
+
+	arguments := make(map[string]interface{}, len(query))
+	for name, values := range query {
+		if len(values) == 0 || name == "pretty" {
+			continue
+		}
+		value := values[0]
+
+		property, declared := schema.Properties[name]
+		if !declared {
+			return nil, fmt.Errorf("unknown parameter %s", name)
+		}
+		switch schemaType, _ := property.Type.(string); schemaType {
+		case "boolean":
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be true or false", name)
+			}
+			arguments[name] = parsed
+		case "integer", "number":
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be a number", name)
+			}
+			arguments[name] = parsed
+		case "array":
+			items := make([]interface{}, len(values))
+			for i, item := range values {
+				items[i] = item
+			}
+			arguments[name] = items
+		case "object":
+			var parsed interface{}
+			if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+				return nil, fmt.Errorf("%s must be a JSON object", name)
+			}
+			arguments[name] = parsed
+		default:
+			arguments[name] = value
+		}
+	}
+	return arguments, nil
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/loag/mcp-server-test/mcp"
+)
+
+// handleHealthz handles the liveness probe. It succeeds as long as the server
+// is able to answer requests.
+func (s *MCPServer) handleHealthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, mcp.HealthResponse{Status: "ok"})
+}
+
+// handleReadyz handles the readiness probe. It runs the health check of every
+// enabled provider and fails with 503 naming the providers whose check failed.
+func (s *MCPServer) handleReadyz(c echo.Context) error {
+	failures := make(map[string]string)
+	for name, provider := range s.enabledProviders() {
+		if err := provider.HealthCheck(); err != nil {
+			failures[name] = err.Error()
+		}
+	}
+
+	if len(failures) > 0 {
+		return c.JSON(http.StatusServiceUnavailable, mcp.HealthResponse{
+			Status:   "unavailable",
+			Failures: failures,
+		})
+	}
+
+	return c.JSON(http.StatusOK, mcp.HealthResponse{Status: "ok"})
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/loag/mcp-server-test/mcp"
+)
+
+// Defaults of a newly created server for remembering the results of tool
+// calls made with an idempotency key
+const (
+	DefaultIdempotencyTTL  = time.Hour
+	DefaultIdempotencyKeys = 10000
+)
+
+// idempotentReplayedHeader marks responses carrying the result of an earlier
+// call made with the same idempotency key
+const idempotentReplayedHeader = "Idempotent-Replayed"
+
+// errIdempotencyConflict is returned when an idempotency key is reused for a
+// different call
+var errIdempotencyConflict = errors.New("idempotency key was already used for a different tool call")
+
+// idempotentCall is a tool call made with an idempotency key. done is closed
+// once the provider has returned, even if the request that made the call gave
+// up on it before; result is nil if it failed without a result, in which case
+// the key is forgotten so the call can be retried. Only finished calls have an
+// element in the expiry order.
+type idempotentCall struct {
+	key         string
+	fingerprint string
+	done        chan struct{}
+	result      *mcp.CallToolResult
+	expires     time.Time
+	element     *list.Element
+}
+
+// idempotentCalls remembers the calls made with each idempotency key for the
+// server's IdempotencyTTL after they finish, keeping at most IdempotencyKeys
+// finished calls; the ones that finished first are forgotten first. Calls
+// still running are always kept, so retries wait for them instead of calling
+// the tool again.
+type idempotentCalls struct {
+	calls map[string]*idempotentCall
+	order *list.List
+}
+
+// callOnce runs call, giving up on it when ctx is done, unless a call with
+// the same idempotency key was made within IdempotencyTTL, in which case it
+// waits for that call to finish and returns a copy of its result, reporting
+// it as replayed. A keyed call stays pending until call really returns, even
+// after its own request timed out, so that retries never run the tool while
+// it is still running. Requests without a key always run call. Reusing a key
+// for a different tool or different arguments fails with
+// errIdempotencyConflict.
+func (s *MCPServer) callOnce(ctx context.Context, request mcp.CallToolRequest, call func() (*mcp.CallToolResult, error)) (result *mcp.CallToolResult, replayed bool, err error) {
+	if request.IdempotencyKey == "" || s.IdempotencyTTL <= 0 {
+		result, err := runWithContext(ctx, call)
+		return result, false, err
+	}
+
+	arguments, _ := json.Marshal(request.Params.Arguments)
+	fingerprint := request.ToolID + "\x00" + string(arguments)
+	for {
+		current, started, err := s.beginIdempotentCall(request.IdempotencyKey, fingerprint)
+		if err != nil {
+			return nil, false, err
+		}
+		var callErr error
+		if started {
+			callDone := make(chan struct{})
+			go func() {
+				defer close(callDone)
+				result, err := call()
+				callErr = err
+				s.finishIdempotentCall(current, result, err)
+			}()
+			select {
+			case <-callDone:
+			case <-ctx.Done():
+				return nil, false, ctx.Err()
+			}
+		}
+
+		select {
+		case <-current.done:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+		if current.result != nil {
+			// Hand out copies, so callers can set their own request ID
+			// without touching the recorded result
+			copied := *current.result
+			copied.RequestID = request.RequestID
+			return &copied, !started, nil
+		}
+		if started {
+			return nil, false, callErr
+		}
+		// The previous call failed, so make it again
+	}
+}
+
+// beginIdempotentCall returns the call registered under an idempotency key,
+// or registers a new one, reporting that the caller has to make it
+func (s *MCPServer) beginIdempotentCall(key, fingerprint string) (call *idempotentCall, started bool, err error) {
+	s.idempotencyMu.Lock()
+	defer s.idempotencyMu.Unlock()
+
+	if s.idempotency.calls == nil {
+		s.idempotency.calls = make(map[string]*idempotentCall)
+		s.idempotency.order = list.New()
+	}
+
+	// Forget expired calls, which are the ones that finished first
+	now := time.Now()
+	for front := s.idempotency.order.Front(); front != nil; front = s.idempotency.order.Front() {
+		if now.Before(front.Value.(*idempotentCall).expires) {
+			break
+		}
+		s.forgetIdempotentCall(front.Value.(*idempotentCall))
+	}
+
+	if previous, exists := s.idempotency.calls[key]; exists {
+		if previous.fingerprint != fingerprint {
+			return nil, false, errIdempotencyConflict
+		}
+		return previous, false, nil
+	}
+
+	call = &idempotentCall{
+		key:         key,
+		fingerprint: fingerprint,
+		done:        make(chan struct{}),
+	}
+	s.idempotency.calls[key] = call
+	return call, true, nil
+}
+
+// finishIdempotentCall records the outcome of a call, starting its TTL, and
+// wakes up the retries waiting for it. Calls that failed without a result are
+// forgotten.
+func (s *MCPServer) finishIdempotentCall(call *idempotentCall, result *mcp.CallToolResult, err error) {
+	s.idempotencyMu.Lock()
+	defer s.idempotencyMu.Unlock()
+
+	if err == nil {
+		call.result = result
+		call.expires = time.Now().Add(s.IdempotencyTTL)
+		call.element = s.idempotency.order.PushBack(call)
+		for s.IdempotencyKeys > 0 && s.idempotency.order.Len() > s.IdempotencyKeys {
+			s.forgetIdempotentCall(s.idempotency.order.Front().Value.(*idempotentCall))
+		}
+	} else {
+		s.forgetIdempotentCall(call)
+	}
+	close(call.done)
+}
+
+// forgetIdempotentCall drops a call from the cache, if it is still there.
+// Callers must hold idempotencyMu.
+func (s *MCPServer) forgetIdempotentCall(call *idempotentCall) {
+	if s.idempotency.calls[call.key] == call {
+		delete(s.idempotency.calls, call.key)
+	}
+	if call.element != nil {
+		s.idempotency.order.Remove(call.element)
+	}
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+	"github.com/loag/mcp-server-test/mcp"
+)
+
+// SupportedProtocolVersions lists the protocol versions the server speaks,
+// newest first
+var SupportedProtocolVersions = []string{ProtocolVersion}
+
+// handleInitialize handles the initialize endpoint. The server answers with
+// the protocol version requested by the client if it supports it, and with
+// its newest version otherwise; the client decides whether it can use that.
+func (s *MCPServer) handleInitialize(c echo.Context) error {
+	var request mcp.InitializeRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, mcp.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Failed to parse request body",
+		})
+	}
+
+	return c.JSON(http.StatusOK, mcp.InitializeResponse{
+		ProtocolVersion: negotiateProtocolVersion(request.ProtocolVersion),
+		ServerInfo: mcp.ServerInfo{
+			Name:        s.Name,
+			Version:     s.Version,
+			Description: s.Description,
+		},
+		Capabilities: mcp.Capabilities{
+			Tools:         true,
+			Resources:     true,
+			Streaming:     true,
+			Subscriptions: false,
+		},
+	})
+}
+
+// negotiateProtocolVersion picks the protocol version to use with a client
+// that requested the given version
+func negotiateProtocolVersion(requested string) string {
+	if slices.Contains(SupportedProtocolVersions, requested) {
+		return requested
+	}
+	return SupportedProtocolVersions[0]
+}
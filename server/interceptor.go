@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/loag/mcp-server-test/mcp"
+)
+
+// ToolInterceptor wraps every tool call. It runs the call, along with the
+// interceptors registered after it, by calling next, and may inspect or change
+// the result. Returning without calling next short-circuits the call, e.g. to
+// deny it with an error result.
+type ToolInterceptor func(ctx context.Context, request mcp.CallToolRequest, next func() (*mcp.CallToolResult, error)) (*mcp.CallToolResult, error)
+
+// Use registers interceptors for every tool call, whichever endpoint it comes
+// from. Interceptors run in the order they were registered, so the first one
+// sees the call first and the result last. Register them before the server
+// handles requests.
+func (s *MCPServer) Use(interceptors ...ToolInterceptor) {
+	s.ToolInterceptors = append(s.ToolInterceptors, interceptors...)
+}
+
+// interceptToolCall runs call, the dispatch of a tool call to its provider,
+// through the registered interceptors. Every endpoint calls tools through
+// here, so this is also where tool calls are counted in the metrics; calls
+// still running when their request gives up count as failed.
+func (s *MCPServer) interceptToolCall(ctx context.Context, request mcp.CallToolRequest, call func() (*mcp.CallToolResult, error)) (*mcp.CallToolResult, error) {
+	next := call
+	for i := len(s.ToolInterceptors) - 1; i >= 0; i-- {
+		interceptor, inner := s.ToolInterceptors[i], next
+		next = func() (*mcp.CallToolResult, error) {
+			return interceptor(ctx, request, inner)
+		}
+	}
+
+	start := time.Now()
+	result, err := next()
+	if result == nil && err == nil {
+		err = errors.New("tool interceptor returned no result")
+	}
+	if s.Metrics != nil {
+		s.Metrics.ObserveToolCall(request.ToolID, time.Since(start), err != nil || ctx.Err() != nil || result.Status == "error")
+	}
+	return result, err
+}
+
+// loadProviderResource loads a resource from its provider, giving up once ctx
+// is done. Every endpoint loads resources through here, so this is also where
+// resource loads are counted in the metrics.
+func (s *MCPServer) loadProviderResource(ctx context.Context, provider mcp.Provider, resourceName string, request mcp.LoadResourceRequest) (*mcp.LoadResourceResult, error) {
+	start := time.Now()
+	result, err := runWithContext(ctx, func() (*mcp.LoadResourceResult, error) {
+		return provider.LoadResource(ctx, resourceName, request)
+	})
+	if s.Metrics != nil {
+		s.Metrics.ObserveResourceLoad(request.ResourceID, time.Since(start), err != nil || result.Status == "error")
+	}
+	return result, err
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Keys of the values the request logger shares with the handlers through the echo context
+const (
+	requestIDKey    = "mcp_request_id"
+	resultStatusKey = "mcp_result_status"
+)
+
+// loggedRequest holds the fields of a request body that identify the call.
+// It covers the call-tool and load-resource bodies as well as JSON-RPC requests.
+type loggedRequest struct {
+	RequestID  string `json:"request_id"`
+	ToolID     string `json:"tool_id"`
+	ResourceID string `json:"resource_id"`
+
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params struct {
+		Name string `json:"name"`
+	} `json:"params"`
+}
+
+// requestLogMiddleware tags every request with a correlation ID, returned in
+// the X-Request-ID header, and logs it to the server's Logger once handled.
+// The correlation ID is the request_id of the body, or a generated one if
+// the client did not send any.
+func (s *MCPServer) requestLogMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		fields := readLoggedRequest(c, s.MaxBodyBytes)
+
+		requestID := fields.RequestID
+		if requestID == "" && len(fields.ID) > 0 {
+			requestID = strings.Trim(string(fields.ID), `"`)
+		}
+		if requestID == "" {
+			requestID = GenerateRequestID()
+		}
+		c.Set(requestIDKey, requestID)
+		c.Response().Header().Set(echo.HeaderXRequestID, requestID)
+
+		err := next(c)
+		if s.Logger == nil {
+			return err
+		}
+
+		// Let echo write the error response so its status is logged
+		if err != nil {
+			c.Error(err)
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request().Method),
+			slog.String("path", c.Request().URL.Path),
+			slog.String("request_id", requestID),
+			slog.Int("status", c.Response().Status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+		}
+		if fields.Method != "" {
+			attrs = append(attrs, slog.String("rpc_method", fields.Method))
+		}
+		switch {
+		case fields.ToolID != "":
+			attrs = append(attrs, slog.String("tool_id", fields.ToolID))
+		case fields.ResourceID != "":
+			attrs = append(attrs, slog.String("resource_id", fields.ResourceID))
+		case fields.Params.Name != "":
+			attrs = append(attrs, slog.String("name", fields.Params.Name))
+		}
+		if resultStatus, ok := c.Get(resultStatusKey).(string); ok {
+			attrs = append(attrs, slog.String("result_status", resultStatus))
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		level := slog.LevelInfo
+		if c.Response().Status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		s.Logger.LogAttrs(c.Request().Context(), level, "request", attrs...)
+		return nil
+	}
+}
+
+// readLoggedRequest extracts the identifying fields of a request, leaving its
+// body intact for the handler. Bodies that are not JSON objects yield no fields,
+// and uploads are never read, so they can be streamed to disk. At most limit
+// bytes are read ahead, if limit is positive, since the request has not been
+// authenticated or held to the body limit yet; larger bodies yield no fields
+// and are left for the body limit to reject.
+func readLoggedRequest(c echo.Context, limit int64) loggedRequest {
+	var fields loggedRequest
+	request := c.Request()
+
+	if request.Method == http.MethodGet {
+		fields.ResourceID = c.QueryParam("resource_id")
+		return fields
+	}
+	if request.Body == nil || c.Path() == "/v1/upload" {
+		return fields
+	}
+
+	body := request.Body
+	reader := io.Reader(body)
+	if limit > 0 {
+		reader = io.LimitReader(body, limit+1)
+	}
+	prefix, err := io.ReadAll(reader)
+	request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(prefix), body), body}
+	if err != nil || (limit > 0 && int64(len(prefix)) > limit) {
+		return fields
+	}
+	json.Unmarshal(prefix, &fields)
+	return fields
+}
+
+// correlationID returns the ID the request logger assigned to the request, if any
+func correlationID(c echo.Context) string {
+	requestID, _ := c.Get(requestIDKey).(string)
+	return requestID
+}
+
+// setResultStatus records the status of a tool or resource result for the request log
+func setResultStatus(c echo.Context, status string) {
+	c.Set(resultStatusKey, status)
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/loag/mcp-server-test/mcp"
+)
+
+// metricsBuckets are the upper bounds, in seconds, of the latency histogram buckets
+var metricsBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics records the number, failures and latency of tool calls and resource
+// loads, keyed by tool and resource ID
+type Metrics struct {
+	mu        sync.Mutex
+	tools     map[string]*operationMetrics
+	resources map[string]*operationMetrics
+}
+
+// operationMetrics holds the counters and latency histogram of one tool or resource
+type operationMetrics struct {
+	count   uint64
+	errors  uint64
+	buckets []uint64
+	sum     float64
+}
+
+// NewMetrics creates an empty metrics registry
+func NewMetrics() *Metrics {
+	return &Metrics{
+		tools:     make(map[string]*operationMetrics),
+		resources: make(map[string]*operationMetrics),
+	}
+}
+
+// ObserveToolCall records a tool call that took duration and whether it failed
+func (m *Metrics) ObserveToolCall(toolID string, duration time.Duration, failed bool) {
+	m.observe(m.tools, toolID, duration, failed)
+}
+
+// ObserveResourceLoad records a resource load that took duration and whether it failed
+func (m *Metrics) ObserveResourceLoad(resourceID string, duration time.Duration, failed bool) {
+	m.observe(m.resources, resourceID, duration, failed)
+}
+
+func (m *Metrics) observe(operations map[string]*operationMetrics, id string, duration time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	op, exists := operations[id]
+	if !exists {
+		op = &operationMetrics{buckets: make([]uint64, len(metricsBuckets))}
+		operations[id] = op
+	}
+
+	seconds := duration.Seconds()
+	op.count++
+	op.sum += seconds
+	if failed {
+		op.errors++
+	}
+	for i, bound := range metricsBuckets {
+		if seconds <= bound {
+			op.buckets[i]++
+		}
+	}
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	writeOperationMetrics(&b, "mcp_tool_calls", "tool", "tool call", m.tools)
+	writeOperationMetrics(&b, "mcp_resource_loads", "resource", "resource load", m.resources)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeOperationMetrics writes the call counter, error counter and latency
+// histogram of a set of operations
+func writeOperationMetrics(b *strings.Builder, name, label, description string, operations map[string]*operationMetrics) {
+	ids := make([]string, 0, len(operations))
+	for id := range operations {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	fmt.Fprintf(b, "# HELP %s_total Number of %ss.\n", name, description)
+	fmt.Fprintf(b, "# TYPE %s_total counter\n", name)
+	for _, id := range ids {
+		fmt.Fprintf(b, "%s_total{%s=\"%s\"} %d\n", name, label, escapeLabelValue(id), operations[id].count)
+	}
+
+	fmt.Fprintf(b, "# HELP %s_errors_total Number of failed %ss.\n", name, description)
+	fmt.Fprintf(b, "# TYPE %s_errors_total counter\n", name)
+	for _, id := range ids {
+		fmt.Fprintf(b, "%s_errors_total{%s=\"%s\"} %d\n", name, label, escapeLabelValue(id), operations[id].errors)
+	}
+
+	fmt.Fprintf(b, "# HELP %s_duration_seconds Latency of %ss.\n", name, description)
+	fmt.Fprintf(b, "# TYPE %s_duration_seconds histogram\n", name)
+	for _, id := range ids {
+		op := operations[id]
+		escaped := escapeLabelValue(id)
+		for i, bound := range metricsBuckets {
+			fmt.Fprintf(b, "%s_duration_seconds_bucket{%s=\"%s\",le=\"%g\"} %d\n", name, label, escaped, bound, op.buckets[i])
+		}
+		fmt.Fprintf(b, "%s_duration_seconds_bucket{%s=\"%s\",le=\"+Inf\"} %d\n", name, label, escaped, op.count)
+		fmt.Fprintf(b, "%s_duration_seconds_sum{%s=\"%s\"} %g\n", name, label, escaped, op.sum)
+		fmt.Fprintf(b, "%s_duration_seconds_count{%s=\"%s\"} %d\n", name, label, escaped, op.count)
+	}
+}
+
+// labelValueEscaper escapes the characters that are special in Prometheus label values
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+// writeCacheMetrics writes the statistics of the provider caches, keyed by provider name
+func writeCacheMetrics(w io.Writer, stats map[string]mcp.CacheStats) error {
+	if len(stats) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	metrics := []struct {
+		name, kind, help string
+		value            func(mcp.CacheStats) interface{}
+	}{
+		{"mcp_read_cache_hits_total", "counter", "Number of file reads served from the read cache.", func(s mcp.CacheStats) interface{} { return s.Hits }},
+		{"mcp_read_cache_misses_total", "counter", "Number of file reads not found in the read cache.", func(s mcp.CacheStats) interface{} { return s.Misses }},
+		{"mcp_read_cache_entries", "gauge", "Number of files in the read cache.", func(s mcp.CacheStats) interface{} { return s.Entries }},
+		{"mcp_read_cache_bytes", "gauge", "Total size of the files in the read cache.", func(s mcp.CacheStats) interface{} { return s.Bytes }},
+	}
+	for _, metric := range metrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(&b, "# TYPE %s %s\n", metric.name, metric.kind)
+		for _, name := range names {
+			fmt.Fprintf(&b, "%s{provider=\"%s\"} %d\n", metric.name, escapeLabelValue(name), metric.value(stats[name]))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// handleMetrics handles the metrics endpoint
+func (s *MCPServer) handleMetrics(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	c.Response().WriteHeader(http.StatusOK)
+	if err := s.Metrics.WritePrometheus(c.Response()); err != nil {
+		return err
+	}
+
+	// Add the statistics of the providers that cache
+	cacheStats := make(map[string]mcp.CacheStats)
+	for name, provider := range s.providers() {
+		if cacher, ok := provider.(mcp.CacheStatsProvider); ok {
+			if stats, enabled := cacher.CacheStats(); enabled {
+				cacheStats[name] = stats
+			}
+		}
+	}
+	return writeCacheMetrics(c.Response(), cacheStats)
+}
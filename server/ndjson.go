@@ -0,0 +1,160 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/loag/mcp-server-test/mcp"
+)
+
+// MIMEApplicationNDJSON is the content type of newline-delimited JSON
+const MIMEApplicationNDJSON = "application/x-ndjson"
+
+// defaultWalkProvider is the provider whose walk tool GET /v1/walk calls when
+// no provider is given
+const defaultWalkProvider = "filesystem"
+
+// handleWalk walks a directory with a provider's streaming walk tool and
+// writes every entry as one line of JSON, flushing as it goes, so huge trees
+// never have to be buffered. Errors before the first entry are returned as a
+// regular JSON response; errors after it end the stream with an ErrorResponse line.
+func (s *MCPServer) handleWalk(c echo.Context) error {
+	providerName := c.QueryParam("provider")
+	if providerName == "" {
+		providerName = defaultWalkProvider
+	}
+	provider, exists := s.provider(providerName)
+	if !exists {
+		return s.providerUnavailable(c, providerName)
+	}
+	streamer, ok := provider.(mcp.StreamingProvider)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, mcp.ErrorResponse{
+			Error:   "streaming_not_supported",
+			Message: "Provider does not support streaming: " + providerName,
+		})
+	}
+
+	arguments, err := walkArguments(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, mcp.ErrorResponse{
+			Error:   "invalid_arguments",
+			Message: err.Error(),
+		})
+	}
+
+	request := mcp.CallToolRequest{
+		ToolID:    providerName + ".walk",
+		RequestID: correlationID(c),
+	}
+	request.Params.Arguments = arguments
+
+	fieldErrors, err := s.validateArguments(provider, request.ToolID, request.Params.Arguments)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, mcp.ErrorResponse{
+			Error:   "invalid_tool_schema",
+			Message: err.Error(),
+		})
+	}
+	if len(fieldErrors) > 0 {
+		return c.JSON(http.StatusBadRequest, mcp.ErrorResponse{
+			Error:   "invalid_arguments",
+			Message: "Arguments do not match the parameters of " + request.ToolID,
+			Details: fieldErrors,
+		})
+	}
+
+	// The response starts with the first entry, so errors found before it
+	// can still get a status of their own
+	ctx := c.Request().Context()
+	response := c.Response()
+	encoder := json.NewEncoder(response)
+	emit := func(entry interface{}) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !response.Committed {
+			response.Header().Set(echo.HeaderContentType, MIMEApplicationNDJSON)
+			response.Header().Set(echo.HeaderCacheControl, "no-cache")
+			response.WriteHeader(http.StatusOK)
+		}
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+		response.Flush()
+		return nil
+	}
+
+	result, err := s.interceptToolCall(ctx, request, func() (*mcp.CallToolResult, error) {
+		return streamer.CallToolStream(ctx, "walk", request, emit)
+	})
+	if err == nil {
+		setResultStatus(c, result.Status)
+	}
+
+	if !response.Committed {
+		switch {
+		case err != nil:
+			return c.JSON(http.StatusInternalServerError, mcp.ErrorResponse{
+				Error:   "tool_execution_error",
+				Message: err.Error(),
+			})
+		case result.Status == "error":
+			if result.RequestID == "" {
+				result.RequestID = request.RequestID
+			}
+			return c.JSON(s.resultHTTPStatus(result.Status, result.Error), result)
+		}
+
+		// An empty directory is an empty stream
+		response.Header().Set(echo.HeaderContentType, MIMEApplicationNDJSON)
+		response.WriteHeader(http.StatusOK)
+		return nil
+	}
+
+	switch {
+	case err != nil && ctx.Err() != nil:
+		// The client went away
+		return nil
+	case err != nil:
+		return encoder.Encode(mcp.ErrorResponse{Error: "tool_execution_error", Message: err.Error()})
+	case result.Status == "error" && result.Error != nil:
+		return encoder.Encode(mcp.ErrorResponse{Error: result.Error.Code, Message: result.Error.Message})
+	}
+	return nil
+}
+
+// walkArguments builds the arguments of the walk tool from the query string
+func walkArguments(c echo.Context) (map[string]interface{}, error) {
+	arguments := map[string]interface{}{}
+	for _, name := range []string{"path", "pattern", "on_permission_error"} {
+		if value := c.QueryParam(name); value != "" {
+			arguments[name] = value
+		}
+	}
+	if ignore := c.QueryParams()["ignore"]; len(ignore) > 0 {
+		patterns := make([]interface{}, len(ignore))
+		for i, pattern := range ignore {
+			patterns[i] = pattern
+		}
+		arguments["ignore"] = patterns
+	}
+	if value := c.QueryParam("include_dirs"); value != "" {
+		includeDirs, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New("include_dirs must be true or false")
+		}
+		arguments["include_dirs"] = includeDirs
+	}
+	if value := c.QueryParam("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, errors.New("limit must be an integer")
+		}
+		arguments["limit"] = float64(limit)
+	}
+	return arguments, nil
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// prettyIndent is the indentation of JSON responses sent to clients that ask
+// for pretty output
+const prettyIndent = "  "
+
+// wantsPrettyJSON reports whether the client asked for indented JSON with the
+// pretty query parameter. A bare ?pretty counts as true.
+func wantsPrettyJSON(c echo.Context) bool {
+	values, ok := c.QueryParams()["pretty"]
+	if !ok {
+		return false
+	}
+	if len(values) == 0 || values[0] == "" {
+		return true
+	}
+	pretty, err := strconv.ParseBool(values[0])
+	return err == nil && pretty
+}
+
+// writeJSON sends v as JSON, indented for clients that ask for it with
+// ?pretty=1 and compact otherwise. Unlike c.JSON, which indents whenever the
+// pretty parameter is present, ?pretty=0 keeps the output compact.
+func writeJSON(c echo.Context, code int, v interface{}) error {
+	if wantsPrettyJSON(c) || c.Echo().Debug {
+		return c.JSONPretty(code, v, prettyIndent)
+	}
+
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	response.WriteHeader(code)
+	return json.NewEncoder(response).Encode(v)
+}
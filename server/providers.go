@@ -0,0 +1,56 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/loag/mcp-server-test/mcp"
+)
+
+// handleListProviders returns the names of the enabled providers, and of
+// the disabled ones separately
+func (s *MCPServer) handleListProviders(c echo.Context) error {
+	list := mcp.ProviderList{Providers: []string{}}
+	for _, name := range s.ListProviders() {
+		if s.providerDisabled(name) {
+			list.Disabled = append(list.Disabled, name)
+		} else {
+			list.Providers = append(list.Providers, name)
+		}
+	}
+	return c.JSON(http.StatusOK, list)
+}
+
+// handleGetProvider returns the tools and resources of a single provider,
+// a lighter alternative to discover for clients that know what they need
+func (s *MCPServer) handleGetProvider(c echo.Context) error {
+	providerName := c.Param("name")
+	provider, exists := s.provider(providerName)
+	if !exists {
+		return s.providerUnavailable(c, providerName)
+	}
+	return c.JSON(http.StatusOK, provider.GetInfo())
+}
+
+// providerUnavailable answers a request for a provider that provider did not
+// return: with 503 if it is disabled, or else 404
+func (s *MCPServer) providerUnavailable(c echo.Context, providerName string) error {
+	if s.providerDisabled(providerName) {
+		return c.JSON(http.StatusServiceUnavailable, mcp.ErrorResponse{
+			Error:   "provider_disabled",
+			Message: "Provider is disabled: " + providerName,
+		})
+	}
+	return c.JSON(http.StatusNotFound, mcp.ErrorResponse{
+		Error:   "provider_not_found",
+		Message: "Provider not found: " + providerName,
+	})
+}
+
+// rpcProviderUnavailable is providerUnavailable for JSON-RPC requests
+func (s *MCPServer) rpcProviderUnavailable(providerName string) *mcp.JSONRPCError {
+	if s.providerDisabled(providerName) {
+		return &mcp.JSONRPCError{Code: mcp.JSONRPCInvalidParams, Message: "Provider is disabled: " + providerName}
+	}
+	return &mcp.JSONRPCError{Code: mcp.JSONRPCInvalidParams, Message: "Provider not found: " + providerName}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/loag/mcp-server-test/mcp"
+	"golang.org/x/time/rate"
+)
+
+// rateLimiterIdleTTL is how long a client's token bucket is kept after its last request
+const rateLimiterIdleTTL = 10 * time.Minute
+
+// clientLimiter is the token bucket of a single client
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimitMiddleware rejects requests from clients that exceed the server's
+// rate limit. Clients are identified by IP address. It lets every request
+// through when the rate limit is zero.
+func (s *MCPServer) rateLimitMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if s.RateLimit <= 0 {
+			return next(c)
+		}
+
+		if !s.clientLimiter(c.RealIP()).Allow() {
+			return c.JSON(http.StatusTooManyRequests, mcp.ErrorResponse{
+				Error:   "rate_limited",
+				Message: "Too many requests, please slow down",
+			})
+		}
+
+		return next(c)
+	}
+}
+
+// clientLimiter returns the token bucket of a client, creating it if needed.
+// Buckets of clients that have been idle for a while are dropped.
+func (s *MCPServer) clientLimiter(ip string) *rate.Limiter {
+	s.limitersMu.Lock()
+	defer s.limitersMu.Unlock()
+
+	now := time.Now()
+	if s.limiters == nil {
+		s.limiters = make(map[string]*clientLimiter)
+	}
+
+	// Drop idle clients at most once per TTL so the map cannot grow without bound
+	if now.Sub(s.limitersPruned) > rateLimiterIdleTTL {
+		for key, client := range s.limiters {
+			if now.Sub(client.lastSeen) > rateLimiterIdleTTL {
+				delete(s.limiters, key)
+			}
+		}
+		s.limitersPruned = now
+	}
+
+	client, exists := s.limiters[ip]
+	if !exists {
+		burst := s.RateBurst
+		if burst < 1 {
+			burst = 1
+		}
+		client = &clientLimiter{limiter: rate.NewLimiter(rate.Limit(s.RateLimit), burst)}
+		s.limiters[ip] = client
+	}
+	client.lastSeen = now
+	return client.limiter
+}
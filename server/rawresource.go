@@ -0,0 +1,31 @@
+package server
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/loag/mcp-server-test/mcp"
+)
+
+// handleRawResource loads a resource with the raw parameter set and sends its
+// content as it is, so browsers can display images and documents directly.
+// The resource is named by the resource_id query parameter and its
+// parameters are passed as further query parameters. An If-None-Match header
+// is answered with 304 Not Modified while the content is unchanged.
+func (s *MCPServer) handleRawResource(c echo.Context) error {
+	params := make(map[string]interface{})
+	for key, values := range c.QueryParams() {
+		if key != "resource_id" && len(values) > 0 {
+			params[key] = values[0]
+		}
+	}
+	params["raw"] = true
+	if ifNoneMatch := c.Request().Header.Get("If-None-Match"); ifNoneMatch != "" {
+		params["if_none_match"] = ifNoneMatch
+	}
+
+	request := mcp.LoadResourceRequest{
+		ResourceID: c.QueryParam("resource_id"),
+		RequestID:  correlationID(c),
+		Params:     params,
+	}
+	return s.loadResource(c, request)
+}
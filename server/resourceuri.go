@@ -0,0 +1,73 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/loag/mcp-server-test/mcp"
+)
+
+// resolveResourceURI maps a request addressing a resource by URI, either as
+// its resource_id or, without one, as its uri parameter, to the resource of
+// the provider advertising a template with the URI's scheme. The URI is
+// passed on as the uri parameter. Other requests are returned unchanged.
+func (s *MCPServer) resolveResourceURI(request mcp.LoadResourceRequest) (mcp.LoadResourceRequest, error) {
+	uri := ""
+	if strings.Contains(request.ResourceID, "://") {
+		uri = request.ResourceID
+	} else if request.ResourceID == "" {
+		uri, _ = request.Params["uri"].(string)
+	}
+	if uri == "" {
+		return request, nil
+	}
+
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme == "" {
+		return request, fmt.Errorf("invalid resource URI: %s", uri)
+	}
+	template, ok := s.resourceTemplate(parsed.Scheme)
+	if !ok {
+		return request, fmt.Errorf("no resource template matches URI scheme %s", parsed.Scheme)
+	}
+
+	params := make(map[string]interface{}, len(request.Params)+1)
+	for key, value := range request.Params {
+		params[key] = value
+	}
+	params["uri"] = uri
+	request.ResourceID = template.ResourceID
+	request.Params = params
+	return request, nil
+}
+
+// resourceTemplate finds the template for URIs of the given scheme among the
+// enabled providers, looking at the providers in order of their names
+func (s *MCPServer) resourceTemplate(scheme string) (mcp.ResourceTemplate, bool) {
+	for _, template := range s.resourceTemplates() {
+		templateScheme, _, _ := strings.Cut(template.URITemplate, ":")
+		if strings.EqualFold(templateScheme, scheme) {
+			return template, true
+		}
+	}
+	return mcp.ResourceTemplate{}, false
+}
+
+// resourceTemplates lists the resource templates of the enabled providers,
+// in order of the providers' names
+func (s *MCPServer) resourceTemplates() []mcp.ResourceTemplate {
+	providers := s.enabledProviders()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	templates := make([]mcp.ResourceTemplate, 0)
+	for _, name := range names {
+		templates = append(templates, providers[name].GetInfo().ResourceTemplates...)
+	}
+	return templates
+}
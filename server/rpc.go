@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/loag/mcp-server-test/mcp"
+)
+
+// ProtocolVersion is the MCP protocol revision reported by initialize
+const ProtocolVersion = "2024-11-05"
+
+// handleRPC handles JSON-RPC 2.0 requests on the /rpc endpoint
+func (s *MCPServer) handleRPC(c echo.Context) error {
+	var request mcp.JSONRPCRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&request); err != nil {
+		return c.JSON(http.StatusOK, rpcError(nil, mcp.JSONRPCParseError, "Failed to parse request body"))
+	}
+
+	if request.JSONRPC != mcp.JSONRPCVersion || request.Method == "" {
+		return c.JSON(http.StatusOK, rpcError(request.ID, mcp.JSONRPCInvalidRequest, "Invalid JSON-RPC 2.0 request"))
+	}
+
+	result, rpcErr := s.dispatchRPC(c.Request().Context(), request)
+
+	// Notifications never get a response
+	if len(request.ID) == 0 {
+		return c.NoContent(http.StatusAccepted)
+	}
+
+	if rpcErr != nil {
+		return c.JSON(http.StatusOK, mcp.JSONRPCResponse{
+			JSONRPC: mcp.JSONRPCVersion,
+			ID:      request.ID,
+			Error:   rpcErr,
+		})
+	}
+	return c.JSON(http.StatusOK, mcp.JSONRPCResponse{
+		JSONRPC: mcp.JSONRPCVersion,
+		ID:      request.ID,
+		Result:  result,
+	})
+}
+
+// dispatchRPC runs a JSON-RPC method against the registered providers
+func (s *MCPServer) dispatchRPC(ctx context.Context, request mcp.JSONRPCRequest) (interface{}, *mcp.JSONRPCError) {
+	switch request.Method {
+	case "initialize":
+		return mcp.InitializeResult{
+			ProtocolVersion: ProtocolVersion,
+			ServerInfo: mcp.ServerInfo{
+				Name:        s.Name,
+				Version:     s.Version,
+				Description: s.Description,
+			},
+			Capabilities: map[string]interface{}{
+				"tools":     map[string]interface{}{},
+				"resources": map[string]interface{}{},
+			},
+		}, nil
+
+	case "notifications/initialized":
+		return nil, nil
+
+	case "tools/list":
+		tools := make([]mcp.ToolInfo, 0)
+		for _, provider := range s.enabledProviders() {
+			tools = append(tools, provider.GetInfo().Tools...)
+		}
+		return map[string]interface{}{"tools": tools}, nil
+
+	case "resources/list":
+		resources := make([]mcp.ResourceInfo, 0)
+		for _, provider := range s.enabledProviders() {
+			resources = append(resources, provider.GetInfo().Resources...)
+		}
+		return map[string]interface{}{"resources": resources}, nil
+
+	case "resources/templates/list":
+		return map[string]interface{}{"resourceTemplates": s.resourceTemplates()}, nil
+
+	case "tools/call":
+		params, rpcErr := parseRPCCallParams(request.Params)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+
+		providerName, toolName, err := parseToolID(params.Name)
+		if err != nil {
+			return nil, &mcp.JSONRPCError{Code: mcp.JSONRPCInvalidParams, Message: "Invalid tool name: " + params.Name}
+		}
+		provider, exists := s.provider(providerName)
+		if !exists {
+			return nil, s.rpcProviderUnavailable(providerName)
+		}
+
+		fieldErrors, err := s.validateArguments(provider, params.Name, params.Arguments)
+		if err != nil {
+			return nil, &mcp.JSONRPCError{Code: mcp.JSONRPCInternalError, Message: err.Error()}
+		}
+		if len(fieldErrors) > 0 {
+			return nil, &mcp.JSONRPCError{Code: mcp.JSONRPCInvalidParams, Message: "Arguments do not match the parameters of " + params.Name, Data: fieldErrors}
+		}
+
+		callRequest := mcp.CallToolRequest{
+			ToolID:    params.Name,
+			RequestID: string(request.ID),
+			Params:    mcp.CallToolParams{Arguments: params.Arguments},
+		}
+		result, err := runWithContext(ctx, func() (*mcp.CallToolResult, error) {
+			return s.interceptToolCall(ctx, callRequest, func() (*mcp.CallToolResult, error) {
+				return provider.CallTool(ctx, toolName, callRequest)
+			})
+		})
+		if err != nil {
+			return nil, rpcExecutionError(err)
+		}
+		return result, nil
+
+	case "resources/read":
+		params, rpcErr := parseRPCCallParams(request.Params)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+
+		loadRequest := mcp.LoadResourceRequest{
+			ResourceID: params.Name,
+			RequestID:  string(request.ID),
+			Params:     params.Arguments,
+		}
+		if loadRequest.ResourceID == "" {
+			loadRequest.ResourceID = params.URI
+		}
+		loadRequest, err := s.resolveResourceURI(loadRequest)
+		if err != nil {
+			return nil, &mcp.JSONRPCError{Code: mcp.JSONRPCInvalidParams, Message: err.Error()}
+		}
+
+		providerName, resourceName, err := parseResourceID(loadRequest.ResourceID)
+		if err != nil {
+			return nil, &mcp.JSONRPCError{Code: mcp.JSONRPCInvalidParams, Message: "Invalid resource name: " + loadRequest.ResourceID}
+		}
+		provider, exists := s.provider(providerName)
+		if !exists {
+			return nil, s.rpcProviderUnavailable(providerName)
+		}
+
+		result, err := s.loadProviderResource(ctx, provider, resourceName, loadRequest)
+		if err != nil {
+			return nil, rpcExecutionError(err)
+		}
+		return result, nil
+
+	default:
+		return nil, &mcp.JSONRPCError{Code: mcp.JSONRPCMethodNotFound, Message: "Method not found: " + request.Method}
+	}
+}
+
+// parseRPCCallParams decodes the parameters of tools/call and resources/read
+func parseRPCCallParams(raw json.RawMessage) (mcp.JSONRPCCallParams, *mcp.JSONRPCError) {
+	var params mcp.JSONRPCCallParams
+	if len(raw) == 0 {
+		return params, &mcp.JSONRPCError{Code: mcp.JSONRPCInvalidParams, Message: "Missing params"}
+	}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return params, &mcp.JSONRPCError{Code: mcp.JSONRPCInvalidParams, Message: "Invalid params: " + err.Error()}
+	}
+	if params.Name == "" && params.URI == "" {
+		return params, &mcp.JSONRPCError{Code: mcp.JSONRPCInvalidParams, Message: "Missing name parameter"}
+	}
+	return params, nil
+}
+
+// rpcExecutionError converts a provider failure into a JSON-RPC error
+func rpcExecutionError(err error) *mcp.JSONRPCError {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &mcp.JSONRPCError{Code: mcp.JSONRPCInternalError, Message: "Request timed out", Data: "timeout"}
+	}
+	return &mcp.JSONRPCError{Code: mcp.JSONRPCInternalError, Message: err.Error()}
+}
+
+// rpcError builds an error response
+func rpcError(id json.RawMessage, code int, message string) mcp.JSONRPCResponse {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return mcp.JSONRPCResponse{
+		JSONRPC: mcp.JSONRPCVersion,
+		ID:      id,
+		Error:   &mcp.JSONRPCError{Code: code, Message: message},
+	}
+}
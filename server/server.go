@@ -1,7 +1,19 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"mime"
 	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -13,9 +25,90 @@ type MCPServer struct {
 	Name        string
 	Version     string
 	Description string
-	Providers   map[string]mcp.Provider
+
+	// Providers holds the registered providers by name. Use RegisterProvider
+	// and UnregisterProvider to change it once the server handles requests.
+	Providers map[string]mcp.Provider
+
+	// RequestTimeout bounds how long a single tool call or resource load may
+	// take. Zero disables the timeout.
+	RequestTimeout time.Duration
+
+	// BatchConcurrency is the number of calls of a batch request that run in
+	// parallel
+	BatchConcurrency int
+
+	// AuthToken, when set, is the bearer token clients must send to use the
+	// /v1 and /rpc endpoints
+	AuthToken string
+
+	// RateLimit is the number of requests per second each client IP may make
+	// to the /v1 and /rpc endpoints, with bursts of up to RateBurst requests.
+	// Zero disables rate limiting.
+	RateLimit float64
+	RateBurst int
+
+	// Metrics, when set, records tool call and resource load statistics and
+	// exposes them at /metrics
+	Metrics *Metrics
+
+	// Compress gzips the responses of the /v1 and /rpc endpoints for clients
+	// that send Accept-Encoding: gzip
+	Compress bool
+
+	// Logger logs every /v1 and /rpc request as a structured record, at
+	// error level for requests that failed with a 5xx status. NewMCPServer
+	// sets it to log JSON to standard output; nil disables logging.
+	Logger *slog.Logger
+
+	// ToolInterceptors wrap every tool call, in order. Use Use to add to them.
+	ToolInterceptors []ToolInterceptor
+
+	// MaxBodyBytes is the largest request body the /v1 and /rpc endpoints
+	// accept; larger ones are rejected with HTTP 413. It is read when the
+	// routes are registered. Zero disables the limit.
+	MaxBodyBytes int64
+
+	// IdempotencyTTL is how long the result of a call-tool request with an
+	// idempotency_key is returned for retries with the same key instead of
+	// calling the tool again. At most IdempotencyKeys keys are remembered,
+	// or any number if it is zero. A zero TTL disables idempotency keys.
+	IdempotencyTTL  time.Duration
+	IdempotencyKeys int
+
+	// AllowedOrigins lists the origins of the web pages that may open a
+	// WebSocket on /ws, or "*" for any. Handshakes from other origins are
+	// refused; with none listed only pages of the server's own origin, and
+	// clients that send no Origin, may connect.
+	AllowedOrigins []string
+
+	// StrictHTTPStatus sends failed tool calls and resource loads with an
+	// HTTP status matching their error code instead of 200. The JSON body is
+	// the same either way.
+	StrictHTTPStatus bool
+
+	// providersMu guards Providers and disabled, which may change while
+	// requests are handled
+	providersMu sync.RWMutex
+
+	// disabled holds the names of the registered providers that are
+	// temporarily taken out of service
+	disabled map[string]bool
+
+	// schemas caches the compiled parameter schemas of tools by tool ID
+	schemas sync.Map
+
+	limitersMu     sync.Mutex
+	limiters       map[string]*clientLimiter
+	limitersPruned time.Time
+
+	idempotencyMu sync.Mutex
+	idempotency   idempotentCalls
 }
 
+// DefaultRequestTimeout is the request timeout of a newly created server
+const DefaultRequestTimeout = 30 * time.Second
+
 // NewMCPServer creates a new MCP server instance
 func NewMCPServer(name, version, description string) *MCPServer {
 	return &MCPServer{
@@ -23,12 +116,200 @@ func NewMCPServer(name, version, description string) *MCPServer {
 		Version:     version,
 		Description: description,
 		Providers:   make(map[string]mcp.Provider),
+
+		RequestTimeout:   DefaultRequestTimeout,
+		BatchConcurrency: DefaultBatchConcurrency,
+		MaxBodyBytes:     DefaultMaxBodyBytes,
+		IdempotencyTTL:   DefaultIdempotencyTTL,
+		IdempotencyKeys:  DefaultIdempotencyKeys,
+		Logger:           slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+	}
+}
+
+// RegisterProvider registers a provider with the server. It fails if the
+// provider's name is empty, contains a dot or is already registered: IDs are
+// split at their first dot, so provider names must not contain dots while
+// tool and resource names may.
+func (s *MCPServer) RegisterProvider(provider mcp.Provider) error {
+	name := provider.GetName()
+	if name == "" {
+		return errors.New("provider name must not be empty")
+	}
+	if strings.Contains(name, ".") {
+		return fmt.Errorf("provider name %q must not contain a dot", name)
+	}
+
+	s.providersMu.Lock()
+	defer s.providersMu.Unlock()
+
+	if _, exists := s.Providers[name]; exists {
+		return fmt.Errorf("provider %q is already registered", name)
 	}
+	s.Providers[name] = provider
+	s.forgetSchemas(name)
+	return nil
 }
 
-// RegisterProvider registers a provider with the server
-func (s *MCPServer) RegisterProvider(provider mcp.Provider) {
-	s.Providers[provider.GetName()] = provider
+// UnregisterProvider removes the provider with the given name, reporting
+// whether it was registered. The provider is not stopped.
+func (s *MCPServer) UnregisterProvider(name string) bool {
+	s.providersMu.Lock()
+	defer s.providersMu.Unlock()
+
+	if _, exists := s.Providers[name]; !exists {
+		return false
+	}
+	delete(s.Providers, name)
+	delete(s.disabled, name)
+	s.forgetSchemas(name)
+	return true
+}
+
+// EnableProvider puts a disabled provider back into service, reporting
+// whether it is registered
+func (s *MCPServer) EnableProvider(name string) bool {
+	s.providersMu.Lock()
+	defer s.providersMu.Unlock()
+
+	if _, exists := s.Providers[name]; !exists {
+		return false
+	}
+	delete(s.disabled, name)
+	return true
+}
+
+// DisableProvider takes a provider out of service without unregistering it,
+// reporting whether it is registered. Disabled providers are left out of
+// discovery and their tools and resources fail with provider_disabled until
+// the provider is enabled again.
+func (s *MCPServer) DisableProvider(name string) bool {
+	s.providersMu.Lock()
+	defer s.providersMu.Unlock()
+
+	if _, exists := s.Providers[name]; !exists {
+		return false
+	}
+	if s.disabled == nil {
+		s.disabled = make(map[string]bool)
+	}
+	s.disabled[name] = true
+	return true
+}
+
+// ProviderEnabled reports whether a provider is registered and not disabled
+func (s *MCPServer) ProviderEnabled(name string) bool {
+	_, enabled := s.provider(name)
+	return enabled
+}
+
+// ListProviders returns the names of the registered providers in sorted order
+func (s *MCPServer) ListProviders() []string {
+	s.providersMu.RLock()
+	defer s.providersMu.RUnlock()
+
+	names := make([]string, 0, len(s.Providers))
+	for name := range s.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// provider returns the provider registered under the given name, unless it
+// is disabled. Use providerUnavailable to answer requests for the others.
+func (s *MCPServer) provider(name string) (mcp.Provider, bool) {
+	s.providersMu.RLock()
+	defer s.providersMu.RUnlock()
+
+	if s.disabled[name] {
+		return nil, false
+	}
+	provider, exists := s.Providers[name]
+	return provider, exists
+}
+
+// providerDisabled reports whether a provider is registered but disabled
+func (s *MCPServer) providerDisabled(name string) bool {
+	s.providersMu.RLock()
+	defer s.providersMu.RUnlock()
+
+	return s.disabled[name]
+}
+
+// providers returns a copy of the registered providers, so callers can use
+// them without holding the lock
+func (s *MCPServer) providers() map[string]mcp.Provider {
+	s.providersMu.RLock()
+	defer s.providersMu.RUnlock()
+
+	providers := make(map[string]mcp.Provider, len(s.Providers))
+	for name, provider := range s.Providers {
+		providers[name] = provider
+	}
+	return providers
+}
+
+// enabledProviders is providers without the disabled ones
+func (s *MCPServer) enabledProviders() map[string]mcp.Provider {
+	s.providersMu.RLock()
+	defer s.providersMu.RUnlock()
+
+	providers := make(map[string]mcp.Provider, len(s.Providers))
+	for name, provider := range s.Providers {
+		if !s.disabled[name] {
+			providers[name] = provider
+		}
+	}
+	return providers
+}
+
+// forgetSchemas drops the cached parameter schemas of a provider's tools
+func (s *MCPServer) forgetSchemas(providerName string) {
+	s.schemas.Range(func(key, _ interface{}) bool {
+		if toolID, ok := key.(string); ok && strings.HasPrefix(toolID, providerName+".") {
+			s.schemas.Delete(key)
+		}
+		return true
+	})
+}
+
+// Start starts every registered provider that implements mcp.ProviderLifecycle.
+// If a provider fails to start, the providers started before it are stopped again.
+func (s *MCPServer) Start(ctx context.Context) error {
+	providers := s.providers()
+	started := make([]mcp.ProviderLifecycle, 0, len(providers))
+	for name, provider := range providers {
+		lifecycle, ok := provider.(mcp.ProviderLifecycle)
+		if !ok {
+			continue
+		}
+
+		if err := lifecycle.Start(ctx); err != nil {
+			for _, startedProvider := range started {
+				startedProvider.Stop(ctx)
+			}
+			return fmt.Errorf("starting provider %s: %w", name, err)
+		}
+		started = append(started, lifecycle)
+	}
+	return nil
+}
+
+// Stop stops every registered provider that implements mcp.ProviderLifecycle.
+// All providers are stopped even if some fail; their errors are joined.
+func (s *MCPServer) Stop(ctx context.Context) error {
+	var errs []error
+	for name, provider := range s.providers() {
+		lifecycle, ok := provider.(mcp.ProviderLifecycle)
+		if !ok {
+			continue
+		}
+
+		if err := lifecycle.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stopping provider %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // RegisterRoutes registers the MCP routes with the Echo instance
@@ -36,10 +317,56 @@ func (s *MCPServer) RegisterRoutes(e *echo.Echo) {
 	// MCP server info endpoint
 	e.GET("/", s.handleServerInfo)
 
+	// Liveness and readiness probes
+	e.GET("/healthz", s.handleHealthz)
+	e.GET("/readyz", s.handleReadyz)
+
+	// Prometheus metrics
+	if s.Metrics != nil {
+		e.GET("/metrics", s.handleMetrics)
+	}
+
 	// MCP protocol endpoints
-	e.POST("/v1/discover", s.handleDiscover)
-	e.POST("/v1/call-tool", s.handleCallTool)
-	e.POST("/v1/load-resource", s.handleLoadResource)
+	compress := s.compressMiddleware()
+	bodyLimit := s.bodyLimitMiddleware()
+	v1 := e.Group("/v1", s.requestLogMiddleware, compress, s.rateLimitMiddleware, s.authMiddleware, bodyLimit)
+	v1.POST("/initialize", s.handleInitialize)
+	v1.POST("/discover", s.handleDiscover)
+	v1.GET("/providers", s.handleListProviders)
+	v1.GET("/providers/:name", s.handleGetProvider)
+	v1.POST("/call-tool", s.handleCallTool, s.timeoutMiddleware)
+	v1.GET("/tool/:id", s.handleGetTool, s.timeoutMiddleware)
+	v1.POST("/load-resource", s.handleLoadResource, s.timeoutMiddleware)
+	v1.POST("/batch", s.handleBatch, s.timeoutMiddleware)
+	v1.GET("/stream-resource", s.handleStreamResource)
+	v1.GET("/download", s.handleDownload)
+	v1.GET("/raw-resource", s.handleRawResource, s.timeoutMiddleware)
+	v1.GET("/walk", s.handleWalk)
+
+	// JSON-RPC over a WebSocket. Compression does not apply to the upgraded
+	// connection and frames are limited by MaxBodyBytes instead.
+	e.GET("/ws", s.handleWebSocket, s.requestLogMiddleware, s.rateLimitMiddleware, s.authMiddleware)
+
+	// Uploads are streamed to disk and capped by the provider's write limit
+	// rather than by MaxBodyBytes
+	e.POST("/v1/upload", s.handleUpload, s.requestLogMiddleware, compress, s.rateLimitMiddleware, s.authMiddleware)
+
+	// JSON-RPC 2.0 transport used by standard MCP clients
+	e.POST("/rpc", s.handleRPC, s.requestLogMiddleware, compress, s.rateLimitMiddleware, s.authMiddleware, bodyLimit, s.timeoutMiddleware)
+}
+
+// timeoutMiddleware bounds the request context by the server's request timeout
+func (s *MCPServer) timeoutMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if s.RequestTimeout <= 0 {
+			return next(c)
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request().Context(), s.RequestTimeout)
+		defer cancel()
+		c.SetRequest(c.Request().WithContext(ctx))
+		return next(c)
+	}
 }
 
 // handleServerInfo handles the server info endpoint
@@ -62,11 +389,11 @@ func (s *MCPServer) handleDiscover(c echo.Context) error {
 			Version:     s.Version,
 			Description: s.Description,
 		},
-		Providers: make([]mcp.ProviderInfo, 0, len(s.Providers)),
+		Providers: make([]mcp.ProviderInfo, 0),
 	}
 
 	// Add provider information
-	for _, provider := range s.Providers {
+	for _, provider := range s.enabledProviders() {
 		providerInfo := provider.GetInfo()
 		response.Providers = append(response.Providers, providerInfo)
 	}
@@ -78,33 +405,75 @@ func (s *MCPServer) handleDiscover(c echo.Context) error {
 func (s *MCPServer) handleCallTool(c echo.Context) error {
 	var request mcp.CallToolRequest
 	if err := c.Bind(&request); err != nil {
-		return c.JSON(http.StatusBadRequest, mcp.ErrorResponse{
+		return writeJSON(c, http.StatusBadRequest, mcp.ErrorResponse{
 			Error:   "invalid_request",
 			Message: "Failed to parse request body",
 		})
 	}
+	if request.RequestID == "" {
+		request.RequestID = correlationID(c)
+	}
+	return s.callTool(c, request)
+}
 
+// callTool validates and runs a tool call and writes its result as the response
+func (s *MCPServer) callTool(c echo.Context, request mcp.CallToolRequest) error {
 	// Find the provider and tool
 	providerName, toolName, err := parseToolID(request.ToolID)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, mcp.ErrorResponse{
+		return writeJSON(c, http.StatusBadRequest, mcp.ErrorResponse{
 			Error:   "invalid_tool_id",
 			Message: err.Error(),
 		})
 	}
 
-	provider, exists := s.Providers[providerName]
+	provider, exists := s.provider(providerName)
 	if !exists {
-		return c.JSON(http.StatusNotFound, mcp.ErrorResponse{
-			Error:   "provider_not_found",
-			Message: "Provider not found: " + providerName,
+		return s.providerUnavailable(c, providerName)
+	}
+
+	// Validate the arguments against the tool's parameter schema
+	fieldErrors, err := s.validateArguments(provider, request.ToolID, request.Params.Arguments)
+	if err != nil {
+		return writeJSON(c, http.StatusInternalServerError, mcp.ErrorResponse{
+			Error:   "invalid_tool_schema",
+			Message: err.Error(),
+		})
+	}
+	if len(fieldErrors) > 0 {
+		return writeJSON(c, http.StatusBadRequest, mcp.ErrorResponse{
+			Error:   "invalid_arguments",
+			Message: "Arguments do not match the parameters of " + request.ToolID,
+			Details: fieldErrors,
 		})
 	}
 
+	// Stream the output to clients that accept Server-Sent Events
+	if wantsEventStream(c) {
+		return s.streamToolCall(c, provider, toolName, request)
+	}
+
 	// Call the tool
-	result, err := provider.CallTool(toolName, request)
+	ctx := c.Request().Context()
+	result, replayed, err := s.callOnce(ctx, request, func() (*mcp.CallToolResult, error) {
+		return s.interceptToolCall(ctx, request, func() (*mcp.CallToolResult, error) {
+			return provider.CallTool(ctx, toolName, request)
+		})
+	})
+	if errors.Is(err, errIdempotencyConflict) {
+		return writeJSON(c, http.StatusUnprocessableEntity, mcp.ErrorResponse{
+			Error:   "idempotency_key_conflict",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return writeJSON(c, http.StatusGatewayTimeout, mcp.ErrorResponse{
+			Error:   "timeout",
+			Message: "Tool call exceeded the request timeout of " + s.RequestTimeout.String(),
+		})
+	}
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, mcp.ErrorResponse{
+		return writeJSON(c, http.StatusInternalServerError, mcp.ErrorResponse{
 			Error:   "tool_execution_error",
 			Message: err.Error(),
 		})
@@ -114,41 +483,66 @@ func (s *MCPServer) handleCallTool(c echo.Context) error {
 	if result.RequestID == "" {
 		result.RequestID = request.RequestID
 	}
+	setResultStatus(c, result.Status)
+	if replayed {
+		c.Response().Header().Set(idempotentReplayedHeader, "true")
+	}
 
-	return c.JSON(http.StatusOK, result)
+	return writeJSON(c, s.resultHTTPStatus(result.Status, result.Error), result)
 }
 
 // handleLoadResource handles the load-resource endpoint
 func (s *MCPServer) handleLoadResource(c echo.Context) error {
 	var request mcp.LoadResourceRequest
 	if err := c.Bind(&request); err != nil {
-		return c.JSON(http.StatusBadRequest, mcp.ErrorResponse{
+		return writeJSON(c, http.StatusBadRequest, mcp.ErrorResponse{
 			Error:   "invalid_request",
 			Message: "Failed to parse request body",
 		})
 	}
+	if request.RequestID == "" {
+		request.RequestID = correlationID(c)
+	}
+	return s.loadResource(c, request)
+}
+
+// loadResource loads a resource and writes the result as the response. Raw
+// content is sent as it is, with its own content type.
+func (s *MCPServer) loadResource(c echo.Context, request mcp.LoadResourceRequest) error {
+	// Resources addressed by URI are loaded from the resource of their template
+	request, err := s.resolveResourceURI(request)
+	if err != nil {
+		return writeJSON(c, http.StatusBadRequest, mcp.ErrorResponse{
+			Error:   "invalid_resource_id",
+			Message: err.Error(),
+		})
+	}
 
 	// Find the provider and resource
 	providerName, resourceName, err := parseResourceID(request.ResourceID)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, mcp.ErrorResponse{
+		return writeJSON(c, http.StatusBadRequest, mcp.ErrorResponse{
 			Error:   "invalid_resource_id",
 			Message: err.Error(),
 		})
 	}
 
-	provider, exists := s.Providers[providerName]
+	provider, exists := s.provider(providerName)
 	if !exists {
-		return c.JSON(http.StatusNotFound, mcp.ErrorResponse{
-			Error:   "provider_not_found",
-			Message: "Provider not found: " + providerName,
-		})
+		return s.providerUnavailable(c, providerName)
 	}
 
 	// Load the resource
-	result, err := provider.LoadResource(resourceName, request)
+	ctx := c.Request().Context()
+	result, err := s.loadProviderResource(ctx, provider, resourceName, request)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return writeJSON(c, http.StatusGatewayTimeout, mcp.ErrorResponse{
+			Error:   "timeout",
+			Message: "Resource load exceeded the request timeout of " + s.RequestTimeout.String(),
+		})
+	}
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, mcp.ErrorResponse{
+		return writeJSON(c, http.StatusInternalServerError, mcp.ErrorResponse{
 			Error:   "resource_load_error",
 			Message: err.Error(),
 		})
@@ -158,14 +552,114 @@ func (s *MCPServer) handleLoadResource(c echo.Context) error {
 	if result.RequestID == "" {
 		result.RequestID = request.RequestID
 	}
+	setResultStatus(c, result.Status)
+
+	// Clients that asked for raw content get plain HTTP responses
+	if raw, _ := request.Params["raw"].(bool); raw && result.Status != "error" {
+		if result.ETag != "" {
+			c.Response().Header().Set("ETag", result.ETag)
+		}
+		if result.Status == "not_modified" {
+			return c.NoContent(http.StatusNotModified)
+		}
+		if result.Raw != nil {
+			// Keep browsers from running files served as HTML or SVG
+			// with the server's origin
+			c.Response().Header().Set("X-Content-Type-Options", "nosniff")
+			c.Response().Header().Set("Content-Security-Policy", "sandbox")
+			return c.Blob(http.StatusOK, result.Raw.ContentType, result.Raw.Data)
+		}
+	}
+	return writeJSON(c, s.resultHTTPStatus(result.Status, result.Error), result)
+}
+
+// handleStreamResource streams a resource as raw bytes. The resource ID is given
+// by the resource_id query parameter and every other query parameter is passed
+// to the provider.
+func (s *MCPServer) handleStreamResource(c echo.Context) error {
+	// Find the provider and resource
+	providerName, resourceName, err := parseResourceID(c.QueryParam("resource_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, mcp.ErrorResponse{
+			Error:   "invalid_resource_id",
+			Message: err.Error(),
+		})
+	}
+
+	provider, exists := s.provider(providerName)
+	if !exists {
+		return s.providerUnavailable(c, providerName)
+	}
 
-	return c.JSON(http.StatusOK, result)
+	streamer, ok := provider.(mcp.ResourceStreamer)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, mcp.ErrorResponse{
+			Error:   "streaming_not_supported",
+			Message: "Provider does not support streaming: " + providerName,
+		})
+	}
+
+	params := make(map[string]interface{})
+	for key, values := range c.QueryParams() {
+		if key != "resource_id" && len(values) > 0 {
+			params[key] = values[0]
+		}
+	}
+
+	// Open the resource
+	stream, err := streamer.StreamResource(c.Request().Context(), resourceName, params)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			status = http.StatusNotFound
+		case errors.Is(err, mcp.ErrBusy):
+			status = http.StatusServiceUnavailable
+		}
+		return c.JSON(status, mcp.ErrorResponse{
+			Error:   "resource_stream_error",
+			Message: err.Error(),
+		})
+	}
+	defer stream.Reader.Close()
+
+	// Have browsers save the file rather than render it, and keep files
+	// served as HTML or SVG from running with the server's origin
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentLength, strconv.FormatInt(stream.Size, 10))
+	header.Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": stream.Name}))
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Content-Security-Policy", "sandbox")
+	return c.Stream(http.StatusOK, stream.ContentType, stream.Reader)
+}
+
+// runWithContext runs fn and waits for it to finish or for ctx to be done,
+// whichever comes first, so a provider that ignores its context cannot hang the request
+func runWithContext[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	type outcome struct {
+		value T
+		err   error
+	}
+
+	done := make(chan outcome, 1)
+	go func() {
+		value, err := fn()
+		done <- outcome{value, err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.value, result.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
 }
 
 // Helper functions for parsing tool and resource IDs
 func parseToolID(toolID string) (providerName, toolName string, err error) {
 	parts := mcp.ParseID(toolID)
-	if len(parts) != 2 {
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
 		return "", "", echo.NewHTTPError(http.StatusBadRequest, "Invalid tool ID format. Expected: provider.tool")
 	}
 	return parts[0], parts[1], nil
@@ -173,7 +667,7 @@ func parseToolID(toolID string) (providerName, toolName string, err error) {
 
 func parseResourceID(resourceID string) (providerName, resourceName string, err error) {
 	parts := mcp.ParseID(resourceID)
-	if len(parts) != 2 {
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
 		return "", "", echo.NewHTTPError(http.StatusBadRequest, "Invalid resource ID format. Expected: provider.resource")
 	}
 	return parts[0], parts[1], nil
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/loag/mcp-server-test/mcp"
+)
+
+// wantsEventStream reports whether the client asked for a Server-Sent Events response
+func wantsEventStream(c echo.Context) bool {
+	return strings.Contains(c.Request().Header.Get(echo.HeaderAccept), "text/event-stream")
+}
+
+// streamToolCall calls a tool and streams its output as Server-Sent Events.
+// Partial results are sent as chunk events and the final result as a result
+// event. Providers that cannot stream, and retries of calls made with the same
+// idempotency key, only send the result event.
+func (s *MCPServer) streamToolCall(c echo.Context, provider mcp.Provider, toolName string, request mcp.CallToolRequest) error {
+	ctx := c.Request().Context()
+
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, "text/event-stream")
+	response.Header().Set(echo.HeaderCacheControl, "no-cache")
+	response.Header().Set(echo.HeaderConnection, "keep-alive")
+	response.WriteHeader(http.StatusOK)
+
+	// Send the headers right away so clients see the stream open before the first event
+	response.Flush()
+
+	// Keyed calls may outlive the request, so chunks are no longer sent
+	// once it has finished
+	var emitMu sync.Mutex
+	finished := false
+	emit := func(chunk interface{}) error {
+		emitMu.Lock()
+		defer emitMu.Unlock()
+		if finished {
+			return context.Canceled
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return writeEvent(response, "chunk", chunk)
+	}
+
+	result, _, err := s.callOnce(ctx, request, func() (*mcp.CallToolResult, error) {
+		return s.interceptToolCall(ctx, request, func() (*mcp.CallToolResult, error) {
+			if streamer, ok := provider.(mcp.StreamingProvider); ok {
+				return streamer.CallToolStream(ctx, toolName, request, emit)
+			}
+			return provider.CallTool(ctx, toolName, request)
+		})
+	})
+	emitMu.Lock()
+	finished = true
+	emitMu.Unlock()
+
+	if errors.Is(err, errIdempotencyConflict) {
+		return writeEvent(response, "error", mcp.ErrorResponse{
+			Error:   "idempotency_key_conflict",
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		errorResponse := mcp.ErrorResponse{
+			Error:   "tool_execution_error",
+			Message: err.Error(),
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			errorResponse = mcp.ErrorResponse{
+				Error:   "timeout",
+				Message: "Tool call exceeded the request timeout of " + s.RequestTimeout.String(),
+			}
+		}
+		return writeEvent(response, "error", errorResponse)
+	}
+
+	// Ensure the request ID is set
+	if result.RequestID == "" {
+		result.RequestID = request.RequestID
+	}
+	return writeEvent(response, "result", result)
+}
+
+// writeEvent writes a single Server-Sent Event with a JSON payload and flushes it to the client
+func writeEvent(response *echo.Response, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(response, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	response.Flush()
+	return nil
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/loag/mcp-server-test/mcp"
+)
+
+// errorHTTPStatus maps the code of a failed tool call or resource load to the
+// HTTP status reported when StrictHTTPStatus is enabled
+var errorHTTPStatus = map[string]int{
+	mcp.ErrorCodeInvalidArgument:     http.StatusBadRequest,
+	mcp.ErrorCodeNotFound:            http.StatusNotFound,
+	mcp.ErrorCodeUnknownTool:         http.StatusNotFound,
+	mcp.ErrorCodeUnknownResource:     http.StatusNotFound,
+	mcp.ErrorCodeAlreadyExists:       http.StatusConflict,
+	mcp.ErrorCodeConflict:            http.StatusConflict,
+	mcp.ErrorCodePermissionDenied:    http.StatusForbidden,
+	mcp.ErrorCodeReadOnly:            http.StatusForbidden,
+	mcp.ErrorCodeExtensionNotAllowed: http.StatusForbidden,
+	mcp.ErrorCodeProtectedPath:       http.StatusForbidden,
+	mcp.ErrorCodeIsDirectory:         http.StatusUnprocessableEntity,
+	mcp.ErrorCodeNotDirectory:        http.StatusUnprocessableEntity,
+	mcp.ErrorCodeNotText:             http.StatusUnprocessableEntity,
+	mcp.ErrorCodeParseError:          http.StatusUnprocessableEntity,
+	mcp.ErrorCodeFileTooLarge:        http.StatusRequestEntityTooLarge,
+	mcp.ErrorCodeLimitExceeded:       http.StatusTooManyRequests,
+	mcp.ErrorCodeBusy:                http.StatusServiceUnavailable,
+	mcp.ErrorCodeStreamingRequired:   http.StatusNotAcceptable,
+	mcp.ErrorCodeUnavailable:         http.StatusNotImplemented,
+}
+
+// resultHTTPStatus returns the HTTP status of a call-tool or load-resource
+// response. Unless StrictHTTPStatus is set, results are always sent with 200
+// and clients have to look at their status.
+func (s *MCPServer) resultHTTPStatus(status string, errorInfo *mcp.ErrorInfo) int {
+	if !s.StrictHTTPStatus || status != "error" {
+		return http.StatusOK
+	}
+	if errorInfo != nil {
+		if code, ok := errorHTTPStatus[errorInfo.Code]; ok {
+			return code
+		}
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/loag/mcp-server-test/mcp"
+)
+
+// defaultUploadProvider is the provider POST /v1/upload writes to when no
+// provider is given
+const defaultUploadProvider = "filesystem"
+
+// handleUpload streams the request body to the file named by the path query
+// parameter. A multipart/form-data body has its first file part written;
+// any other body is written as is. The body is not subject to MaxBodyBytes:
+// the provider enforces its own write limit while copying.
+func (s *MCPServer) handleUpload(c echo.Context) error {
+	providerName := c.QueryParam("provider")
+	if providerName == "" {
+		providerName = defaultUploadProvider
+	}
+	provider, exists := s.provider(providerName)
+	if !exists {
+		return s.providerUnavailable(c, providerName)
+	}
+	uploader, ok := provider.(mcp.FileUploader)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, mcp.ErrorResponse{
+			Error:   "upload_not_supported",
+			Message: "Provider does not support uploads: " + providerName,
+		})
+	}
+
+	body, err := uploadBody(c.Request())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, mcp.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+	}
+
+	result, err := uploader.UploadFile(c.Request().Context(), c.QueryParam("path"), body)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, mcp.ErrorResponse{
+			Error:   "tool_execution_error",
+			Message: err.Error(),
+		})
+	}
+
+	result.RequestID = correlationID(c)
+	setResultStatus(c, result.Status)
+	return c.JSON(s.resultHTTPStatus(result.Status, result.Error), result)
+}
+
+// uploadBody returns the reader of the uploaded content: the first file part
+// of a multipart/form-data request, or else the request body itself
+func uploadBody(req *http.Request) (io.Reader, error) {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+	if mediaType != echo.MIMEMultipartForm {
+		return req.Body, nil
+	}
+
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, errors.New("multipart upload contains no file")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FileName() != "" {
+			return part, nil
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/loag/mcp-server-test/mcp"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// validationPrinter renders validation error messages
+var validationPrinter = message.NewPrinter(language.English)
+
+// validateArguments validates the arguments of a tool call against the
+// parameter schema the provider declares for the tool. It returns one error
+// per offending field, or none if the arguments are valid or the tool declares
+// no schema.
+func (s *MCPServer) validateArguments(provider mcp.Provider, toolID string, arguments map[string]interface{}) ([]mcp.FieldError, error) {
+	schema, err := s.toolSchema(provider, toolID)
+	if err != nil || schema == nil {
+		return nil, err
+	}
+
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+
+	// Round-trip the arguments through JSON so they have the types the validator expects
+	instance, err := toJSONValue(arguments)
+	if err != nil {
+		return nil, fmt.Errorf("encoding arguments: %w", err)
+	}
+
+	err = schema.Validate(instance)
+	if err == nil {
+		return nil, nil
+	}
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return nil, err
+	}
+
+	fieldErrors := collectFieldErrors(validationErr, nil)
+	sort.SliceStable(fieldErrors, func(i, j int) bool {
+		return fieldErrors[i].Field < fieldErrors[j].Field
+	})
+	return fieldErrors, nil
+}
+
+// toolSchema returns the compiled parameter schema of a tool, compiling and
+// caching it on first use. It returns nil if the tool declares no schema.
+func (s *MCPServer) toolSchema(provider mcp.Provider, toolID string) (*jsonschema.Schema, error) {
+	if cached, ok := s.schemas.Load(toolID); ok {
+		return cached.(*jsonschema.Schema), nil
+	}
+
+	var parameters interface{}
+	for _, tool := range provider.GetInfo().Tools {
+		if tool.ID == toolID {
+			parameters = tool.Parameters
+			break
+		}
+	}
+	if parameters == nil {
+		return nil, nil
+	}
+
+	document, err := toJSONValue(parameters)
+	if err != nil {
+		return nil, fmt.Errorf("encoding schema of %s: %w", toolID, err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	url := "mcp:///" + toolID
+	if err := compiler.AddResource(url, document); err != nil {
+		return nil, fmt.Errorf("loading schema of %s: %w", toolID, err)
+	}
+	schema, err := compiler.Compile(url)
+	if err != nil {
+		return nil, fmt.Errorf("compiling schema of %s: %w", toolID, err)
+	}
+
+	s.schemas.Store(toolID, schema)
+	return schema, nil
+}
+
+// collectFieldErrors flattens a validation error tree into field-level errors.
+// A missing required property is reported against the property itself.
+func collectFieldErrors(err *jsonschema.ValidationError, fieldErrors []mcp.FieldError) []mcp.FieldError {
+	if len(err.Causes) > 0 {
+		for _, cause := range err.Causes {
+			fieldErrors = collectFieldErrors(cause, fieldErrors)
+		}
+		return fieldErrors
+	}
+
+	if required, ok := err.ErrorKind.(*kind.Required); ok {
+		for _, missing := range required.Missing {
+			fieldErrors = append(fieldErrors, mcp.FieldError{
+				Field:   strings.Join(append(append([]string{}, err.InstanceLocation...), missing), "."),
+				Message: "is required",
+			})
+		}
+		return fieldErrors
+	}
+
+	return append(fieldErrors, mcp.FieldError{
+		Field:   strings.Join(err.InstanceLocation, "."),
+		Message: err.ErrorKind.LocalizedString(validationPrinter),
+	})
+}
+
+// toJSONValue converts a Go value into the generic form produced by decoding JSON
+func toJSONValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return jsonschema.UnmarshalJSON(bytes.NewReader(data))
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/loag/mcp-server-test/mcp"
+	"golang.org/x/net/websocket"
+)
+
+// webSocketInFlight is how many requests of one WebSocket connection run at
+// the same time. Further frames are not read until one of them finishes.
+const webSocketInFlight = 32
+
+// handleWebSocket upgrades the connection to a WebSocket that carries the
+// same JSON-RPC 2.0 messages as /rpc, one per text frame. Requests run
+// concurrently and their responses are sent as they finish, matched to their
+// request by id. Closing the connection cancels the requests still running.
+func (s *MCPServer) handleWebSocket(c echo.Context) error {
+	// Browsers cannot send the bearer token on a WebSocket handshake, and
+	// handshakes are not subject to CORS, so the Origin is checked here to
+	// keep other sites from opening a connection in the user's browser
+	server := websocket.Server{
+		Handshake: func(config *websocket.Config, request *http.Request) error {
+			if origin := request.Header.Get(echo.HeaderOrigin); !s.originAllowed(origin, request) {
+				return fmt.Errorf("origin not allowed: %s", origin)
+			}
+			return nil
+		},
+		Handler: s.serveWebSocket,
+	}
+	server.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
+
+// originAllowed reports whether a WebSocket handshake from origin may proceed
+func (s *MCPServer) originAllowed(origin string, request *http.Request) bool {
+	if origin == "" {
+		return true
+	}
+	if len(s.AllowedOrigins) == 0 {
+		u, err := url.Parse(origin)
+		return err == nil && u.Host == request.Host
+	}
+	return slices.Contains(s.AllowedOrigins, "*") || slices.Contains(s.AllowedOrigins, origin)
+}
+
+// serveWebSocket reads requests from ws until the client disconnects
+func (s *MCPServer) serveWebSocket(ws *websocket.Conn) {
+	defer ws.Close()
+	if s.MaxBodyBytes > 0 {
+		ws.MaxPayloadBytes = int(s.MaxBodyBytes)
+	}
+
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+
+	var writeMu sync.Mutex
+	send := func(response mcp.JSONRPCResponse) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		websocket.JSON.Send(ws, response)
+	}
+
+	// Once the client is gone, cancel the requests still running and wait
+	// for them before closing the connection
+	var inFlight sync.WaitGroup
+	slots := make(chan struct{}, webSocketInFlight)
+	defer inFlight.Wait()
+	defer cancel()
+
+	for {
+		var frame []byte
+		if err := websocket.Message.Receive(ws, &frame); err != nil {
+			// The client disconnected or sent a frame that is too large
+			return
+		}
+
+		var request mcp.JSONRPCRequest
+		if err := json.Unmarshal(frame, &request); err != nil {
+			send(rpcError(nil, mcp.JSONRPCParseError, "Failed to parse request"))
+			continue
+		}
+		if request.JSONRPC != mcp.JSONRPCVersion || request.Method == "" {
+			send(rpcError(request.ID, mcp.JSONRPCInvalidRequest, "Invalid JSON-RPC 2.0 request"))
+			continue
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		inFlight.Add(1)
+		go func() {
+			defer inFlight.Done()
+			defer func() { <-slots }()
+
+			requestCtx := ctx
+			if s.RequestTimeout > 0 {
+				var cancelRequest context.CancelFunc
+				requestCtx, cancelRequest = context.WithTimeout(ctx, s.RequestTimeout)
+				defer cancelRequest()
+			}
+			result, rpcErr := s.dispatchRPC(requestCtx, request)
+
+			// Notifications never get a response
+			if len(request.ID) == 0 {
+				return
+			}
+			response := mcp.JSONRPCResponse{JSONRPC: mcp.JSONRPCVersion, ID: request.ID}
+			if rpcErr != nil {
+				response.Error = rpcErr
+			} else {
+				response.Result = result
+			}
+			send(response)
+		}()
+	}
+}